@@ -3,3 +3,5 @@ if [ "" = "$GOPATH" ]; then
 	GOPATH="$(realpath ~/go)"
 fi
 go build -buildmode=exe -o $GOPATH/bin/ github.com/hellgate75/k8s-deploy/cmd/k8srepo/...
+go build -buildmode=exe -o $GOPATH/bin/ github.com/hellgate75/k8s-deploy/cmd/scheduler/...
+go build -buildmode=exe -o $GOPATH/bin/ github.com/hellgate75/k8s-deploy/cmd/executor/...
@@ -196,7 +196,7 @@ func main() {
 		os.Exit(1)
 	}
 	// Create Data Store
-	var dataManager model.RepositoryDataManager
+	var dataManager model.DataManager
 	// Read from remote db or local folder
 	if mongoDbEnabled {
 		driver := mongodb.GetMongoDriver()
@@ -209,9 +209,9 @@ func main() {
 			logger.Fatalf("%s is unable to connect to mongo db, reason: %s", ApplicationFullName, err.Error())
 			os.Exit(1)
 		}
-		dataManager = data.GetMongoRepositoryDataManager(conn, rwDirPath, repositoryStorageManager, logger)
+		dataManager = data.GetMongoDataManager(conn, storageNamePrefix, rwDirPath, repositoryStorageManager, logger)
 	} else {
-		dataManager = data.GetDeviceRepositoryDataManager(rwDirPath, repositoryStorageManager, logger)
+		dataManager = data.GetDeviceDataManager(rwDirPath, repositoryStorageManager, logger)
 	}
 	// Handler stuf for the API service groups
 	apiHandler := func(service services.RestService) http.HandlerFunc {
@@ -245,7 +245,7 @@ func main() {
 	// Creates/Sets API endpoints handlers
 	err = services.CreateApiEndpoints(rtr, withAuth, apiHandler,
 		logger, fmt.Sprintf("%s://%s:%v", proto, listenIP, listenPort),
-		services.RepositoryEndpoint, config, dataManager, repositoryStorageManager)
+		services.RepositoryEndpoint, config, dataManager, repositoryStorageManager, nil, nil)
 	if err != nil {
 		logger.Infof("%s RestService start-up:: Error creating API endpoints: %s\n", ApplicationFullName, err.Error())
 		os.Exit(1)
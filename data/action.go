@@ -12,14 +12,48 @@ func GetDeviceRepositoryDataManager(baseFolder string, manager model.RepositoryS
 	return device.GetRepositoryDataManager(baseFolder, manager, logger)
 }
 
-func GetMongoRepositoryDataManager(conn database.Connection, baseFolder string, manager model.RepositoryStorageManager, logger log.Logger) model.RepositoryDataManager {
-	return mongo.GetRepositoryDataManager(conn, baseFolder, manager, logger)
+func GetMongoRepositoryDataManager(conn database.Connection, databaseName string, baseFolder string, manager model.RepositoryStorageManager, logger log.Logger) model.RepositoryDataManager {
+	return mongo.GetRepositoryDataManager(conn, databaseName, baseFolder, manager, logger)
 }
 
 func GetDeviceDocumentsDataManager(baseFolder string, repo *model.Repository) model.DocumentsDataManager {
 	return device.GetDocumentDataManager(baseFolder, repo)
 }
 
-func GetMongoDocumentsDataManager(conn database.Connection, repo *model.Repository) model.DocumentsDataManager {
-	return mongo.GetDocumentDataManager(conn, repo)
+func GetMongoDocumentsDataManager(conn database.Connection, databaseName string, repo *model.Repository) model.DocumentsDataManager {
+	return mongo.GetDocumentDataManager(conn, databaseName, repo)
+}
+
+func GetDeviceProjectDataManager(baseFolder string, logger log.Logger) model.ProjectDataManager {
+	return device.GetProjectDataManager(baseFolder, logger)
+}
+
+func GetMongoProjectDataManager(conn database.Connection, databaseName string, logger log.Logger) model.ProjectDataManager {
+	return mongo.GetProjectDataManager(conn, databaseName, logger)
+}
+
+func GetDeviceDeployDataManager(baseFolder string, logger log.Logger) model.DeployDataManager {
+	return device.GetDeployDataManager(baseFolder, logger)
+}
+
+func GetMongoDeployDataManager(conn database.Connection, databaseName string, logger log.Logger) model.DeployDataManager {
+	return mongo.GetDeployDataManager(conn, databaseName, logger)
+}
+
+// Gets the global data manager, storing data in the device folders
+func GetDeviceDataManager(baseFolder string, manager model.RepositoryStorageManager, logger log.Logger) model.DataManager {
+	return model.DataManager{
+		Repos:    GetDeviceRepositoryDataManager(baseFolder, manager, logger),
+		Projects: GetDeviceProjectDataManager(baseFolder, logger),
+		Deploys:  GetDeviceDeployDataManager(baseFolder, logger),
+	}
+}
+
+// Gets the global data manager, storing data in MongoDb, charts and Kubernetes files payloads stay in the device folders
+func GetMongoDataManager(conn database.Connection, databaseName string, baseFolder string, manager model.RepositoryStorageManager, logger log.Logger) model.DataManager {
+	return model.DataManager{
+		Repos:    GetMongoRepositoryDataManager(conn, databaseName, baseFolder, manager, logger),
+		Projects: GetMongoProjectDataManager(conn, databaseName, logger),
+		Deploys:  GetMongoDeployDataManager(conn, databaseName, logger),
+	}
 }
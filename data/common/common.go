@@ -0,0 +1,39 @@
+package common
+
+import (
+	"fmt"
+	"github.com/hellgate75/k8s-deploy/model"
+)
+
+// Compose a data response, the response is successful when no error message is provided
+func dataResponse(objects []interface{}, changes int64, message string) model.DataResponse {
+	if len(message) == 0 {
+		return model.DataResponse{
+			Success:         true,
+			Message:         "OK",
+			Changes:         changes,
+			ResponseObjects: objects,
+		}
+	}
+	return model.DataResponse{
+		Success:         false,
+		Message:         message,
+		Changes:         changes,
+		ResponseObjects: objects,
+	}
+}
+
+func errorResponse(format string, in ...interface{}) model.DataResponse {
+	return model.DataResponse{
+		Success:         false,
+		Message:         fmt.Sprintf(format, in...),
+		ResponseObjects: make([]interface{}, 0),
+	}
+}
+
+func appendMessage(message string, format string, in ...interface{}) string {
+	if len(message) > 0 {
+		message += ", "
+	}
+	return message + fmt.Sprintf(format, in...)
+}
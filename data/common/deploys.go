@@ -0,0 +1,534 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hellgate75/k8s-deploy/log"
+	"github.com/hellgate75/k8s-deploy/model"
+	"github.com/hellgate75/k8s-deploy/utils"
+	model2 "github.com/hellgate75/k8s-deploy/utils/model"
+	"strings"
+	"sync"
+)
+
+// Describes the deploys persistence used by the deploys and jobs data managers
+type DeployStore interface {
+	// Load all stored deploys
+	LoadDeploys() ([]model.Deploy, error)
+	// Store a new deploy
+	InsertDeploy(d model.Deploy) error
+	// Save an existing deploy, including its jobs
+	SaveDeploy(d model.Deploy) error
+	// Delete permanently an existing deploy, including its jobs
+	DeleteDeploy(d model.Deploy) error
+}
+
+func checkDeployValue(d model.Deploy, key string, value string, cond model.Aggregator) bool {
+	switch key {
+	case "id":
+		return model2.CompareValues(d.Id, value, model2.DataTypeString, cond)
+	case "name":
+		return model2.CompareValues(d.Name, value, model2.DataTypeString, cond)
+	case "state":
+		return model2.CompareValues(string(d.State), value, model2.DataTypeString, cond)
+	case "jobs":
+		return model2.CompareValues(fmt.Sprintf("%v", len(d.Job)), value, model2.DataTypeNumber, cond)
+	}
+	return false
+}
+
+// Jobs are identified by the job instance id and name
+func checkJobValue(j model.Job, key string, value string, cond model.Aggregator) bool {
+	switch key {
+	case "id":
+		return model2.CompareValues(j.Instance.Id, value, model2.DataTypeString, cond)
+	case "name":
+		return model2.CompareValues(j.Instance.Name, value, model2.DataTypeString, cond)
+	case "state":
+		return model2.CompareValues(string(j.State), value, model2.DataTypeString, cond)
+	case "projectid", "project":
+		return model2.CompareValues(j.ProjectId, value, model2.DataTypeString, cond)
+	case "versionid", "version":
+		return model2.CompareValues(j.VersionId, value, model2.DataTypeString, cond)
+	case "documentid", "document":
+		return model2.CompareValues(j.DocumentId, value, model2.DataTypeString, cond)
+	case "ischart", "chart":
+		return model2.CompareValues(fmt.Sprintf("%v", j.IsChart), value, model2.DataTypeBool, cond)
+	}
+	return false
+}
+
+// Verify if a deploy matches all the queries
+func MatchDeploy(d model.Deploy, q ...model.Query) bool {
+	return model2.MatchQueries(func(key string, value string, cond model.Aggregator) bool {
+		return checkDeployValue(d, key, value, cond)
+	}, q...)
+}
+
+// Verify if a job matches all the queries
+func MatchJob(j model.Job, q ...model.Query) bool {
+	return model2.MatchQueries(func(key string, value string, cond model.Aggregator) bool {
+		return checkJobValue(j, key, value, cond)
+	}, q...)
+}
+
+// Assign identifier and default state to a job, job instance name is required
+func prepareJob(j model.Job) (model.Job, error) {
+	j.Instance.Name = strings.TrimSpace(j.Instance.Name)
+	if j.Instance.Name == "" {
+		return j, errors.New("Job instance name is required")
+	}
+	if j.Instance.Id == "" {
+		j.Instance.Id = utils.NewUniqueIdentifier()
+	}
+	if j.State == "" {
+		j.State = model.StateCreated
+	}
+	if j.Instance.State == "" {
+		j.Instance.State = j.State
+	}
+	return j, nil
+}
+
+// Assign identifier and default state to a deploy and its jobs, job names are unique in a deploy
+func prepareDeploy(d model.Deploy) (model.Deploy, error) {
+	d.Name = strings.TrimSpace(d.Name)
+	if d.Name == "" {
+		return d, errors.New("Deploy name is required")
+	}
+	if d.Id == "" {
+		d.Id = utils.NewUniqueIdentifier()
+	}
+	if d.State == "" {
+		d.State = model.StateCreated
+	}
+	if d.Job == nil {
+		d.Job = make([]model.Job, 0)
+	}
+	var names = make(map[string]bool)
+	for i := range d.Job {
+		j, err := prepareJob(d.Job[i])
+		if err != nil {
+			return d, err
+		}
+		if names[j.Instance.Name] {
+			return d, errors.New(fmt.Sprintf("Duplicate job %s", j.Instance.Name))
+		}
+		names[j.Instance.Name] = true
+		d.Job[i] = j
+	}
+	return d, nil
+}
+
+func transitionError(name string, from model.State, to model.State) error {
+	return errors.New(fmt.Sprintf("%s cannot change state from %s to %s", name, from, to))
+}
+
+type deploysManager struct {
+	sync.Mutex
+	store  DeployStore
+	logger log.Logger
+}
+
+func (dm *deploysManager) filter(q ...model.Query) ([]model.Deploy, error) {
+	list, err := dm.store.LoadDeploys()
+	if err != nil {
+		return nil, err
+	}
+	var out = make([]model.Deploy, 0)
+	for _, d := range list {
+		if MatchDeploy(d, q...) {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+// Find a deploy by id or, when the id is empty, by name
+func (dm *deploysManager) find(id string, name string) (*model.Deploy, error) {
+	list, err := dm.store.LoadDeploys()
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range list {
+		if (id != "" && d.Id == id) || (id == "" && d.Name == strings.TrimSpace(name)) {
+			return &d, nil
+		}
+	}
+	if id != "" {
+		return nil, errors.New(fmt.Sprintf("Deploy id: %s not found", id))
+	}
+	return nil, errors.New(fmt.Sprintf("Deploy %s not found", name))
+}
+
+// Apply a change to a single deploy
+func (dm *deploysManager) updateOne(id string, name string, change func(d *model.Deploy) error) model.DataResponse {
+	dm.Lock()
+	defer dm.Unlock()
+	d, err := dm.find(id, name)
+	if err != nil {
+		return errorResponse("%v", err)
+	}
+	if err := change(d); err != nil {
+		return errorResponse("Error updating deploy %s, error: %v", d.Name, err)
+	}
+	if err := dm.store.SaveDeploy(*d); err != nil {
+		return errorResponse("Error saving deploy %s, error: %v", d.Name, err)
+	}
+	return dataResponse([]interface{}{*d}, 1, "")
+}
+
+func (dm *deploysManager) ListDeploys() model.DataResponse {
+	return dm.QueryDeploys()
+}
+
+func (dm *deploysManager) QueryDeploys(q ...model.Query) model.DataResponse {
+	list, err := dm.filter(q...)
+	if err != nil {
+		return errorResponse("Error loading deploys, error: %v", err)
+	}
+	var objects = make([]interface{}, 0)
+	for _, d := range list {
+		objects = append(objects, d)
+	}
+	return dataResponse(objects, 0, "")
+}
+
+func (dm *deploysManager) AddDeploy(d model.Deploy) model.DataResponse {
+	dm.Lock()
+	defer dm.Unlock()
+	d, err := prepareDeploy(d)
+	if err != nil {
+		return errorResponse("Error creating deploy: %s, error: %v", d.Name, err)
+	}
+	list, err := dm.store.LoadDeploys()
+	if err != nil {
+		return errorResponse("Error loading deploys, error: %v", err)
+	}
+	for _, cd := range list {
+		if cd.Name == d.Name || cd.Id == d.Id {
+			return errorResponse("Error creating deploy: %s, error: deploy already exists with id: %s", d.Name, cd.Id)
+		}
+	}
+	if err := dm.store.InsertDeploy(d); err != nil {
+		return errorResponse("Error creating deploy: %s, error: %v", d.Name, err)
+	}
+	return dataResponse([]interface{}{d}, 1, "")
+}
+
+// Soft delete the matching deploys, deploys that cannot change to deleted state (e.g.: running) are refused
+func (dm *deploysManager) DeleteDeploys(q ...model.Query) model.DataResponse {
+	dm.Lock()
+	defer dm.Unlock()
+	list, err := dm.filter(q...)
+	if err != nil {
+		return errorResponse("Error loading deploys, error: %v", err)
+	}
+	var objects = make([]interface{}, 0)
+	var message = ""
+	for _, d := range list {
+		if !d.State.CanTransitionTo(model.StateDeleted) {
+			message = appendMessage(message, "deploy: %s - Error: %v", d.Name, transitionError(d.Name, d.State, model.StateDeleted))
+			continue
+		}
+		d.State = model.StateDeleted
+		if err := dm.store.SaveDeploy(d); err != nil {
+			message = appendMessage(message, "deploy: %s - Error: %v", d.Name, err)
+			continue
+		}
+		objects = append(objects, d)
+	}
+	return dataResponse(objects, int64(len(objects)), message)
+}
+
+// Purge permanently the matching deploys, running deploys are refused
+func (dm *deploysManager) PurgeDeploys(q ...model.Query) model.DataResponse {
+	dm.Lock()
+	defer dm.Unlock()
+	list, err := dm.filter(q...)
+	if err != nil {
+		return errorResponse("Error loading deploys, error: %v", err)
+	}
+	var objects = make([]interface{}, 0)
+	var message = ""
+	for _, d := range list {
+		if d.State == model.StateRunning {
+			message = appendMessage(message, "deploy: %s - Error: %v", d.Name, transitionError(d.Name, d.State, model.StatePutged))
+			continue
+		}
+		if err := dm.store.DeleteDeploy(d); err != nil {
+			message = appendMessage(message, "deploy: %s - Error: %v", d.Name, err)
+			continue
+		}
+		d.State = model.StatePutged
+		objects = append(objects, d)
+	}
+	return dataResponse(objects, int64(len(objects)), message)
+}
+
+// Remove all jobs of a deploy
+func clearDeploy(d *model.Deploy) error {
+	if d.State == model.StateRunning {
+		return errors.New(fmt.Sprintf("Deploy %s is running", d.Name))
+	}
+	d.Job = make([]model.Job, 0)
+	return nil
+}
+
+func (dm *deploysManager) ClearDeploy(id string) model.DataResponse {
+	return dm.updateOne(id, "", clearDeploy)
+}
+
+func (dm *deploysManager) ClearDeployByName(name string) model.DataResponse {
+	return dm.updateOne("", name, clearDeploy)
+}
+
+func (dm *deploysManager) GetDeploy(id string) *model.Deploy {
+	d, err := dm.find(id, "")
+	if err != nil {
+		if dm.logger != nil {
+			dm.logger.Debugf("DeploysManager::GetDeploy() - %v", err)
+		}
+		return nil
+	}
+	return d
+}
+
+func (dm *deploysManager) GetDeployByName(name string) *model.Deploy {
+	d, err := dm.find("", name)
+	if err != nil {
+		if dm.logger != nil {
+			dm.logger.Debugf("DeploysManager::GetDeployByName() - %v", err)
+		}
+		return nil
+	}
+	return d
+}
+
+func (dm *deploysManager) AccessDeploy(d model.Deploy) *model.JobsDataManager {
+	var jm model.JobsDataManager = &jobsManager{
+		deploys:  dm,
+		deployId: d.Id,
+	}
+	return &jm
+}
+
+func (dm *deploysManager) OverrideDeploy(id string, d model.Deploy) model.DataResponse {
+	dm.Lock()
+	defer dm.Unlock()
+	current, err := dm.find(id, "")
+	if err != nil {
+		return errorResponse("%v", err)
+	}
+	d.Id = id
+	if strings.TrimSpace(d.Name) == "" {
+		d.Name = current.Name
+	}
+	if d.State == "" {
+		d.State = current.State
+	}
+	d, err = prepareDeploy(d)
+	if err != nil {
+		return errorResponse("Error overriding deploy id: %s, error: %v", id, err)
+	}
+	if !current.State.CanTransitionTo(d.State) {
+		return errorResponse("Error overriding deploy id: %s, error: %v", id, transitionError(current.Name, current.State, d.State))
+	}
+	if d.Name != current.Name {
+		if _, err := dm.find("", d.Name); err == nil {
+			return errorResponse("Error overriding deploy id: %s, error: deploy name %s already in use", id, d.Name)
+		}
+	}
+	if err := dm.store.SaveDeploy(d); err != nil {
+		return errorResponse("Error saving deploy %s, error: %v", d.Name, err)
+	}
+	return dataResponse([]interface{}{d}, 1, "")
+}
+
+func (dm *deploysManager) UpdateDeployState(id string, s model.State) model.DataResponse {
+	return dm.updateOne(id, "", func(d *model.Deploy) error {
+		if !d.State.CanTransitionTo(s) {
+			return transitionError(d.Name, d.State, s)
+		}
+		d.State = s
+		return nil
+	})
+}
+
+// Manages the jobs of a deploy, jobs are stored in the deploy record
+type jobsManager struct {
+	deploys  *deploysManager
+	deployId string
+}
+
+// Apply a change to the deploy jobs
+func (jm *jobsManager) update(change func(d *model.Deploy) ([]interface{}, string)) model.DataResponse {
+	jm.deploys.Lock()
+	defer jm.deploys.Unlock()
+	d, err := jm.deploys.find(jm.deployId, "")
+	if err != nil {
+		return errorResponse("%v", err)
+	}
+	objects, message := change(d)
+	if len(objects) > 0 {
+		if err := jm.deploys.store.SaveDeploy(*d); err != nil {
+			return errorResponse("Error saving deploy %s, error: %v", d.Name, err)
+		}
+	}
+	return dataResponse(objects, int64(len(objects)), message)
+}
+
+func (jm *jobsManager) find(id string, name string) *model.Job {
+	d, err := jm.deploys.find(jm.deployId, "")
+	if err != nil {
+		return nil
+	}
+	for _, j := range d.Job {
+		if (id != "" && j.Instance.Id == id) || (id == "" && j.Instance.Name == strings.TrimSpace(name)) {
+			return &j
+		}
+	}
+	return nil
+}
+
+func (jm *jobsManager) ListJobs() model.DataResponse {
+	return jm.QueryJobs()
+}
+
+func (jm *jobsManager) QueryJobs(q ...model.Query) model.DataResponse {
+	d, err := jm.deploys.find(jm.deployId, "")
+	if err != nil {
+		return errorResponse("%v", err)
+	}
+	var objects = make([]interface{}, 0)
+	for _, j := range d.Job {
+		if MatchJob(j, q...) {
+			objects = append(objects, j)
+		}
+	}
+	return dataResponse(objects, 0, "")
+}
+
+func (jm *jobsManager) AddJob(j model.Job) model.DataResponse {
+	return jm.update(func(d *model.Deploy) ([]interface{}, string) {
+		j, err := prepareJob(j)
+		if err != nil {
+			return nil, fmt.Sprintf("Error adding job to deploy %s, error: %v", d.Name, err)
+		}
+		for _, cj := range d.Job {
+			if cj.Instance.Name == j.Instance.Name || cj.Instance.Id == j.Instance.Id {
+				return nil, fmt.Sprintf("Error adding job to deploy %s, error: job %s already exists", d.Name, j.Instance.Name)
+			}
+		}
+		d.Job = append(d.Job, j)
+		return []interface{}{j}, ""
+	})
+}
+
+// Soft delete the matching jobs, jobs that cannot change to deleted state (e.g.: running) are refused
+func (jm *jobsManager) DeleteJobs(q ...model.Query) model.DataResponse {
+	return jm.update(func(d *model.Deploy) ([]interface{}, string) {
+		var objects = make([]interface{}, 0)
+		var message = ""
+		for i := range d.Job {
+			if !MatchJob(d.Job[i], q...) {
+				continue
+			}
+			if !d.Job[i].State.CanTransitionTo(model.StateDeleted) {
+				message = appendMessage(message, "job: %s - Error: %v", d.Job[i].Instance.Name, transitionError(d.Job[i].Instance.Name, d.Job[i].State, model.StateDeleted))
+				continue
+			}
+			d.Job[i].State = model.StateDeleted
+			objects = append(objects, d.Job[i])
+		}
+		return objects, message
+	})
+}
+
+// Purge permanently the matching jobs, running jobs are refused
+func (jm *jobsManager) PurgeJobs(q ...model.Query) model.DataResponse {
+	return jm.update(func(d *model.Deploy) ([]interface{}, string) {
+		var objects = make([]interface{}, 0)
+		var jobs = make([]model.Job, 0)
+		var message = ""
+		for _, j := range d.Job {
+			if !MatchJob(j, q...) {
+				jobs = append(jobs, j)
+				continue
+			}
+			if j.State == model.StateRunning {
+				message = appendMessage(message, "job: %s - Error: %v", j.Instance.Name, transitionError(j.Instance.Name, j.State, model.StatePutged))
+				jobs = append(jobs, j)
+				continue
+			}
+			j.State = model.StatePutged
+			objects = append(objects, j)
+		}
+		d.Job = jobs
+		return objects, message
+	})
+}
+
+func (jm *jobsManager) GetJob(id string) *model.Job {
+	return jm.find(id, "")
+}
+
+func (jm *jobsManager) GetJobByName(name string) *model.Job {
+	return jm.find("", name)
+}
+
+func (jm *jobsManager) OverrideJob(id string, j model.Job) model.DataResponse {
+	return jm.update(func(d *model.Deploy) ([]interface{}, string) {
+		for i := range d.Job {
+			if d.Job[i].Instance.Id != id {
+				continue
+			}
+			j.Instance.Id = id
+			if strings.TrimSpace(j.Instance.Name) == "" {
+				j.Instance.Name = d.Job[i].Instance.Name
+			}
+			if j.State == "" {
+				j.State = d.Job[i].State
+			}
+			nj, err := prepareJob(j)
+			if err != nil {
+				return nil, fmt.Sprintf("Error overriding job id: %s, error: %v", id, err)
+			}
+			if !d.Job[i].State.CanTransitionTo(nj.State) {
+				return nil, fmt.Sprintf("Error overriding job id: %s, error: %v", id, transitionError(d.Job[i].Instance.Name, d.Job[i].State, nj.State))
+			}
+			for k, cj := range d.Job {
+				if k != i && cj.Instance.Name == nj.Instance.Name {
+					return nil, fmt.Sprintf("Error overriding job id: %s, error: job name %s already in use", id, nj.Instance.Name)
+				}
+			}
+			d.Job[i] = nj
+			return []interface{}{nj}, ""
+		}
+		return nil, fmt.Sprintf("Job id: %s not found in deploy %s", id, d.Name)
+	})
+}
+
+func (jm *jobsManager) UpdateJobState(id string, s model.State) model.DataResponse {
+	return jm.update(func(d *model.Deploy) ([]interface{}, string) {
+		for i := range d.Job {
+			if d.Job[i].Instance.Id != id {
+				continue
+			}
+			if !d.Job[i].State.CanTransitionTo(s) {
+				return nil, transitionError(d.Job[i].Instance.Name, d.Job[i].State, s).Error()
+			}
+			d.Job[i].State = s
+			return []interface{}{d.Job[i]}, ""
+		}
+		return nil, fmt.Sprintf("Job id: %s not found in deploy %s", id, d.Name)
+	})
+}
+
+// Create a deploys data manager, storing deploys and their jobs via the given store
+func NewDeployDataManager(store DeployStore, logger log.Logger) model.DeployDataManager {
+	return &deploysManager{
+		store:  store,
+		logger: logger,
+	}
+}
@@ -0,0 +1,561 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hellgate75/k8s-deploy/log"
+	"github.com/hellgate75/k8s-deploy/model"
+	"github.com/hellgate75/k8s-deploy/utils"
+	model2 "github.com/hellgate75/k8s-deploy/utils/model"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Describes the projects persistence used by the projects data manager
+type ProjectStore interface {
+	// Load all stored projects
+	LoadProjects() ([]model.Project, error)
+	// Store a new project
+	InsertProject(p model.Project) error
+	// Save an existing project
+	SaveProject(p model.Project) error
+	// Delete permanently an existing project
+	DeleteProject(p model.Project) error
+}
+
+// Project identifiers are used as storage keys and file names, so only safe characters are allowed
+var projectIdRegexp = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+// Verify a project identifier is safe to be used as a file name
+func ValidateProjectId(id string) error {
+	if !projectIdRegexp.MatchString(id) || strings.Contains(id, "..") {
+		return errors.New(fmt.Sprintf("Invalid project id: %s, allowed characters are letters, digits, '.', '_' and '-'", id))
+	}
+	return nil
+}
+
+func checkProjectValue(p model.Project, key string, value string, cond model.Aggregator) bool {
+	switch key {
+	case "id":
+		return model2.CompareValues(p.Id, value, model2.DataTypeString, cond)
+	case "name":
+		return model2.CompareValues(p.Name, value, model2.DataTypeString, cond)
+	case "version":
+		return model2.CompareValues(p.Version, value, model2.DataTypeString, cond)
+	case "state":
+		return model2.CompareValues(string(p.State), value, model2.DataTypeString, cond)
+	case "readonly":
+		return model2.CompareValues(fmt.Sprintf("%v", p.ReadOnly), value, model2.DataTypeBool, cond)
+	case "versions":
+		return model2.CompareValues(fmt.Sprintf("%v", len(p.Versions)), value, model2.DataTypeNumber, cond)
+	}
+	return false
+}
+
+func checkProjectVersionValue(v model.ProjectVersion, key string, value string, cond model.Aggregator) bool {
+	switch key {
+	case "version", "name":
+		return model2.CompareValues(v.Version, value, model2.DataTypeString, cond)
+	case "state":
+		return model2.CompareValues(string(v.State), value, model2.DataTypeString, cond)
+	case "charts":
+		return model2.CompareValues(fmt.Sprintf("%v", len(v.Charts)), value, model2.DataTypeNumber, cond)
+	case "kubefiles":
+		return model2.CompareValues(fmt.Sprintf("%v", len(v.KubeFiles)), value, model2.DataTypeNumber, cond)
+	case "variables":
+		return model2.CompareValues(fmt.Sprintf("%v", len(v.Variables)), value, model2.DataTypeNumber, cond)
+	}
+	return false
+}
+
+// Verify if a project matches all the queries
+func MatchProject(p model.Project, q ...model.Query) bool {
+	return model2.MatchQueries(func(key string, value string, cond model.Aggregator) bool {
+		return checkProjectValue(p, key, value, cond)
+	}, q...)
+}
+
+// Verify if a project version matches all the queries
+func MatchProjectVersion(v model.ProjectVersion, q ...model.Query) bool {
+	return model2.MatchQueries(func(key string, value string, cond model.Aggregator) bool {
+		return checkProjectVersionValue(v, key, value, cond)
+	}, q...)
+}
+
+// Assign identifiers and default state to a project version and its charts, Kubernetes files and variables
+func prepareProjectVersion(v model.ProjectVersion) model.ProjectVersion {
+	v.Version = strings.TrimSpace(v.Version)
+	if v.State == "" {
+		v.State = model.StateCreated
+	}
+	if v.Charts == nil {
+		v.Charts = make([]model.ProjectChart, 0)
+	}
+	if v.KubeFiles == nil {
+		v.KubeFiles = make([]model.ProjectKubeFile, 0)
+	}
+	if v.Variables == nil {
+		v.Variables = make([]model.Variable, 0)
+	}
+	for i := range v.Charts {
+		if v.Charts[i].Id == "" {
+			v.Charts[i].Id = utils.NewUniqueIdentifier()
+		}
+		if v.Charts[i].State == "" {
+			v.Charts[i].State = model.StateCreated
+		}
+	}
+	for i := range v.KubeFiles {
+		if v.KubeFiles[i].Id == "" {
+			v.KubeFiles[i].Id = utils.NewUniqueIdentifier()
+		}
+		if v.KubeFiles[i].State == "" {
+			v.KubeFiles[i].State = model.StateCreated
+		}
+	}
+	for i := range v.Variables {
+		if v.Variables[i].Id == "" {
+			v.Variables[i].Id = utils.NewUniqueIdentifier()
+		}
+		for j := range v.Variables[i].Rules {
+			if v.Variables[i].Rules[j].Id == "" {
+				v.Variables[i].Rules[j].Id = utils.NewUniqueIdentifier()
+			}
+		}
+	}
+	return v
+}
+
+// Verify a project version content: version is required, chart, Kubernetes file and variable names are required and unique
+func verifyProjectVersion(v model.ProjectVersion) error {
+	if v.Version == "" {
+		return errors.New("Project version is required")
+	}
+	var names = make(map[string]bool)
+	for _, c := range v.Charts {
+		if strings.TrimSpace(c.Name) == "" || strings.TrimSpace(c.Version) == "" {
+			return errors.New(fmt.Sprintf("Project version %s: chart name and version are required", v.Version))
+		}
+		if names["chart:"+c.Name] {
+			return errors.New(fmt.Sprintf("Project version %s: duplicate chart %s", v.Version, c.Name))
+		}
+		names["chart:"+c.Name] = true
+	}
+	for _, f := range v.KubeFiles {
+		if strings.TrimSpace(f.Name) == "" || strings.TrimSpace(f.Version) == "" {
+			return errors.New(fmt.Sprintf("Project version %s: Kubernetes file name and version are required", v.Version))
+		}
+		if names["kubefile:"+f.Name] {
+			return errors.New(fmt.Sprintf("Project version %s: duplicate Kubernetes file %s", v.Version, f.Name))
+		}
+		names["kubefile:"+f.Name] = true
+	}
+	for _, vr := range v.Variables {
+		if strings.TrimSpace(vr.Name) == "" {
+			return errors.New(fmt.Sprintf("Project version %s: variable name is required", v.Version))
+		}
+		if names["variable:"+vr.Name] {
+			return errors.New(fmt.Sprintf("Project version %s: duplicate variable %s", v.Version, vr.Name))
+		}
+		names["variable:"+vr.Name] = true
+	}
+	return nil
+}
+
+// Assign identifier and default state to a project and verify its versions
+func prepareProject(p model.Project) (model.Project, error) {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return p, errors.New("Project name is required")
+	}
+	if p.Id == "" {
+		p.Id = utils.NewUniqueIdentifier()
+	}
+	if err := ValidateProjectId(p.Id); err != nil {
+		return p, err
+	}
+	if p.State == "" {
+		p.State = model.StateCreated
+	}
+	if p.Versions == nil {
+		p.Versions = make([]model.ProjectVersion, 0)
+	}
+	var versions = make(map[string]bool)
+	for i := range p.Versions {
+		p.Versions[i] = prepareProjectVersion(p.Versions[i])
+		if err := verifyProjectVersion(p.Versions[i]); err != nil {
+			return p, err
+		}
+		if versions[p.Versions[i].Version] {
+			return p, errors.New(fmt.Sprintf("Duplicate project version %s", p.Versions[i].Version))
+		}
+		versions[p.Versions[i].Version] = true
+	}
+	if p.Version == "" && len(p.Versions) > 0 {
+		p.Version = p.Versions[len(p.Versions)-1].Version
+	}
+	return p, nil
+}
+
+func readOnlyError(p model.Project) error {
+	return errors.New(fmt.Sprintf("Project %s is read-only", p.Name))
+}
+
+type projectsManager struct {
+	sync.Mutex
+	store  ProjectStore
+	logger log.Logger
+}
+
+func (pm *projectsManager) filter(q ...model.Query) ([]model.Project, error) {
+	list, err := pm.store.LoadProjects()
+	if err != nil {
+		return nil, err
+	}
+	var out = make([]model.Project, 0)
+	for _, p := range list {
+		if MatchProject(p, q...) {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+// Find a project by id or, when the id is empty, by name
+func (pm *projectsManager) find(id string, name string) (*model.Project, error) {
+	list, err := pm.store.LoadProjects()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range list {
+		if (id != "" && p.Id == id) || (id == "" && p.Name == strings.TrimSpace(name)) {
+			return &p, nil
+		}
+	}
+	if id != "" {
+		return nil, errors.New(fmt.Sprintf("Project id: %s not found", id))
+	}
+	return nil, errors.New(fmt.Sprintf("Project %s not found", name))
+}
+
+// Apply a change to all the projects matching the queries, read-only projects are refused
+func (pm *projectsManager) update(change func(p *model.Project) error, q ...model.Query) model.DataResponse {
+	pm.Lock()
+	defer pm.Unlock()
+	list, err := pm.filter(q...)
+	if err != nil {
+		return errorResponse("Error loading projects, error: %v", err)
+	}
+	var objects = make([]interface{}, 0)
+	var message = ""
+	for _, p := range list {
+		if p.ReadOnly {
+			message = appendMessage(message, "project: %s - Error: %v", p.Name, readOnlyError(p))
+			continue
+		}
+		if err := change(&p); err != nil {
+			message = appendMessage(message, "project: %s - Error: %v", p.Name, err)
+			continue
+		}
+		if err := pm.store.SaveProject(p); err != nil {
+			message = appendMessage(message, "project: %s - Error: %v", p.Name, err)
+			continue
+		}
+		objects = append(objects, p)
+	}
+	return dataResponse(objects, int64(len(objects)), message)
+}
+
+// Apply a change to a single project, read-only projects are refused
+func (pm *projectsManager) updateOne(id string, name string, change func(p *model.Project) error) model.DataResponse {
+	pm.Lock()
+	defer pm.Unlock()
+	p, err := pm.find(id, name)
+	if err != nil {
+		return errorResponse("%v", err)
+	}
+	if p.ReadOnly {
+		return errorResponse("%v", readOnlyError(*p))
+	}
+	if err := change(p); err != nil {
+		return errorResponse("Error updating project %s, error: %v", p.Name, err)
+	}
+	if err := pm.store.SaveProject(*p); err != nil {
+		return errorResponse("Error saving project %s, error: %v", p.Name, err)
+	}
+	return dataResponse([]interface{}{*p}, 1, "")
+}
+
+func (pm *projectsManager) ListProjects() model.DataResponse {
+	return pm.QueryProjects()
+}
+
+func (pm *projectsManager) AddProject(p model.Project) model.DataResponse {
+	pm.Lock()
+	defer pm.Unlock()
+	p, err := prepareProject(p)
+	if err != nil {
+		return errorResponse("Error creating project: %s, error: %v", p.Name, err)
+	}
+	list, err := pm.store.LoadProjects()
+	if err != nil {
+		return errorResponse("Error loading projects, error: %v", err)
+	}
+	for _, cp := range list {
+		if cp.Name == p.Name || cp.Id == p.Id {
+			return errorResponse("Error creating project: %s, error: project already exists with id: %s", p.Name, cp.Id)
+		}
+	}
+	if err := pm.store.InsertProject(p); err != nil {
+		return errorResponse("Error creating project: %s, error: %v", p.Name, err)
+	}
+	return dataResponse([]interface{}{p}, 1, "")
+}
+
+func (pm *projectsManager) AddProjectVersion(p model.Project, version model.ProjectVersion) model.DataResponse {
+	version = prepareProjectVersion(version)
+	return pm.updateOne(p.Id, p.Name, func(cp *model.Project) error {
+		if err := verifyProjectVersion(version); err != nil {
+			return err
+		}
+		for _, v := range cp.Versions {
+			if v.Version == version.Version {
+				return errors.New(fmt.Sprintf("Project version %s already exists", version.Version))
+			}
+		}
+		cp.Versions = append(cp.Versions, version)
+		cp.Version = version.Version
+		return nil
+	})
+}
+
+func (pm *projectsManager) DeleteProjects(q ...model.Query) model.DataResponse {
+	return pm.update(func(p *model.Project) error {
+		p.State = model.StateDeleted
+		return nil
+	}, q...)
+}
+
+func (pm *projectsManager) DeleteProjectVersions(p model.Project, q ...model.Query) model.DataResponse {
+	return pm.updateOne(p.Id, p.Name, func(cp *model.Project) error {
+		for i := range cp.Versions {
+			if MatchProjectVersion(cp.Versions[i], q...) {
+				cp.Versions[i].State = model.StateDeleted
+			}
+		}
+		return nil
+	})
+}
+
+func (pm *projectsManager) PurgeProjects(q ...model.Query) model.DataResponse {
+	pm.Lock()
+	defer pm.Unlock()
+	list, err := pm.filter(q...)
+	if err != nil {
+		return errorResponse("Error loading projects, error: %v", err)
+	}
+	var objects = make([]interface{}, 0)
+	var message = ""
+	for _, p := range list {
+		if p.ReadOnly {
+			message = appendMessage(message, "project: %s - Error: %v", p.Name, readOnlyError(p))
+			continue
+		}
+		if err := pm.store.DeleteProject(p); err != nil {
+			message = appendMessage(message, "project: %s - Error: %v", p.Name, err)
+			continue
+		}
+		p.State = model.StatePutged
+		objects = append(objects, p)
+	}
+	return dataResponse(objects, int64(len(objects)), message)
+}
+
+func (pm *projectsManager) PurgeProjectVersions(p model.Project, q ...model.Query) model.DataResponse {
+	return pm.updateOne(p.Id, p.Name, func(cp *model.Project) error {
+		var versions = make([]model.ProjectVersion, 0)
+		for _, v := range cp.Versions {
+			if !MatchProjectVersion(v, q...) {
+				versions = append(versions, v)
+			}
+		}
+		cp.Versions = versions
+		if len(versions) == 0 {
+			cp.Version = ""
+		} else if !projectHasVersion(*cp, cp.Version) {
+			cp.Version = versions[len(versions)-1].Version
+		}
+		return nil
+	})
+}
+
+func projectHasVersion(p model.Project, version string) bool {
+	for _, v := range p.Versions {
+		if v.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// Remove all the versions of a project
+func clearProject(p *model.Project) error {
+	p.Versions = make([]model.ProjectVersion, 0)
+	p.Version = ""
+	return nil
+}
+
+func (pm *projectsManager) ClearProject(id string) model.DataResponse {
+	return pm.updateOne(id, "", clearProject)
+}
+
+func (pm *projectsManager) ClearProjectByName(name string) model.DataResponse {
+	return pm.updateOne("", name, clearProject)
+}
+
+func (pm *projectsManager) GetProject(id string) *model.Project {
+	p, err := pm.find(id, "")
+	if err != nil {
+		if pm.logger != nil {
+			pm.logger.Debugf("ProjectsManager::GetProject() - %v", err)
+		}
+		return nil
+	}
+	return p
+}
+
+func (pm *projectsManager) GetProjectByName(name string) *model.Project {
+	p, err := pm.find("", name)
+	if err != nil {
+		if pm.logger != nil {
+			pm.logger.Debugf("ProjectsManager::GetProjectByName() - %v", err)
+		}
+		return nil
+	}
+	return p
+}
+
+func (pm *projectsManager) GetProjectVersions(p model.Project, q ...model.Query) *model.Project {
+	cp, err := pm.find(p.Id, p.Name)
+	if err != nil {
+		if pm.logger != nil {
+			pm.logger.Debugf("ProjectsManager::GetProjectVersions() - %v", err)
+		}
+		return nil
+	}
+	var versions = make([]model.ProjectVersion, 0)
+	for _, v := range cp.Versions {
+		if MatchProjectVersion(v, q...) {
+			versions = append(versions, v)
+		}
+	}
+	cp.Versions = versions
+	return cp
+}
+
+// Override a project, a read-only project can only be unlocked, overriding it with read-only flag off
+func (pm *projectsManager) OverrideProject(id string, p model.Project) model.DataResponse {
+	pm.Lock()
+	defer pm.Unlock()
+	current, err := pm.find(id, "")
+	if err != nil {
+		return errorResponse("%v", err)
+	}
+	if current.ReadOnly {
+		if p.ReadOnly {
+			return errorResponse("%v", readOnlyError(*current))
+		}
+		current.ReadOnly = false
+		if err := pm.store.SaveProject(*current); err != nil {
+			return errorResponse("Error saving project %s, error: %v", current.Name, err)
+		}
+		return dataResponse([]interface{}{*current}, 1, "")
+	}
+	p.Id = id
+	if strings.TrimSpace(p.Name) == "" {
+		p.Name = current.Name
+	}
+	p, err = prepareProject(p)
+	if err != nil {
+		return errorResponse("Error overriding project id: %s, error: %v", id, err)
+	}
+	if p.Name != current.Name {
+		if _, err := pm.find("", p.Name); err == nil {
+			return errorResponse("Error overriding project id: %s, error: project name %s already in use", id, p.Name)
+		}
+	}
+	if err := pm.store.SaveProject(p); err != nil {
+		return errorResponse("Error saving project %s, error: %v", p.Name, err)
+	}
+	return dataResponse([]interface{}{p}, 1, "")
+}
+
+func (pm *projectsManager) OverrideProjectVersions(id string, v model.Version, q ...model.Query) model.DataResponse {
+	return pm.updateOne(id, "", func(cp *model.Project) error {
+		for i := range cp.Versions {
+			if MatchProjectVersion(cp.Versions[i], q...) && v.State != "" {
+				cp.Versions[i].State = v.State
+			}
+		}
+		return nil
+	})
+}
+
+func (pm *projectsManager) QueryProjects(q ...model.Query) model.DataResponse {
+	list, err := pm.filter(q...)
+	if err != nil {
+		return errorResponse("Error loading projects, error: %v", err)
+	}
+	var objects = make([]interface{}, 0)
+	for _, p := range list {
+		objects = append(objects, p)
+	}
+	return dataResponse(objects, 0, "")
+}
+
+// Query the project versions referring a chart, by chart name and, when provided, by chart versions.
+// All project versions are queried when chart name is empty
+func (pm *projectsManager) QueryProjectVersions(c model.Chart, q ...model.Query) model.DataResponse {
+	list, err := pm.store.LoadProjects()
+	if err != nil {
+		return errorResponse("Error loading projects, error: %v", err)
+	}
+	var objects = make([]interface{}, 0)
+	for _, p := range list {
+		for _, v := range p.Versions {
+			if projectVersionRefersChart(v, c) && MatchProjectVersion(v, q...) {
+				objects = append(objects, v)
+			}
+		}
+	}
+	return dataResponse(objects, 0, "")
+}
+
+func projectVersionRefersChart(v model.ProjectVersion, c model.Chart) bool {
+	if strings.TrimSpace(c.Name) == "" {
+		return true
+	}
+	for _, pc := range v.Charts {
+		if pc.Name != c.Name {
+			continue
+		}
+		if len(c.Versions) == 0 {
+			return true
+		}
+		for _, cv := range c.Versions {
+			if cv.Name == pc.Version {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Create a projects data manager, storing projects via the given store
+func NewProjectDataManager(store ProjectStore, logger log.Logger) model.ProjectDataManager {
+	return &projectsManager{
+		store:  store,
+		logger: logger,
+	}
+}
@@ -0,0 +1,80 @@
+package device
+
+import (
+	"fmt"
+	"github.com/hellgate75/k8s-deploy/data/common"
+	"github.com/hellgate75/k8s-deploy/log"
+	"github.com/hellgate75/k8s-deploy/model"
+	"github.com/hellgate75/k8s-deploy/utils"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const (
+	deploysFolder = "deploys"
+)
+
+// Stores each deploy, including its jobs, in its own yaml file, named by deploy id, in the deploys folder
+type deployStore struct {
+	baseDataFolder string
+	logger         log.Logger
+}
+
+func (ds *deployStore) folder() string {
+	return fmt.Sprintf("%s%c%s", ds.baseDataFolder, os.PathSeparator, deploysFolder)
+}
+
+func (ds *deployStore) file(id string) string {
+	return fmt.Sprintf("%s%c%s.%v", ds.folder(), os.PathSeparator, id, documentsFormatExtension)
+}
+
+func (ds *deployStore) LoadDeploys() ([]model.Deploy, error) {
+	var out = make([]model.Deploy, 0)
+	if !utils.ExistsFileOrFolder(ds.folder()) {
+		return out, nil
+	}
+	files, err := ioutil.ReadDir(ds.folder())
+	if err != nil {
+		return nil, err
+	}
+	var suffix = fmt.Sprintf(".%v", documentsFormatExtension)
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), suffix) {
+			continue
+		}
+		var d = model.Deploy{
+			Job: make([]model.Job, 0),
+		}
+		if err := utils.LoadStructureByType(ds.file(strings.TrimSuffix(f.Name(), suffix)), &d, documentsFormatExtension); err != nil {
+			if ds.logger != nil {
+				ds.logger.Warnf("DeviceDeployStore::LoadDeploys() - Unable to load deploy file %s, error: %v", f.Name(), err)
+			}
+			continue
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+func (ds *deployStore) InsertDeploy(d model.Deploy) error {
+	return ds.SaveDeploy(d)
+}
+
+func (ds *deployStore) SaveDeploy(d model.Deploy) error {
+	if err := utils.CreateFolder(ds.folder()); err != nil {
+		return err
+	}
+	return utils.SaveStructureByType(ds.file(d.Id), &d, documentsFormatExtension)
+}
+
+func (ds *deployStore) DeleteDeploy(d model.Deploy) error {
+	return utils.DeleteFileOrFolder(ds.file(d.Id))
+}
+
+func GetDeployDataManager(baseFolder string, logger log.Logger) model.DeployDataManager {
+	return common.NewDeployDataManager(&deployStore{
+		baseDataFolder: baseFolder,
+		logger:         logger,
+	}, logger)
+}
@@ -0,0 +1,153 @@
+package device
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hellgate75/k8s-deploy/model"
+)
+
+func TestDeployDataManagerDeploys(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "deploys-test")
+	defer os.RemoveAll(dir)
+	var dm = GetDeployDataManager(dir, nil)
+	var resp = dm.AddDeploy(model.Deploy{Name: "release", Job: []model.Job{{Instance: model.Instance{Name: "web"}}}})
+	if !resp.Success {
+		t.Fatalf("Unexpected error: %s", resp.Message)
+	}
+	var d = resp.ResponseObjects[0].(model.Deploy)
+	if d.Id == "" || d.State != model.StateCreated || d.Job[0].Instance.Id == "" || d.Job[0].State != model.StateCreated {
+		t.Fatalf("Expected generated ids and created states, found: %+v", d)
+	}
+	if _, err := os.Stat(filepath.Join(dir, deploysFolder, d.Id+".yaml")); err != nil {
+		t.Fatalf("Expected deploy file named by id: %v", err)
+	}
+	if resp := dm.AddDeploy(model.Deploy{Name: "release"}); resp.Success {
+		t.Fatal("Expected duplicate deploy name error")
+	}
+	if resp := dm.AddDeploy(model.Deploy{Name: "other", Job: []model.Job{{Instance: model.Instance{Name: "db"}}, {Instance: model.Instance{Name: "db"}}}}); resp.Success {
+		t.Fatal("Expected duplicate job name error")
+	}
+	if resp := dm.AddDeploy(model.Deploy{Name: "nightly"}); !resp.Success {
+		t.Fatalf("Unexpected error: %s", resp.Message)
+	}
+	if got := dm.GetDeploy(d.Id); got == nil || got.Name != "release" {
+		t.Fatalf("Unexpected deploy: %+v", got)
+	}
+	if got := dm.GetDeployByName("nightly"); got == nil || len(got.Job) != 0 {
+		t.Fatalf("Unexpected deploy: %+v", got)
+	}
+	if dm.GetDeploy("missing") != nil {
+		t.Fatal("Expected missing deploy")
+	}
+	resp = dm.UpdateDeployState(d.Id, model.StateRunning)
+	if !resp.Success || resp.ResponseObjects[0].(model.Deploy).State != model.StateRunning {
+		t.Fatalf("Unexpected response: %+v", resp)
+	}
+	if resp := dm.UpdateDeployState(d.Id, model.StateCreated); resp.Success {
+		t.Fatal("Expected invalid state transition error")
+	}
+	resp = dm.QueryDeploys(fieldQuery("state", string(model.StateRunning)))
+	if !resp.Success || len(resp.ResponseObjects) != 1 || resp.ResponseObjects[0].(model.Deploy).Id != d.Id {
+		t.Fatalf("Expected running deploy, found: %+v", resp.ResponseObjects)
+	}
+	// Running deploys cannot be cleared, deleted or purged
+	if resp := dm.ClearDeploy(d.Id); resp.Success {
+		t.Fatal("Expected running deploy clear error")
+	}
+	if resp := dm.DeleteDeploys(fieldQuery("name", "release")); resp.Success || resp.Changes != 0 {
+		t.Fatalf("Expected running deploy delete error, found: %+v", resp)
+	}
+	if resp := dm.PurgeDeploys(fieldQuery("name", "release")); resp.Success || resp.Changes != 0 {
+		t.Fatalf("Expected running deploy purge error, found: %+v", resp)
+	}
+	if resp := dm.OverrideDeploy(d.Id, model.Deploy{Name: "nightly", State: model.StateComplete}); resp.Success {
+		t.Fatal("Expected deploy name already in use error")
+	}
+	resp = dm.OverrideDeploy(d.Id, model.Deploy{Name: "release-1", State: model.StateComplete})
+	if !resp.Success || resp.ResponseObjects[0].(model.Deploy).Name != "release-1" {
+		t.Fatalf("Unexpected response: %+v", resp)
+	}
+	if resp := dm.ClearDeployByName("release-1"); !resp.Success || len(resp.ResponseObjects[0].(model.Deploy).Job) != 0 {
+		t.Fatalf("Expected deploy jobs cleared, found: %+v", resp)
+	}
+	resp = dm.DeleteDeploys()
+	if !resp.Success || resp.Changes != 2 {
+		t.Fatalf("Expected 2 deploys deleted, found: %+v", resp)
+	}
+	resp = dm.PurgeDeploys(fieldQuery("state", string(model.StateDeleted)))
+	if !resp.Success || resp.Changes != 2 {
+		t.Fatalf("Expected 2 deploys purged, found: %+v", resp)
+	}
+	if resp := dm.ListDeploys(); !resp.Success || len(resp.ResponseObjects) != 0 {
+		t.Fatalf("Expected no deploys left, found: %+v", resp.ResponseObjects)
+	}
+}
+
+func TestDeployDataManagerJobs(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "deploys-test")
+	defer os.RemoveAll(dir)
+	var dm = GetDeployDataManager(dir, nil)
+	var resp = dm.AddDeploy(model.Deploy{Name: "release"})
+	if !resp.Success {
+		t.Fatalf("Unexpected error: %s", resp.Message)
+	}
+	var jm = *dm.AccessDeploy(resp.ResponseObjects[0].(model.Deploy))
+	for _, j := range []model.Job{
+		{Instance: model.Instance{Name: "db"}, IsChart: true, ProjectId: "project"},
+		{Instance: model.Instance{Name: "web"}, DependsOn: []string{"db"}, ProjectId: "project"},
+	} {
+		if resp := jm.AddJob(j); !resp.Success {
+			t.Fatalf("Unexpected error: %s", resp.Message)
+		}
+	}
+	if resp := jm.AddJob(model.Job{Instance: model.Instance{Name: "web"}}); resp.Success {
+		t.Fatal("Expected duplicate job error")
+	}
+	if resp := jm.AddJob(model.Job{}); resp.Success {
+		t.Fatal("Expected job name required error")
+	}
+	var web = jm.GetJobByName("web")
+	if web == nil || web.Instance.Id == "" || web.State != model.StateCreated {
+		t.Fatalf("Unexpected job: %+v", web)
+	}
+	if j := jm.GetJob(web.Instance.Id); j == nil || j.Instance.Name != "web" {
+		t.Fatalf("Unexpected job: %+v", j)
+	}
+	resp = jm.QueryJobs(fieldQuery("chart", "true"))
+	if !resp.Success || len(resp.ResponseObjects) != 1 || resp.ResponseObjects[0].(model.Job).Instance.Name != "db" {
+		t.Fatalf("Expected chart job db, found: %+v", resp.ResponseObjects)
+	}
+	if resp := jm.UpdateJobState(web.Instance.Id, model.StateRunning); !resp.Success {
+		t.Fatalf("Unexpected error: %s", resp.Message)
+	}
+	if resp := jm.UpdateJobState(web.Instance.Id, model.StateReady); resp.Success {
+		t.Fatal("Expected invalid job state transition error")
+	}
+	if resp := jm.OverrideJob(web.Instance.Id, model.Job{Instance: model.Instance{Name: "db"}}); resp.Success {
+		t.Fatal("Expected job name already in use error")
+	}
+	if resp := jm.DeleteJobs(); resp.Success || resp.Changes != 1 {
+		t.Fatalf("Expected running job delete refused, found: %+v", resp)
+	}
+	if resp := jm.PurgeJobs(fieldQuery("name", "web")); resp.Success || resp.Changes != 0 {
+		t.Fatalf("Expected running job purge refused, found: %+v", resp)
+	}
+	resp = jm.OverrideJob(web.Instance.Id, model.Job{State: model.StateComplete, ProjectId: "other"})
+	if !resp.Success || resp.ResponseObjects[0].(model.Job).Instance.Name != "web" {
+		t.Fatalf("Unexpected response: %+v", resp)
+	}
+	if resp := jm.PurgeJobs(fieldQuery("project", "other")); !resp.Success || resp.Changes != 1 {
+		t.Fatalf("Expected job purged, found: %+v", resp)
+	}
+	// Jobs are stored in the deploy record
+	var stored = GetDeployDataManager(dir, nil).GetDeployByName("release")
+	if stored == nil || len(stored.Job) != 1 || stored.Job[0].Instance.Name != "db" || stored.Job[0].State != model.StateDeleted {
+		t.Fatalf("Unexpected stored deploy: %+v", stored)
+	}
+	if resp := (*dm.AccessDeploy(model.Deploy{Id: "missing"})).ListJobs(); resp.Success {
+		t.Fatal("Expected missing deploy error")
+	}
+}
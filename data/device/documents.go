@@ -1,178 +1,653 @@
 package device
 
-import "github.com/hellgate75/k8s-deploy/model"
+import (
+	"errors"
+	"fmt"
+	"github.com/hellgate75/k8s-deploy/model"
+	"github.com/hellgate75/k8s-deploy/utils"
+	model2 "github.com/hellgate75/k8s-deploy/utils/model"
+	"os"
+	"strings"
+	"sync"
+)
 
+const (
+	documentsChartsFolder    = "charts"
+	documentsKubeFilesFolder = "kubefiles"
+	documentsFormatExtension = utils.YAML_FORMAT
+)
+
+// Serializes the access to the repository documents folders, shared by all the documents managers
+var documentsMutex sync.RWMutex
+
+// Charts and Kubernetes files share the same structure and the same folder layout (index, details and versions folders),
+// so documents are managed as model.Chart and converted to model.KubernetesFile on the Kubernetes files side
 type _documentsManager struct {
 	baseDataFolder string
 	repo           *model.Repository
 }
 
-func (dm *_documentsManager) AddChart(c model.Chart) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+func (dm *_documentsManager) documentsFolder(kind string) string {
+	return fmt.Sprintf("%s%crepositories%c%s%c%s", dm.baseDataFolder, os.PathSeparator, os.PathSeparator, dm.repo.Name, os.PathSeparator, kind)
+}
+
+func (dm *_documentsManager) documentsIndexFile(kind string) string {
+	return fmt.Sprintf("%s%cindex.%v", dm.documentsFolder(kind), os.PathSeparator, documentsFormatExtension)
+}
+
+func (dm *_documentsManager) documentFolder(kind string, name string) string {
+	return fmt.Sprintf("%s%c%s", dm.documentsFolder(kind), os.PathSeparator, name)
+}
+
+func (dm *_documentsManager) documentIndexFile(kind string, name string) string {
+	return fmt.Sprintf("%s%cindex.%v", dm.documentFolder(kind, name), os.PathSeparator, documentsFormatExtension)
+}
+
+func (dm *_documentsManager) documentVersionFolder(kind string, name string, version string) string {
+	return fmt.Sprintf("%s%c%s", dm.documentFolder(kind, name), os.PathSeparator, version)
+}
+
+// Load the documents index, an empty index is returned when the index file doesn't exist
+func (dm *_documentsManager) loadIndex(kind string) ([]model.ChartInfo, error) {
+	var file = dm.documentsIndexFile(kind)
+	if !utils.ExistsFileOrFolder(file) {
+		return make([]model.ChartInfo, 0), nil
+	}
+	if kind == documentsKubeFilesFolder {
+		var list = model.KubernetesFileList{
+			Files: make([]model.KubernetesFileInfo, 0),
+		}
+		if err := utils.LoadStructureByType(file, &list, documentsFormatExtension); err != nil {
+			return nil, err
+		}
+		var out = make([]model.ChartInfo, 0)
+		for _, f := range list.Files {
+			out = append(out, model.ChartInfo(f))
+		}
+		return out, nil
+	}
+	var list = model.ChartList{
+		Charts: make([]model.ChartInfo, 0),
+	}
+	if err := utils.LoadStructureByType(file, &list, documentsFormatExtension); err != nil {
+		return nil, err
 	}
+	return list.Charts, nil
 }
 
-func (dm *_documentsManager) AddKubeFile(f model.KubernetesFile) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+func (dm *_documentsManager) saveIndex(kind string, index []model.ChartInfo) error {
+	if err := utils.CreateFolder(dm.documentsFolder(kind)); err != nil {
+		return err
+	}
+	var file = dm.documentsIndexFile(kind)
+	if kind == documentsKubeFilesFolder {
+		var list = model.KubernetesFileList{
+			RepoName: dm.repo.Name,
+			Files:    make([]model.KubernetesFileInfo, 0),
+		}
+		for _, i := range index {
+			list.Files = append(list.Files, model.KubernetesFileInfo(i))
+		}
+		return utils.SaveStructureByType(file, &list, documentsFormatExtension)
 	}
+	var list = model.ChartList{
+		RepoName: dm.repo.Name,
+		Charts:   index,
+	}
+	return utils.SaveStructureByType(file, &list, documentsFormatExtension)
 }
 
-func (dm *_documentsManager) AddChartVersion(c model.Chart, v model.Version) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+func (dm *_documentsManager) loadDocument(kind string, name string) (*model.Chart, error) {
+	var file = dm.documentIndexFile(kind, name)
+	if !utils.ExistsFileOrFolder(file) {
+		return nil, errors.New(fmt.Sprintf("Document %s not found in %s of repository %s", name, kind, dm.repo.Name))
+	}
+	var doc = model.Chart{
+		Versions: make([]model.Version, 0),
+	}
+	if err := utils.LoadStructureByType(file, &doc, documentsFormatExtension); err != nil {
+		return nil, err
 	}
+	return &doc, nil
 }
 
-func (dm *_documentsManager) AddKubeFileVersion(f model.KubernetesFile, v model.Version) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+func (dm *_documentsManager) saveDocument(kind string, doc model.Chart) error {
+	if err := utils.CreateFolder(dm.documentFolder(kind, doc.Name)); err != nil {
+		return err
 	}
+	return utils.SaveStructureByType(dm.documentIndexFile(kind, doc.Name), &doc, documentsFormatExtension)
 }
 
-func (dm *_documentsManager) RemoveCharts(q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+// Load all indexed documents, documents with missing details are reported in error state
+func (dm *_documentsManager) loadDocuments(kind string) ([]model.Chart, error) {
+	index, err := dm.loadIndex(kind)
+	if err != nil {
+		return nil, err
+	}
+	var out = make([]model.Chart, 0)
+	for _, i := range index {
+		doc, err := dm.loadDocument(kind, i.Name)
+		if err != nil {
+			out = append(out, model.Chart{
+				Id:       i.Id,
+				Name:     i.Name,
+				Versions: make([]model.Version, 0),
+				State:    model.StateError,
+			})
+			continue
+		}
+		out = append(out, *doc)
 	}
+	return out, nil
 }
 
-func (dm *_documentsManager) RemoveKubeFiles(q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+// Find the document referenced by name or, when the name is empty, by id
+func (dm *_documentsManager) findDocument(kind string, ref model.Chart) (*model.Chart, error) {
+	if strings.TrimSpace(ref.Name) != "" {
+		return dm.loadDocument(kind, ref.Name)
+	}
+	docs, err := dm.loadDocuments(kind)
+	if err != nil {
+		return nil, err
 	}
+	for _, doc := range docs {
+		if ref.Id != "" && doc.Id == ref.Id {
+			return &doc, nil
+		}
+	}
+	return nil, errors.New(fmt.Sprintf("Document id %s not found in %s of repository %s", ref.Id, kind, dm.repo.Name))
 }
 
-func (dm *_documentsManager) RemoveChartVersions(c model.Chart, q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+func checkDocumentValue(d model.Chart, key string, value string, cond model.Aggregator) bool {
+	switch key {
+	case "id":
+		return model2.CompareValues(d.Id, value, model2.DataTypeString, cond)
+	case "name":
+		return model2.CompareValues(d.Name, value, model2.DataTypeString, cond)
+	case "state":
+		return model2.CompareValues(string(d.State), value, model2.DataTypeString, cond)
+	case "versions":
+		return model2.CompareValues(fmt.Sprintf("%v", len(d.Versions)), value, model2.DataTypeNumber, cond)
 	}
+	return false
 }
 
-func (dm *_documentsManager) RemoveKubeFileVersions(f model.KubernetesFile, q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+func checkVersionValue(v model.Version, key string, value string, cond model.Aggregator) bool {
+	switch key {
+	case "id":
+		return model2.CompareValues(v.Id, value, model2.DataTypeString, cond)
+	case "name", "version":
+		return model2.CompareValues(v.Name, value, model2.DataTypeString, cond)
+	case "state":
+		return model2.CompareValues(string(v.State), value, model2.DataTypeString, cond)
 	}
+	return false
 }
 
-func (dm *_documentsManager) PurgeCharts(q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+func matchDocument(d model.Chart, q ...model.Query) bool {
+	return model2.MatchQueries(func(key string, value string, cond model.Aggregator) bool {
+		return checkDocumentValue(d, key, value, cond)
+	}, q...)
+}
+
+func matchVersion(v model.Version, q ...model.Query) bool {
+	return model2.MatchQueries(func(key string, value string, cond model.Aggregator) bool {
+		return checkVersionValue(v, key, value, cond)
+	}, q...)
+}
+
+// Convert a document to the response object of its kind
+func documentObject(kind string, d model.Chart) interface{} {
+	if kind == documentsKubeFilesFolder {
+		return model.KubernetesFile(d)
 	}
+	return d
 }
 
-func (dm *_documentsManager) PurgeKubeFiles(q ...model.Query) model.DataResponse {
+func documentsResponse(objects []interface{}, changes int64, message string) model.DataResponse {
+	if len(message) == 0 {
+		return model.DataResponse{
+			Success:         true,
+			Message:         "OK",
+			Changes:         changes,
+			ResponseObjects: objects,
+		}
+	}
 	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+		Success:         false,
+		Message:         message,
+		Changes:         changes,
+		ResponseObjects: objects,
 	}
 }
 
-func (dm *_documentsManager) PurgeChartVersions(c model.Chart, q ...model.Query) model.DataResponse {
+func documentsErrorResponse(format string, in ...interface{}) model.DataResponse {
 	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+		Success:         false,
+		Message:         fmt.Sprintf(format, in...),
+		ResponseObjects: make([]interface{}, 0),
 	}
 }
 
-func (dm *_documentsManager) PurgeKubeFileVersions(f model.KubernetesFile, q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+func appendMessage(message string, format string, in ...interface{}) string {
+	if len(message) > 0 {
+		message += ", "
 	}
+	return message + fmt.Sprintf(format, in...)
 }
 
-func (dm *_documentsManager) UpdateCharts(c model.Chart, q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+func (dm *_documentsManager) addDocument(kind string, d model.Chart) model.DataResponse {
+	if dm.repo == nil {
+		return documentsErrorResponse("Repository is required")
+	}
+	documentsMutex.Lock()
+	defer documentsMutex.Unlock()
+	d.Name = strings.TrimSpace(d.Name)
+	if d.Name == "" {
+		return documentsErrorResponse("Name is required to add a document in %s of repository %s", kind, dm.repo.Name)
+	}
+	index, err := dm.loadIndex(kind)
+	if err != nil {
+		return documentsErrorResponse("Error loading %s index of repository %s, error: %v", kind, dm.repo.Name, err)
+	}
+	for _, i := range index {
+		if i.Name == d.Name {
+			return documentsErrorResponse("Document %s already exists in %s of repository %s", d.Name, kind, dm.repo.Name)
+		}
+	}
+	if d.Id == "" {
+		d.Id = utils.NewUniqueIdentifier()
 	}
+	if d.State == "" {
+		d.State = model.StateCreated
+	}
+	if d.Versions == nil {
+		d.Versions = make([]model.Version, 0)
+	}
+	for i := range d.Versions {
+		if d.Versions[i].Id == "" {
+			d.Versions[i].Id = utils.NewUniqueIdentifier()
+		}
+		if d.Versions[i].State == "" {
+			d.Versions[i].State = model.StateCreated
+		}
+	}
+	if err := dm.saveDocument(kind, d); err != nil {
+		return documentsErrorResponse("Error saving document %s in %s of repository %s, error: %v", d.Name, kind, dm.repo.Name, err)
+	}
+	index = append(index, model.ChartInfo{
+		Id:   d.Id,
+		Name: d.Name,
+	})
+	if err := dm.saveIndex(kind, index); err != nil {
+		return documentsErrorResponse("Error saving %s index of repository %s, error: %v", kind, dm.repo.Name, err)
+	}
+	return documentsResponse([]interface{}{documentObject(kind, d)}, 1, "")
 }
 
-func (dm *_documentsManager) UpdateKubeFiles(f model.KubernetesFile, v model.Version, q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+func (dm *_documentsManager) addVersion(kind string, ref model.Chart, v model.Version) model.DataResponse {
+	if dm.repo == nil {
+		return documentsErrorResponse("Repository is required")
+	}
+	documentsMutex.Lock()
+	defer documentsMutex.Unlock()
+	doc, err := dm.findDocument(kind, ref)
+	if err != nil {
+		return documentsErrorResponse("%v", err)
+	}
+	v.Name = strings.TrimSpace(v.Name)
+	if v.Name == "" {
+		return documentsErrorResponse("Version name is required to add a version to %s in %s of repository %s", doc.Name, kind, dm.repo.Name)
+	}
+	for _, ver := range doc.Versions {
+		if ver.Name == v.Name {
+			return documentsErrorResponse("Version %s already exists for %s in %s of repository %s", v.Name, doc.Name, kind, dm.repo.Name)
+		}
+	}
+	if v.Id == "" {
+		v.Id = utils.NewUniqueIdentifier()
 	}
+	if v.State == "" {
+		v.State = model.StateCreated
+	}
+	doc.Versions = append(doc.Versions, v)
+	if err := dm.saveDocument(kind, *doc); err != nil {
+		return documentsErrorResponse("Error saving document %s in %s of repository %s, error: %v", doc.Name, kind, dm.repo.Name, err)
+	}
+	return documentsResponse([]interface{}{v}, 1, "")
 }
 
-func (dm *_documentsManager) UpdateChartVersions(c model.Chart, v model.Version, q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+// Soft delete the documents matching the queries, setting the deleted state
+func (dm *_documentsManager) removeDocuments(kind string, q ...model.Query) model.DataResponse {
+	return dm.updateDocuments(kind, func(d *model.Chart) {
+		d.State = model.StateDeleted
+	}, q...)
+}
+
+// Apply a change to all documents matching the queries
+func (dm *_documentsManager) updateDocuments(kind string, change func(d *model.Chart), q ...model.Query) model.DataResponse {
+	if dm.repo == nil {
+		return documentsErrorResponse("Repository is required")
 	}
+	documentsMutex.Lock()
+	defer documentsMutex.Unlock()
+	docs, err := dm.loadDocuments(kind)
+	if err != nil {
+		return documentsErrorResponse("Error loading %s of repository %s, error: %v", kind, dm.repo.Name, err)
+	}
+	var objects = make([]interface{}, 0)
+	var message = ""
+	var changes int64
+	for _, d := range docs {
+		if !matchDocument(d, q...) {
+			continue
+		}
+		change(&d)
+		if err := dm.saveDocument(kind, d); err != nil {
+			message = appendMessage(message, "document: %s - Error: %v", d.Name, err)
+			continue
+		}
+		changes++
+		objects = append(objects, documentObject(kind, d))
+	}
+	return documentsResponse(objects, changes, message)
 }
 
-func (dm *_documentsManager) UpdateKubeFileVersions(f model.KubernetesFile, v model.Version, q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+// Permanently delete the documents matching the queries, including all version folders
+func (dm *_documentsManager) purgeDocuments(kind string, q ...model.Query) model.DataResponse {
+	if dm.repo == nil {
+		return documentsErrorResponse("Repository is required")
+	}
+	documentsMutex.Lock()
+	defer documentsMutex.Unlock()
+	docs, err := dm.loadDocuments(kind)
+	if err != nil {
+		return documentsErrorResponse("Error loading %s of repository %s, error: %v", kind, dm.repo.Name, err)
 	}
+	var objects = make([]interface{}, 0)
+	var index = make([]model.ChartInfo, 0)
+	var message = ""
+	var changes int64
+	for _, d := range docs {
+		if !matchDocument(d, q...) {
+			index = append(index, model.ChartInfo{Id: d.Id, Name: d.Name})
+			continue
+		}
+		if err := utils.DeleteFileOrFolder(dm.documentFolder(kind, d.Name)); err != nil {
+			message = appendMessage(message, "document: %s - Error: %v", d.Name, err)
+			index = append(index, model.ChartInfo{Id: d.Id, Name: d.Name})
+			continue
+		}
+		changes++
+		d.State = model.StatePutged
+		objects = append(objects, documentObject(kind, d))
+	}
+	if changes > 0 {
+		if err := dm.saveIndex(kind, index); err != nil {
+			message = appendMessage(message, "index: %s - Error: %v", kind, err)
+		}
+	}
+	return documentsResponse(objects, changes, message)
 }
 
-func (dm *_documentsManager) QueryCharts(q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+// Apply a change to all versions of a document matching the queries
+func (dm *_documentsManager) updateVersions(kind string, ref model.Chart, change func(v *model.Version), q ...model.Query) model.DataResponse {
+	if dm.repo == nil {
+		return documentsErrorResponse("Repository is required")
+	}
+	documentsMutex.Lock()
+	defer documentsMutex.Unlock()
+	doc, err := dm.findDocument(kind, ref)
+	if err != nil {
+		return documentsErrorResponse("%v", err)
+	}
+	var objects = make([]interface{}, 0)
+	for i := range doc.Versions {
+		if matchVersion(doc.Versions[i], q...) {
+			change(&doc.Versions[i])
+			objects = append(objects, doc.Versions[i])
+		}
 	}
+	if len(objects) > 0 {
+		if err := dm.saveDocument(kind, *doc); err != nil {
+			return documentsErrorResponse("Error saving document %s in %s of repository %s, error: %v", doc.Name, kind, dm.repo.Name, err)
+		}
+	}
+	return documentsResponse(objects, int64(len(objects)), "")
 }
 
-func (dm *_documentsManager) QueryKubeFiles(q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+// Permanently delete the versions of a document matching the queries, including the version folders
+func (dm *_documentsManager) purgeVersions(kind string, ref model.Chart, q ...model.Query) model.DataResponse {
+	if dm.repo == nil {
+		return documentsErrorResponse("Repository is required")
+	}
+	documentsMutex.Lock()
+	defer documentsMutex.Unlock()
+	doc, err := dm.findDocument(kind, ref)
+	if err != nil {
+		return documentsErrorResponse("%v", err)
+	}
+	var objects = make([]interface{}, 0)
+	var versions = make([]model.Version, 0)
+	var message = ""
+	for _, v := range doc.Versions {
+		if !matchVersion(v, q...) {
+			versions = append(versions, v)
+			continue
+		}
+		if err := utils.DeleteFileOrFolder(dm.documentVersionFolder(kind, doc.Name, v.Name)); err != nil {
+			message = appendMessage(message, "version: %s - Error: %v", v.Name, err)
+			versions = append(versions, v)
+			continue
+		}
+		v.State = model.StatePutged
+		objects = append(objects, v)
 	}
+	if len(objects) > 0 {
+		doc.Versions = versions
+		if err := dm.saveDocument(kind, *doc); err != nil {
+			message = appendMessage(message, "document: %s - Error: %v", doc.Name, err)
+		}
+	}
+	return documentsResponse(objects, int64(len(objects)), message)
 }
 
-func (dm *_documentsManager) QueryChartVersions(c model.Chart, q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+func (dm *_documentsManager) queryDocuments(kind string, q ...model.Query) model.DataResponse {
+	if dm.repo == nil {
+		return documentsErrorResponse("Repository is required")
 	}
+	documentsMutex.RLock()
+	defer documentsMutex.RUnlock()
+	docs, err := dm.loadDocuments(kind)
+	if err != nil {
+		return documentsErrorResponse("Error loading %s of repository %s, error: %v", kind, dm.repo.Name, err)
+	}
+	var objects = make([]interface{}, 0)
+	for _, d := range docs {
+		if matchDocument(d, q...) {
+			objects = append(objects, documentObject(kind, d))
+		}
+	}
+	return documentsResponse(objects, 0, "")
 }
 
-func (dm *_documentsManager) QueryKubeFileVersions(f model.KubernetesFile, q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+func (dm *_documentsManager) queryVersions(kind string, ref model.Chart, q ...model.Query) model.DataResponse {
+	if dm.repo == nil {
+		return documentsErrorResponse("Repository is required")
+	}
+	documentsMutex.RLock()
+	defer documentsMutex.RUnlock()
+	doc, err := dm.findDocument(kind, ref)
+	if err != nil {
+		return documentsErrorResponse("%v", err)
+	}
+	var objects = make([]interface{}, 0)
+	for _, v := range doc.Versions {
+		if matchVersion(v, q...) {
+			objects = append(objects, v)
+		}
 	}
+	return documentsResponse(objects, 0, "")
 }
 
-func (dm *_documentsManager) ListCharts() model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+// List the versions of all documents matching the queries
+func (dm *_documentsManager) listVersions(kind string, q ...model.Query) model.DataResponse {
+	if dm.repo == nil {
+		return documentsErrorResponse("Repository is required")
 	}
+	documentsMutex.RLock()
+	defer documentsMutex.RUnlock()
+	docs, err := dm.loadDocuments(kind)
+	if err != nil {
+		return documentsErrorResponse("Error loading %s of repository %s, error: %v", kind, dm.repo.Name, err)
+	}
+	var objects = make([]interface{}, 0)
+	for _, d := range docs {
+		if !matchDocument(d, q...) {
+			continue
+		}
+		for _, v := range d.Versions {
+			objects = append(objects, v)
+		}
+	}
+	return documentsResponse(objects, 0, "")
 }
 
-func (dm *_documentsManager) ListKubeFiles() model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+// Update the state and, when provided, the versions of the matching documents
+func updateDocumentFunc(d model.Chart) func(doc *model.Chart) {
+	return func(doc *model.Chart) {
+		if d.State != "" {
+			doc.State = d.State
+		}
+		if len(d.Versions) > 0 {
+			doc.Versions = d.Versions
+		}
 	}
 }
 
-func (dm *_documentsManager) ListChartVersions(q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+// Update the state of the matching versions
+func updateVersionFunc(v model.Version) func(ver *model.Version) {
+	return func(ver *model.Version) {
+		if v.State != "" {
+			ver.State = v.State
+		}
 	}
 }
 
+func (dm *_documentsManager) AddChart(c model.Chart) model.DataResponse {
+	return dm.addDocument(documentsChartsFolder, c)
+}
+
+func (dm *_documentsManager) AddKubeFile(f model.KubernetesFile) model.DataResponse {
+	return dm.addDocument(documentsKubeFilesFolder, model.Chart(f))
+}
+
+func (dm *_documentsManager) AddChartVersion(c model.Chart, v model.Version) model.DataResponse {
+	return dm.addVersion(documentsChartsFolder, c, v)
+}
+
+func (dm *_documentsManager) AddKubeFileVersion(f model.KubernetesFile, v model.Version) model.DataResponse {
+	return dm.addVersion(documentsKubeFilesFolder, model.Chart(f), v)
+}
+
+func (dm *_documentsManager) RemoveCharts(q ...model.Query) model.DataResponse {
+	return dm.removeDocuments(documentsChartsFolder, q...)
+}
+
+func (dm *_documentsManager) RemoveKubeFiles(q ...model.Query) model.DataResponse {
+	return dm.removeDocuments(documentsKubeFilesFolder, q...)
+}
+
+func (dm *_documentsManager) RemoveChartVersions(c model.Chart, q ...model.Query) model.DataResponse {
+	return dm.updateVersions(documentsChartsFolder, c, updateVersionFunc(model.Version{State: model.StateDeleted}), q...)
+}
+
+func (dm *_documentsManager) RemoveKubeFileVersions(f model.KubernetesFile, q ...model.Query) model.DataResponse {
+	return dm.updateVersions(documentsKubeFilesFolder, model.Chart(f), updateVersionFunc(model.Version{State: model.StateDeleted}), q...)
+}
+
+func (dm *_documentsManager) PurgeCharts(q ...model.Query) model.DataResponse {
+	return dm.purgeDocuments(documentsChartsFolder, q...)
+}
+
+func (dm *_documentsManager) PurgeKubeFiles(q ...model.Query) model.DataResponse {
+	return dm.purgeDocuments(documentsKubeFilesFolder, q...)
+}
+
+func (dm *_documentsManager) PurgeChartVersions(c model.Chart, q ...model.Query) model.DataResponse {
+	return dm.purgeVersions(documentsChartsFolder, c, q...)
+}
+
+func (dm *_documentsManager) PurgeKubeFileVersions(f model.KubernetesFile, q ...model.Query) model.DataResponse {
+	return dm.purgeVersions(documentsKubeFilesFolder, model.Chart(f), q...)
+}
+
+func (dm *_documentsManager) UpdateCharts(c model.Chart, q ...model.Query) model.DataResponse {
+	return dm.updateDocuments(documentsChartsFolder, updateDocumentFunc(c), q...)
+}
+
+func (dm *_documentsManager) UpdateKubeFiles(f model.KubernetesFile, v model.Version, q ...model.Query) model.DataResponse {
+	var update = updateDocumentFunc(model.Chart(f))
+	return dm.updateDocuments(documentsKubeFilesFolder, func(doc *model.Chart) {
+		update(doc)
+		if strings.TrimSpace(v.Name) == "" {
+			return
+		}
+		// Replace or add the given version
+		for i := range doc.Versions {
+			if doc.Versions[i].Name == v.Name {
+				doc.Versions[i] = v
+				return
+			}
+		}
+		if v.Id == "" {
+			v.Id = utils.NewUniqueIdentifier()
+		}
+		if v.State == "" {
+			v.State = model.StateCreated
+		}
+		doc.Versions = append(doc.Versions, v)
+	}, q...)
+}
+
+func (dm *_documentsManager) UpdateChartVersions(c model.Chart, v model.Version, q ...model.Query) model.DataResponse {
+	return dm.updateVersions(documentsChartsFolder, c, updateVersionFunc(v), q...)
+}
+
+func (dm *_documentsManager) UpdateKubeFileVersions(f model.KubernetesFile, v model.Version, q ...model.Query) model.DataResponse {
+	return dm.updateVersions(documentsKubeFilesFolder, model.Chart(f), updateVersionFunc(v), q...)
+}
+
+func (dm *_documentsManager) QueryCharts(q ...model.Query) model.DataResponse {
+	return dm.queryDocuments(documentsChartsFolder, q...)
+}
+
+func (dm *_documentsManager) QueryKubeFiles(q ...model.Query) model.DataResponse {
+	return dm.queryDocuments(documentsKubeFilesFolder, q...)
+}
+
+func (dm *_documentsManager) QueryChartVersions(c model.Chart, q ...model.Query) model.DataResponse {
+	return dm.queryVersions(documentsChartsFolder, c, q...)
+}
+
+func (dm *_documentsManager) QueryKubeFileVersions(f model.KubernetesFile, q ...model.Query) model.DataResponse {
+	return dm.queryVersions(documentsKubeFilesFolder, model.Chart(f), q...)
+}
+
+func (dm *_documentsManager) ListCharts() model.DataResponse {
+	return dm.queryDocuments(documentsChartsFolder)
+}
+
+func (dm *_documentsManager) ListKubeFiles() model.DataResponse {
+	return dm.queryDocuments(documentsKubeFilesFolder)
+}
+
+func (dm *_documentsManager) ListChartVersions(q ...model.Query) model.DataResponse {
+	return dm.listVersions(documentsChartsFolder, q...)
+}
+
 func (dm *_documentsManager) ListKubeFileVersions(q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
-	}
+	return dm.listVersions(documentsKubeFilesFolder, q...)
 }
 
 func GetDocumentDataManager(baseFolder string, repo *model.Repository) model.DocumentsDataManager {
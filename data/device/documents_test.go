@@ -0,0 +1,177 @@
+package device
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hellgate75/k8s-deploy/model"
+)
+
+func fieldQuery(key string, value string) model.Query {
+	return model.Query{
+		Oper: model.OperAnd,
+		Items: []model.QueryItem{
+			{Key: key, Value: value, Aggregator: model.AggregatorEq},
+		},
+	}
+}
+
+func TestDocumentsManagerChartVersions(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "documents-test")
+	defer os.RemoveAll(dir)
+	var dm = GetDocumentDataManager(dir, &model.Repository{Name: "repo"})
+	var resp = dm.AddChart(model.Chart{Name: "web", Versions: []model.Version{{Name: "1.0.0"}}})
+	if !resp.Success {
+		t.Fatalf("Unexpected error: %s", resp.Message)
+	}
+	var chart = resp.ResponseObjects[0].(model.Chart)
+	if chart.Id == "" || chart.State != model.StateCreated || chart.Versions[0].Id == "" || chart.Versions[0].State != model.StateCreated {
+		t.Fatalf("Expected generated ids and created states, found: %+v", chart)
+	}
+	if resp := dm.AddChart(model.Chart{Name: "web"}); resp.Success {
+		t.Fatal("Expected duplicate chart error")
+	}
+	if resp := dm.AddChartVersion(model.Chart{Name: "web"}, model.Version{Name: "1.1.0"}); !resp.Success {
+		t.Fatalf("Unexpected error: %s", resp.Message)
+	}
+	if resp := dm.AddChartVersion(model.Chart{Id: chart.Id}, model.Version{Name: "1.2.0"}); !resp.Success {
+		t.Fatalf("Unexpected error adding a version by chart id: %s", resp.Message)
+	}
+	if resp := dm.AddChartVersion(model.Chart{Name: "web"}, model.Version{Name: "1.1.0"}); resp.Success {
+		t.Fatal("Expected duplicate version error")
+	}
+	if resp := dm.AddChartVersion(model.Chart{Name: "missing"}, model.Version{Name: "1.0.0"}); resp.Success {
+		t.Fatal("Expected missing chart error")
+	}
+	resp = dm.UpdateChartVersions(model.Chart{Name: "web"}, model.Version{State: model.StateReady}, fieldQuery("name", "1.1.0"))
+	if !resp.Success || resp.Changes != 1 {
+		t.Fatalf("Expected 1 version updated, found %v: %s", resp.Changes, resp.Message)
+	}
+	resp = dm.QueryChartVersions(model.Chart{Name: "web"}, fieldQuery("state", string(model.StateReady)))
+	if !resp.Success || len(resp.ResponseObjects) != 1 || resp.ResponseObjects[0].(model.Version).Name != "1.1.0" {
+		t.Fatalf("Expected ready version 1.1.0, found: %+v", resp.ResponseObjects)
+	}
+	resp = dm.RemoveChartVersions(model.Chart{Name: "web"}, fieldQuery("name", "1.0.0"))
+	if !resp.Success || resp.ResponseObjects[0].(model.Version).State != model.StateDeleted {
+		t.Fatalf("Expected version soft deleted, found: %+v", resp)
+	}
+	var versionFolder = filepath.Join(dir, "repositories", "repo", "charts", "web", "1.2.0")
+	if err := os.MkdirAll(versionFolder, 0755); err != nil {
+		t.Fatal(err)
+	}
+	resp = dm.PurgeChartVersions(model.Chart{Name: "web"}, fieldQuery("name", "1.2.0"))
+	if !resp.Success || resp.Changes != 1 || resp.ResponseObjects[0].(model.Version).State != model.StatePutged {
+		t.Fatalf("Expected version purged, found: %+v", resp)
+	}
+	if _, err := os.Stat(versionFolder); !os.IsNotExist(err) {
+		t.Fatal("Expected purged version folder deleted")
+	}
+	resp = dm.ListChartVersions()
+	if !resp.Success || len(resp.ResponseObjects) != 2 {
+		t.Fatalf("Expected 2 versions left, found: %+v", resp.ResponseObjects)
+	}
+	resp = dm.UpdateCharts(model.Chart{State: model.StateReady}, fieldQuery("name", "web"))
+	if !resp.Success || resp.Changes != 1 || resp.ResponseObjects[0].(model.Chart).State != model.StateReady {
+		t.Fatalf("Expected chart updated, found: %+v", resp)
+	}
+	resp = dm.PurgeCharts(fieldQuery("name", "web"))
+	if !resp.Success || resp.Changes != 1 {
+		t.Fatalf("Expected chart purged, found: %+v", resp)
+	}
+	if resp := dm.ListCharts(); !resp.Success || len(resp.ResponseObjects) != 0 {
+		t.Fatalf("Expected no charts left, found: %+v", resp.ResponseObjects)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "repositories", "repo", "charts", "web")); !os.IsNotExist(err) {
+		t.Fatal("Expected purged chart folder deleted")
+	}
+}
+
+func TestDocumentsManagerKubeFileVersions(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "documents-test")
+	defer os.RemoveAll(dir)
+	var dm = GetDocumentDataManager(dir, &model.Repository{Name: "repo"})
+	if resp := dm.AddKubeFile(model.KubernetesFile{Name: "config"}); !resp.Success {
+		t.Fatalf("Unexpected error: %s", resp.Message)
+	}
+	if resp := dm.AddKubeFileVersion(model.KubernetesFile{Name: "config"}, model.Version{Name: "1.0.0"}); !resp.Success {
+		t.Fatalf("Unexpected error: %s", resp.Message)
+	}
+	// Update replaces the given version or adds it when missing
+	var resp = dm.UpdateKubeFiles(model.KubernetesFile{}, model.Version{Name: "2.0.0"}, fieldQuery("name", "config"))
+	if !resp.Success || resp.Changes != 1 {
+		t.Fatalf("Expected kubernetes file updated, found: %+v", resp)
+	}
+	var file = resp.ResponseObjects[0].(model.KubernetesFile)
+	if len(file.Versions) != 2 || file.Versions[1].Id == "" || file.Versions[1].State != model.StateCreated {
+		t.Fatalf("Expected version 2.0.0 added, found: %+v", file.Versions)
+	}
+	resp = dm.UpdateKubeFileVersions(model.KubernetesFile{Name: "config"}, model.Version{State: model.StateReady})
+	if !resp.Success || resp.Changes != 2 {
+		t.Fatalf("Expected 2 versions updated, found: %+v", resp)
+	}
+	resp = dm.QueryKubeFiles(fieldQuery("versions", "2"))
+	if !resp.Success || len(resp.ResponseObjects) != 1 {
+		t.Fatalf("Expected kubernetes file with 2 versions, found: %+v", resp.ResponseObjects)
+	}
+	if _, ok := resp.ResponseObjects[0].(model.KubernetesFile); !ok {
+		t.Fatalf("Expected kubernetes file response object, found %T", resp.ResponseObjects[0])
+	}
+	resp = dm.PurgeKubeFileVersions(model.KubernetesFile{Name: "config"}, fieldQuery("version", "1.0.0"))
+	if !resp.Success || resp.Changes != 1 {
+		t.Fatalf("Expected version purged, found: %+v", resp)
+	}
+	resp = dm.QueryKubeFileVersions(model.KubernetesFile{Name: "config"})
+	if !resp.Success || len(resp.ResponseObjects) != 1 || resp.ResponseObjects[0].(model.Version).Name != "2.0.0" {
+		t.Fatalf("Expected only version 2.0.0 left, found: %+v", resp.ResponseObjects)
+	}
+	resp = dm.RemoveKubeFiles(fieldQuery("name", "config"))
+	if !resp.Success || resp.ResponseObjects[0].(model.KubernetesFile).State != model.StateDeleted {
+		t.Fatalf("Expected kubernetes file soft deleted, found: %+v", resp)
+	}
+	// Charts and Kubernetes files are kept apart
+	if resp := dm.ListCharts(); !resp.Success || len(resp.ResponseObjects) != 0 {
+		t.Fatalf("Expected no charts, found: %+v", resp.ResponseObjects)
+	}
+	if resp := dm.ListKubeFileVersions(); !resp.Success || len(resp.ResponseObjects) != 1 {
+		t.Fatalf("Expected 1 kubernetes file version, found: %+v", resp.ResponseObjects)
+	}
+}
+
+func TestDocumentsManagerMissingDetails(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "documents-test")
+	defer os.RemoveAll(dir)
+	var dm = GetDocumentDataManager(dir, &model.Repository{Name: "repo"})
+	for _, name := range []string{"web", "api"} {
+		if resp := dm.AddChart(model.Chart{Name: name, Versions: []model.Version{{Name: "1.0.0"}}}); !resp.Success {
+			t.Fatalf("Unexpected error: %s", resp.Message)
+		}
+	}
+	if err := os.Remove(filepath.Join(dir, "repositories", "repo", "charts", "api", "index.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	var resp = dm.ListCharts()
+	if !resp.Success || len(resp.ResponseObjects) != 2 {
+		t.Fatalf("Expected 2 indexed charts, found: %+v", resp)
+	}
+	for _, o := range resp.ResponseObjects {
+		var chart = o.(model.Chart)
+		if chart.Name == "api" && (chart.State != model.StateError || chart.Id == "" || len(chart.Versions) != 0) {
+			t.Fatalf("Expected chart with missing details in error state, found: %+v", chart)
+		}
+		if chart.Name == "web" && chart.State != model.StateCreated {
+			t.Fatalf("Unexpected chart: %+v", chart)
+		}
+	}
+	resp = dm.QueryCharts(fieldQuery("state", string(model.StateError)))
+	if !resp.Success || len(resp.ResponseObjects) != 1 || resp.ResponseObjects[0].(model.Chart).Name != "api" {
+		t.Fatalf("Expected api chart in error state, found: %+v", resp.ResponseObjects)
+	}
+	if resp := dm.QueryChartVersions(model.Chart{Name: "api"}); resp.Success {
+		t.Fatal("Expected missing details error querying the versions")
+	}
+	if resp := GetDocumentDataManager(dir, nil).ListCharts(); resp.Success {
+		t.Fatal("Expected repository required error")
+	}
+}
@@ -0,0 +1,96 @@
+package device
+
+import (
+	"fmt"
+	"github.com/hellgate75/k8s-deploy/data/common"
+	"github.com/hellgate75/k8s-deploy/log"
+	"github.com/hellgate75/k8s-deploy/model"
+	"github.com/hellgate75/k8s-deploy/utils"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const (
+	projectsFolder = "projects"
+)
+
+// Stores each project in its own yaml file, named by project id, in the projects folder
+type projectStore struct {
+	baseDataFolder string
+	logger         log.Logger
+}
+
+func (ps *projectStore) folder() string {
+	return fmt.Sprintf("%s%c%s", ps.baseDataFolder, os.PathSeparator, projectsFolder)
+}
+
+func (ps *projectStore) file(id string) string {
+	return fmt.Sprintf("%s%c%s.%v", ps.folder(), os.PathSeparator, id, documentsFormatExtension)
+}
+
+// Gets the project file, refusing ids that could point outside the projects folder
+func (ps *projectStore) projectFile(id string) (string, error) {
+	if err := common.ValidateProjectId(id); err != nil {
+		return "", err
+	}
+	return ps.file(id), nil
+}
+
+func (ps *projectStore) LoadProjects() ([]model.Project, error) {
+	var out = make([]model.Project, 0)
+	if !utils.ExistsFileOrFolder(ps.folder()) {
+		return out, nil
+	}
+	files, err := ioutil.ReadDir(ps.folder())
+	if err != nil {
+		return nil, err
+	}
+	var suffix = fmt.Sprintf(".%v", documentsFormatExtension)
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), suffix) {
+			continue
+		}
+		var p = model.Project{
+			Versions: make([]model.ProjectVersion, 0),
+		}
+		if err := utils.LoadStructureByType(ps.file(strings.TrimSuffix(f.Name(), suffix)), &p, documentsFormatExtension); err != nil {
+			if ps.logger != nil {
+				ps.logger.Warnf("DeviceProjectStore::LoadProjects() - Unable to load project file %s, error: %v", f.Name(), err)
+			}
+			continue
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func (ps *projectStore) InsertProject(p model.Project) error {
+	return ps.SaveProject(p)
+}
+
+func (ps *projectStore) SaveProject(p model.Project) error {
+	file, err := ps.projectFile(p.Id)
+	if err != nil {
+		return err
+	}
+	if err := utils.CreateFolder(ps.folder()); err != nil {
+		return err
+	}
+	return utils.SaveStructureByType(file, &p, documentsFormatExtension)
+}
+
+func (ps *projectStore) DeleteProject(p model.Project) error {
+	file, err := ps.projectFile(p.Id)
+	if err != nil {
+		return err
+	}
+	return utils.DeleteFileOrFolder(file)
+}
+
+func GetProjectDataManager(baseFolder string, logger log.Logger) model.ProjectDataManager {
+	return common.NewProjectDataManager(&projectStore{
+		baseDataFolder: baseFolder,
+		logger:         logger,
+	}, logger)
+}
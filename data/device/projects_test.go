@@ -0,0 +1,37 @@
+package device
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hellgate75/k8s-deploy/model"
+)
+
+func TestProjectDataManagerRejectsUnsafeIds(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "projects-test")
+	defer os.RemoveAll(dir)
+	var data = filepath.Join(dir, "data")
+	var pm = GetProjectDataManager(data, nil)
+	for _, id := range []string{"../../outside", "a/b", "..", ""} {
+		var resp = pm.AddProject(model.Project{Id: id, Name: "project-" + id})
+		if id == "" {
+			if !resp.Success {
+				t.Fatalf("Expected generated id, found error: %s", resp.Message)
+			}
+			continue
+		}
+		if resp.Success {
+			t.Fatalf("Expected id %s rejected", id)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, fmt.Sprintf("outside.%v", documentsFormatExtension))); !os.IsNotExist(err) {
+		t.Fatal("Expected no project file outside the projects folder")
+	}
+	files, _ := ioutil.ReadDir(filepath.Join(data, projectsFolder))
+	if len(files) != 1 {
+		t.Fatalf("Expected only the generated id project file, found %v files", len(files))
+	}
+}
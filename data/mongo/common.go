@@ -0,0 +1,277 @@
+package mongo
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hellgate75/go-services/database"
+	"github.com/hellgate75/k8s-deploy/model"
+	"github.com/hellgate75/k8s-deploy/model/rest"
+	"github.com/hellgate75/k8s-deploy/utils"
+	"go.mongodb.org/mongo-driver/mongo"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	// Record identifier field, used as Mongo primary key
+	mongoIdField = "_id"
+	// Record field containing the json encoded model entity
+	mongoDataField = "data"
+	// Mongo duplicate key error code, returned when a unique index is violated
+	mongoDuplicateKeyCode = 11000
+)
+
+// Optional database.Connection extension, creating a simple or compound index on the collection fields.
+// The go-services Mongo connection doesn't provide it, so the Mongo driver is used in that case
+type indexCreator interface {
+	CreateIndex(dbRef database.DataRef, fields []database.Field, unique bool) error
+}
+
+// Optional database.Connection extension, returning all records matching the conditions.
+// The go-services Mongo connection Query returns only the first matching record, so the Mongo driver is used in that case
+type recordsFinder interface {
+	Find(dbRef database.DataRef, conditions []database.Condition) (database.ResultSet, error)
+}
+
+// Describes a collection index on one or more fields
+type collectionIndex struct {
+	fields []string
+	unique bool
+}
+
+// Collections already created on each connection, by database and collection name
+var ensuredCollections = make(map[database.Connection]map[string]bool)
+var ensuredCollectionsMutex sync.Mutex
+
+// Gets the database name, or the default storage name prefix when empty
+func databaseNameOrDefault(databaseName string) string {
+	if strings.TrimSpace(databaseName) == "" {
+		return rest.DefaultDatabaseNamePrefix
+	}
+	return strings.TrimSpace(databaseName)
+}
+
+// Translate a unique index violation into a name conflict error
+func uniqueNameError(err error, kind string, name string) error {
+	if isDuplicateKeyError(err) {
+		return errors.New(fmt.Sprintf("%s name %s already in use", kind, name))
+	}
+	return err
+}
+
+func collectionRef(databaseName string, collection string) database.DataRef {
+	return database.DataRef{
+		Database:  databaseName,
+		Namespace: collection,
+	}
+}
+
+// Gets the index creator of a connection, the connection itself or the Mongo driver behind it
+func getIndexCreator(conn database.Connection) (indexCreator, error) {
+	if ic, ok := conn.(indexCreator); ok {
+		return ic, nil
+	}
+	if dc := getDriverConnection(conn); dc != nil {
+		return dc, nil
+	}
+	return nil, errors.New(fmt.Sprintf("Connection %T doesn't support index creation", conn))
+}
+
+// Create the collection and its indexes. The identifier field is the collection primary key, so it's always indexed and unique
+func ensureCollection(conn database.Connection, databaseName string, collection string, fields []database.Field, indexes ...collectionIndex) error {
+	ensuredCollectionsMutex.Lock()
+	defer ensuredCollectionsMutex.Unlock()
+	var key = databaseName + "." + collection
+	if ensuredCollections[conn][key] {
+		return nil
+	}
+	var ref = collectionRef(databaseName, collection)
+	if err := conn.Create(ref, fields); err != nil {
+		return err
+	}
+	ic, err := getIndexCreator(conn)
+	if err != nil {
+		return err
+	}
+	for _, index := range indexes {
+		var indexFields = make([]database.Field, 0)
+		for _, name := range index.fields {
+			indexFields = append(indexFields, database.Field{Name: name, Type: "string"})
+		}
+		if err := ic.CreateIndex(ref, indexFields, index.unique); err != nil {
+			return errors.New(fmt.Sprintf("Unable to create index on %s fields %s, error: %v", key, strings.Join(index.fields, ", "), err))
+		}
+	}
+	if _, ok := ensuredCollections[conn]; !ok {
+		ensuredCollections[conn] = make(map[string]bool)
+	}
+	ensuredCollections[conn][key] = true
+	return nil
+}
+
+// Query all records matching the conditions
+func queryRecords(conn database.Connection, ref database.DataRef, fields []string, conditions []database.Condition) (database.ResultSet, error) {
+	if f, ok := conn.(recordsFinder); ok {
+		return f.Find(ref, conditions)
+	}
+	if dc := getDriverConnection(conn); dc != nil {
+		return dc.Find(ref, conditions)
+	}
+	return conn.Query(ref, fields, conditions)
+}
+
+// Verify if the error reports a unique index violation
+func isDuplicateKeyError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var bwe mongo.BulkWriteException
+	if errors.As(err, &bwe) {
+		for _, e := range bwe.WriteErrors {
+			if e.Code == mongoDuplicateKeyCode {
+				return true
+			}
+		}
+	}
+	var we mongo.WriteException
+	if errors.As(err, &we) {
+		for _, e := range we.WriteErrors {
+			if e.Code == mongoDuplicateKeyCode {
+				return true
+			}
+		}
+	}
+	return strings.Contains(err.Error(), fmt.Sprintf("E%d", mongoDuplicateKeyCode))
+}
+
+// Translate an aggregator into a Mongo field filter expression
+func aggregatorFilter(value string, cond model.Aggregator) interface{} {
+	switch cond {
+	case model.AggregatorNeq, model.AggregatorNot:
+		return map[string]interface{}{"$ne": value}
+	case model.AggregatorIn:
+		return map[string]interface{}{"$in": splitFilterValues(value)}
+	case model.AggregatorNotIn:
+		return map[string]interface{}{"$nin": splitFilterValues(value)}
+	case model.AggregatorLike:
+		return map[string]interface{}{"$regex": regexp.QuoteMeta(value)}
+	case model.AggregatorNotLike:
+		return map[string]interface{}{"$not": map[string]interface{}{"$regex": regexp.QuoteMeta(value)}}
+	}
+	return value
+}
+
+func splitFilterValues(value string) []interface{} {
+	var out = make([]interface{}, 0)
+	for _, v := range strings.Split(value, ",") {
+		out = append(out, strings.TrimSpace(v))
+	}
+	return out
+}
+
+// Translate a query into a Mongo filter document, combining the items via the query operator.
+// The fields map binds the query keys to the collection fields
+func queryFilter(fields map[string]string, q model.Query) (map[string]interface{}, error) {
+	var docs = make([]interface{}, 0)
+	for _, qi := range q.Items {
+		var key = utils.TrimFieldName(qi.Key)
+		field, ok := fields[key]
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("Unsupported query field: %s", qi.Key))
+		}
+		docs = append(docs, map[string]interface{}{field: aggregatorFilter(qi.Value, qi.Aggregator)})
+	}
+	switch q.Oper {
+	case model.OperOr:
+		return map[string]interface{}{"$or": docs}, nil
+	case model.OperNor:
+		return map[string]interface{}{"$nor": docs}, nil
+	case model.OperNAnd:
+		return map[string]interface{}{"$nor": []interface{}{map[string]interface{}{"$and": docs}}}, nil
+	}
+	return map[string]interface{}{"$and": docs}, nil
+}
+
+// Translate the queries into Mongo query conditions, queries are all required to match, or
+// just one of them when inclusive is true. No conditions are returned for empty queries
+func queryConditions(fields map[string]string, inclusive bool, q ...model.Query) ([]database.Condition, error) {
+	var docs = make([]interface{}, 0)
+	for _, qr := range q {
+		if len(qr.Items) == 0 {
+			continue
+		}
+		doc, err := queryFilter(fields, qr)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	if len(docs) == 0 {
+		return make([]database.Condition, 0), nil
+	}
+	var oper = "$and"
+	if inclusive {
+		oper = "$or"
+	}
+	return []database.Condition{
+		{
+			Field:     oper,
+			Operation: database.In,
+			Value: database.Value{
+				Type:  database.ListType,
+				Value: docs,
+			},
+		},
+	}, nil
+}
+
+// Equality condition on a single field
+func equalsCondition(field string, value string) database.Condition {
+	return database.Condition{
+		Field:     field,
+		Operation: database.Equals,
+		Value: database.Value{
+			Type:  database.StringType,
+			Value: value,
+		},
+	}
+}
+
+// Decode the json encoded entity of a record, stored in the last record field
+func decodeRecord(r database.Result, itf interface{}) error {
+	if len(r.Values) == 0 {
+		return errors.New("Empty record")
+	}
+	data, ok := r.Values[len(r.Values)-1].(string)
+	if !ok {
+		return errors.New(fmt.Sprintf("Invalid record %s field type: %T", mongoDataField, r.Values[len(r.Values)-1]))
+	}
+	return utils.JsonToStructure(data, itf)
+}
+
+// Encode an entity as json, to be stored in the record data field
+func encodeRecord(itf interface{}) (string, error) {
+	data, err := utils.StructureToJson(itf)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Mongo update document setting the given fields
+func setUpdate(fields map[string]interface{}) []database.Value {
+	return []database.Value{
+		{
+			Type:  database.StructType,
+			Value: map[string]interface{}{"$set": fields},
+		},
+	}
+}
+
+func appendMessage(message string, format string, in ...interface{}) string {
+	if len(message) > 0 {
+		message += ", "
+	}
+	return message + fmt.Sprintf(format, in...)
+}
@@ -0,0 +1,324 @@
+package mongo
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/hellgate75/go-services/database"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// In memory database.Connection, supporting the filters produced by the data managers and the unique indexes.
+// As the go-services Mongo connection, Query returns only the first matching record, while Find returns all of them
+type fakeConnection struct {
+	sync.Mutex
+	collections map[string][]bson.D
+	indexes     map[string][]fakeIndex
+	queries     int
+}
+
+type fakeIndex struct {
+	fields []string
+	unique bool
+}
+
+func newFakeConnection() *fakeConnection {
+	return &fakeConnection{
+		collections: make(map[string][]bson.D),
+		indexes:     make(map[string][]fakeIndex),
+	}
+}
+
+func fakeKey(dbRef database.DataRef) string {
+	return dbRef.Database + "." + dbRef.Namespace
+}
+
+func toDocument(v interface{}) (bson.D, error) {
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.D
+	err = bson.Unmarshal(data, &doc)
+	return doc, err
+}
+
+func documentValue(doc bson.D, field string) (interface{}, bool) {
+	for _, e := range doc {
+		if e.Key == field {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
+
+func toList(v interface{}) []interface{} {
+	switch t := v.(type) {
+	case []interface{}:
+		return t
+	case bson.A:
+		return t
+	}
+	return []interface{}{v}
+}
+
+func toFilter(v interface{}) map[string]interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		return t
+	case bson.M:
+		return t
+	}
+	return nil
+}
+
+// Match a field value against a plain value or an operators document
+func matchValue(value interface{}, found bool, expected interface{}) bool {
+	var ops = toFilter(expected)
+	if ops == nil {
+		return found && value == expected
+	}
+	for op, arg := range ops {
+		var ok bool
+		switch op {
+		case "$ne":
+			ok = !found || value != arg
+		case "$in", "$nin":
+			for _, item := range toList(arg) {
+				if found && value == item {
+					ok = true
+				}
+			}
+			if op == "$nin" {
+				ok = !ok
+			}
+		case "$regex":
+			ok = found && regexp.MustCompile(fmt.Sprintf("%v", arg)).MatchString(fmt.Sprintf("%v", value))
+		case "$not":
+			ok = !matchValue(value, found, arg)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func matchFilter(doc bson.D, filter map[string]interface{}) bool {
+	for key, expected := range filter {
+		switch key {
+		case "$and", "$or", "$nor":
+			var matches = 0
+			var items = toList(expected)
+			for _, item := range items {
+				if matchFilter(doc, toFilter(item)) {
+					matches++
+				}
+			}
+			if (key == "$and" && matches != len(items)) || (key == "$or" && matches == 0) || (key == "$nor" && matches > 0) {
+				return false
+			}
+		default:
+			value, found := documentValue(doc, key)
+			if !matchValue(value, found, expected) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func conditionsMap(conditions []database.Condition) map[string]interface{} {
+	var filter = make(map[string]interface{})
+	for _, c := range conditions {
+		filter[c.Field] = c.Value.Value
+	}
+	return filter
+}
+
+func (fc *fakeConnection) find(dbRef database.DataRef, conditions []database.Condition) []bson.D {
+	var filter = conditionsMap(conditions)
+	var out = make([]bson.D, 0)
+	for _, doc := range fc.collections[fakeKey(dbRef)] {
+		if matchFilter(doc, filter) {
+			out = append(out, doc)
+		}
+	}
+	return out
+}
+
+func resultSet(docs []bson.D) database.ResultSet {
+	var rs = database.ResultSet{Records: make([]database.Result, 0)}
+	for _, doc := range docs {
+		var res = database.Result{Columns: int64(len(doc)), Values: make([]interface{}, 0)}
+		for _, e := range doc {
+			res.Values = append(res.Values, e.Value)
+		}
+		rs.Records = append(rs.Records, res)
+	}
+	rs.Lines = int64(len(rs.Records))
+	return rs
+}
+
+// Verify the unique indexes, ignoring the document at the given position
+func (fc *fakeConnection) checkUnique(key string, doc bson.D, skip int) error {
+	var indexes = append([]fakeIndex{{fields: []string{mongoIdField}, unique: true}}, fc.indexes[key]...)
+	for i, other := range fc.collections[key] {
+		if i == skip {
+			continue
+		}
+		for _, index := range indexes {
+			if !index.unique {
+				continue
+			}
+			var same = true
+			for _, f := range index.fields {
+				a, _ := documentValue(doc, f)
+				b, _ := documentValue(other, f)
+				if a != b {
+					same = false
+					break
+				}
+			}
+			if same {
+				return errors.New(fmt.Sprintf("E11000 duplicate key error collection: %s index: %s", key, strings.Join(index.fields, "_")))
+			}
+		}
+	}
+	return nil
+}
+
+func (fc *fakeConnection) Find(dbRef database.DataRef, conditions []database.Condition) (database.ResultSet, error) {
+	fc.Lock()
+	defer fc.Unlock()
+	fc.queries++
+	return resultSet(fc.find(dbRef, conditions)), nil
+}
+
+func (fc *fakeConnection) Query(dbRef database.DataRef, fields []string, conditions []database.Condition) (database.ResultSet, error) {
+	fc.Lock()
+	defer fc.Unlock()
+	fc.queries++
+	var docs = fc.find(dbRef, conditions)
+	if len(docs) > 1 {
+		docs = docs[:1]
+	}
+	return resultSet(docs), nil
+}
+
+func (fc *fakeConnection) Insert(dbRef database.DataRef, fields []database.Field, values []database.Value) error {
+	fc.Lock()
+	defer fc.Unlock()
+	var key = fakeKey(dbRef)
+	for _, v := range values {
+		doc, err := toDocument(v.Value)
+		if err != nil {
+			return err
+		}
+		if err := fc.checkUnique(key, doc, -1); err != nil {
+			return err
+		}
+		fc.collections[key] = append(fc.collections[key], doc)
+	}
+	return nil
+}
+
+func (fc *fakeConnection) Update(dbRef database.DataRef, conditions []database.Condition, fields []database.Field, values []database.Value) (int64, error) {
+	fc.Lock()
+	defer fc.Unlock()
+	var key = fakeKey(dbRef)
+	var filter = conditionsMap(conditions)
+	var changes int64
+	for i, doc := range fc.collections[key] {
+		if !matchFilter(doc, filter) {
+			continue
+		}
+		var updated = append(bson.D{}, doc...)
+		for _, v := range values {
+			set, _ := toFilter(v.Value)["$set"].(map[string]interface{})
+			for field, value := range set {
+				var replaced = false
+				for j := range updated {
+					if updated[j].Key == field {
+						updated[j].Value = value
+						replaced = true
+					}
+				}
+				if !replaced {
+					updated = append(updated, bson.E{Key: field, Value: value})
+				}
+			}
+		}
+		if err := fc.checkUnique(key, updated, i); err != nil {
+			return changes, err
+		}
+		fc.collections[key][i] = updated
+		changes++
+	}
+	return changes, nil
+}
+
+func (fc *fakeConnection) Delete(dbRef database.DataRef, conditions []database.Condition) (int64, error) {
+	fc.Lock()
+	defer fc.Unlock()
+	var key = fakeKey(dbRef)
+	var filter = conditionsMap(conditions)
+	var kept = make([]bson.D, 0)
+	for _, doc := range fc.collections[key] {
+		if !matchFilter(doc, filter) {
+			kept = append(kept, doc)
+		}
+	}
+	var deleted = int64(len(fc.collections[key]) - len(kept))
+	fc.collections[key] = kept
+	return deleted, nil
+}
+
+func (fc *fakeConnection) Purge(dbRef database.DataRef) (int64, error) {
+	return fc.Delete(dbRef, nil)
+}
+
+func (fc *fakeConnection) Create(dbRef database.DataRef, fields []database.Field) error {
+	return nil
+}
+
+func (fc *fakeConnection) CreateIndex(dbRef database.DataRef, fields []database.Field, unique bool) error {
+	fc.Lock()
+	defer fc.Unlock()
+	var index = fakeIndex{unique: unique}
+	for _, f := range fields {
+		index.fields = append(index.fields, f.Name)
+	}
+	fc.indexes[fakeKey(dbRef)] = append(fc.indexes[fakeKey(dbRef)], index)
+	return nil
+}
+
+func (fc *fakeConnection) CreateDb(dbRef database.DataRef) error {
+	return nil
+}
+
+func (fc *fakeConnection) Drop(dbRef database.DataRef) error {
+	fc.Lock()
+	defer fc.Unlock()
+	delete(fc.collections, fakeKey(dbRef))
+	return nil
+}
+
+func (fc *fakeConnection) DropDb(dbRef database.DataRef) error {
+	return nil
+}
+
+func (fc *fakeConnection) Close() error {
+	return nil
+}
+
+func (fc *fakeConnection) IsConnected() bool {
+	return true
+}
+
+func (fc *fakeConnection) GetLastError() error {
+	return nil
+}
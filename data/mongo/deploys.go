@@ -0,0 +1,106 @@
+package mongo
+
+import (
+	"github.com/hellgate75/go-services/database"
+	"github.com/hellgate75/k8s-deploy/data/common"
+	"github.com/hellgate75/k8s-deploy/log"
+	"github.com/hellgate75/k8s-deploy/model"
+)
+
+const (
+	deploysCollection = "deploys"
+)
+
+// Deploys collection record, the deploy is stored as json data including jobs
+type deployRecord struct {
+	Id    string `bson:"_id"`
+	Name  string `bson:"name"`
+	State string `bson:"state"`
+	Data  string `bson:"data"`
+}
+
+type deployStore struct {
+	conn     database.Connection
+	database string
+	logger   log.Logger
+}
+
+// Create the deploys collection with a unique name index
+func (ds *deployStore) init() error {
+	return ensureCollection(ds.conn, ds.database, deploysCollection, []database.Field{
+		{Name: mongoIdField, Type: "string"},
+		{Name: "name", Type: "string"},
+		{Name: "state", Type: "string"},
+		{Name: mongoDataField, Type: "string"},
+	}, collectionIndex{fields: []string{"name"}, unique: true})
+}
+
+func (ds *deployStore) LoadDeploys() ([]model.Deploy, error) {
+	if err := ds.init(); err != nil {
+		return nil, err
+	}
+	rs, err := queryRecords(ds.conn, collectionRef(ds.database, deploysCollection), []string{mongoIdField, "name", "state", mongoDataField}, nil)
+	if err != nil {
+		return nil, err
+	}
+	var out = make([]model.Deploy, 0)
+	for _, rec := range rs.Records {
+		var d = model.Deploy{}
+		if err := decodeRecord(rec, &d); err != nil {
+			if ds.logger != nil {
+				ds.logger.Warnf("MongoDeployStore::LoadDeploys() - Unable to decode deploy record, error: %v", err)
+			}
+			continue
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+func (ds *deployStore) InsertDeploy(d model.Deploy) error {
+	if err := ds.init(); err != nil {
+		return err
+	}
+	data, err := encodeRecord(d)
+	if err != nil {
+		return err
+	}
+	err = ds.conn.Insert(collectionRef(ds.database, deploysCollection), nil, []database.Value{
+		{
+			Type: database.StructType,
+			Value: deployRecord{
+				Id:    d.Id,
+				Name:  d.Name,
+				State: string(d.State),
+				Data:  data,
+			},
+		},
+	})
+	return uniqueNameError(err, "Deploy", d.Name)
+}
+
+func (ds *deployStore) SaveDeploy(d model.Deploy) error {
+	data, err := encodeRecord(d)
+	if err != nil {
+		return err
+	}
+	_, err = ds.conn.Update(collectionRef(ds.database, deploysCollection), []database.Condition{equalsCondition(mongoIdField, d.Id)}, nil, setUpdate(map[string]interface{}{
+		"name":         d.Name,
+		"state":        string(d.State),
+		mongoDataField: data,
+	}))
+	return uniqueNameError(err, "Deploy", d.Name)
+}
+
+func (ds *deployStore) DeleteDeploy(d model.Deploy) error {
+	_, err := ds.conn.Delete(collectionRef(ds.database, deploysCollection), []database.Condition{equalsCondition(mongoIdField, d.Id)})
+	return err
+}
+
+func GetDeployDataManager(conn database.Connection, databaseName string, logger log.Logger) model.DeployDataManager {
+	return common.NewDeployDataManager(&deployStore{
+		conn:     conn,
+		database: databaseNameOrDefault(databaseName),
+		logger:   logger,
+	}, logger)
+}
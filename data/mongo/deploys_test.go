@@ -0,0 +1,133 @@
+package mongo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hellgate75/go-services/database"
+	"github.com/hellgate75/k8s-deploy/model"
+)
+
+func fieldQuery(key string, value string) model.Query {
+	return model.Query{
+		Oper: model.OperAnd,
+		Items: []model.QueryItem{
+			{Key: key, Value: value, Aggregator: model.AggregatorEq},
+		},
+	}
+}
+
+func TestDeployDataManagerDeploys(t *testing.T) {
+	var conn = newFakeConnection()
+	var dm = GetDeployDataManager(conn, "test-prefix", nil)
+	var resp = dm.AddDeploy(model.Deploy{Name: "release", Job: []model.Job{{Instance: model.Instance{Name: "web"}}}})
+	if !resp.Success {
+		t.Fatalf("Unexpected error: %s", resp.Message)
+	}
+	var d = resp.ResponseObjects[0].(model.Deploy)
+	if resp := dm.AddDeploy(model.Deploy{Name: "nightly"}); !resp.Success {
+		t.Fatalf("Unexpected error: %s", resp.Message)
+	}
+	if len(conn.collections["test-prefix."+deploysCollection]) != 2 {
+		t.Fatalf("Expected deploys stored in the test-prefix database, found: %v", conn.collections)
+	}
+	if resp := dm.AddDeploy(model.Deploy{Name: "release"}); resp.Success {
+		t.Fatal("Expected duplicate deploy name error")
+	}
+	if got := dm.GetDeploy(d.Id); got == nil || got.Name != "release" || len(got.Job) != 1 || got.Job[0].Instance.Id == "" {
+		t.Fatalf("Unexpected deploy: %+v", got)
+	}
+	if resp := dm.UpdateDeployState(d.Id, model.StateRunning); !resp.Success {
+		t.Fatalf("Unexpected error: %s", resp.Message)
+	}
+	if resp := dm.UpdateDeployState(d.Id, model.StateReady); resp.Success {
+		t.Fatal("Expected invalid state transition error")
+	}
+	resp = dm.QueryDeploys(fieldQuery("state", string(model.StateRunning)))
+	if !resp.Success || len(resp.ResponseObjects) != 1 || resp.ResponseObjects[0].(model.Deploy).Name != "release" {
+		t.Fatalf("Expected running deploy, found: %+v", resp.ResponseObjects)
+	}
+	// The stored state is queryable without decoding the deploy data
+	if docs := conn.find(collectionRef("test-prefix", deploysCollection), []database.Condition{equalsCondition("state", string(model.StateRunning))}); len(docs) != 1 {
+		t.Fatalf("Expected deploy record state updated, found: %v", docs)
+	}
+	if resp := dm.PurgeDeploys(fieldQuery("name", "release")); resp.Success || resp.Changes != 0 {
+		t.Fatalf("Expected running deploy purge refused, found: %+v", resp)
+	}
+	resp = dm.OverrideDeploy(d.Id, model.Deploy{Name: "release-1", State: model.StateComplete})
+	if !resp.Success || dm.GetDeployByName("release-1") == nil || dm.GetDeployByName("release") != nil {
+		t.Fatalf("Expected deploy renamed, found: %+v", resp)
+	}
+	if resp := dm.DeleteDeploys(fieldQuery("name", "release-1")); !resp.Success || resp.Changes != 1 {
+		t.Fatalf("Expected deploy deleted, found: %+v", resp)
+	}
+	if resp := dm.PurgeDeploys(fieldQuery("state", string(model.StateDeleted))); !resp.Success || resp.Changes != 1 {
+		t.Fatalf("Expected deploy purged, found: %+v", resp)
+	}
+	if resp := dm.ListDeploys(); !resp.Success || len(resp.ResponseObjects) != 1 {
+		t.Fatalf("Expected 1 deploy left, found: %+v", resp.ResponseObjects)
+	}
+}
+
+func TestDeployDataManagerUniqueNameIndex(t *testing.T) {
+	var conn = newFakeConnection()
+	var ds = &deployStore{conn: conn, database: "test-prefix"}
+	if err := ds.InsertDeploy(model.Deploy{Id: "first", Name: "release", State: model.StateCreated}); err != nil {
+		t.Fatal(err)
+	}
+	var indexes = conn.indexes["test-prefix."+deploysCollection]
+	if len(indexes) != 1 || !indexes[0].unique || indexes[0].fields[0] != "name" {
+		t.Fatalf("Expected unique name index, found: %+v", indexes)
+	}
+	// The index rejects the duplicate even bypassing the manager name check
+	err := ds.InsertDeploy(model.Deploy{Id: "second", Name: "release", State: model.StateCreated})
+	if err == nil || !strings.Contains(err.Error(), "already in use") {
+		t.Fatalf("Expected name already in use error, found: %v", err)
+	}
+	if err := ds.InsertDeploy(model.Deploy{Id: "third", Name: "nightly", State: model.StateCreated}); err != nil {
+		t.Fatal(err)
+	}
+	err = ds.SaveDeploy(model.Deploy{Id: "third", Name: "release", State: model.StateCreated})
+	if err == nil || !strings.Contains(err.Error(), "already in use") {
+		t.Fatalf("Expected name already in use error on save, found: %v", err)
+	}
+}
+
+func TestDeployDataManagerJobs(t *testing.T) {
+	var conn = newFakeConnection()
+	var dm = GetDeployDataManager(conn, "test-prefix", nil)
+	var resp = dm.AddDeploy(model.Deploy{Name: "release"})
+	if !resp.Success {
+		t.Fatalf("Unexpected error: %s", resp.Message)
+	}
+	var jm = *dm.AccessDeploy(resp.ResponseObjects[0].(model.Deploy))
+	for _, name := range []string{"db", "web"} {
+		if resp := jm.AddJob(model.Job{Instance: model.Instance{Name: name}}); !resp.Success {
+			t.Fatalf("Unexpected error: %s", resp.Message)
+		}
+	}
+	if resp := jm.AddJob(model.Job{Instance: model.Instance{Name: "db"}}); resp.Success {
+		t.Fatal("Expected duplicate job error")
+	}
+	var web = jm.GetJobByName("web")
+	if web == nil {
+		t.Fatal("Expected job web")
+	}
+	if resp := jm.UpdateJobState(web.Instance.Id, model.StateRunning); !resp.Success {
+		t.Fatalf("Unexpected error: %s", resp.Message)
+	}
+	if resp := jm.UpdateJobState(web.Instance.Id, model.StateCreated); resp.Success {
+		t.Fatal("Expected invalid job state transition error")
+	}
+	resp = jm.QueryJobs(fieldQuery("state", string(model.StateRunning)))
+	if !resp.Success || len(resp.ResponseObjects) != 1 || resp.ResponseObjects[0].(model.Job).Instance.Name != "web" {
+		t.Fatalf("Expected running job web, found: %+v", resp.ResponseObjects)
+	}
+	if resp := jm.PurgeJobs(fieldQuery("name", "db")); !resp.Success || resp.Changes != 1 {
+		t.Fatalf("Expected job purged, found: %+v", resp)
+	}
+	var stored = GetDeployDataManager(conn, "test-prefix", nil).GetDeployByName("release")
+	if stored == nil || len(stored.Job) != 1 || stored.Job[0].Instance.Name != "web" || stored.Job[0].State != model.StateRunning {
+		t.Fatalf("Unexpected stored deploy: %+v", stored)
+	}
+}
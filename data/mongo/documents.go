@@ -1,186 +1,727 @@
 package mongo
 
 import (
+	"errors"
+	"fmt"
 	"github.com/hellgate75/go-services/database"
 	"github.com/hellgate75/k8s-deploy/model"
+	"github.com/hellgate75/k8s-deploy/utils"
+	"strings"
 )
 
+const (
+	chartsCollection           = "charts"
+	chartVersionsCollection    = "chart_versions"
+	kubeFilesCollection        = "kubefiles"
+	kubeFileVersionsCollection = "kubefile_versions"
+	// Field scoping the documents records to the owner repository id
+	repositoryField = "repository"
+	// Field binding the version records to the owner document id
+	documentField = "document"
+)
+
+// Query keys bound to the documents collections fields
+var documentQueryFields = map[string]string{
+	"id":    mongoIdField,
+	"name":  "name",
+	"state": "state",
+}
+
+// Query keys bound to the versions collections fields
+var versionQueryFields = map[string]string{
+	"id":      mongoIdField,
+	"name":    "name",
+	"version": "name",
+	"state":   "state",
+}
+
+// Charts and Kubernetes files collection record, the document is stored as json data without versions
+type documentRecord struct {
+	Id         string `bson:"_id"`
+	Repository string `bson:"repository"`
+	Name       string `bson:"name"`
+	State      string `bson:"state"`
+	Data       string `bson:"data"`
+}
+
+// Charts and Kubernetes files versions collection record
+type versionRecord struct {
+	Id         string `bson:"_id"`
+	Repository string `bson:"repository"`
+	Document   string `bson:"document"`
+	Name       string `bson:"name"`
+	State      string `bson:"state"`
+	Data       string `bson:"data"`
+}
+
+// Json data of a version record, carrying the owner document id
+type versionData struct {
+	Document string        `json:"document"`
+	Version  model.Version `json:"version"`
+}
+
+// Collections of a document kind
+type documentsKind struct {
+	collection         string
+	versionsCollection string
+}
+
+var (
+	chartsKind = documentsKind{
+		collection:         chartsCollection,
+		versionsCollection: chartVersionsCollection,
+	}
+	kubeFilesKind = documentsKind{
+		collection:         kubeFilesCollection,
+		versionsCollection: kubeFileVersionsCollection,
+	}
+)
+
+// Charts and Kubernetes files share the same structure, so documents are managed as model.Chart
+// and converted to model.KubernetesFile on the Kubernetes files side. Only metadata are stored
+// in Mongo, charts and Kubernetes files payloads stay in the repository device folders
 type _documentsManager struct {
-	conn database.Connection
-	repo *model.Repository
+	conn     database.Connection
+	database string
+	repo     *model.Repository
 }
 
-func (dm *_documentsManager) AddChart(c model.Chart) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+// Create the documents and versions collections. The unique indexes grant unique document names
+// in a repository and unique version names in a document
+func (dm *_documentsManager) init() error {
+	for _, kind := range []documentsKind{chartsKind, kubeFilesKind} {
+		err := ensureCollection(dm.conn, dm.database, kind.collection, []database.Field{
+			{Name: mongoIdField, Type: "string"},
+			{Name: repositoryField, Type: "string"},
+			{Name: "name", Type: "string"},
+			{Name: "state", Type: "string"},
+			{Name: mongoDataField, Type: "string"},
+		}, collectionIndex{fields: []string{repositoryField, "name"}, unique: true})
+		if err != nil {
+			return err
+		}
+		err = ensureCollection(dm.conn, dm.database, kind.versionsCollection, []database.Field{
+			{Name: mongoIdField, Type: "string"},
+			{Name: repositoryField, Type: "string"},
+			{Name: documentField, Type: "string"},
+			{Name: "name", Type: "string"},
+			{Name: "state", Type: "string"},
+			{Name: mongoDataField, Type: "string"},
+		}, collectionIndex{fields: []string{repositoryField, documentField, "name"}, unique: true})
+		if err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-func (dm *_documentsManager) AddKubeFile(f model.KubernetesFile) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+func (dm *_documentsManager) check() error {
+	if dm.repo == nil {
+		return errors.New("Repository is required")
 	}
+	return dm.init()
 }
 
-func (dm *_documentsManager) AddChartVersion(c model.Chart, v model.Version) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+// Conditions scoped to the manager repository
+func (dm *_documentsManager) scoped(conditions ...database.Condition) []database.Condition {
+	return append([]database.Condition{equalsCondition(repositoryField, dm.repo.Id)}, conditions...)
+}
+
+func (dm *_documentsManager) findVersions(kind documentsKind, conditions ...database.Condition) ([]versionData, error) {
+	rs, err := queryRecords(dm.conn, collectionRef(dm.database, kind.versionsCollection), []string{mongoIdField, repositoryField, documentField, "name", "state", mongoDataField}, dm.scoped(conditions...))
+	if err != nil {
+		return nil, err
+	}
+	var out = make([]versionData, 0)
+	for _, rec := range rs.Records {
+		var v = versionData{}
+		if err := decodeRecord(rec, &v); err != nil {
+			return nil, err
+		}
+		out = append(out, v)
 	}
+	return out, nil
 }
 
-func (dm *_documentsManager) AddKubeFileVersion(f model.KubernetesFile, v model.Version) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+// Find the documents matching the conditions, including their versions
+func (dm *_documentsManager) findDocuments(kind documentsKind, conditions ...database.Condition) ([]model.Chart, error) {
+	rs, err := queryRecords(dm.conn, collectionRef(dm.database, kind.collection), []string{mongoIdField, repositoryField, "name", "state", mongoDataField}, dm.scoped(conditions...))
+	if err != nil {
+		return nil, err
+	}
+	var out = make([]model.Chart, 0)
+	if len(rs.Records) == 0 {
+		return out, nil
 	}
+	versions, err := dm.findVersions(kind)
+	if err != nil {
+		return nil, err
+	}
+	for _, rec := range rs.Records {
+		var d = model.Chart{}
+		if err := decodeRecord(rec, &d); err != nil {
+			return nil, err
+		}
+		d.Versions = make([]model.Version, 0)
+		for _, v := range versions {
+			if v.Document == d.Id {
+				d.Versions = append(d.Versions, v.Version)
+			}
+		}
+		out = append(out, d)
+	}
+	return out, nil
 }
 
-func (dm *_documentsManager) RemoveCharts(q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+func (dm *_documentsManager) filterDocuments(kind documentsKind, q ...model.Query) ([]model.Chart, error) {
+	conditions, err := queryConditions(documentQueryFields, false, q...)
+	if err != nil {
+		return nil, err
 	}
+	return dm.findDocuments(kind, conditions...)
 }
 
-func (dm *_documentsManager) RemoveKubeFiles(q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+// Find the document referenced by name or, when the name is empty, by id
+func (dm *_documentsManager) findDocument(kind documentsKind, ref model.Chart) (*model.Chart, error) {
+	var cond = equalsCondition(mongoIdField, ref.Id)
+	var key = ref.Id
+	if strings.TrimSpace(ref.Name) != "" {
+		cond = equalsCondition("name", strings.TrimSpace(ref.Name))
+		key = ref.Name
 	}
+	docs, err := dm.findDocuments(kind, cond)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, errors.New(fmt.Sprintf("Document %s not found in %s of repository %s", key, kind.collection, dm.repo.Name))
+	}
+	return &docs[0], nil
 }
 
-func (dm *_documentsManager) RemoveChartVersions(c model.Chart, q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+func (dm *_documentsManager) insertDocument(kind documentsKind, d model.Chart) error {
+	var doc = d
+	doc.Versions = nil
+	data, err := encodeRecord(doc)
+	if err != nil {
+		return err
 	}
+	return dm.conn.Insert(collectionRef(dm.database, kind.collection), nil, []database.Value{
+		{
+			Type: database.StructType,
+			Value: documentRecord{
+				Id:         d.Id,
+				Repository: dm.repo.Id,
+				Name:       d.Name,
+				State:      string(d.State),
+				Data:       data,
+			},
+		},
+	})
 }
 
-func (dm *_documentsManager) RemoveKubeFileVersions(f model.KubernetesFile, q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+func (dm *_documentsManager) saveDocument(kind documentsKind, d model.Chart) error {
+	var doc = d
+	doc.Versions = nil
+	data, err := encodeRecord(doc)
+	if err != nil {
+		return err
 	}
+	_, err = dm.conn.Update(collectionRef(dm.database, kind.collection), dm.scoped(equalsCondition(mongoIdField, d.Id)), nil, setUpdate(map[string]interface{}{
+		"name":         d.Name,
+		"state":        string(d.State),
+		mongoDataField: data,
+	}))
+	return err
 }
 
-func (dm *_documentsManager) PurgeCharts(q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+func (dm *_documentsManager) insertVersion(kind documentsKind, docId string, v model.Version) error {
+	data, err := encodeRecord(versionData{Document: docId, Version: v})
+	if err != nil {
+		return err
 	}
+	return dm.conn.Insert(collectionRef(dm.database, kind.versionsCollection), nil, []database.Value{
+		{
+			Type: database.StructType,
+			Value: versionRecord{
+				Id:         v.Id,
+				Repository: dm.repo.Id,
+				Document:   docId,
+				Name:       v.Name,
+				State:      string(v.State),
+				Data:       data,
+			},
+		},
+	})
 }
 
-func (dm *_documentsManager) PurgeKubeFiles(q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+func (dm *_documentsManager) saveVersion(kind documentsKind, docId string, v model.Version) error {
+	data, err := encodeRecord(versionData{Document: docId, Version: v})
+	if err != nil {
+		return err
 	}
+	_, err = dm.conn.Update(collectionRef(dm.database, kind.versionsCollection), dm.scoped(equalsCondition(mongoIdField, v.Id)), nil, setUpdate(map[string]interface{}{
+		"name":         v.Name,
+		"state":        string(v.State),
+		mongoDataField: data,
+	}))
+	return err
 }
 
-func (dm *_documentsManager) PurgeChartVersions(c model.Chart, q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+// Save the document versions, inserting the new ones and deleting the ones not in the document anymore
+func (dm *_documentsManager) saveVersions(kind documentsKind, d model.Chart) error {
+	current, err := dm.findVersions(kind, equalsCondition(documentField, d.Id))
+	if err != nil {
+		return err
+	}
+	for _, c := range current {
+		var found = false
+		for _, v := range d.Versions {
+			if c.Version.Id == v.Id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			if _, err := dm.conn.Delete(collectionRef(dm.database, kind.versionsCollection), dm.scoped(equalsCondition(mongoIdField, c.Version.Id))); err != nil {
+				return err
+			}
+		}
+	}
+	for _, v := range d.Versions {
+		var exists = false
+		for _, c := range current {
+			if c.Version.Id == v.Id {
+				exists = true
+				break
+			}
+		}
+		if exists {
+			err = dm.saveVersion(kind, d.Id, v)
+		} else {
+			err = dm.insertVersion(kind, d.Id, v)
+		}
+		if err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-func (dm *_documentsManager) PurgeKubeFileVersions(f model.KubernetesFile, q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+// Assign identifier and created state to a new version
+func newVersion(v model.Version) model.Version {
+	v.Name = strings.TrimSpace(v.Name)
+	if v.Id == "" {
+		v.Id = utils.NewUniqueIdentifier()
+	}
+	if v.State == "" {
+		v.State = model.StateCreated
 	}
+	return v
 }
 
-func (dm *_documentsManager) UpdateCharts(c model.Chart, q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+// Convert a document to the response object of its kind
+func documentObject(kind documentsKind, d model.Chart) interface{} {
+	if kind == kubeFilesKind {
+		return model.KubernetesFile(d)
 	}
+	return d
 }
 
-func (dm *_documentsManager) UpdateKubeFiles(f model.KubernetesFile, v model.Version, q ...model.Query) model.DataResponse {
+func documentsResponse(objects []interface{}, changes int64, message string) model.DataResponse {
+	if len(message) == 0 {
+		return model.DataResponse{
+			Success:         true,
+			Message:         "OK",
+			Changes:         changes,
+			ResponseObjects: objects,
+		}
+	}
 	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+		Success:         false,
+		Message:         message,
+		Changes:         changes,
+		ResponseObjects: objects,
 	}
 }
 
-func (dm *_documentsManager) UpdateChartVersions(c model.Chart, v model.Version, q ...model.Query) model.DataResponse {
+func documentsErrorResponse(format string, in ...interface{}) model.DataResponse {
 	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+		Success:         false,
+		Message:         fmt.Sprintf(format, in...),
+		ResponseObjects: make([]interface{}, 0),
 	}
 }
 
-func (dm *_documentsManager) UpdateKubeFileVersions(f model.KubernetesFile, v model.Version, q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+func (dm *_documentsManager) addDocument(kind documentsKind, d model.Chart) model.DataResponse {
+	if err := dm.check(); err != nil {
+		return documentsErrorResponse("%v", err)
+	}
+	d.Name = strings.TrimSpace(d.Name)
+	if d.Name == "" {
+		return documentsErrorResponse("Name is required to add a document in %s of repository %s", kind.collection, dm.repo.Name)
+	}
+	if d.Id == "" {
+		d.Id = utils.NewUniqueIdentifier()
+	}
+	if d.State == "" {
+		d.State = model.StateCreated
+	}
+	if d.Versions == nil {
+		d.Versions = make([]model.Version, 0)
+	}
+	for i := range d.Versions {
+		d.Versions[i] = newVersion(d.Versions[i])
 	}
+	if err := dm.insertDocument(kind, d); isDuplicateKeyError(err) {
+		return documentsErrorResponse("Document %s already exists in %s of repository %s", d.Name, kind.collection, dm.repo.Name)
+	} else if err != nil {
+		return documentsErrorResponse("Error saving document %s in %s of repository %s, error: %v", d.Name, kind.collection, dm.repo.Name, err)
+	}
+	for _, v := range d.Versions {
+		if err := dm.insertVersion(kind, d.Id, v); err != nil {
+			return documentsErrorResponse("Error saving document %s version %s in %s of repository %s, error: %v", d.Name, v.Name, kind.collection, dm.repo.Name, err)
+		}
+	}
+	return documentsResponse([]interface{}{documentObject(kind, d)}, 1, "")
 }
 
-func (dm *_documentsManager) QueryCharts(q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+func (dm *_documentsManager) addVersion(kind documentsKind, ref model.Chart, v model.Version) model.DataResponse {
+	if err := dm.check(); err != nil {
+		return documentsErrorResponse("%v", err)
+	}
+	doc, err := dm.findDocument(kind, ref)
+	if err != nil {
+		return documentsErrorResponse("%v", err)
 	}
+	v = newVersion(v)
+	if v.Name == "" {
+		return documentsErrorResponse("Version name is required to add a version to %s in %s of repository %s", doc.Name, kind.collection, dm.repo.Name)
+	}
+	if err := dm.insertVersion(kind, doc.Id, v); isDuplicateKeyError(err) {
+		return documentsErrorResponse("Version %s already exists for %s in %s of repository %s", v.Name, doc.Name, kind.collection, dm.repo.Name)
+	} else if err != nil {
+		return documentsErrorResponse("Error saving document %s version %s in %s of repository %s, error: %v", doc.Name, v.Name, kind.collection, dm.repo.Name, err)
+	}
+	return documentsResponse([]interface{}{v}, 1, "")
 }
 
-func (dm *_documentsManager) QueryKubeFiles(q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+// Apply a change to all documents matching the queries, saving the document and its versions
+func (dm *_documentsManager) updateDocuments(kind documentsKind, change func(d *model.Chart), q ...model.Query) model.DataResponse {
+	if err := dm.check(); err != nil {
+		return documentsErrorResponse("%v", err)
+	}
+	docs, err := dm.filterDocuments(kind, q...)
+	if err != nil {
+		return documentsErrorResponse("Error loading %s of repository %s, error: %v", kind.collection, dm.repo.Name, err)
+	}
+	var objects = make([]interface{}, 0)
+	var message = ""
+	var changes int64
+	for _, d := range docs {
+		change(&d)
+		if err := dm.saveDocument(kind, d); err != nil {
+			message = appendMessage(message, "document: %s - Error: %v", d.Name, err)
+			continue
+		}
+		if err := dm.saveVersions(kind, d); err != nil {
+			message = appendMessage(message, "document: %s - Error: %v", d.Name, err)
+			continue
+		}
+		changes++
+		objects = append(objects, documentObject(kind, d))
 	}
+	return documentsResponse(objects, changes, message)
 }
 
-func (dm *_documentsManager) QueryChartVersions(c model.Chart, q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+// Permanently delete the documents matching the queries, including all versions
+func (dm *_documentsManager) purgeDocuments(kind documentsKind, q ...model.Query) model.DataResponse {
+	if err := dm.check(); err != nil {
+		return documentsErrorResponse("%v", err)
+	}
+	docs, err := dm.filterDocuments(kind, q...)
+	if err != nil {
+		return documentsErrorResponse("Error loading %s of repository %s, error: %v", kind.collection, dm.repo.Name, err)
 	}
+	var objects = make([]interface{}, 0)
+	var message = ""
+	var changes int64
+	for _, d := range docs {
+		if _, err := dm.conn.Delete(collectionRef(dm.database, kind.versionsCollection), dm.scoped(equalsCondition(documentField, d.Id))); err != nil {
+			message = appendMessage(message, "document: %s - Error: %v", d.Name, err)
+			continue
+		}
+		if _, err := dm.conn.Delete(collectionRef(dm.database, kind.collection), dm.scoped(equalsCondition(mongoIdField, d.Id))); err != nil {
+			message = appendMessage(message, "document: %s - Error: %v", d.Name, err)
+			continue
+		}
+		changes++
+		d.State = model.StatePutged
+		objects = append(objects, documentObject(kind, d))
+	}
+	return documentsResponse(objects, changes, message)
 }
 
-func (dm *_documentsManager) QueryKubeFileVersions(f model.KubernetesFile, q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+func (dm *_documentsManager) filterVersions(kind documentsKind, docId string, q ...model.Query) ([]model.Version, error) {
+	conditions, err := queryConditions(versionQueryFields, false, q...)
+	if err != nil {
+		return nil, err
 	}
+	versions, err := dm.findVersions(kind, append([]database.Condition{equalsCondition(documentField, docId)}, conditions...)...)
+	if err != nil {
+		return nil, err
+	}
+	var out = make([]model.Version, 0)
+	for _, v := range versions {
+		out = append(out, v.Version)
+	}
+	return out, nil
 }
 
-func (dm *_documentsManager) ListCharts() model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+// Apply a change to all versions of a document matching the queries
+func (dm *_documentsManager) updateVersions(kind documentsKind, ref model.Chart, change func(v *model.Version), q ...model.Query) model.DataResponse {
+	if err := dm.check(); err != nil {
+		return documentsErrorResponse("%v", err)
+	}
+	doc, err := dm.findDocument(kind, ref)
+	if err != nil {
+		return documentsErrorResponse("%v", err)
+	}
+	versions, err := dm.filterVersions(kind, doc.Id, q...)
+	if err != nil {
+		return documentsErrorResponse("Error loading %s versions of repository %s, error: %v", doc.Name, dm.repo.Name, err)
 	}
+	var objects = make([]interface{}, 0)
+	var message = ""
+	for _, v := range versions {
+		change(&v)
+		if err := dm.saveVersion(kind, doc.Id, v); err != nil {
+			message = appendMessage(message, "version: %s - Error: %v", v.Name, err)
+			continue
+		}
+		objects = append(objects, v)
+	}
+	return documentsResponse(objects, int64(len(objects)), message)
 }
 
-func (dm *_documentsManager) ListKubeFiles() model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+// Permanently delete the versions of a document matching the queries
+func (dm *_documentsManager) purgeVersions(kind documentsKind, ref model.Chart, q ...model.Query) model.DataResponse {
+	if err := dm.check(); err != nil {
+		return documentsErrorResponse("%v", err)
+	}
+	doc, err := dm.findDocument(kind, ref)
+	if err != nil {
+		return documentsErrorResponse("%v", err)
+	}
+	versions, err := dm.filterVersions(kind, doc.Id, q...)
+	if err != nil {
+		return documentsErrorResponse("Error loading %s versions of repository %s, error: %v", doc.Name, dm.repo.Name, err)
 	}
+	var objects = make([]interface{}, 0)
+	var message = ""
+	for _, v := range versions {
+		if _, err := dm.conn.Delete(collectionRef(dm.database, kind.versionsCollection), dm.scoped(equalsCondition(mongoIdField, v.Id))); err != nil {
+			message = appendMessage(message, "version: %s - Error: %v", v.Name, err)
+			continue
+		}
+		v.State = model.StatePutged
+		objects = append(objects, v)
+	}
+	return documentsResponse(objects, int64(len(objects)), message)
 }
 
-func (dm *_documentsManager) ListChartVersions(q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+func (dm *_documentsManager) queryDocuments(kind documentsKind, q ...model.Query) model.DataResponse {
+	if err := dm.check(); err != nil {
+		return documentsErrorResponse("%v", err)
 	}
+	docs, err := dm.filterDocuments(kind, q...)
+	if err != nil {
+		return documentsErrorResponse("Error loading %s of repository %s, error: %v", kind.collection, dm.repo.Name, err)
+	}
+	var objects = make([]interface{}, 0)
+	for _, d := range docs {
+		objects = append(objects, documentObject(kind, d))
+	}
+	return documentsResponse(objects, 0, "")
 }
 
-func (dm *_documentsManager) ListKubeFileVersions(q ...model.Query) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+func (dm *_documentsManager) queryVersions(kind documentsKind, ref model.Chart, q ...model.Query) model.DataResponse {
+	if err := dm.check(); err != nil {
+		return documentsErrorResponse("%v", err)
+	}
+	doc, err := dm.findDocument(kind, ref)
+	if err != nil {
+		return documentsErrorResponse("%v", err)
+	}
+	versions, err := dm.filterVersions(kind, doc.Id, q...)
+	if err != nil {
+		return documentsErrorResponse("Error loading %s versions of repository %s, error: %v", doc.Name, dm.repo.Name, err)
+	}
+	var objects = make([]interface{}, 0)
+	for _, v := range versions {
+		objects = append(objects, v)
+	}
+	return documentsResponse(objects, 0, "")
+}
+
+// List the versions of all documents matching the queries
+func (dm *_documentsManager) listVersions(kind documentsKind, q ...model.Query) model.DataResponse {
+	if err := dm.check(); err != nil {
+		return documentsErrorResponse("%v", err)
+	}
+	docs, err := dm.filterDocuments(kind, q...)
+	if err != nil {
+		return documentsErrorResponse("Error loading %s of repository %s, error: %v", kind.collection, dm.repo.Name, err)
+	}
+	var objects = make([]interface{}, 0)
+	for _, d := range docs {
+		for _, v := range d.Versions {
+			objects = append(objects, v)
+		}
+	}
+	return documentsResponse(objects, 0, "")
+}
+
+// Update the state and, when provided, the versions of the matching documents
+func updateDocumentFunc(d model.Chart) func(doc *model.Chart) {
+	return func(doc *model.Chart) {
+		if d.State != "" {
+			doc.State = d.State
+		}
+		if len(d.Versions) > 0 {
+			doc.Versions = make([]model.Version, 0)
+			for _, v := range d.Versions {
+				doc.Versions = append(doc.Versions, newVersion(v))
+			}
+		}
 	}
 }
 
-func GetDocumentDataManager(conn database.Connection, repo *model.Repository) model.DocumentsDataManager {
+// Update the state of the matching versions
+func updateVersionFunc(v model.Version) func(ver *model.Version) {
+	return func(ver *model.Version) {
+		if v.State != "" {
+			ver.State = v.State
+		}
+	}
+}
+
+func (dm *_documentsManager) AddChart(c model.Chart) model.DataResponse {
+	return dm.addDocument(chartsKind, c)
+}
+
+func (dm *_documentsManager) AddKubeFile(f model.KubernetesFile) model.DataResponse {
+	return dm.addDocument(kubeFilesKind, model.Chart(f))
+}
+
+func (dm *_documentsManager) AddChartVersion(c model.Chart, v model.Version) model.DataResponse {
+	return dm.addVersion(chartsKind, c, v)
+}
+
+func (dm *_documentsManager) AddKubeFileVersion(f model.KubernetesFile, v model.Version) model.DataResponse {
+	return dm.addVersion(kubeFilesKind, model.Chart(f), v)
+}
+
+func (dm *_documentsManager) RemoveCharts(q ...model.Query) model.DataResponse {
+	return dm.updateDocuments(chartsKind, updateDocumentFunc(model.Chart{State: model.StateDeleted}), q...)
+}
+
+func (dm *_documentsManager) RemoveKubeFiles(q ...model.Query) model.DataResponse {
+	return dm.updateDocuments(kubeFilesKind, updateDocumentFunc(model.Chart{State: model.StateDeleted}), q...)
+}
+
+func (dm *_documentsManager) RemoveChartVersions(c model.Chart, q ...model.Query) model.DataResponse {
+	return dm.updateVersions(chartsKind, c, updateVersionFunc(model.Version{State: model.StateDeleted}), q...)
+}
+
+func (dm *_documentsManager) RemoveKubeFileVersions(f model.KubernetesFile, q ...model.Query) model.DataResponse {
+	return dm.updateVersions(kubeFilesKind, model.Chart(f), updateVersionFunc(model.Version{State: model.StateDeleted}), q...)
+}
+
+func (dm *_documentsManager) PurgeCharts(q ...model.Query) model.DataResponse {
+	return dm.purgeDocuments(chartsKind, q...)
+}
+
+func (dm *_documentsManager) PurgeKubeFiles(q ...model.Query) model.DataResponse {
+	return dm.purgeDocuments(kubeFilesKind, q...)
+}
+
+func (dm *_documentsManager) PurgeChartVersions(c model.Chart, q ...model.Query) model.DataResponse {
+	return dm.purgeVersions(chartsKind, c, q...)
+}
+
+func (dm *_documentsManager) PurgeKubeFileVersions(f model.KubernetesFile, q ...model.Query) model.DataResponse {
+	return dm.purgeVersions(kubeFilesKind, model.Chart(f), q...)
+}
+
+func (dm *_documentsManager) UpdateCharts(c model.Chart, q ...model.Query) model.DataResponse {
+	return dm.updateDocuments(chartsKind, updateDocumentFunc(c), q...)
+}
+
+func (dm *_documentsManager) UpdateKubeFiles(f model.KubernetesFile, v model.Version, q ...model.Query) model.DataResponse {
+	var update = updateDocumentFunc(model.Chart(f))
+	return dm.updateDocuments(kubeFilesKind, func(doc *model.Chart) {
+		update(doc)
+		if strings.TrimSpace(v.Name) == "" {
+			return
+		}
+		// Replace or add the given version
+		for i := range doc.Versions {
+			if doc.Versions[i].Name == v.Name {
+				var id = doc.Versions[i].Id
+				doc.Versions[i] = v
+				doc.Versions[i].Id = id
+				return
+			}
+		}
+		doc.Versions = append(doc.Versions, newVersion(v))
+	}, q...)
+}
+
+func (dm *_documentsManager) UpdateChartVersions(c model.Chart, v model.Version, q ...model.Query) model.DataResponse {
+	return dm.updateVersions(chartsKind, c, updateVersionFunc(v), q...)
+}
+
+func (dm *_documentsManager) UpdateKubeFileVersions(f model.KubernetesFile, v model.Version, q ...model.Query) model.DataResponse {
+	return dm.updateVersions(kubeFilesKind, model.Chart(f), updateVersionFunc(v), q...)
+}
+
+func (dm *_documentsManager) QueryCharts(q ...model.Query) model.DataResponse {
+	return dm.queryDocuments(chartsKind, q...)
+}
+
+func (dm *_documentsManager) QueryKubeFiles(q ...model.Query) model.DataResponse {
+	return dm.queryDocuments(kubeFilesKind, q...)
+}
+
+func (dm *_documentsManager) QueryChartVersions(c model.Chart, q ...model.Query) model.DataResponse {
+	return dm.queryVersions(chartsKind, c, q...)
+}
+
+func (dm *_documentsManager) QueryKubeFileVersions(f model.KubernetesFile, q ...model.Query) model.DataResponse {
+	return dm.queryVersions(kubeFilesKind, model.Chart(f), q...)
+}
+
+func (dm *_documentsManager) ListCharts() model.DataResponse {
+	return dm.queryDocuments(chartsKind)
+}
+
+func (dm *_documentsManager) ListKubeFiles() model.DataResponse {
+	return dm.queryDocuments(kubeFilesKind)
+}
+
+func (dm *_documentsManager) ListChartVersions(q ...model.Query) model.DataResponse {
+	return dm.listVersions(chartsKind, q...)
+}
+
+func (dm *_documentsManager) ListKubeFileVersions(q ...model.Query) model.DataResponse {
+	return dm.listVersions(kubeFilesKind, q...)
+}
+
+func GetDocumentDataManager(conn database.Connection, databaseName string, repo *model.Repository) model.DocumentsDataManager {
 	return &_documentsManager{
-		conn: conn,
-		repo: repo,
+		conn:     conn,
+		database: databaseNameOrDefault(databaseName),
+		repo:     repo,
 	}
 }
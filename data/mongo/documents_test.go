@@ -0,0 +1,63 @@
+package mongo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hellgate75/k8s-deploy/model"
+)
+
+func TestDocumentsManagerListsAllDocuments(t *testing.T) {
+	var conn = newFakeConnection()
+	var dm = GetDocumentDataManager(conn, "test-prefix", &model.Repository{Id: "repo-id", Name: "repo"})
+	for _, name := range []string{"web", "api", "db"} {
+		if resp := dm.AddChart(model.Chart{Name: name, Versions: []model.Version{{Name: "0.1.0"}, {Name: "0.2.0"}}}); !resp.Success {
+			t.Fatalf("Unexpected error: %s", resp.Message)
+		}
+	}
+	var resp = dm.ListCharts()
+	if !resp.Success || len(resp.ResponseObjects) != 3 {
+		t.Fatalf("Expected 3 charts, found %v: %s", len(resp.ResponseObjects), resp.Message)
+	}
+	resp = dm.ListChartVersions()
+	if !resp.Success || len(resp.ResponseObjects) != 6 {
+		t.Fatalf("Expected 6 chart versions, found %v: %s", len(resp.ResponseObjects), resp.Message)
+	}
+	resp = dm.QueryChartVersions(model.Chart{Name: "api"})
+	if !resp.Success || len(resp.ResponseObjects) != 2 {
+		t.Fatalf("Expected 2 api versions, found %v: %s", len(resp.ResponseObjects), resp.Message)
+	}
+	if resp := dm.ListKubeFiles(); !resp.Success || len(resp.ResponseObjects) != 0 {
+		t.Fatalf("Expected no kubernetes files, found %v: %s", len(resp.ResponseObjects), resp.Message)
+	}
+}
+
+func TestDocumentsManagerUniqueIndexes(t *testing.T) {
+	var conn = newFakeConnection()
+	var dm = GetDocumentDataManager(conn, "test-prefix", &model.Repository{Id: "repo-id", Name: "repo"}).(*_documentsManager)
+	if resp := dm.AddKubeFile(model.KubernetesFile{Name: "web", Versions: []model.Version{{Name: "1.0.0"}}}); !resp.Success {
+		t.Fatalf("Unexpected error: %s", resp.Message)
+	}
+	var resp = dm.AddKubeFile(model.KubernetesFile{Name: "web"})
+	if resp.Success || !strings.Contains(resp.Message, "already exists") {
+		t.Fatalf("Expected duplicate document error, found: %+v", resp)
+	}
+	resp = dm.AddKubeFileVersion(model.KubernetesFile{Name: "web"}, model.Version{Name: "1.0.0"})
+	if resp.Success || !strings.Contains(resp.Message, "already exists") {
+		t.Fatalf("Expected duplicate version error, found: %+v", resp)
+	}
+	if resp := dm.AddKubeFileVersion(model.KubernetesFile{Name: "web"}, model.Version{Name: "1.1.0"}); !resp.Success {
+		t.Fatalf("Unexpected error: %s", resp.Message)
+	}
+	for _, collection := range []string{kubeFilesCollection, kubeFileVersionsCollection} {
+		var indexes = conn.indexes["test-prefix."+collection]
+		if len(indexes) != 1 || !indexes[0].unique {
+			t.Fatalf("Expected unique index on %s, found: %+v", collection, indexes)
+		}
+	}
+	// The same name is allowed in another repository
+	var other = GetDocumentDataManager(conn, "test-prefix", &model.Repository{Id: "other-id", Name: "other"})
+	if resp := other.AddKubeFile(model.KubernetesFile{Name: "web", Versions: []model.Version{{Name: "1.0.0"}}}); !resp.Success {
+		t.Fatalf("Unexpected error: %s", resp.Message)
+	}
+}
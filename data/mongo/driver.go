@@ -0,0 +1,105 @@
+package mongo
+
+import (
+	"context"
+	"github.com/hellgate75/go-services/database"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"reflect"
+)
+
+// Mongo driver access to the client of a go-services Mongo connection, used for the
+// operations the connection doesn't provide or doesn't implement correctly
+type driverConnection struct {
+	client *mongo.Client
+	ctx    context.Context
+}
+
+// Read an exported field of a structure, returning nil when missing
+func structField(v reflect.Value, name string) interface{} {
+	var f = v.FieldByName(name)
+	if !f.IsValid() || !f.CanInterface() {
+		return nil
+	}
+	return f.Interface()
+}
+
+// Gets the Mongo driver client exposed by the Client and Context fields of the go-services
+// Mongo connection, returning nil for any other connection or for a closed connection
+func getDriverConnection(conn database.Connection) *driverConnection {
+	var v = reflect.ValueOf(conn)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	client, _ := structField(v.Elem(), "Client").(*mongo.Client)
+	ctx, _ := structField(v.Elem(), "Context").(*context.Context)
+	if client == nil || ctx == nil || !conn.IsConnected() {
+		return nil
+	}
+	return &driverConnection{
+		client: client,
+		ctx:    *ctx,
+	}
+}
+
+// Translate the conditions in a Mongo filter document, as the go-services connection does
+func conditionsFilter(conditions []database.Condition) bson.D {
+	var filter = make(bson.D, 0)
+	for _, cond := range conditions {
+		filter = append(filter, bson.E{
+			Key:   cond.Field,
+			Value: cond.Value.Value,
+		})
+	}
+	return filter
+}
+
+func (dc *driverConnection) collection(dbRef database.DataRef) *mongo.Collection {
+	return dc.client.Database(dbRef.Database).Collection(dbRef.Namespace)
+}
+
+func (dc *driverConnection) CreateIndex(dbRef database.DataRef, fields []database.Field, unique bool) error {
+	var keys = make(bson.D, 0)
+	for _, f := range fields {
+		keys = append(keys, bson.E{Key: f.Name, Value: 1})
+	}
+	_, err := dc.collection(dbRef).Indexes().CreateOne(dc.ctx, mongo.IndexModel{
+		Keys:    keys,
+		Options: options.Index().SetUnique(unique),
+	})
+	return err
+}
+
+func (dc *driverConnection) Find(dbRef database.DataRef, conditions []database.Condition) (database.ResultSet, error) {
+	cursor, err := dc.collection(dbRef).Find(dc.ctx, conditionsFilter(conditions))
+	if err != nil {
+		return database.ResultSet{}, err
+	}
+	defer func() {
+		_ = cursor.Close(dc.ctx)
+	}()
+	var records = make([]database.Result, 0)
+	for cursor.Next(dc.ctx) {
+		var doc bson.D
+		if err := cursor.Decode(&doc); err != nil {
+			return database.ResultSet{}, err
+		}
+		var res = database.Result{
+			Columns:  int64(len(doc)),
+			Values:   make([]interface{}, 0),
+			Document: []byte(cursor.Current),
+		}
+		for _, e := range doc {
+			res.Values = append(res.Values, e.Value)
+		}
+		records = append(records, res)
+	}
+	if err := cursor.Err(); err != nil {
+		return database.ResultSet{}, err
+	}
+	return database.ResultSet{
+		Lines:   int64(len(records)),
+		Records: records,
+	}, nil
+}
@@ -0,0 +1,106 @@
+package mongo
+
+import (
+	"github.com/hellgate75/go-services/database"
+	"github.com/hellgate75/k8s-deploy/data/common"
+	"github.com/hellgate75/k8s-deploy/log"
+	"github.com/hellgate75/k8s-deploy/model"
+)
+
+const (
+	projectsCollection = "projects"
+)
+
+// Projects collection record, the project is stored as json data including versions
+type projectRecord struct {
+	Id    string `bson:"_id"`
+	Name  string `bson:"name"`
+	State string `bson:"state"`
+	Data  string `bson:"data"`
+}
+
+type projectStore struct {
+	conn     database.Connection
+	database string
+	logger   log.Logger
+}
+
+// Create the projects collection with a unique name index
+func (ps *projectStore) init() error {
+	return ensureCollection(ps.conn, ps.database, projectsCollection, []database.Field{
+		{Name: mongoIdField, Type: "string"},
+		{Name: "name", Type: "string"},
+		{Name: "state", Type: "string"},
+		{Name: mongoDataField, Type: "string"},
+	}, collectionIndex{fields: []string{"name"}, unique: true})
+}
+
+func (ps *projectStore) LoadProjects() ([]model.Project, error) {
+	if err := ps.init(); err != nil {
+		return nil, err
+	}
+	rs, err := queryRecords(ps.conn, collectionRef(ps.database, projectsCollection), []string{mongoIdField, "name", "state", mongoDataField}, nil)
+	if err != nil {
+		return nil, err
+	}
+	var out = make([]model.Project, 0)
+	for _, rec := range rs.Records {
+		var p = model.Project{}
+		if err := decodeRecord(rec, &p); err != nil {
+			if ps.logger != nil {
+				ps.logger.Warnf("MongoProjectStore::LoadProjects() - Unable to decode project record, error: %v", err)
+			}
+			continue
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func (ps *projectStore) InsertProject(p model.Project) error {
+	if err := ps.init(); err != nil {
+		return err
+	}
+	data, err := encodeRecord(p)
+	if err != nil {
+		return err
+	}
+	err = ps.conn.Insert(collectionRef(ps.database, projectsCollection), nil, []database.Value{
+		{
+			Type: database.StructType,
+			Value: projectRecord{
+				Id:    p.Id,
+				Name:  p.Name,
+				State: string(p.State),
+				Data:  data,
+			},
+		},
+	})
+	return uniqueNameError(err, "Project", p.Name)
+}
+
+func (ps *projectStore) SaveProject(p model.Project) error {
+	data, err := encodeRecord(p)
+	if err != nil {
+		return err
+	}
+	_, err = ps.conn.Update(collectionRef(ps.database, projectsCollection), []database.Condition{equalsCondition(mongoIdField, p.Id)}, nil, setUpdate(map[string]interface{}{
+		"name":         p.Name,
+		"state":        string(p.State),
+		mongoDataField: data,
+	}))
+	return uniqueNameError(err, "Project", p.Name)
+}
+
+func (ps *projectStore) DeleteProject(p model.Project) error {
+	_, err := ps.conn.Delete(collectionRef(ps.database, projectsCollection), []database.Condition{equalsCondition(mongoIdField, p.Id)})
+	return err
+}
+
+func GetProjectDataManager(conn database.Connection, databaseName string, logger log.Logger) model.ProjectDataManager {
+	return common.NewProjectDataManager(&projectStore{
+		conn:     conn,
+		database: databaseNameOrDefault(databaseName),
+		logger:   logger,
+	}, logger)
+}
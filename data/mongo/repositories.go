@@ -1,96 +1,456 @@
 package mongo
 
 import (
+	"errors"
 	"fmt"
 	"github.com/hellgate75/go-services/database"
 	"github.com/hellgate75/k8s-deploy/log"
 	"github.com/hellgate75/k8s-deploy/model"
+	"github.com/hellgate75/k8s-deploy/utils"
 	"os"
+	"strings"
+	"sync"
 )
 
+const (
+	repositoriesCollection = "repositories"
+)
+
+// Query keys bound to the repositories collection fields
+var repositoryQueryFields = map[string]string{
+	"id":    mongoIdField,
+	"name":  "name",
+	"state": "state",
+}
+
+// Serializes the repositories changes and the related storage operations, the unique name index grants the unique repository name
+var repositoriesMutex sync.Mutex
+
 func getRepositoryFolder(basePath string, repoName string) string {
 	return fmt.Sprintf("%s%crepos%c%s", basePath, os.PathSeparator, os.PathSeparator, repoName)
 }
 
+// Repositories collection record, id and name are indexed and the repository is stored as json data
+type repositoryRecord struct {
+	Id    string `bson:"_id"`
+	Name  string `bson:"name"`
+	State string `bson:"state"`
+	Data  string `bson:"data"`
+}
+
 type repositoryManager struct {
 	conn       database.Connection
+	database   string
 	manager    model.RepositoryStorageManager
 	logger     log.Logger
 	baseFolder string
 }
 
+// Create the repositories collection with id and name indexes, name is unique
+func (rn *repositoryManager) init() error {
+	err := ensureCollection(rn.conn, rn.database, repositoriesCollection, []database.Field{
+		{Name: mongoIdField, Type: "string"},
+		{Name: "name", Type: "string"},
+		{Name: "state", Type: "string"},
+		{Name: mongoDataField, Type: "string"},
+	}, collectionIndex{fields: []string{"name"}, unique: true})
+	if err != nil && rn.logger != nil {
+		rn.logger.Errorf("MongoRepositoryManager::init() - Unable to create collection %s, error: %v", repositoriesCollection, err)
+	}
+	return err
+}
+
+func (rn *repositoryManager) find(conditions ...database.Condition) ([]model.Repository, error) {
+	if err := rn.init(); err != nil {
+		return nil, err
+	}
+	rs, err := queryRecords(rn.conn, collectionRef(rn.database, repositoriesCollection), []string{mongoIdField, "name", "state", mongoDataField}, conditions)
+	if err != nil {
+		return nil, err
+	}
+	var out = make([]model.Repository, 0)
+	for _, rec := range rs.Records {
+		var r = model.Repository{}
+		if err := decodeRecord(rec, &r); err != nil {
+			if rn.logger != nil {
+				rn.logger.Warnf("MongoRepositoryManager::find() - Unable to decode repository record, error: %v", err)
+			}
+			continue
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+func (rn *repositoryManager) findOne(field string, value string) (*model.Repository, error) {
+	list, err := rn.find(equalsCondition(field, value))
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	return &list[0], nil
+}
+
+func (rn *repositoryManager) filter(inclusive bool, q ...model.Query) ([]model.Repository, error) {
+	conditions, err := queryConditions(repositoryQueryFields, inclusive, q...)
+	if err != nil {
+		return nil, err
+	}
+	return rn.find(conditions...)
+}
+
+func (rn *repositoryManager) insert(r model.Repository) error {
+	data, err := encodeRecord(r)
+	if err != nil {
+		return err
+	}
+	err = rn.conn.Insert(collectionRef(rn.database, repositoriesCollection), nil, []database.Value{
+		{
+			Type: database.StructType,
+			Value: repositoryRecord{
+				Id:    r.Id,
+				Name:  r.Name,
+				State: string(r.State),
+				Data:  data,
+			},
+		},
+	})
+	return uniqueNameError(err, "Repository", r.Name)
+}
+
+func (rn *repositoryManager) save(r model.Repository) error {
+	data, err := encodeRecord(r)
+	if err != nil {
+		return err
+	}
+	_, err = rn.conn.Update(collectionRef(rn.database, repositoriesCollection), []database.Condition{equalsCondition(mongoIdField, r.Id)}, nil, setUpdate(map[string]interface{}{
+		"name":         r.Name,
+		"state":        string(r.State),
+		mongoDataField: data,
+	}))
+	return uniqueNameError(err, "Repository", r.Name)
+}
+
+// Verify the repository name is not used by any other repository
+func (rn *repositoryManager) checkUniqueName(id string, name string) error {
+	r, err := rn.findOne("name", name)
+	if err != nil {
+		return err
+	}
+	if r != nil && r.Id != id {
+		return errors.New(fmt.Sprintf("Repository name %s already in use by repository id: %s", name, r.Id))
+	}
+	return nil
+}
+
 func (rn *repositoryManager) ListRepositories() model.DataResponse {
+	list, err := rn.find()
+	var response = make([]interface{}, 0)
+	if err != nil {
+		return model.DataResponse{
+			Success:         false,
+			Message:         fmt.Sprintf("Error listing repositories, error: %v", err),
+			ResponseObjects: response,
+		}
+	}
+	for _, r := range list {
+		response = append(response, r)
+	}
 	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+		Success:         true,
+		Message:         "OK",
+		ResponseObjects: response,
 	}
 }
 
 func (rn *repositoryManager) AddRepository(n string) model.DataResponse {
+	repositoriesMutex.Lock()
+	defer repositoriesMutex.Unlock()
+	var repoName = utils.ConvertName(n)
+	var response = make([]interface{}, 0)
+	if err := rn.checkUniqueName("", repoName); err != nil {
+		return model.DataResponse{
+			Success:         false,
+			Message:         fmt.Sprintf("Error creating repository: %s, error: %v", n, err),
+			ResponseObjects: response,
+		}
+	}
+	var r = &model.Repository{
+		Id:    utils.NewUniqueIdentifier(),
+		Name:  repoName,
+		State: model.StateCreated,
+	}
+	if rn.manager != nil {
+		rp, err := rn.manager.CreateRepository(repoName)
+		if err != nil {
+			return model.DataResponse{
+				Success:         false,
+				Message:         fmt.Sprintf("Error creating repository: %s, error: %v", n, err),
+				ResponseObjects: response,
+			}
+		}
+		r = rp
+	}
+	if err := rn.insert(*r); err != nil {
+		return model.DataResponse{
+			Success:         false,
+			Message:         fmt.Sprintf("Error creating repository: %s, error: %v", n, err),
+			ResponseObjects: response,
+		}
+	}
+	response = append(response, *r)
 	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+		Success:         true,
+		Message:         "REPOSITORY CREATED",
+		Changes:         1,
+		ResponseObjects: response,
 	}
 }
 
 func (rn *repositoryManager) DeleteRepositories(inclusive bool, q ...model.Query) model.DataResponse {
+	repositoriesMutex.Lock()
+	defer repositoriesMutex.Unlock()
+	var respObjs = make([]interface{}, 0)
+	list, err := rn.filter(inclusive, q...)
+	if err != nil {
+		return model.DataResponse{
+			Success:         false,
+			Message:         fmt.Sprintf("Error deleting repositories, error: %v", err),
+			ResponseObjects: respObjs,
+		}
+	}
+	var message = ""
+	for _, r := range list {
+		r.State = model.StateDeleted
+		if err := rn.save(r); err != nil {
+			message = appendMessage(message, "repository: %s - Error: %v", r.Name, err)
+		} else {
+			respObjs = append(respObjs, r)
+		}
+	}
+	var success = false
+	if len(message) == 0 {
+		success = true
+		message = "OK"
+	}
 	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+		Success:         success,
+		Message:         message,
+		ResponseObjects: respObjs,
+		Changes:         int64(len(respObjs)),
 	}
 }
 
 func (rn *repositoryManager) PurgeRepositories(inclusive bool, q ...model.Query) model.DataResponse {
+	repositoriesMutex.Lock()
+	defer repositoriesMutex.Unlock()
+	var respObjs = make([]interface{}, 0)
+	list, err := rn.filter(inclusive, q...)
+	if err != nil {
+		return model.DataResponse{
+			Success:         false,
+			Message:         fmt.Sprintf("Error purging repositories, error: %v", err),
+			ResponseObjects: respObjs,
+		}
+	}
+	var message = ""
+	for _, r := range list {
+		if _, err := rn.conn.Delete(collectionRef(rn.database, repositoriesCollection), []database.Condition{equalsCondition(mongoIdField, r.Id)}); err != nil {
+			message = appendMessage(message, "repository: %s - Error: %v", r.Name, err)
+			continue
+		}
+		if rn.manager != nil {
+			if err := rn.manager.DeleteRepositoryById(r.Id); err != nil && rn.logger != nil {
+				rn.logger.Warnf("MongoRepositoryManager::PurgeRepositories() - Unable to delete repository %s storage, error: %v", r.Name, err)
+			}
+		}
+		r.State = model.StatePutged
+		respObjs = append(respObjs, r)
+	}
+	var success = false
+	if len(message) == 0 {
+		success = true
+		message = "OK"
+	}
 	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+		Success:         success,
+		Message:         message,
+		ResponseObjects: respObjs,
+		Changes:         int64(len(respObjs)),
 	}
 }
 
-func (rn *repositoryManager) ClearRepository(id string) model.DataResponse {
+// Purge all charts and Kubernetes files of a repository
+func (rn *repositoryManager) clear(r *model.Repository, ref string) model.DataResponse {
+	if r == nil {
+		return model.DataResponse{
+			Success:         false,
+			Message:         fmt.Sprintf("Repository %s not found", ref),
+			ResponseObjects: make([]interface{}, 0),
+		}
+	}
+	var dm = GetDocumentDataManager(rn.conn, rn.database, r)
+	var message = ""
+	var changes int64
+	var respObjs = make([]interface{}, 0)
+	for _, resp := range []model.DataResponse{dm.PurgeCharts(), dm.PurgeKubeFiles()} {
+		changes += resp.Changes
+		respObjs = append(respObjs, resp.ResponseObjects...)
+		if !resp.Success {
+			message = appendMessage(message, "repository: %s - Error: %s", r.Name, resp.Message)
+		}
+	}
+	var success = false
+	if len(message) == 0 {
+		success = true
+		message = "OK"
+	}
 	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+		Success:         success,
+		Message:         message,
+		ResponseObjects: respObjs,
+		Changes:         changes,
 	}
 }
 
+func (rn *repositoryManager) ClearRepository(id string) model.DataResponse {
+	return rn.clear(rn.GetRepository(id), id)
+}
+
 func (rn *repositoryManager) ClearRepositoryByName(name string) model.DataResponse {
-	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
-	}
+	return rn.clear(rn.GetRepositoryByName(name), name)
 }
 
 func (rn *repositoryManager) GetRepository(id string) *model.Repository {
-	return nil
+	r, err := rn.findOne(mongoIdField, id)
+	if err != nil {
+		if rn.logger != nil {
+			rn.logger.Errorf("MongoRepositoryManager::GetRepository() - Unable to get repository id: %s, error: %v", id, err)
+		}
+		return nil
+	}
+	return r
 }
 
 func (rn *repositoryManager) GetRepositoryByName(name string) *model.Repository {
-	return nil
+	r, err := rn.findOne("name", utils.ConvertName(name))
+	if err != nil {
+		if rn.logger != nil {
+			rn.logger.Errorf("MongoRepositoryManager::GetRepositoryByName() - Unable to get repository name: %s, error: %v", name, err)
+		}
+		return nil
+	}
+	return r
 }
 
 func (rn *repositoryManager) AccessRepository(r model.Repository) *model.DocumentsDataManager {
-	dm := GetDocumentDataManager(rn.conn, &r)
+	dm := GetDocumentDataManager(rn.conn, rn.database, &r)
 	return &dm
 }
 
+// Update an existing repository, or create it when override is true
+func (rn *repositoryManager) update(id string, r *model.Repository, override bool) (*model.Repository, error) {
+	repositoriesMutex.Lock()
+	defer repositoriesMutex.Unlock()
+	current, err := rn.findOne(mongoIdField, id)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil && !override {
+		return nil, errors.New(fmt.Sprintf("Repository id: %s not found", id))
+	}
+	var updated = *r
+	updated.Id = id
+	updated.Name = utils.ConvertName(strings.TrimSpace(updated.Name))
+	if updated.Name == "" {
+		if current == nil {
+			return nil, errors.New("Repository name is required")
+		}
+		updated.Name = current.Name
+	}
+	if updated.State == "" {
+		updated.State = model.StateCreated
+		if current != nil {
+			updated.State = current.State
+		}
+	}
+	if err := rn.checkUniqueName(id, updated.Name); err != nil {
+		return nil, err
+	}
+	if current == nil {
+		if rn.manager != nil {
+			if _, err := rn.manager.OverrideRepository(id, updated); err != nil && rn.logger != nil {
+				rn.logger.Warnf("MongoRepositoryManager::update() - Unable to create repository %s storage, error: %v", updated.Name, err)
+			}
+		}
+		return &updated, rn.insert(updated)
+	}
+	if current.Name != updated.Name && rn.manager != nil {
+		if err := rn.manager.RenameRepository(current.Name, updated.Name); err != nil && rn.logger != nil {
+			rn.logger.Warnf("MongoRepositoryManager::update() - Unable to rename repository %s storage, error: %v", current.Name, err)
+		}
+	}
+	return &updated, rn.save(updated)
+}
+
 func (rn *repositoryManager) UpdateRepository(id string, r *model.Repository) model.DataResponse {
+	if r == nil {
+		return model.DataResponse{
+			Success:         false,
+			Message:         fmt.Sprintf("Error updating repository id : %s with empty body", id),
+			ResponseObjects: nil,
+		}
+	}
+	var response = make([]interface{}, 0)
+	rp, err := rn.update(id, r, false)
+	if err != nil {
+		return model.DataResponse{
+			Success:         false,
+			Message:         fmt.Sprintf("Error updating repository id: %s, error: %v", id, err),
+			ResponseObjects: response,
+		}
+	}
+	response = append(response, *rp)
 	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+		Success:         true,
+		Message:         "REPOSITORY Updated",
+		Changes:         1,
+		ResponseObjects: response,
 	}
 }
 
 func (rn *repositoryManager) OverrideRepository(id string, r *model.Repository) model.DataResponse {
+	if r == nil {
+		return model.DataResponse{
+			Success:         false,
+			Message:         fmt.Sprintf("Error overriding repository id : %s with empty body", id),
+			ResponseObjects: nil,
+		}
+	}
+	var response = make([]interface{}, 0)
+	rp, err := rn.update(id, r, true)
+	if err != nil {
+		return model.DataResponse{
+			Success:         false,
+			Message:         fmt.Sprintf("Error overriding repository id: %s, error: %v", id, err),
+			ResponseObjects: response,
+		}
+	}
+	response = append(response, *rp)
 	return model.DataResponse{
-		Success: false,
-		Message: "Not Implemented",
+		Success:         true,
+		Message:         "REPOSITORY Overridden",
+		Changes:         1,
+		ResponseObjects: response,
 	}
 }
 
-func GetRepositoryDataManager(conn database.Connection, baseFolder string, manager model.RepositoryStorageManager, logger log.Logger) model.RepositoryDataManager {
+func GetRepositoryDataManager(conn database.Connection, databaseName string, baseFolder string, manager model.RepositoryStorageManager, logger log.Logger) model.RepositoryDataManager {
 	return &repositoryManager{
 		conn:       conn,
+		database:   databaseNameOrDefault(databaseName),
 		manager:    manager,
 		logger:     logger,
 		baseFolder: baseFolder,
@@ -0,0 +1,132 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hellgate75/go-services/database"
+	"github.com/hellgate75/k8s-deploy/model"
+	"github.com/hellgate75/k8s-deploy/model/rest"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestRepositoryManagerListsAllRepositories(t *testing.T) {
+	var conn = newFakeConnection()
+	var rm = GetRepositoryDataManager(conn, "test-prefix", "", nil, nil)
+	for i := 0; i < 3; i++ {
+		if resp := rm.AddRepository(fmt.Sprintf("repo%v", i)); !resp.Success {
+			t.Fatalf("Unexpected error: %s", resp.Message)
+		}
+	}
+	var resp = rm.ListRepositories()
+	if !resp.Success || len(resp.ResponseObjects) != 3 {
+		t.Fatalf("Expected 3 repositories, found %v: %s", len(resp.ResponseObjects), resp.Message)
+	}
+	if len(conn.collections["test-prefix."+repositoriesCollection]) != 3 {
+		t.Fatalf("Expected repositories stored in the test-prefix database, found: %v", conn.collections)
+	}
+	if r := rm.GetRepositoryByName("repo2"); r == nil || r.Name != "repo2" {
+		t.Fatalf("Unexpected repository: %+v", r)
+	}
+}
+
+func TestRepositoryManagerUniqueNameIndex(t *testing.T) {
+	var conn = newFakeConnection()
+	var rm = GetRepositoryDataManager(conn, "test-prefix", "", nil, nil).(*repositoryManager)
+	if resp := rm.AddRepository("repo"); !resp.Success {
+		t.Fatalf("Unexpected error: %s", resp.Message)
+	}
+	if resp := rm.AddRepository("repo"); resp.Success {
+		t.Fatal("Expected duplicate repository name error")
+	}
+	var indexes = conn.indexes["test-prefix."+repositoriesCollection]
+	if len(indexes) != 1 || !indexes[0].unique || indexes[0].fields[0] != "name" {
+		t.Fatalf("Expected unique name index, found: %+v", indexes)
+	}
+	// The index rejects the duplicate even bypassing the name check
+	err := rm.insert(model.Repository{Id: "other", Name: "repo", State: model.StateCreated})
+	if err == nil || !strings.Contains(err.Error(), "already in use") {
+		t.Fatalf("Expected name already in use error, found: %v", err)
+	}
+}
+
+func TestDatabaseNameOrDefault(t *testing.T) {
+	if databaseNameOrDefault(" ") != rest.DefaultDatabaseNamePrefix {
+		t.Fatalf("Expected default database name %s", rest.DefaultDatabaseNamePrefix)
+	}
+	if databaseNameOrDefault("custom") != "custom" {
+		t.Fatal("Expected custom database name")
+	}
+}
+
+func TestQueryRecordsUsesRecordsFinder(t *testing.T) {
+	var conn = newFakeConnection()
+	var ref = collectionRef("test", "items")
+	for _, name := range []string{"a", "b", "c"} {
+		if err := conn.Insert(ref, nil, []database.Value{{Type: database.StructType, Value: repositoryRecord{Id: name, Name: name, State: "created"}}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conditions, err := queryConditions(repositoryQueryFields, false, model.Query{
+		Oper: model.OperAnd,
+		Items: []model.QueryItem{
+			{Key: "name", Value: "a,c", Aggregator: model.AggregatorIn},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := queryRecords(conn, ref, nil, conditions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rs.Records) != 2 {
+		t.Fatalf("Expected 2 records, found %v", len(rs.Records))
+	}
+}
+
+func TestIsDuplicateKeyError(t *testing.T) {
+	var err error = mongo.BulkWriteException{
+		WriteErrors: []mongo.BulkWriteError{{WriteError: mongo.WriteError{Code: mongoDuplicateKeyCode, Message: "duplicate"}}},
+	}
+	if !isDuplicateKeyError(err) {
+		t.Fatal("Expected duplicate key error")
+	}
+	if isDuplicateKeyError(fmt.Errorf("connection refused")) || isDuplicateKeyError(nil) {
+		t.Fatal("Unexpected duplicate key error")
+	}
+}
+
+// Connection exposing the driver client as the go-services Mongo connection does
+type clientConnection struct {
+	*fakeConnection
+	Client  *mongo.Client
+	Context *context.Context
+}
+
+func TestGetDriverConnection(t *testing.T) {
+	client, err := mongo.NewClient(options.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ctx = context.Background()
+	var dc = getDriverConnection(&clientConnection{fakeConnection: newFakeConnection(), Client: client, Context: &ctx})
+	if dc == nil || dc.client != client {
+		t.Fatal("Expected driver connection using the connection client")
+	}
+	if getDriverConnection(&clientConnection{fakeConnection: newFakeConnection()}) != nil {
+		t.Fatal("Unexpected driver connection without client")
+	}
+}
+
+func TestGetDriverConnectionIgnoresOtherConnections(t *testing.T) {
+	if getDriverConnection(newFakeConnection()) != nil {
+		t.Fatal("Unexpected driver connection for a fake connection")
+	}
+	if _, err := getIndexCreator(newFakeConnection()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
@@ -13,16 +13,40 @@ func GetDeviceRepositoryDataManager(baseFolder string, manager model.RepositoryS
 	return data.GetDeviceRepositoryDataManager(baseFolder, manager, logger)
 }
 
-func GetMongoRepositoryDataManager(conn database.Connection, baseFolder string, manager model.RepositoryStorageManager, logger log.Logger) model.RepositoryDataManager {
-	return data.GetMongoRepositoryDataManager(conn, baseFolder, manager, logger)
+func GetMongoRepositoryDataManager(conn database.Connection, databaseName string, baseFolder string, manager model.RepositoryStorageManager, logger log.Logger) model.RepositoryDataManager {
+	return data.GetMongoRepositoryDataManager(conn, databaseName, baseFolder, manager, logger)
 }
 
 func GetDeviceDocumentsDataManager(baseFolder string, repo *model.Repository) model.DocumentsDataManager {
 	return data.GetDeviceDocumentsDataManager(baseFolder, repo)
 }
 
-func GetMongoDocumentsDataManager(conn database.Connection, repo *model.Repository) model.DocumentsDataManager {
-	return data.GetMongoDocumentsDataManager(conn, repo)
+func GetMongoDocumentsDataManager(conn database.Connection, databaseName string, repo *model.Repository) model.DocumentsDataManager {
+	return data.GetMongoDocumentsDataManager(conn, databaseName, repo)
+}
+
+func GetDeviceProjectDataManager(baseFolder string, logger log.Logger) model.ProjectDataManager {
+	return data.GetDeviceProjectDataManager(baseFolder, logger)
+}
+
+func GetMongoProjectDataManager(conn database.Connection, databaseName string, logger log.Logger) model.ProjectDataManager {
+	return data.GetMongoProjectDataManager(conn, databaseName, logger)
+}
+
+func GetDeviceDeployDataManager(baseFolder string, logger log.Logger) model.DeployDataManager {
+	return data.GetDeviceDeployDataManager(baseFolder, logger)
+}
+
+func GetMongoDeployDataManager(conn database.Connection, databaseName string, logger log.Logger) model.DeployDataManager {
+	return data.GetMongoDeployDataManager(conn, databaseName, logger)
+}
+
+func GetDeviceDataManager(baseFolder string, manager model.RepositoryStorageManager, logger log.Logger) model.DataManager {
+	return data.GetDeviceDataManager(baseFolder, manager, logger)
+}
+
+func GetMongoDataManager(conn database.Connection, databaseName string, baseFolder string, manager model.RepositoryStorageManager, logger log.Logger) model.DataManager {
+	return data.GetMongoDataManager(conn, databaseName, baseFolder, manager, logger)
 }
 
 func NewLogger(appName string, verbosity log.LogLevel) log.Logger {
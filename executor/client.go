@@ -0,0 +1,103 @@
+package executor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hellgate75/k8s-deploy/model"
+	"github.com/hellgate75/k8s-deploy/rest/client"
+	"github.com/hellgate75/k8s-deploy/rest/common"
+	"net/url"
+	"strings"
+)
+
+// Rest Api response envelope, with the data decoded in the given structure
+type apiResponse struct {
+	Status  int         `json:"status"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data"`
+}
+
+// Sends a request and collects the response content, responses out of the 2xx range are reported as error
+func sendRequest(c client.ApiClient, method common.WebMethod, path string, accept common.MediaType) ([]byte, error) {
+	var content []byte
+	var err error
+	sErr := c.Send(method, path, accept, "", nil, func(code int, message string, mediaType common.MediaType, data []byte) {
+		if code < 200 || code > 299 {
+			var response = apiResponse{}
+			if jErr := json.Unmarshal(data, &response); jErr == nil && response.Message != "" {
+				message = response.Message
+			} else if s := strings.TrimSpace(string(data)); s != "" {
+				message = s
+			}
+			err = errors.New(fmt.Sprintf("%s %s failed, status: %v, message: %s", method, path, code, message))
+			return
+		}
+		content = data
+	})
+	if sErr != nil {
+		return nil, sErr
+	}
+	return content, err
+}
+
+// Sends a request and decodes the json response envelope data in the given structure
+func sendApiRequest(c client.ApiClient, method common.WebMethod, path string, data interface{}) error {
+	content, err := sendRequest(c, method, path, common.JSON_MEDIA_TYPE)
+	if err != nil {
+		return err
+	}
+	var response = apiResponse{
+		Data: data,
+	}
+	return json.Unmarshal(content, &response)
+}
+
+// Scheduler Rest Api client, used to pull the ready jobs and to report the state transitions
+type schedulerClient struct {
+	api client.ApiClient
+}
+
+// Lists the deploys in the given state
+func (s *schedulerClient) listDeploys(state model.State) ([]model.Deploy, error) {
+	var data = struct {
+		Deploys []model.Deploy `json:"deploys"`
+	}{}
+	err := sendApiRequest(s.api, common.GET_WEB_METHOD, fmt.Sprintf("/v1/deploys?state=%s", url.QueryEscape(string(state))), &data)
+	return data.Deploys, err
+}
+
+// Collects a deploy by id
+func (s *schedulerClient) getDeploy(deployId string) (*model.Deploy, error) {
+	var d = model.Deploy{}
+	err := sendApiRequest(s.api, common.GET_WEB_METHOD, fmt.Sprintf("/v1/deploys/%s", url.PathEscape(deployId)), &d)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// Reports a deploy state transition
+func (s *schedulerClient) updateDeployState(deployId string, state model.State) error {
+	return sendApiRequest(s.api, common.PUT_WEB_METHOD, fmt.Sprintf("/v1/deploys/%s?state=%s", url.PathEscape(deployId), url.QueryEscape(string(state))), nil)
+}
+
+// Reports a deploy job state transition
+func (s *schedulerClient) updateJobState(deployId string, jobId string, state model.State) error {
+	return sendApiRequest(s.api, common.PUT_WEB_METHOD, fmt.Sprintf("/v1/deploys/%s/jobs/%s?state=%s", url.PathEscape(deployId), url.PathEscape(jobId), url.QueryEscape(string(state))), nil)
+}
+
+// Repository Rest Api client, used to fetch the charts and the Kubernetes files
+type repositoryClient struct {
+	api client.ApiClient
+}
+
+// Fetches a chart version, packaged as Helm chart archive
+func (r *repositoryClient) fetchChartPackage(repo string, name string, version string) ([]byte, error) {
+	return sendRequest(r.api, common.GET_WEB_METHOD, fmt.Sprintf("/helm/%s/charts/%s-%s.tgz", url.PathEscape(repo), url.PathEscape(name), url.PathEscape(version)), "")
+}
+
+// Fetches a Kubernetes file version raw manifest
+func (r *repositoryClient) fetchKubeFile(repo string, name string, version string) ([]byte, error) {
+	return sendRequest(r.api, common.GET_WEB_METHOD, fmt.Sprintf("/v1/repositories/%s/kubefiles/%s/versions/%s", url.PathEscape(repo), url.PathEscape(name), url.PathEscape(version)), common.YAML_MEDIA_TYPE)
+}
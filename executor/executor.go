@@ -0,0 +1,534 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hellgate75/k8s-deploy/log"
+	"github.com/hellgate75/k8s-deploy/model"
+	"github.com/hellgate75/k8s-deploy/rest/client"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Default interval between two scheduler polls
+	DefaultPollInterval = 10 * time.Second
+	// Default timeout of the scheduler and repository Api requests
+	DefaultRequestTimeout = 5 * time.Minute
+	// Default repository used when the job doesn't define the repository parameter
+	DefaultRepository = "__default"
+	// Number of job executions kept in memory
+	MaxExecutionsHistory = 200
+	// Default maximum number of jobs of a Deploy running in parallel
+	DefaultMaxParallelJobs = 4
+)
+
+// Jobs executor, pulling the ready deploys from the scheduler and running their jobs following the jobs dependencies.
+// Charts and Kubernetes files are fetched from the repository Api and cached in the local repository storage
+type jobExecutor struct {
+	sync.Mutex
+	poll              sync.Mutex
+	repositories      sync.Mutex
+	scheduler         *schedulerClient
+	repository        *repositoryClient
+	storage           model.RepositoryStorageManager
+	defaultRepository string
+	parallelism       int
+	interval          time.Duration
+	logger            log.Logger
+	stop              chan bool
+	running           bool
+	recovered         bool
+	executions        []model.JobExecution
+	locks             map[string]*sync.Mutex
+}
+
+// Job execution outcome, collected from the parallel job runs
+type jobResult struct {
+	execution model.JobExecution
+	err       error
+}
+
+// Recovers the lock of a local storage resource, serializing the accesses of parallel jobs
+func (e *jobExecutor) lock(key string) *sync.Mutex {
+	e.Lock()
+	defer e.Unlock()
+	m, ok := e.locks[key]
+	if !ok {
+		m = &sync.Mutex{}
+		e.locks[key] = m
+	}
+	return m
+}
+
+// Keeps the job execution in the history, newest first
+func (e *jobExecutor) record(x model.JobExecution) {
+	e.Lock()
+	defer e.Unlock()
+	e.executions = append([]model.JobExecution{x}, e.executions...)
+	if len(e.executions) > MaxExecutionsHistory {
+		e.executions = e.executions[:MaxExecutionsHistory]
+	}
+}
+
+func (e *jobExecutor) Executions() []model.JobExecution {
+	e.Lock()
+	defer e.Unlock()
+	var out = make([]model.JobExecution, len(e.executions))
+	copy(out, e.executions)
+	return out
+}
+
+// Runs a job, reporting the running state before the execution and the complete or failed state after it
+func (e *jobExecutor) executeJob(d model.Deploy, j model.Job) (model.JobExecution, error) {
+	var x = model.JobExecution{
+		DeployId:   d.Id,
+		DeployName: d.Name,
+		JobId:      j.Instance.Id,
+		JobName:    j.Instance.Name,
+		IsChart:    j.IsChart,
+		Started:    time.Now(),
+	}
+	if err := e.scheduler.updateJobState(d.Id, j.Instance.Id, model.StateRunning); err != nil {
+		return x, err
+	}
+	x.State = model.StateComplete
+	plan, err := e.resolveJob(j)
+	if err == nil {
+		x.Repository = plan.repository
+		x.Document = plan.name
+		x.Version = plan.version
+		x.Target = plan.target.Key()
+		x.Output, err = e.runJob(plan)
+	}
+	if err != nil {
+		x.State = model.StateFailed
+		x.Message = fmt.Sprintf("%v", err)
+	}
+	x.Finished = time.Now()
+	if e.logger != nil {
+		if x.State == model.StateFailed {
+			e.logger.Errorf("JobExecutor - Deploy %s job %s failed, Error: %s", d.Name, j.Instance.Name, x.Message)
+		} else {
+			e.logger.Infof("JobExecutor - Deploy %s job %s complete", d.Name, j.Instance.Name)
+		}
+	}
+	if err := e.scheduler.updateJobState(d.Id, j.Instance.Id, x.State); err != nil {
+		if x.Message != "" {
+			x.Message += ", "
+		}
+		x.Message += fmt.Sprintf("unable to report state: %v", err)
+	}
+	e.record(x)
+	return x, nil
+}
+
+// Rolls back a completed job, reporting the rolled-back state. The job keeps the complete state when the rollback fails
+func (e *jobExecutor) rollbackDeployJob(d model.Deploy, j model.Job) (model.JobExecution, error) {
+	var x = model.JobExecution{
+		DeployId:   d.Id,
+		DeployName: d.Name,
+		JobId:      j.Instance.Id,
+		JobName:    j.Instance.Name,
+		IsChart:    j.IsChart,
+		State:      model.StateRollback,
+		Rollback:   true,
+		Started:    time.Now(),
+	}
+	plan, err := e.resolveJob(j)
+	if err == nil {
+		x.Repository = plan.repository
+		x.Document = plan.name
+		x.Version = plan.version
+		x.Target = plan.target.Key()
+		x.Output, err = e.rollbackJob(plan)
+	}
+	x.Finished = time.Now()
+	if err != nil {
+		x.State = model.StateFailed
+		x.Message = fmt.Sprintf("Rollback failed: %v", err)
+		if e.logger != nil {
+			e.logger.Errorf("JobExecutor - Deploy %s job %s rollback failed, Error: %v", d.Name, j.Instance.Name, err)
+		}
+		e.record(x)
+		return x, nil
+	}
+	if e.logger != nil {
+		e.logger.Infof("JobExecutor - Deploy %s job %s rolled back", d.Name, j.Instance.Name)
+	}
+	if err := e.scheduler.updateJobState(d.Id, j.Instance.Id, model.StateRollback); err != nil {
+		x.Message = fmt.Sprintf("unable to report state: %v", err)
+		e.record(x)
+		return x, err
+	}
+	e.record(x)
+	return x, nil
+}
+
+// Rolls back the completed jobs of a failed Deploy in reverse plan order, so a job is rolled back before its
+// dependencies. A failed job rollback doesn't stop the other jobs rollback, the errors are collected and the Deploy
+// is left failed, otherwise the Deploy state changes to rolled-back. Jobs already rolled back are not rolled back again
+func (e *jobExecutor) rollbackDeploy(d model.Deploy) (int, error) {
+	if d.State != model.StateFailed {
+		return 0, errors.New(fmt.Sprintf("Deploy %s is in state %s, only failed deploys can be rolled back", d.Name, d.State))
+	}
+	plan, err := model.BuildDeployPlan(d)
+	if err != nil {
+		return 0, err
+	}
+	var jobs = make(map[string]model.Job)
+	for _, j := range d.Job {
+		jobs[j.Instance.Name] = j
+	}
+	var count = 0
+	var errs = make([]string, 0)
+	for i := len(plan.Stages) - 1; i >= 0; i-- {
+		var stage = plan.Stages[i]
+		for k := len(stage.Jobs) - 1; k >= 0; k-- {
+			if stage.Jobs[k].State != model.StateComplete {
+				continue
+			}
+			x, err := e.rollbackDeployJob(d, jobs[stage.Jobs[k].JobName])
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("job %s: %v", x.JobName, err))
+			} else if x.State != model.StateRollback {
+				errs = append(errs, fmt.Sprintf("job %s: %s", x.JobName, x.Message))
+			} else {
+				count++
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return count, errors.New(strings.Join(errs, ", "))
+	}
+	if e.logger != nil {
+		e.logger.Infof("JobExecutor - Deploy %s %s, %v job(s) rolled back", d.Name, model.StateRollback, count)
+	}
+	return count, e.scheduler.updateDeployState(d.Id, model.StateRollback)
+}
+
+func (e *jobExecutor) Rollback(deployId string) (int, error) {
+	e.poll.Lock()
+	defer e.poll.Unlock()
+	d, err := e.scheduler.getDeploy(deployId)
+	if err != nil {
+		return 0, err
+	}
+	if d.Rollback == model.RollbackPolicyNone {
+		return 0, errors.New(fmt.Sprintf("Deploy %s rollback policy is %s", d.Name, d.Rollback))
+	}
+	return e.rollbackDeploy(*d)
+}
+
+// Computes the Deploy final state from its jobs state: unchanged while jobs are pending, failed when a job failed or
+// was skipped, complete otherwise
+func deployFinalState(d model.Deploy, states map[string]model.State) model.State {
+	var failed = false
+	for _, j := range d.Job {
+		switch states[j.Instance.Name] {
+		case model.StateFailed, model.StateError, model.StateSkipped:
+			failed = true
+		case model.StateComplete, model.StateDeleted, model.StatePutged:
+		default:
+			return d.State
+		}
+	}
+	if failed {
+		return model.StateFailed
+	}
+	return model.StateComplete
+}
+
+// Verifies the job dependencies: all completed, some not completed yet, or a dependency failed, returning the first
+// failed dependency
+func dependenciesState(deps []string, states map[string]model.State) (bool, string) {
+	var done = true
+	for _, dep := range deps {
+		switch states[dep] {
+		case model.StateComplete, model.StateDeleted, model.StatePutged:
+		case model.StateFailed, model.StateError, model.StateSkipped, model.StateRollback:
+			return false, dep
+		default:
+			done = false
+		}
+	}
+	return done, ""
+}
+
+// Skips a job because of a failed dependency, reporting the skipped state
+func (e *jobExecutor) skipJob(d model.Deploy, j model.Job, dep string, depState model.State) error {
+	var now = time.Now()
+	var x = model.JobExecution{
+		DeployId:   d.Id,
+		DeployName: d.Name,
+		JobId:      j.Instance.Id,
+		JobName:    j.Instance.Name,
+		IsChart:    j.IsChart,
+		State:      model.StateSkipped,
+		Message:    fmt.Sprintf("Skipped, dependency %s is %s", dep, depState),
+		Started:    now,
+		Finished:   now,
+	}
+	if e.logger != nil {
+		e.logger.Warnf("JobExecutor - Deploy %s job %s skipped, dependency %s is %s", d.Name, j.Instance.Name, dep, depState)
+	}
+	if err := e.scheduler.updateJobState(d.Id, j.Instance.Id, model.StateSkipped); err != nil {
+		return err
+	}
+	e.record(x)
+	return nil
+}
+
+// Runs the Deploy ready jobs as a dependency graph: a job starts when all its dependencies are complete, independent
+// jobs run in parallel and the dependents of a failed job are skipped. Then reports the Deploy state, rolling back the
+// completed jobs of a failed Deploy with auto rollback policy
+func (e *jobExecutor) executeDeploy(d model.Deploy) (int, error) {
+	plan, err := model.BuildDeployPlan(d)
+	if err != nil {
+		if d.State == model.StateReady || d.State == model.StateRunning {
+			_ = e.scheduler.updateDeployState(d.Id, model.StateError)
+		}
+		return 0, err
+	}
+	var deps = plan.Dependencies()
+	var states = make(map[string]model.State)
+	var hasReady = false
+	for _, j := range d.Job {
+		states[j.Instance.Name] = j.State
+		if j.State == model.StateReady {
+			hasReady = true
+		}
+	}
+	if hasReady && d.State == model.StateReady {
+		if err := e.scheduler.updateDeployState(d.Id, model.StateRunning); err != nil {
+			return 0, err
+		}
+		d.State = model.StateRunning
+	}
+	var results = make(chan jobResult)
+	var launched = make(map[string]bool)
+	var running = 0
+	var count = 0
+	var errs = make([]string, 0)
+	for {
+		for progress := true; progress; {
+			progress = false
+			for _, j := range d.Job {
+				if states[j.Instance.Name] != model.StateReady || launched[j.Instance.Name] {
+					continue
+				}
+				done, failed := dependenciesState(deps[j.Instance.Name], states)
+				if failed != "" {
+					if err := e.skipJob(d, j, failed, states[failed]); err != nil {
+						errs = append(errs, fmt.Sprintf("job %s: %v", j.Instance.Name, err))
+						launched[j.Instance.Name] = true
+						continue
+					}
+					states[j.Instance.Name] = model.StateSkipped
+					progress = true
+					continue
+				}
+				if !done || running >= e.parallelism {
+					continue
+				}
+				launched[j.Instance.Name] = true
+				states[j.Instance.Name] = model.StateRunning
+				running++
+				go func(j model.Job) {
+					x, err := e.executeJob(d, j)
+					results <- jobResult{execution: x, err: err}
+				}(j)
+			}
+		}
+		if running == 0 {
+			break
+		}
+		var r = <-results
+		running--
+		if r.err != nil {
+			states[r.execution.JobName] = model.StateReady
+			errs = append(errs, fmt.Sprintf("job %s: %v", r.execution.JobName, r.err))
+			continue
+		}
+		count++
+		states[r.execution.JobName] = r.execution.State
+	}
+	if len(errs) > 0 {
+		return count, errors.New(strings.Join(errs, ", "))
+	}
+	var state = deployFinalState(d, states)
+	if state == d.State || d.State != model.StateRunning {
+		return count, nil
+	}
+	if e.logger != nil {
+		e.logger.Infof("JobExecutor - Deploy %s %s", d.Name, state)
+	}
+	if err := e.scheduler.updateDeployState(d.Id, state); err != nil {
+		return count, err
+	}
+	if state != model.StateFailed || (d.Rollback != "" && d.Rollback != model.RollbackPolicyAuto) {
+		return count, nil
+	}
+	d.State = state
+	for i := range d.Job {
+		d.Job[i].State = states[d.Job[i].Instance.Name]
+	}
+	if _, err := e.rollbackDeploy(d); err != nil {
+		return count, errors.New(fmt.Sprintf("rollback: %v", err))
+	}
+	return count, nil
+}
+
+// Marks as error the jobs left running by a previous executor process, interrupted by a crash or a restart.
+// Jobs run only inside a poll, so no job of this process is running while the poll lock is held. The failed
+// state of the Deploy is then reported by the next poll, rolling back the completed jobs as for any failed job
+func (e *jobExecutor) recoverJobs() (int, error) {
+	deploys, err := e.scheduler.listDeploys(model.StateRunning)
+	if err != nil {
+		return 0, err
+	}
+	var count = 0
+	for _, d := range deploys {
+		for _, j := range d.Job {
+			if j.State != model.StateRunning {
+				continue
+			}
+			if err := e.scheduler.updateJobState(d.Id, j.Instance.Id, model.StateError); err != nil {
+				return count, errors.New(fmt.Sprintf("deploy %s job %s: %v", d.Name, j.Instance.Name, err))
+			}
+			var now = time.Now()
+			e.record(model.JobExecution{
+				DeployId:   d.Id,
+				DeployName: d.Name,
+				JobId:      j.Instance.Id,
+				JobName:    j.Instance.Name,
+				IsChart:    j.IsChart,
+				State:      model.StateError,
+				Message:    "Interrupted, the job was running when the executor stopped",
+				Started:    now,
+				Finished:   now,
+			})
+			if e.logger != nil {
+				e.logger.Warnf("JobExecutor - Deploy %s job %s was running when the executor stopped, state changed to %s", d.Name, j.Instance.Name, model.StateError)
+			}
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (e *jobExecutor) Poll() (int, error) {
+	e.poll.Lock()
+	defer e.poll.Unlock()
+	if !e.recovered {
+		if _, err := e.recoverJobs(); err != nil {
+			return 0, errors.New(fmt.Sprintf("Error recovering interrupted jobs: %v", err))
+		}
+		e.recovered = true
+	}
+	var deploys = make([]model.Deploy, 0)
+	for _, state := range []model.State{model.StateReady, model.StateRunning} {
+		list, err := e.scheduler.listDeploys(state)
+		if err != nil {
+			return 0, err
+		}
+		deploys = append(deploys, list...)
+	}
+	var count = 0
+	var errs = make([]string, 0)
+	for _, d := range deploys {
+		n, err := e.executeDeploy(d)
+		count += n
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("deploy %s: %v", d.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return count, errors.New(fmt.Sprintf("Errors executing jobs: %v", errs))
+	}
+	return count, nil
+}
+
+func (e *jobExecutor) process() {
+	if _, err := e.Poll(); err != nil && e.logger != nil {
+		e.logger.Errorf("JobExecutor - %v", err)
+	}
+}
+
+func (e *jobExecutor) Start() error {
+	e.Lock()
+	defer e.Unlock()
+	if e.running {
+		return errors.New("Job executor is already running")
+	}
+	e.stop = make(chan bool)
+	e.running = true
+	go func(stop chan bool) {
+		var ticker = time.NewTicker(e.interval)
+		defer ticker.Stop()
+		e.process()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				e.process()
+			}
+		}
+	}(e.stop)
+	if e.logger != nil {
+		e.logger.Infof("JobExecutor - Jobs polling started, poll interval: %v", e.interval)
+	}
+	return nil
+}
+
+func (e *jobExecutor) Stop() {
+	e.Lock()
+	defer e.Unlock()
+	if !e.running {
+		return
+	}
+	close(e.stop)
+	e.running = false
+	if e.logger != nil {
+		e.logger.Infof("JobExecutor - Jobs polling stopped")
+	}
+}
+
+func (e *jobExecutor) IsRunning() bool {
+	e.Lock()
+	defer e.Unlock()
+	return e.running
+}
+
+// Create a Job executor, pulling jobs from the scheduler Api and fetching charts and Kubernetes files from the
+// repository Api. The storage manager caches the fetched documents and tracks the installed releases.
+// Up to parallelism jobs of a Deploy run at the same time, the default maximum is used when it isn't positive.
+// The scheduler is polled at the given interval, or at the default one when the interval isn't positive
+func NewJobExecutor(schedulerUrl string, repositoryUrl string, storage model.RepositoryStorageManager, defaultRepository string, parallelism int, interval time.Duration, logger log.Logger) model.JobExecutor {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	if parallelism <= 0 {
+		parallelism = DefaultMaxParallelJobs
+	}
+	if defaultRepository == "" {
+		defaultRepository = DefaultRepository
+	}
+	return &jobExecutor{
+		scheduler: &schedulerClient{
+			api: client.NewApiClient(schedulerUrl, DefaultRequestTimeout),
+		},
+		repository: &repositoryClient{
+			api: client.NewApiClient(repositoryUrl, DefaultRequestTimeout),
+		},
+		storage:           storage,
+		defaultRepository: defaultRepository,
+		parallelism:       parallelism,
+		interval:          interval,
+		logger:            logger,
+		executions:        make([]model.JobExecution, 0),
+		locks:             make(map[string]*sync.Mutex),
+	}
+}
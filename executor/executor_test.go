@@ -0,0 +1,143 @@
+package executor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hellgate75/k8s-deploy/model"
+)
+
+// In memory scheduler Api, serving the deploys list and recording the state transitions
+type fakeScheduler struct {
+	sync.Mutex
+	deploys     []model.Deploy
+	transitions []string
+}
+
+func (fs *fakeScheduler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fs.Lock()
+	defer fs.Unlock()
+	var state = model.State(r.URL.Query().Get("state"))
+	var parts = strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	var data interface{}
+	switch {
+	case r.Method == http.MethodGet && len(parts) == 2:
+		var list = make([]model.Deploy, 0)
+		for _, d := range fs.deploys {
+			if d.State == state {
+				list = append(list, d)
+			}
+		}
+		data = map[string]interface{}{"deploys": list}
+	case r.Method == http.MethodGet && len(parts) == 3:
+		for _, d := range fs.deploys {
+			if d.Id == parts[2] {
+				data = d
+			}
+		}
+	case r.Method == http.MethodPut && len(parts) == 3:
+		for i := range fs.deploys {
+			if fs.deploys[i].Id == parts[2] {
+				fs.deploys[i].State = state
+				fs.transitions = append(fs.transitions, parts[2]+":"+string(state))
+			}
+		}
+	case r.Method == http.MethodPut && len(parts) == 5:
+		for i := range fs.deploys {
+			for k := range fs.deploys[i].Job {
+				if fs.deploys[i].Id == parts[2] && fs.deploys[i].Job[k].Instance.Id == parts[4] {
+					if !fs.deploys[i].Job[k].State.CanTransitionTo(state) {
+						w.WriteHeader(http.StatusBadRequest)
+						return
+					}
+					fs.deploys[i].Job[k].State = state
+					fs.transitions = append(fs.transitions, parts[4]+":"+string(state))
+				}
+			}
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"status": http.StatusOK, "message": "OK", "data": data})
+}
+
+func TestExecutorRecoversInterruptedJobsOnRestart(t *testing.T) {
+	var scheduler = &fakeScheduler{
+		deploys: []model.Deploy{
+			{
+				Id:       "deploy",
+				Name:     "deploy",
+				State:    model.StateRunning,
+				Rollback: model.RollbackPolicyNone,
+				Job: []model.Job{
+					{State: model.StateComplete, Instance: model.Instance{Id: "db-id", Name: "db"}},
+					{State: model.StateRunning, DependsOn: []string{"db"}, Instance: model.Instance{Id: "web-id", Name: "web"}},
+				},
+			},
+		},
+	}
+	var server = httptest.NewServer(scheduler)
+	defer server.Close()
+	// A new executor process finds the job left running by the previous one
+	var e = NewJobExecutor(server.URL, server.URL, nil, "", 1, 0, nil)
+	if _, err := e.Poll(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var expected = []string{"web-id:" + string(model.StateError), "deploy:" + string(model.StateFailed)}
+	if strings.Join(scheduler.transitions, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected transitions %v, found %v", expected, scheduler.transitions)
+	}
+	var executions = e.Executions()
+	if len(executions) != 1 || executions[0].JobName != "web" || executions[0].State != model.StateError {
+		t.Fatalf("Unexpected executions: %+v", executions)
+	}
+	// Recovery runs once per process, the failed deploy isn't polled anymore
+	if _, err := e.Poll(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(scheduler.transitions) != len(expected) {
+		t.Fatalf("Unexpected transitions after recovery: %v", scheduler.transitions)
+	}
+}
+
+func TestExecutorRollbackContinuesAfterFailedJob(t *testing.T) {
+	var scheduler = &fakeScheduler{
+		deploys: []model.Deploy{
+			{
+				Id:       "deploy",
+				Name:     "deploy",
+				State:    model.StateFailed,
+				Rollback: model.RollbackPolicyManual,
+				Job: []model.Job{
+					{State: model.StateComplete, Instance: model.Instance{Id: "db-id", Name: "db"}},
+					{State: model.StateComplete, DependsOn: []string{"db"}, Instance: model.Instance{Id: "web-id", Name: "web"}},
+					{State: model.StateFailed, DependsOn: []string{"db"}, Instance: model.Instance{Id: "api-id", Name: "api"}},
+				},
+			},
+		},
+	}
+	var server = httptest.NewServer(scheduler)
+	defer server.Close()
+	var e = NewJobExecutor(server.URL, server.URL, nil, "", 1, 0, nil)
+	// The jobs documents cannot be resolved, every completed job rollback fails
+	count, err := e.Rollback("deploy")
+	if err == nil || !strings.Contains(err.Error(), "job web") || !strings.Contains(err.Error(), "job db") {
+		t.Fatalf("Expected both job rollback errors, found: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("Expected no job rolled back, found %v", count)
+	}
+	var executions = e.Executions()
+	if len(executions) != 2 || executions[0].JobName != "db" || executions[1].JobName != "web" {
+		t.Fatalf("Expected rollback attempted on all completed jobs, found: %+v", executions)
+	}
+	if len(scheduler.transitions) != 0 {
+		t.Fatalf("Expected deploy left failed, found transitions: %v", scheduler.transitions)
+	}
+}
@@ -0,0 +1,253 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hellgate75/k8s-deploy/model"
+	"github.com/hellgate75/k8s-deploy/utils"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// Describes a resolved job instance: the chart or Kubernetes file to deploy, the repository, the deploy target and the values
+type jobPlan struct {
+	repository string
+	name       string
+	version    string
+	isChart    bool
+	force      bool
+	target     model.DeployTarget
+	values     model.ValueSet
+	variables  []model.Variable
+}
+
+// Recovers a job instance parameter value, parameter names are case insensitive
+func getJobParameter(j model.Job, name string) string {
+	for _, p := range j.Instance.Parameters {
+		if strings.EqualFold(strings.TrimSpace(p.Name), name) && p.Value != nil {
+			return strings.TrimSpace(fmt.Sprintf("%v", p.Value))
+		}
+	}
+	return ""
+}
+
+// Resolves the job instance: the project version document referred by the job, the parameters and the values
+func (e *jobExecutor) resolveJob(j model.Job) (*jobPlan, error) {
+	var plan = jobPlan{
+		isChart:   j.IsChart,
+		values:    j.Instance.Values,
+		variables: j.Instance.Version.Variables,
+	}
+	if j.IsChart {
+		for _, c := range j.Instance.Version.Charts {
+			if c.Id == j.DocumentId || (c.Name == j.DocumentId && c.Name != "") {
+				plan.name = c.Name
+				plan.version = c.Version
+				break
+			}
+		}
+	} else {
+		for _, f := range j.Instance.Version.KubeFiles {
+			if f.Id == j.DocumentId || (f.Name == j.DocumentId && f.Name != "") {
+				plan.name = f.Name
+				plan.version = f.Version
+				break
+			}
+		}
+	}
+	if plan.name == "" || plan.version == "" {
+		return nil, errors.New(fmt.Sprintf("Job %s document %s not found in project version %s", j.Instance.Name, j.DocumentId, j.Instance.Version.Version))
+	}
+	plan.repository = getJobParameter(j, model.JobParamRepository)
+	if plan.repository == "" {
+		plan.repository = e.defaultRepository
+	}
+	plan.force = strings.ToLower(getJobParameter(j, model.JobParamForce)) == "true"
+	plan.target = model.DeployTarget{
+		KubeConfig: getJobParameter(j, model.JobParamKubeConfig),
+		Context:    getJobParameter(j, model.JobParamContext),
+		Namespace:  getJobParameter(j, model.JobParamNamespace),
+	}
+	return &plan, nil
+}
+
+// Recovers the local cache repository, creating it when missing
+func (e *jobExecutor) getCacheRepository(name string) (*model.Repository, error) {
+	e.repositories.Lock()
+	defer e.repositories.Unlock()
+	if repo, err := e.storage.GetRepository(name); err == nil {
+		return repo, nil
+	}
+	return e.storage.CreateRepository(name)
+}
+
+// Writes the fetched content in a temporary folder, returning the temporary folder to be removed after use and the file path
+func storeFetchedFile(fileName string, data []byte) (string, string, error) {
+	var tmpFolder = utils.GetTempFolder(utils.GetRandPath())
+	err := utils.CleanCreateFolder(tmpFolder)
+	if err != nil {
+		return "", "", err
+	}
+	var file = filepath.Join(tmpFolder, fileName)
+	err = ioutil.WriteFile(file, data, 0666)
+	if err != nil {
+		_ = utils.DeleteFileOrFolder(tmpFolder)
+		return "", "", err
+	}
+	return tmpFolder, file, nil
+}
+
+// Verifies the version is stored and not in error state
+func containsVersion(versions []model.Version, version string) bool {
+	for _, v := range versions {
+		if v.Name == version && v.State != model.StateError && v.State != model.StateDeleted {
+			return true
+		}
+	}
+	return false
+}
+
+// Fetches the chart version from the repository Api, when it isn't in the local cache yet
+func (e *jobExecutor) cacheChart(cm model.RepositoryChartManager, plan *jobPlan) error {
+	if versions, err := cm.GetChartVersions(plan.name); err == nil && containsVersion(versions, plan.version) {
+		return nil
+	}
+	data, err := e.repository.fetchChartPackage(plan.repository, plan.name, plan.version)
+	if err != nil {
+		return err
+	}
+	tmpFolder, file, err := storeFetchedFile(fmt.Sprintf("%s-%s.tgz", plan.name, plan.version), data)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = utils.DeleteFileOrFolder(tmpFolder)
+	}()
+	return cm.InstallChart(plan.name, plan.version, file, false)
+}
+
+// Fetches the Kubernetes file version from the repository Api, when it isn't in the local cache yet
+func (e *jobExecutor) cacheKubeFile(km model.RepositoryKubernetesFilesManager, plan *jobPlan) error {
+	if versions, err := km.GetKubernetesFileVersions(plan.name); err == nil && containsVersion(versions, plan.version) {
+		return nil
+	}
+	data, err := e.repository.fetchKubeFile(plan.repository, plan.name, plan.version)
+	if err != nil {
+		return err
+	}
+	tmpFolder, file, err := storeFetchedFile(fmt.Sprintf("%s-%s.yaml", plan.name, plan.version), data)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = utils.DeleteFileOrFolder(tmpFolder)
+	}()
+	return km.InstallKubernetesFile(plan.name, plan.version, file)
+}
+
+// Deploys the job chart or Kubernetes file on the deploy target: installs it the first time, upgrades it later.
+// Parallel jobs share the local repository storage: the cache is updated one job at time per repository and a
+// chart or Kubernetes file is deployed one job at time
+func (e *jobExecutor) runJob(plan *jobPlan) (string, error) {
+	repo, err := e.getCacheRepository(plan.repository)
+	if err != nil {
+		return "", err
+	}
+	if plan.isChart {
+		cm, err := e.storage.GetRepositoryChartsManager(repo.Id)
+		if err != nil {
+			return "", err
+		}
+		cm.SetDeployTarget(plan.target)
+		var cacheLock = e.lock(repo.Name)
+		cacheLock.Lock()
+		err = e.cacheChart(cm, plan)
+		cacheLock.Unlock()
+		if err != nil {
+			return "", err
+		}
+		var deployLock = e.lock(fmt.Sprintf("%s/charts/%s", repo.Name, plan.name))
+		deployLock.Lock()
+		defer deployLock.Unlock()
+		if _, err := cm.GetInstalledChartVersion(plan.name); err == nil {
+			return cm.DeployUpgradeChart(plan.name, plan.version, plan.values, plan.force)
+		}
+		return cm.DeployInstallChart(plan.name, plan.version, plan.values)
+	}
+	km, err := e.storage.GetRepositoryKubernetesFilesManager(repo.Id)
+	if err != nil {
+		return "", err
+	}
+	km.SetDeployTarget(plan.target)
+	var cacheLock = e.lock(repo.Name)
+	cacheLock.Lock()
+	err = e.cacheKubeFile(km, plan)
+	cacheLock.Unlock()
+	if err != nil {
+		return "", err
+	}
+	var deployLock = e.lock(fmt.Sprintf("%s/kubefiles/%s", repo.Name, plan.name))
+	deployLock.Lock()
+	defer deployLock.Unlock()
+	if _, err := km.GetInstalledKubernetesFileVersion(plan.name); err == nil {
+		return km.DeployUpgradeKubernetesFileWithValues(plan.name, plan.version, plan.values, plan.variables, plan.force)
+	}
+	return km.DeployInstallKubernetesFileWithValues(plan.name, plan.version, plan.values, plan.variables)
+}
+
+// Rolls back the job chart or Kubernetes file on the deploy target: a chart to the release revision preceding the
+// latest one, a Kubernetes file to the version applied before the current one, expanded with the job values. When
+// the job installed it for the first time it's uninstalled. The job version must still be the deployed one
+func (e *jobExecutor) rollbackJob(plan *jobPlan) (string, error) {
+	repo, err := e.getCacheRepository(plan.repository)
+	if err != nil {
+		return "", err
+	}
+	if plan.isChart {
+		cm, err := e.storage.GetRepositoryChartsManager(repo.Id)
+		if err != nil {
+			return "", err
+		}
+		cm.SetDeployTarget(plan.target)
+		var deployLock = e.lock(fmt.Sprintf("%s/charts/%s", repo.Name, plan.name))
+		deployLock.Lock()
+		defer deployLock.Unlock()
+		release, err := cm.GetChartRelease(plan.name)
+		if err != nil {
+			return "", err
+		}
+		if !release.IsInstalled() || release.Current.Name != plan.version {
+			return "", errors.New(fmt.Sprintf("Chart %s version %s is not installed on target %s", plan.name, plan.version, plan.target.Key()))
+		}
+		if _, ok := release.PreviousRevision(); ok {
+			return cm.DeployRollbackChart(plan.name, plan.force)
+		}
+		if _, err := cm.UnDeployInstalledChart(plan.name); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Chart %s version %s uninstalled, no previous revision", plan.name, plan.version), nil
+	}
+	km, err := e.storage.GetRepositoryKubernetesFilesManager(repo.Id)
+	if err != nil {
+		return "", err
+	}
+	km.SetDeployTarget(plan.target)
+	var deployLock = e.lock(fmt.Sprintf("%s/kubefiles/%s", repo.Name, plan.name))
+	deployLock.Lock()
+	defer deployLock.Unlock()
+	release, err := km.GetKubernetesFileRelease(plan.name)
+	if err != nil {
+		return "", err
+	}
+	if !release.IsInstalled() || release.Current.Name != plan.version {
+		return "", errors.New(fmt.Sprintf("Kubernetes File %s version %s is not applied on target %s", plan.name, plan.version, plan.target.Key()))
+	}
+	if _, ok := release.PreviousVersion(); ok {
+		return km.DeployRollbackKubernetesFileWithValues(plan.name, plan.values, plan.variables)
+	}
+	if _, err := km.UnDeployInstalledKubernetesFile(plan.name); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Kubernetes File %s version %s deleted, no previous version", plan.name, plan.version), nil
+}
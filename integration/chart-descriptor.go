@@ -0,0 +1,115 @@
+package integration
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	chartValuesFileName       = "values.yaml"
+	chartRequirementsFileName = "requirements.yaml"
+	chartTemplatesFolderName  = "templates"
+	chartDependenciesFolder   = "charts"
+)
+
+// Describes a chart dependency, as declared in Chart.yaml (apiVersion v2) or requirements.yaml (apiVersion v1)
+type chartDependency struct {
+	Name       string `yaml:"name" json:"name" xml:"name"`
+	Version    string `yaml:"version,omitempty" json:"version,omitempty" xml:"version,omitempty"`
+	Repository string `yaml:"repository,omitempty" json:"repository,omitempty" xml:"repository,omitempty"`
+	Condition  string `yaml:"condition,omitempty" json:"condition,omitempty" xml:"condition,omitempty"`
+	Alias      string `yaml:"alias,omitempty" json:"alias,omitempty" xml:"alias,omitempty"`
+}
+
+// Describes the Chart.yaml chart descriptor file content
+type chartDescriptor struct {
+	ApiVersion   string            `yaml:"apiVersion" json:"apiVersion" xml:"api-version"`
+	Name         string            `yaml:"name" json:"name" xml:"name"`
+	Version      string            `yaml:"version" json:"version" xml:"version"`
+	KubeVersion  string            `yaml:"kubeVersion,omitempty" json:"kubeVersion,omitempty" xml:"kube-version,omitempty"`
+	Description  string            `yaml:"description,omitempty" json:"description,omitempty" xml:"description,omitempty"`
+	Type         string            `yaml:"type,omitempty" json:"type,omitempty" xml:"type,omitempty"`
+	Keywords     []string          `yaml:"keywords,omitempty" json:"keywords,omitempty" xml:"keyword,omitempty"`
+	Home         string            `yaml:"home,omitempty" json:"home,omitempty" xml:"home,omitempty"`
+	Sources      []string          `yaml:"sources,omitempty" json:"sources,omitempty" xml:"source,omitempty"`
+	Icon         string            `yaml:"icon,omitempty" json:"icon,omitempty" xml:"icon,omitempty"`
+	AppVersion   string            `yaml:"appVersion,omitempty" json:"appVersion,omitempty" xml:"app-version,omitempty"`
+	Deprecated   bool              `yaml:"deprecated,omitempty" json:"deprecated,omitempty" xml:"deprecated,omitempty"`
+	Annotations  map[string]string `yaml:"annotations,omitempty" json:"annotations,omitempty" xml:"-"`
+	Dependencies []chartDependency `yaml:"dependencies,omitempty" json:"dependencies,omitempty" xml:"dependency,omitempty"`
+}
+
+type chartRequirements struct {
+	Dependencies []chartDependency `yaml:"dependencies" json:"dependencies" xml:"dependency"`
+}
+
+// Load the chart descriptor from a chart folder, merging apiVersion v1 requirements.yaml dependencies
+func loadChartDescriptor(chartFolder string) (*chartDescriptor, error) {
+	var file = filepath.Join(chartFolder, chartDescriptorFileName)
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var descriptor = chartDescriptor{}
+	err = yaml.Unmarshal(data, &descriptor)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to parse %s, Error: %v", chartDescriptorFileName, err))
+	}
+	var reqFile = filepath.Join(chartFolder, chartRequirementsFileName)
+	if len(descriptor.Dependencies) == 0 {
+		if reqData, err := ioutil.ReadFile(reqFile); err == nil {
+			var requirements = chartRequirements{}
+			err = yaml.Unmarshal(reqData, &requirements)
+			if err != nil {
+				return &descriptor, errors.New(fmt.Sprintf("Unable to parse %s, Error: %v", chartRequirementsFileName, err))
+			}
+			descriptor.Dependencies = requirements.Dependencies
+		}
+	}
+	return &descriptor, nil
+}
+
+// Verify a dependency is available in the charts sub-folder, as folder or as packaged archive
+func chartDependencyPresent(chartFolder string, dep chartDependency) bool {
+	var depsFolder = filepath.Join(chartFolder, chartDependenciesFolder)
+	if fs, err := os.Stat(filepath.Join(depsFolder, dep.Name)); err == nil && fs.IsDir() {
+		return true
+	}
+	files, err := ioutil.ReadDir(depsFolder)
+	if err != nil {
+		return false
+	}
+	for _, f := range files {
+		if !f.IsDir() && strings.HasPrefix(f.Name(), dep.Name+"-") &&
+			(strings.HasSuffix(f.Name(), ".tgz") || strings.HasSuffix(f.Name(), ".tar.gz")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Collect the chart template files, with paths relative to the chart folder
+func listChartTemplates(chartFolder string) ([]string, error) {
+	var out = make([]string, 0)
+	var templatesFolder = filepath.Join(chartFolder, chartTemplatesFolderName)
+	err := filepath.Walk(templatesFolder, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(chartFolder, path)
+		if err != nil {
+			return err
+		}
+		out = append(out, filepath.ToSlash(rel))
+		return nil
+	})
+	return out, err
+}
@@ -0,0 +1,857 @@
+package integration
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hellgate75/k8s-deploy/utils"
+	"gopkg.in/yaml.v2"
+	"math"
+	"math/rand"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+// Creates the Sprig compatible template function set, shared to verify and render chart templates.
+// The include and tpl functions execute the templates defined in the given root template.
+func chartTemplateFuncMap(root *template.Template) template.FuncMap {
+	return template.FuncMap{
+		// Strings
+		"trim":       strings.TrimSpace,
+		"trimAll":    func(cut string, s string) string { return strings.Trim(s, cut) },
+		"trimPrefix": func(prefix string, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix string, s string) string { return strings.TrimSuffix(s, suffix) },
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"title":      strings.Title,
+		"untitle":    untitle,
+		"repeat":     func(count int, s string) string { return strings.Repeat(s, count) },
+		"substr":     substring,
+		"nospace":    func(s string) string { return strings.Join(strings.Fields(s), "") },
+		"trunc":      truncate,
+		"abbrev":     abbreviate,
+		"initials":   initials,
+		"contains":   func(substr string, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix string, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix string, s string) bool { return strings.HasSuffix(s, suffix) },
+		"quote":      quote,
+		"squote":     squote,
+		"cat":        cat,
+		"indent":     indent,
+		"nindent":    func(spaces int, s string) string { return "\n" + indent(spaces, s) },
+		"replace":    func(old string, new string, s string) string { return strings.ReplaceAll(s, old, new) },
+		"plural": func(one string, many string, count int) string {
+			return map[bool]string{true: one, false: many}[count == 1]
+		},
+		"snakecase":  func(s string) string { return joinWords(s, "_") },
+		"kebabcase":  func(s string) string { return joinWords(s, "-") },
+		"camelcase":  camelcase,
+		"split":      split,
+		"splitList":  func(sep string, s string) []string { return strings.Split(s, sep) },
+		"join":       join,
+		"sortAlpha":  sortAlpha,
+		"toString":   toString,
+		"toStrings":  toStrings,
+		"b64enc":     func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+		"b64dec":     b64dec,
+		"sha1sum":    func(s string) string { h := sha1.Sum([]byte(s)); return hex.EncodeToString(h[:]) },
+		"sha256sum":  func(s string) string { h := sha256.Sum256([]byte(s)); return hex.EncodeToString(h[:]) },
+		"regexMatch": func(regex string, s string) bool { m, _ := regexp.MatchString(regex, s); return m },
+		"regexFind":  regexFind,
+		"regexReplaceAll": func(regex string, s string, repl string) string {
+			return regexp.MustCompile(regex).ReplaceAllString(s, repl)
+		},
+		"uuidv4": utils.NewUniqueIdentifier,
+		// Defaults and flow
+		"default":  defaultValue,
+		"empty":    empty,
+		"coalesce": coalesce,
+		"ternary": func(vt interface{}, vf interface{}, v bool) interface{} {
+			return map[bool]interface{}{true: vt, false: vf}[v]
+		},
+		"fail":     func(msg string) (string, error) { return "", errors.New(msg) },
+		"required": required,
+		// Encoding
+		"toYaml":       toYaml,
+		"fromYaml":     fromYaml,
+		"toJson":       toJson,
+		"fromJson":     fromJson,
+		"toPrettyJson": toPrettyJson,
+		// Math
+		"add": func(a ...interface{}) int64 {
+			var r int64
+			for _, i := range a {
+				r += toInt64(i)
+			}
+			return r
+		},
+		"add1": func(i interface{}) int64 { return toInt64(i) + 1 },
+		"sub":  func(a interface{}, b interface{}) int64 { return toInt64(a) - toInt64(b) },
+		"mul": func(a interface{}, v ...interface{}) int64 {
+			r := toInt64(a)
+			for _, i := range v {
+				r *= toInt64(i)
+			}
+			return r
+		},
+		"div": div,
+		"mod": mod,
+		"max": func(a interface{}, v ...interface{}) int64 {
+			r := toInt64(a)
+			for _, i := range v {
+				r = int64(math.Max(float64(r), float64(toInt64(i))))
+			}
+			return r
+		},
+		"min": func(a interface{}, v ...interface{}) int64 {
+			r := toInt64(a)
+			for _, i := range v {
+				r = int64(math.Min(float64(r), float64(toInt64(i))))
+			}
+			return r
+		},
+		"int":       func(v interface{}) int { return int(toInt64(v)) },
+		"int64":     toInt64,
+		"float64":   toFloat64,
+		"atoi":      func(s string) int { i, _ := strconv.Atoi(s); return i },
+		"until":     func(count int) []int { return untilStep(0, count, 1) },
+		"untilStep": untilStep,
+		"seq":       seq,
+		"ceil":      func(v interface{}) float64 { return math.Ceil(toFloat64(v)) },
+		"floor":     func(v interface{}) float64 { return math.Floor(toFloat64(v)) },
+		"round":     round,
+		// Lists
+		"list":    func(v ...interface{}) []interface{} { return v },
+		"first":   first,
+		"last":    last,
+		"rest":    rest,
+		"initial": initial,
+		"append":  push,
+		"push":    push,
+		"prepend": prepend,
+		"concat":  concat,
+		"reverse": reverse,
+		"uniq":    uniq,
+		"without": without,
+		"has":     func(needle interface{}, list interface{}) bool { return inList(list, needle) },
+		"compact": compact,
+		// Dictionaries
+		"dict": dict,
+		"get": func(d map[string]interface{}, key string) interface{} {
+			if v, ok := d[key]; ok {
+				return v
+			}
+			return ""
+		},
+		"set": func(d map[string]interface{}, key string, value interface{}) map[string]interface{} {
+			d[key] = value
+			return d
+		},
+		"unset":  func(d map[string]interface{}, key string) map[string]interface{} { delete(d, key); return d },
+		"hasKey": func(d map[string]interface{}, key string) bool { _, ok := d[key]; return ok },
+		"keys":   keys,
+		"values": values,
+		"pluck":  pluck,
+		"pick":   pick,
+		"omit":   omit,
+		"merge": func(dst map[string]interface{}, src ...map[string]interface{}) interface{} {
+			return mergeDicts(false, dst, src...)
+		},
+		"mergeOverwrite": func(dst map[string]interface{}, src ...map[string]interface{}) interface{} {
+			return mergeDicts(true, dst, src...)
+		},
+		"deepCopy": deepCopy,
+		// Types
+		"kindOf":    func(v interface{}) string { return reflect.ValueOf(v).Kind().String() },
+		"kindIs":    func(kind string, v interface{}) bool { return reflect.ValueOf(v).Kind().String() == kind },
+		"typeOf":    func(v interface{}) string { return fmt.Sprintf("%T", v) },
+		"typeIs":    func(target string, v interface{}) bool { return fmt.Sprintf("%T", v) == target },
+		"deepEqual": reflect.DeepEqual,
+		// Dates
+		"now":  time.Now,
+		"date": func(layout string, t time.Time) string { return t.Format(layout) },
+		// Semantic versions
+		"semver":        semver,
+		"semverCompare": semverCompare,
+		// Random strings
+		"randAlphaNum": func(count int) string { return randString(count, randAlphaNumChars) },
+		"randAlpha":    func(count int) string { return randString(count, randAlphaChars) },
+		"randNumeric":  func(count int) string { return randString(count, randNumericChars) },
+		"randAscii":    func(count int) string { return randString(count, randAsciiChars) },
+		// Rendering context functions
+		"include": func(name string, data interface{}) (string, error) {
+			var buf bytes.Buffer
+			if err := root.ExecuteTemplate(&buf, name, data); err != nil {
+				return "", err
+			}
+			return buf.String(), nil
+		},
+		"tpl": func(text string, data interface{}) (string, error) {
+			t, err := root.Clone()
+			if err != nil {
+				return "", err
+			}
+			t, err = t.New("tpl").Parse(text)
+			if err != nil {
+				return "", err
+			}
+			var buf bytes.Buffer
+			if err := t.Execute(&buf, data); err != nil {
+				return "", err
+			}
+			return strings.ReplaceAll(buf.String(), "<no value>", ""), nil
+		},
+		"lookup": func(apiVersion string, kind string, namespace string, name string) map[string]interface{} {
+			return map[string]interface{}{}
+		},
+	}
+}
+
+const (
+	randNumericChars  = "0123456789"
+	randAlphaChars    = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	randAlphaNumChars = randAlphaChars + randNumericChars
+	randAsciiChars    = randAlphaNumChars + "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+)
+
+func randString(count int, chars string) string {
+	if count <= 0 {
+		return ""
+	}
+	var out = make([]byte, count)
+	for i := range out {
+		out[i] = chars[rand.Intn(len(chars))]
+	}
+	return string(out)
+}
+
+func semver(v string) (*utils.SemVer, error) {
+	return utils.ParseSemVer(v)
+}
+
+func semverCompare(constraint string, v string) (bool, error) {
+	c, err := utils.ParseSemVerConstraint(constraint)
+	if err != nil {
+		return false, err
+	}
+	sv, err := utils.ParseSemVer(v)
+	if err != nil {
+		return false, err
+	}
+	return c.Check(sv), nil
+}
+
+func untitle(s string) string {
+	var out = make([]string, 0)
+	for _, w := range strings.Fields(s) {
+		r := []rune(w)
+		r[0] = unicode.ToLower(r[0])
+		out = append(out, string(r))
+	}
+	return strings.Join(out, " ")
+}
+
+func substring(start int, end int, s string) string {
+	if start < 0 {
+		return s[:end]
+	}
+	if end < 0 || end > len(s) {
+		return s[start:]
+	}
+	return s[start:end]
+}
+
+func truncate(c int, s string) string {
+	if c < 0 && len(s)+c > 0 {
+		return s[len(s)+c:]
+	}
+	if c >= 0 && len(s) > c {
+		return s[:c]
+	}
+	return s
+}
+
+func abbreviate(width int, s string) string {
+	if width < 4 || len(s) <= width {
+		return s
+	}
+	return s[:width-3] + "..."
+}
+
+func initials(s string) string {
+	var out = ""
+	for _, w := range strings.Fields(s) {
+		out += string([]rune(w)[0])
+	}
+	return out
+}
+
+func quote(str ...interface{}) string {
+	var out = make([]string, 0)
+	for _, s := range str {
+		if s != nil {
+			out = append(out, strconv.Quote(toString(s)))
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+func squote(str ...interface{}) string {
+	var out = make([]string, 0)
+	for _, s := range str {
+		if s != nil {
+			out = append(out, fmt.Sprintf("'%s'", toString(s)))
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+func cat(v ...interface{}) string {
+	var out = make([]string, 0)
+	for _, s := range v {
+		if s != nil {
+			out = append(out, toString(s))
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+func indent(spaces int, s string) string {
+	var pad = strings.Repeat(" ", spaces)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+func splitWords(s string) []string {
+	var words = make([]string, 0)
+	var current = make([]rune, 0)
+	var runes = []rune(s)
+	for i, r := range runes {
+		if r == '_' || r == '-' || r == ' ' || r == '.' {
+			if len(current) > 0 {
+				words = append(words, string(current))
+				current = make([]rune, 0)
+			}
+			continue
+		}
+		if unicode.IsUpper(r) && len(current) > 0 && (unicode.IsLower(runes[i-1]) ||
+			(i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+			words = append(words, string(current))
+			current = make([]rune, 0)
+		}
+		current = append(current, r)
+	}
+	if len(current) > 0 {
+		words = append(words, string(current))
+	}
+	return words
+}
+
+func joinWords(s string, sep string) string {
+	var words = splitWords(s)
+	for i, w := range words {
+		words[i] = strings.ToLower(w)
+	}
+	return strings.Join(words, sep)
+}
+
+func camelcase(s string) string {
+	var out = ""
+	for _, w := range splitWords(s) {
+		out += strings.Title(strings.ToLower(w))
+	}
+	return out
+}
+
+func split(sep string, s string) map[string]string {
+	var out = make(map[string]string)
+	for i, v := range strings.Split(s, sep) {
+		out[fmt.Sprintf("_%d", i)] = v
+	}
+	return out
+}
+
+func join(sep string, v interface{}) string {
+	return strings.Join(toStrings(v), sep)
+}
+
+func sortAlpha(list interface{}) []string {
+	var out = toStrings(list)
+	sort.Strings(out)
+	return out
+}
+
+func toString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case []byte:
+		return string(s)
+	case error:
+		return s.Error()
+	case fmt.Stringer:
+		return s.String()
+	case nil:
+		return ""
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func toStrings(v interface{}) []string {
+	var out = make([]string, 0)
+	for _, item := range toList(v) {
+		out = append(out, toString(item))
+	}
+	return out
+}
+
+func b64dec(s string) string {
+	d, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return err.Error()
+	}
+	return string(d)
+}
+
+func regexFind(regex string, s string) string {
+	return regexp.MustCompile(regex).FindString(s)
+}
+
+// Verify if a value is empty, accordingly to Sprig rules
+func empty(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Bool:
+		return !rv.Bool()
+	case reflect.Complex64, reflect.Complex128:
+		return rv.Complex() == 0
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() == 0
+	case reflect.Ptr, reflect.Interface:
+		return rv.IsNil()
+	case reflect.Struct:
+		return false
+	}
+	return true
+}
+
+func defaultValue(d interface{}, given ...interface{}) interface{} {
+	if len(given) == 0 || empty(given[0]) {
+		return d
+	}
+	return given[0]
+}
+
+func coalesce(v ...interface{}) interface{} {
+	for _, val := range v {
+		if !empty(val) {
+			return val
+		}
+	}
+	return nil
+}
+
+func required(msg string, v interface{}) (interface{}, error) {
+	if v == nil {
+		return v, errors.New(msg)
+	}
+	if s, ok := v.(string); ok && s == "" {
+		return v, errors.New(msg)
+	}
+	return v, nil
+}
+
+func toYaml(v interface{}) string {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(string(data), "\n")
+}
+
+func fromYaml(s string) map[string]interface{} {
+	var out = make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(s), &out); err != nil {
+		out["Error"] = err.Error()
+	}
+	return normalizeYamlMap(out)
+}
+
+func toJson(v interface{}) string {
+	data, err := json.Marshal(normalizeYamlValue(v))
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func toPrettyJson(v interface{}) string {
+	data, err := json.MarshalIndent(normalizeYamlValue(v), "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func fromJson(s string) map[string]interface{} {
+	var out = make(map[string]interface{})
+	if err := json.Unmarshal([]byte(s), &out); err != nil {
+		out["Error"] = err.Error()
+	}
+	return out
+}
+
+// Convert yaml.v2 map[interface{}]interface{} structures into map[string]interface{} structures
+func normalizeYamlValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		var out = make(map[string]interface{})
+		for k, val := range t {
+			out[fmt.Sprintf("%v", k)] = normalizeYamlValue(val)
+		}
+		return out
+	case map[string]interface{}:
+		return normalizeYamlMap(t)
+	case []interface{}:
+		var out = make([]interface{}, len(t))
+		for i, val := range t {
+			out[i] = normalizeYamlValue(val)
+		}
+		return out
+	}
+	return v
+}
+
+func normalizeYamlMap(m map[string]interface{}) map[string]interface{} {
+	var out = make(map[string]interface{})
+	for k, v := range m {
+		out[k] = normalizeYamlValue(v)
+	}
+	return out
+}
+
+func toInt64(v interface{}) int64 {
+	switch t := v.(type) {
+	case string:
+		i, _ := strconv.ParseInt(t, 10, 64)
+		return i
+	case nil:
+		return 0
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return int64(rv.Float())
+	case reflect.Bool:
+		if rv.Bool() {
+			return 1
+		}
+	}
+	return 0
+}
+
+func toFloat64(v interface{}) float64 {
+	switch t := v.(type) {
+	case string:
+		f, _ := strconv.ParseFloat(t, 64)
+		return f
+	case nil:
+		return 0
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	}
+	return float64(toInt64(v))
+}
+
+func div(a interface{}, b interface{}) (int64, error) {
+	if toInt64(b) == 0 {
+		return 0, errors.New("division by zero")
+	}
+	return toInt64(a) / toInt64(b), nil
+}
+
+func mod(a interface{}, b interface{}) (int64, error) {
+	if toInt64(b) == 0 {
+		return 0, errors.New("division by zero")
+	}
+	return toInt64(a) % toInt64(b), nil
+}
+
+func round(v interface{}, precision int) float64 {
+	var pow = math.Pow(10, float64(precision))
+	return math.Round(toFloat64(v)*pow) / pow
+}
+
+func untilStep(start int, stop int, step int) []int {
+	var out = make([]int, 0)
+	if step == 0 {
+		return out
+	}
+	if step > 0 {
+		for i := start; i < stop; i += step {
+			out = append(out, i)
+		}
+	} else {
+		for i := start; i > stop; i += step {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+func seq(params ...int) string {
+	var list []int
+	switch len(params) {
+	case 1:
+		list = untilStep(1, params[0]+1, 1)
+	case 2:
+		list = untilStep(params[0], params[1]+1, 1)
+	case 3:
+		list = untilStep(params[0], params[2]+1, params[1])
+	}
+	var out = make([]string, 0)
+	for _, i := range list {
+		out = append(out, strconv.Itoa(i))
+	}
+	return strings.Join(out, " ")
+}
+
+// Convert any slice or array to a list of interfaces
+func toList(v interface{}) []interface{} {
+	if v == nil {
+		return make([]interface{}, 0)
+	}
+	if l, ok := v.([]interface{}); ok {
+		return l
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		var out = make([]interface{}, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			out[i] = rv.Index(i).Interface()
+		}
+		return out
+	}
+	return []interface{}{v}
+}
+
+func first(list interface{}) interface{} {
+	var l = toList(list)
+	if len(l) == 0 {
+		return nil
+	}
+	return l[0]
+}
+
+func last(list interface{}) interface{} {
+	var l = toList(list)
+	if len(l) == 0 {
+		return nil
+	}
+	return l[len(l)-1]
+}
+
+func rest(list interface{}) []interface{} {
+	var l = toList(list)
+	if len(l) == 0 {
+		return l
+	}
+	return l[1:]
+}
+
+func initial(list interface{}) []interface{} {
+	var l = toList(list)
+	if len(l) == 0 {
+		return l
+	}
+	return l[:len(l)-1]
+}
+
+func push(list interface{}, v interface{}) []interface{} {
+	return append(append(make([]interface{}, 0), toList(list)...), v)
+}
+
+func prepend(list interface{}, v interface{}) []interface{} {
+	return append([]interface{}{v}, toList(list)...)
+}
+
+func concat(lists ...interface{}) []interface{} {
+	var out = make([]interface{}, 0)
+	for _, l := range lists {
+		out = append(out, toList(l)...)
+	}
+	return out
+}
+
+func reverse(list interface{}) []interface{} {
+	var l = toList(list)
+	var out = make([]interface{}, len(l))
+	for i, v := range l {
+		out[len(l)-1-i] = v
+	}
+	return out
+}
+
+func inList(list interface{}, needle interface{}) bool {
+	for _, v := range toList(list) {
+		if reflect.DeepEqual(v, needle) {
+			return true
+		}
+	}
+	return false
+}
+
+func uniq(list interface{}) []interface{} {
+	var out = make([]interface{}, 0)
+	for _, v := range toList(list) {
+		if !inList(out, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func without(list interface{}, omit ...interface{}) []interface{} {
+	var out = make([]interface{}, 0)
+	for _, v := range toList(list) {
+		if !inList(omit, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func compact(list interface{}) []interface{} {
+	var out = make([]interface{}, 0)
+	for _, v := range toList(list) {
+		if !empty(v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func dict(v ...interface{}) map[string]interface{} {
+	var out = make(map[string]interface{})
+	for i := 0; i < len(v); i += 2 {
+		var key = toString(v[i])
+		if i+1 >= len(v) {
+			out[key] = ""
+			continue
+		}
+		out[key] = v[i+1]
+	}
+	return out
+}
+
+func keys(dicts ...map[string]interface{}) []string {
+	var out = make([]string, 0)
+	for _, d := range dicts {
+		for k := range d {
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func values(d map[string]interface{}) []interface{} {
+	var out = make([]interface{}, 0)
+	for _, k := range keys(d) {
+		out = append(out, d[k])
+	}
+	return out
+}
+
+func pluck(key string, dicts ...map[string]interface{}) []interface{} {
+	var out = make([]interface{}, 0)
+	for _, d := range dicts {
+		if v, ok := d[key]; ok {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func pick(d map[string]interface{}, names ...string) map[string]interface{} {
+	var out = make(map[string]interface{})
+	for _, n := range names {
+		if v, ok := d[n]; ok {
+			out[n] = v
+		}
+	}
+	return out
+}
+
+func omit(d map[string]interface{}, names ...string) map[string]interface{} {
+	var out = make(map[string]interface{})
+	for k, v := range d {
+		if !utils.StringsListContainItem(k, names, false) {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// Deep merge source dictionaries into destination one, overwriting existing keys only if required
+func mergeDicts(overwrite bool, dst map[string]interface{}, src ...map[string]interface{}) map[string]interface{} {
+	for _, s := range src {
+		for k, v := range s {
+			dv, exists := dst[k]
+			dm, dIsMap := dv.(map[string]interface{})
+			sm, sIsMap := v.(map[string]interface{})
+			if exists && dIsMap && sIsMap {
+				dst[k] = mergeDicts(overwrite, dm, sm)
+			} else if !exists || overwrite {
+				dst[k] = deepCopy(v)
+			}
+		}
+	}
+	return dst
+}
+
+func deepCopy(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		var out = make(map[string]interface{})
+		for k, val := range t {
+			out[k] = deepCopy(val)
+		}
+		return out
+	case []interface{}:
+		var out = make([]interface{}, len(t))
+		for i, val := range t {
+			out[i] = deepCopy(val)
+		}
+		return out
+	}
+	return v
+}
@@ -0,0 +1,244 @@
+package integration
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/hellgate75/k8s-deploy/model"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	helmIndexApiVersion  = "v1"
+	helmChartsUrlPrefix  = "charts"
+	helmPackageExtension = "tgz"
+)
+
+// Get the Helm chart archive file name for a chart version
+func getHelmPackageFileName(name string, version string) string {
+	return fmt.Sprintf("%s-%s.%s", name, version, helmPackageExtension)
+}
+
+// Package a chart folder as Helm chart archive, with all files in a root folder named as the chart.
+// Files are sorted and share the same modification time, so the archive digest is stable over the calls
+func packageChartFolder(folder string, name string, modTime time.Time) ([]byte, error) {
+	var files = make([]string, 0)
+	err := filepath.Walk(folder, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.Mode().IsRegular() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	var buffer = bytes.Buffer{}
+	gw := gzip.NewWriter(&buffer)
+	tw := tar.NewWriter(gw)
+	for _, file := range files {
+		rel, err := filepath.Rel(folder, file)
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		err = tw.WriteHeader(&tar.Header{
+			Name:     name + "/" + filepath.ToSlash(rel),
+			Mode:     0644,
+			Size:     int64(len(data)),
+			ModTime:  modTime,
+			Typeflag: tar.TypeReg,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err = tw.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err = tw.Close(); err != nil {
+		return nil, err
+	}
+	if err = gw.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// Get the chart version creation time, as the stored chart descriptor modification time
+func getChartVersionCreated(versionFolder string) time.Time {
+	if fs, err := os.Stat(filepath.Join(versionFolder, chartDescriptorFileName)); err == nil {
+		return fs.ModTime().UTC().Truncate(time.Second)
+	}
+	return time.Unix(0, 0).UTC()
+}
+
+func (c *chartsRepositoryManager) packageChartVersion(name string, version string) ([]byte, time.Time, error) {
+	var versionFolder = getChartVersionFolder(c.dataFolder, c.repository.Name, name, version)
+	if fs, err := os.Stat(versionFolder); err != nil || !fs.IsDir() {
+		return nil, time.Time{}, errors.New(fmt.Sprintf("Chart %s version %s not found in repository %s", name, version, c.repository.Name))
+	}
+	var created = getChartVersionCreated(versionFolder)
+	data, err := packageChartFolder(versionFolder, name, created)
+	return data, created, err
+}
+
+// Package a stored chart version and save the archive next to the version folder, returning the archive digest.
+// The stored archive is served as is, so the digest in the index stays valid when the files are copied or restored
+func (c *chartsRepositoryManager) storeChartVersionPackage(name string, version string) (string, error) {
+	data, _, err := c.packageChartVersion(name, version)
+	if err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(getChartVersionPackageFile(c.dataFolder, c.repository.Name, name, version), data, 0644); err != nil {
+		return "", err
+	}
+	var digest = sha256.Sum256(data)
+	return hex.EncodeToString(digest[:]), nil
+}
+
+// Read the stored package of a chart version, with its digest and creation time. Versions stored before the
+// packages were saved are packaged on the fly
+func (c *chartsRepositoryManager) readChartVersionPackage(name string, v model.Version) ([]byte, string, time.Time, error) {
+	var file = getChartVersionPackageFile(c.dataFolder, c.repository.Name, name, v.Name)
+	if fs, err := os.Stat(file); err == nil && !fs.IsDir() && v.Digest != "" {
+		data, err := ioutil.ReadFile(file)
+		return data, v.Digest, fs.ModTime().UTC().Truncate(time.Second), err
+	}
+	data, created, err := c.packageChartVersion(name, v.Name)
+	if err != nil {
+		return nil, "", created, err
+	}
+	var digest = sha256.Sum256(data)
+	return data, hex.EncodeToString(digest[:]), created, nil
+}
+
+// Get the stored digest and creation time of a chart version package, without reading the package
+func (c *chartsRepositoryManager) chartVersionPackageDigest(name string, v model.Version) (string, time.Time, error) {
+	var file = getChartVersionPackageFile(c.dataFolder, c.repository.Name, name, v.Name)
+	if fs, err := os.Stat(file); err == nil && !fs.IsDir() && v.Digest != "" {
+		return v.Digest, fs.ModTime().UTC().Truncate(time.Second), nil
+	}
+	_, digest, created, err := c.readChartVersionPackage(name, v)
+	return digest, created, err
+}
+
+func (c *chartsRepositoryManager) GetChartVersionPackage(name string, version string) ([]byte, error) {
+	c.RLock()
+	defer c.RUnlock()
+	_, versions, err := c.listChartVersions(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range versions {
+		if v.Name == version && v.State != model.StateError {
+			data, _, _, err := c.readChartVersionPackage(name, v)
+			return data, err
+		}
+	}
+	return nil, errors.New(fmt.Sprintf("Chart %s version %s not found in repository %s", name, version, c.repository.Name))
+}
+
+func (c *chartsRepositoryManager) GetHelmPackage(fileName string) ([]byte, error) {
+	c.RLock()
+	defer c.RUnlock()
+	// Chart names and versions can both contain dashes, so the package is resolved against the stored versions
+	for _, ch := range c.charts {
+		if !strings.HasPrefix(fileName, ch.Name+"-") {
+			continue
+		}
+		_, versions, err := c.listChartVersions(ch.Name)
+		if err != nil {
+			continue
+		}
+		for _, v := range versions {
+			if v.State != model.StateError && getHelmPackageFileName(ch.Name, v.Name) == fileName {
+				data, _, _, err := c.readChartVersionPackage(ch.Name, v)
+				return data, err
+			}
+		}
+	}
+	return nil, errors.New(fmt.Sprintf("Chart package %s not found in repository %s", fileName, c.repository.Name))
+}
+
+func (c *chartsRepositoryManager) GetHelmRepositoryIndex() (*model.HelmIndex, error) {
+	c.RLock()
+	defer c.RUnlock()
+	var index = model.HelmIndex{
+		ApiVersion: helmIndexApiVersion,
+		Entries:    make(map[string][]model.HelmIndexEntry),
+		Generated:  time.Now().UTC(),
+	}
+	for _, ch := range c.charts {
+		_, versions, err := c.listChartVersions(ch.Name)
+		if err != nil {
+			if c.logger != nil {
+				c.logger.Warnf("Unable to list chart %s versions for Helm index, Error: %v", ch.Name, err)
+			}
+			continue
+		}
+		var entries = make([]model.HelmIndexEntry, 0)
+		// Helm expects the newest version first
+		for i := len(versions) - 1; i >= 0; i-- {
+			var v = versions[i]
+			if v.State == model.StateError {
+				continue
+			}
+			var versionFolder = getChartVersionFolder(c.dataFolder, c.repository.Name, ch.Name, v.Name)
+			descriptor, err := loadChartDescriptor(versionFolder)
+			if err != nil {
+				if c.logger != nil {
+					c.logger.Warnf("Unable to load chart %s version %s descriptor for Helm index, Error: %v", ch.Name, v.Name, err)
+				}
+				continue
+			}
+			digest, created, err := c.chartVersionPackageDigest(ch.Name, v)
+			if err != nil {
+				if c.logger != nil {
+					c.logger.Warnf("Unable to package chart %s version %s for Helm index, Error: %v", ch.Name, v.Name, err)
+				}
+				continue
+			}
+			var apiVersion = descriptor.ApiVersion
+			if strings.TrimSpace(apiVersion) == "" {
+				apiVersion = "v1"
+			}
+			entries = append(entries, model.HelmIndexEntry{
+				ApiVersion:  apiVersion,
+				Name:        ch.Name,
+				Version:     v.Name,
+				AppVersion:  descriptor.AppVersion,
+				KubeVersion: descriptor.KubeVersion,
+				Description: descriptor.Description,
+				Type:        descriptor.Type,
+				Keywords:    descriptor.Keywords,
+				Home:        descriptor.Home,
+				Sources:     descriptor.Sources,
+				Icon:        descriptor.Icon,
+				Deprecated:  descriptor.Deprecated,
+				Created:     created,
+				Digest:      digest,
+				Urls:        []string{fmt.Sprintf("%s/%s", helmChartsUrlPrefix, getHelmPackageFileName(ch.Name, v.Name))},
+			})
+		}
+		if len(entries) > 0 {
+			index.Entries[ch.Name] = entries
+		}
+	}
+	return &index, nil
+}
@@ -0,0 +1,66 @@
+package integration
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hellgate75/k8s-deploy/model"
+)
+
+func TestHelmRepositoryIndexUsesStoredDigest(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "charts-test")
+	defer os.RemoveAll(dir)
+	var data = filepath.Join(dir, "data")
+	var repo = model.Repository{Name: "test"}
+	cm, err := NewRepositoryChartManager(repo, data, nil)
+	if err != nil && cm == nil {
+		t.Fatal(err)
+	}
+	if err := cm.InstallChart("my-chart", "1.0.0-rc.1", writeTestChartArchive(t, dir, "my-chart", "1.0.0-rc.1"), false); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	chart, err := loadChartDetails(data, nil, repo.Name, "my-chart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chart.Versions) != 1 || chart.Versions[0].Digest == "" {
+		t.Fatalf("Expected package digest stored in the chart index, found: %+v", chart.Versions)
+	}
+	var stored = chart.Versions[0].Digest
+	// Copying or restoring the chart files changes their modification time, the stored package must not change
+	var later = time.Now().Add(time.Hour)
+	var versionFolder = getChartVersionFolder(data, repo.Name, "my-chart", "1.0.0-rc.1")
+	if err := os.Chtimes(filepath.Join(versionFolder, chartDescriptorFileName), later, later); err != nil {
+		t.Fatal(err)
+	}
+	index, err := cm.GetHelmRepositoryIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries = index.Entries["my-chart"]
+	if len(entries) != 1 || entries[0].Digest != stored {
+		t.Fatalf("Expected index digest %s, found: %+v", stored, entries)
+	}
+	pkg, err := cm.GetHelmPackage("my-chart-1.0.0-rc.1.tgz")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var digest = sha256.Sum256(pkg)
+	if hex.EncodeToString(digest[:]) != stored {
+		t.Fatal("Expected served package matching the index digest")
+	}
+	if _, err := cm.GetHelmPackage("my-chart-2.0.0.tgz"); err == nil {
+		t.Fatal("Expected missing package error")
+	}
+	if err := cm.DeleteChartVersion("my-chart", "1.0.0-rc.1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(getChartVersionPackageFile(data, repo.Name, "my-chart", "1.0.0-rc.1")); !os.IsNotExist(err) {
+		t.Fatal("Expected package deleted with the chart version")
+	}
+}
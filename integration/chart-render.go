@@ -0,0 +1,359 @@
+package integration
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/hellgate75/k8s-deploy/model"
+	"github.com/hellgate75/k8s-deploy/utils"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+const (
+	defaultReleaseNamespace = "default"
+	defaultKubeVersion      = "v1.18.0"
+	chartNotesFileName      = "NOTES.txt"
+	chartGlobalValuesKey    = "global"
+)
+
+// Describes the chart files accessible from templates via .Files
+type chartFiles map[string][]byte
+
+// Get the content of a chart file as string
+func (f chartFiles) Get(name string) string {
+	return string(f[name])
+}
+
+// Get the content of a chart file as bytes
+func (f chartFiles) GetBytes(name string) []byte {
+	return f[name]
+}
+
+// Get the lines of a chart file
+func (f chartFiles) Lines(name string) []string {
+	if _, ok := f[name]; !ok {
+		return make([]string, 0)
+	}
+	return strings.Split(string(f[name]), "\n")
+}
+
+// Collect the chart files matching a glob pattern
+func (f chartFiles) Glob(pattern string) chartFiles {
+	var out = make(chartFiles)
+	for name, data := range f {
+		if ok, _ := path.Match(pattern, name); ok {
+			out[name] = data
+		}
+	}
+	return out
+}
+
+// Render the chart files as ConfigMap/Secret data entries
+func (f chartFiles) AsConfig() string {
+	var out = make(map[string]string)
+	for name, data := range f {
+		out[path.Base(name)] = string(data)
+	}
+	return toYaml(out)
+}
+
+// Describes the API versions available to templates via .Capabilities.APIVersions
+type chartAPIVersions []string
+
+// Verify if an API version (e.g.: apps/v1) or a resource type (e.g.: apps/v1/Deployment) is available
+func (a chartAPIVersions) Has(version string) bool {
+	for _, v := range a {
+		if v == version || strings.HasPrefix(version, v+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Describes the Kubernetes version available to templates via .Capabilities.KubeVersion
+type chartKubeVersion struct {
+	Version    string
+	Major      string
+	Minor      string
+	GitVersion string
+}
+
+func (v chartKubeVersion) String() string {
+	return v.Version
+}
+
+// Describes the cluster capabilities available to templates via .Capabilities
+type chartCapabilities struct {
+	KubeVersion chartKubeVersion
+	APIVersions chartAPIVersions
+}
+
+// Default capabilities used rendering charts offline
+func defaultChartCapabilities() chartCapabilities {
+	return chartCapabilities{
+		KubeVersion: chartKubeVersion{
+			Version:    defaultKubeVersion,
+			Major:      "1",
+			Minor:      "18",
+			GitVersion: defaultKubeVersion,
+		},
+		APIVersions: chartAPIVersions{
+			"v1",
+			"apps/v1",
+			"batch/v1",
+			"batch/v1beta1",
+			"autoscaling/v1",
+			"autoscaling/v2beta1",
+			"autoscaling/v2beta2",
+			"networking.k8s.io/v1beta1",
+			"extensions/v1beta1",
+			"policy/v1beta1",
+			"rbac.authorization.k8s.io/v1",
+		},
+	}
+}
+
+// Describes a chart loaded for rendering, including its sub-charts
+type renderChart struct {
+	descriptor *chartDescriptor
+	folder     string
+	values     map[string]interface{}
+	files      chartFiles
+	templates  map[string]string
+	subCharts  []*renderChart
+}
+
+// Load a chart folder with its default values, files, templates and sub-charts
+func loadRenderChart(chartFolder string) (*renderChart, error) {
+	descriptor, err := loadChartDescriptor(chartFolder)
+	if err != nil {
+		return nil, err
+	}
+	var chart = &renderChart{
+		descriptor: descriptor,
+		folder:     chartFolder,
+		values:     make(map[string]interface{}),
+		files:      make(chartFiles),
+		templates:  make(map[string]string),
+		subCharts:  make([]*renderChart, 0),
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(chartFolder, chartValuesFileName)); err == nil {
+		var values = make(map[string]interface{})
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return nil, errors.New(fmt.Sprintf("Unable to parse %s values, Error: %v", descriptor.Name, err))
+		}
+		chart.values = normalizeYamlMap(values)
+	}
+	err = filepath.Walk(chartFolder, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(chartFolder, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if info.IsDir() {
+			if rel == chartDependenciesFolder {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		data, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(rel, chartTemplatesFolderName+"/") {
+			chart.templates[rel] = string(data)
+		} else {
+			chart.files[rel] = data
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var depsFolder = filepath.Join(chartFolder, chartDependenciesFolder)
+	if entries, err := ioutil.ReadDir(depsFolder); err == nil {
+		for _, entry := range entries {
+			var subFolder = filepath.Join(depsFolder, entry.Name())
+			if !entry.IsDir() {
+				if !strings.HasSuffix(entry.Name(), ".tgz") && !strings.HasSuffix(entry.Name(), ".tar.gz") {
+					continue
+				}
+				var tmpFolder = utils.GetTempFolder(utils.GetRandPath())
+				if err := utils.TarUnCompress(subFolder, tmpFolder, true); err != nil {
+					return nil, err
+				}
+				defer func() {
+					_ = utils.DeleteFileOrFolder(tmpFolder)
+				}()
+				subFolder, err = findChartRootFolder(tmpFolder)
+				if err != nil {
+					return nil, err
+				}
+			}
+			sub, err := loadRenderChart(subFolder)
+			if err != nil {
+				return nil, err
+			}
+			chart.subCharts = append(chart.subCharts, sub)
+		}
+	}
+	return chart, nil
+}
+
+// Set a value in a values tree, using a dotted path (e.g.: image.tag)
+func setValuePath(values map[string]interface{}, key string, value interface{}) {
+	var tokens = strings.Split(key, ".")
+	var current = values
+	for _, t := range tokens[:len(tokens)-1] {
+		next, ok := current[t].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			current[t] = next
+		}
+		current = next
+	}
+	current[tokens[len(tokens)-1]] = normalizeYamlValue(value)
+}
+
+// Load the values override set: the values file first and then the single values
+func loadValueSet(values model.ValueSet) (map[string]interface{}, error) {
+	var out = make(map[string]interface{})
+	if strings.TrimSpace(values.File) != "" {
+		data, err := ioutil.ReadFile(values.File)
+		if err != nil {
+			return out, errors.New(fmt.Sprintf("Unable to read values file %s, Error: %v", values.File, err))
+		}
+		if err := yaml.Unmarshal(data, &out); err != nil {
+			return out, errors.New(fmt.Sprintf("Unable to parse values file %s, Error: %v", values.File, err))
+		}
+		out = normalizeYamlMap(out)
+	}
+	for _, v := range values.Value {
+		if strings.TrimSpace(v.Name) == "" {
+			continue
+		}
+		setValuePath(out, strings.TrimSpace(v.Name), v.Value)
+	}
+	return out, nil
+}
+
+// Compute the chart values merging the chart defaults with the given overrides
+func mergeChartValues(chart *renderChart, overrides map[string]interface{}) map[string]interface{} {
+	var values = deepCopy(chart.values).(map[string]interface{})
+	return mergeDicts(true, values, overrides)
+}
+
+// Compute sub-chart values, from the parent section named as the sub-chart and the global values
+func subChartValues(parent map[string]interface{}, sub *renderChart) map[string]interface{} {
+	var overrides = make(map[string]interface{})
+	if section, ok := parent[sub.descriptor.Name].(map[string]interface{}); ok {
+		overrides = deepCopy(section).(map[string]interface{})
+	}
+	if global, ok := parent[chartGlobalValuesKey].(map[string]interface{}); ok {
+		overrides[chartGlobalValuesKey] = deepCopy(global)
+	}
+	return mergeChartValues(sub, overrides)
+}
+
+type chartTemplateEntry struct {
+	name   string
+	text   string
+	values map[string]interface{}
+	chart  *renderChart
+	prefix string
+}
+
+// Collect all templates of a chart and its sub-charts, with the values computed for each chart
+func collectChartTemplates(chart *renderChart, values map[string]interface{}, prefix string, out []chartTemplateEntry) []chartTemplateEntry {
+	var base = path.Join(prefix, chart.descriptor.Name)
+	for name, text := range chart.templates {
+		out = append(out, chartTemplateEntry{
+			name:   path.Join(base, name),
+			text:   text,
+			values: values,
+			chart:  chart,
+			prefix: base,
+		})
+	}
+	for _, sub := range chart.subCharts {
+		out = collectChartTemplates(sub, subChartValues(values, sub), path.Join(base, chartDependenciesFolder), out)
+	}
+	return out
+}
+
+// Render the chart templates to plain Kubernetes yaml, with the given values overrides, release name and namespace
+func renderChartTemplates(chartFolder string, releaseName string, namespace string, overrides map[string]interface{}) (string, error) {
+	chart, err := loadRenderChart(chartFolder)
+	if err != nil {
+		return "", err
+	}
+	if namespace == "" {
+		namespace = defaultReleaseNamespace
+	}
+	var values = mergeChartValues(chart, overrides)
+	var entries = collectChartTemplates(chart, values, "", make([]chartTemplateEntry, 0))
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].name < entries[j].name
+	})
+	var root = template.New(chart.descriptor.Name).Option("missingkey=zero")
+	root.Funcs(chartTemplateFuncMap(root))
+	for _, entry := range entries {
+		if _, err := root.New(entry.name).Parse(entry.text); err != nil {
+			return "", err
+		}
+	}
+	var capabilities = defaultChartCapabilities()
+	var out = make([]string, 0)
+	for _, entry := range entries {
+		var base = path.Base(entry.name)
+		if strings.HasPrefix(base, "_") || base == chartNotesFileName {
+			continue
+		}
+		var data = map[string]interface{}{
+			"Values": entry.values,
+			"Chart": map[string]interface{}{
+				"Name":        entry.chart.descriptor.Name,
+				"Version":     entry.chart.descriptor.Version,
+				"AppVersion":  entry.chart.descriptor.AppVersion,
+				"ApiVersion":  entry.chart.descriptor.ApiVersion,
+				"Description": entry.chart.descriptor.Description,
+				"Type":        entry.chart.descriptor.Type,
+				"KubeVersion": entry.chart.descriptor.KubeVersion,
+			},
+			"Release": map[string]interface{}{
+				"Name":      releaseName,
+				"Namespace": namespace,
+				"Service":   "Helm",
+				"IsInstall": true,
+				"IsUpgrade": false,
+				"Revision":  1,
+			},
+			"Capabilities": capabilities,
+			"Template": map[string]interface{}{
+				"Name":     entry.name,
+				"BasePath": path.Join(entry.prefix, chartTemplatesFolderName),
+			},
+			"Files": entry.chart.files,
+		}
+		var buf bytes.Buffer
+		if err := root.ExecuteTemplate(&buf, entry.name, data); err != nil {
+			return "", err
+		}
+		var text = strings.TrimSpace(strings.ReplaceAll(buf.String(), "<no value>", ""))
+		if text == "" {
+			continue
+		}
+		out = append(out, fmt.Sprintf("---\n# Source: %s\n%s\n", entry.name, text))
+	}
+	return strings.Join(out, ""), nil
+}
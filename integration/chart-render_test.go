@@ -0,0 +1,113 @@
+package integration
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderChartTemplatesHelmCreateChart(t *testing.T) {
+	out, err := renderChartTemplates(testHelmCreateChart, "web", "", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, expected := range []string{
+		"# Source: mychart/templates/deployment.yaml",
+		"# Source: mychart/templates/service.yaml",
+		"# Source: mychart/templates/serviceaccount.yaml",
+		"name: web-mychart",
+		"helm.sh/chart: mychart-0.1.0",
+		"image: \"nginx:1.16.0\"",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("Expected %q in rendered chart:\n%s", expected, out)
+		}
+	}
+	for _, unexpected := range []string{"kind: Ingress", "kind: HorizontalPodAutoscaler", "NOTES", "<no value>"} {
+		if strings.Contains(out, unexpected) {
+			t.Fatalf("Unexpected %q in rendered chart:\n%s", unexpected, out)
+		}
+	}
+}
+
+func TestRenderChartTemplatesHelmCreateIngress(t *testing.T) {
+	var overrides = map[string]interface{}{
+		"ingress": map[string]interface{}{
+			"enabled":   true,
+			"className": "nginx",
+			"tls": []interface{}{
+				map[string]interface{}{
+					"secretName": "web-tls",
+					"hosts":      []interface{}{"chart-example.local"},
+				},
+			},
+		},
+		"autoscaling": map[string]interface{}{
+			"enabled": true,
+		},
+	}
+	out, err := renderChartTemplates(testHelmCreateChart, "web", "test", overrides)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, expected := range []string{
+		"apiVersion: networking.k8s.io/v1beta1\nkind: Ingress",
+		"ingressClassName: nginx",
+		"pathType: ImplementationSpecific",
+		"serviceName: web-mychart",
+		"secretName: web-tls",
+		"kind: HorizontalPodAutoscaler",
+		"targetAverageUtilization: 80",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Fatalf("Expected %q in rendered chart:\n%s", expected, out)
+		}
+	}
+	if strings.Contains(out, "replicas:") {
+		t.Fatalf("Unexpected replicas with autoscaling enabled:\n%s", out)
+	}
+}
+
+func TestChartCapabilities(t *testing.T) {
+	var capabilities = defaultChartCapabilities()
+	if !capabilities.APIVersions.Has("apps/v1") || !capabilities.APIVersions.Has("apps/v1/Deployment") {
+		t.Fatal("Expected apps/v1 to be available")
+	}
+	if capabilities.APIVersions.Has("networking.k8s.io/v1") {
+		t.Fatal("Unexpected networking.k8s.io/v1 availability")
+	}
+	if capabilities.KubeVersion.GitVersion != defaultKubeVersion {
+		t.Fatalf("Expected git version %s, found %s", defaultKubeVersion, capabilities.KubeVersion.GitVersion)
+	}
+}
+
+func TestChartSemVerAndRandomFunctions(t *testing.T) {
+	for _, c := range []struct {
+		constraint string
+		version    string
+		expected   bool
+	}{
+		{">=1.14-0", "v1.18.0", true},
+		{">=1.19-0", "v1.18.0", false},
+		{"^1.2.0", "1.9.3", true},
+		{"~1.2.0", "1.3.0", false},
+	} {
+		ok, err := semverCompare(c.constraint, c.version)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if ok != c.expected {
+			t.Fatalf("semverCompare %q %q: expected %v, found %v", c.constraint, c.version, c.expected, ok)
+		}
+	}
+	if _, err := semverCompare(">=1.0", "not-a-version"); err == nil {
+		t.Fatal("Expected error comparing an invalid version")
+	}
+	v, err := semver("v1.18.3")
+	if err != nil || v.Minor != 18 || v.Patch != 3 {
+		t.Fatalf("Unexpected semver result: %v, %v", v, err)
+	}
+	var s = randString(16, randAlphaNumChars)
+	if len(s) != 16 || strings.Trim(s, randAlphaNumChars) != "" {
+		t.Fatalf("Unexpected random string: %q", s)
+	}
+}
@@ -1,19 +1,25 @@
 package integration
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/hellgate75/k8s-deploy/utils"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 )
 
 func InitPackage() {
 	checkPath()
-	if ! checkPresenctOfHelm() {
+	if !checkPresenctOfHelm() {
 		err := downloadInstallHelm()
 		if err != nil {
 			fmt.Printf("Problems during installation of helm: %v\n", err)
 		}
 	}
-	if ! checkPresenctOfKubectl() {
+	if !checkPresenctOfKubectl() {
 		err := downloadInstallKubectl()
 		if err != nil {
 			fmt.Printf("Problems during installation of kubectl: %v\n", err)
@@ -21,38 +27,271 @@ func InitPackage() {
 	}
 }
 
+var binariesMutex sync.RWMutex
+var helmBinaryPath = utils.HELM_CMD
+var kubectlBinaryPath = utils.KUBECTL_CMD
+
+// Set the helm executable used to run the chart requests (e.g.: a full path or a test double script)
+func SetHelmBinaryPath(path string) {
+	binariesMutex.Lock()
+	defer binariesMutex.Unlock()
+	if strings.TrimSpace(path) == "" {
+		path = utils.HELM_CMD
+	}
+	helmBinaryPath = path
+}
+
+// Get the helm executable used to run the chart requests
+func GetHelmBinaryPath() string {
+	binariesMutex.RLock()
+	defer binariesMutex.RUnlock()
+	return helmBinaryPath
+}
+
+// Set the kubectl executable used to run the Kubernetes File requests (e.g.: a full path or a test double script)
+func SetKubectlBinaryPath(path string) {
+	binariesMutex.Lock()
+	defer binariesMutex.Unlock()
+	if strings.TrimSpace(path) == "" {
+		path = utils.KUBECTL_CMD
+	}
+	kubectlBinaryPath = path
+}
+
+// Get the kubectl executable used to run the Kubernetes File requests
+func GetKubectlBinaryPath() string {
+	binariesMutex.RLock()
+	defer binariesMutex.RUnlock()
+	return kubectlBinaryPath
+}
+
+// Helm release action type
+type ChartAction string
+
+const (
+	// Install a new helm release
+	ChartInstallAction ChartAction = "install"
+	// Upgrade an existing helm release
+	ChartUpgradeAction ChartAction = "upgrade"
+	// Uninstall an existing helm release
+	ChartUninstallAction ChartAction = "uninstall"
+	// Roll back an existing helm release to a previous revision
+	ChartRollbackAction ChartAction = "rollback"
+	// Report the status of an existing helm release
+	ChartStatusAction ChartAction = "status"
+	// Report the revisions of an existing helm release, in json format
+	ChartHistoryAction ChartAction = "history"
+)
+
 type ChartDeployRequest struct {
-	ChartName		string
-	ChartVersion	string
-	Namespace		string
-	KubeConfig		string
+	Action       ChartAction
+	ReleaseName  string
+	ChartName    string
+	ChartVersion string
+	// Chart folder or archive, required by install and upgrade actions
+	ChartPath string
+	// Optional values file passed to install and upgrade actions
+	ValuesFile string
+	// Force resources update on upgrade and rollback actions
+	Force bool
+	// Release revision restored by rollback action, the previous revision when not provided
+	Revision    int
+	Namespace   string
+	KubeConfig  string
+	KubeContext string
 }
 
+// Kubernetes File request action type
+type KubeFileAction string
+
+const (
+	// Apply the manifest resources
+	KubeFileApplyAction KubeFileAction = "apply"
+	// Delete the manifest resources
+	KubeFileDeleteAction KubeFileAction = "delete"
+	// Compare the manifest resources with the live cluster resources
+	KubeFileDiffAction KubeFileAction = "diff"
+)
+
 type KubeFileDeployRequest struct {
-	ChartName		string
-	ChartVersion	string
-	Namespace		string
-	KubeConfig		string
+	Action      KubeFileAction
+	FileName    string
+	FileVersion string
+	// Rendered manifest file passed to kubectl
+	ManifestPath string
+	// Submit apply and delete requests as server-side dry-run, without persisting any change
+	DryRun bool
+	// Force apply deleting and re-creating the resources when required
+	Force       bool
+	Namespace   string
+	KubeConfig  string
+	KubeContext string
 }
 
+// Describes a command execution response. Code is the command exit code, or 400 for
+// an invalid request and 500 when the command cannot be started. Output contains the
+// standard output, followed by the standard error on success (e.g.: warnings and
+// deprecation notices). On failure Error contains the standard error, or the execution error
 type HelmResponse struct {
-	Code		int
-	Response	string
-	Output		string
-	Error		error
+	Code     int
+	Response string
+	Output   string
+	Error    error
 }
 
+// Compose the helm command line for the given request
+func helmCommandArgs(req ChartDeployRequest) ([]string, error) {
+	var release = strings.TrimSpace(req.ReleaseName)
+	if release == "" {
+		release = strings.TrimSpace(req.ChartName)
+	}
+	if release == "" {
+		return nil, errors.New("Release name or chart name is required")
+	}
+	var args = []string{GetHelmBinaryPath(), string(req.Action), release}
+	switch req.Action {
+	case ChartInstallAction, ChartUpgradeAction:
+		if strings.TrimSpace(req.ChartPath) == "" {
+			return nil, errors.New(fmt.Sprintf("Chart path is required by helm %s", req.Action))
+		}
+		args = append(args, req.ChartPath)
+		if strings.TrimSpace(req.ValuesFile) != "" {
+			args = append(args, "--values", req.ValuesFile)
+		}
+		if req.Action == ChartUpgradeAction && req.Force {
+			args = append(args, "--force")
+		}
+	case ChartRollbackAction:
+		if req.Revision > 0 {
+			args = append(args, strconv.Itoa(req.Revision))
+		}
+		if req.Force {
+			args = append(args, "--force")
+		}
+	case ChartHistoryAction:
+		args = append(args, "--output", "json")
+	case ChartUninstallAction, ChartStatusAction:
+	default:
+		return nil, errors.New(fmt.Sprintf("Unsupported helm action: %s", req.Action))
+	}
+	if strings.TrimSpace(req.Namespace) != "" {
+		args = append(args, "--namespace", req.Namespace)
+	}
+	if strings.TrimSpace(req.KubeConfig) != "" {
+		args = append(args, "--kubeconfig", req.KubeConfig)
+	}
+	if strings.TrimSpace(req.KubeContext) != "" {
+		args = append(args, "--kube-context", req.KubeContext)
+	}
+	return args, nil
+}
+
+// Execute a command, collecting exit code, standard output and standard error in the response
+func executeCommandRequest(args []string) HelmResponse {
+	result, err := utils.ExecuteCommandArgsWithResult(args...)
+	var response = HelmResponse{
+		Code:     result.ExitCode,
+		Response: strings.Join(args, " "),
+		Output:   result.Stdout,
+	}
+	if result.ExitCode < 0 {
+		response.Code = 500
+		response.Error = err
+	} else if err != nil {
+		if strings.TrimSpace(result.Stderr) != "" {
+			response.Error = errors.New(strings.TrimSpace(result.Stderr))
+		} else {
+			response.Error = err
+		}
+	} else if strings.TrimSpace(result.Stderr) != "" {
+		response.Output += result.Stderr
+	}
+	return response
+}
+
+// Execute a helm install, upgrade, rollback, uninstall or status request
 func ExecuteChartRequest(req ChartDeployRequest) HelmResponse {
-	return HelmResponse {
-		Code: 404,
-		Error: errors.New("Not Implemented!!"),
+	args, err := helmCommandArgs(req)
+	if err != nil {
+		return HelmResponse{
+			Code:  400,
+			Error: err,
+		}
+	}
+	return executeCommandRequest(args)
+}
+
+// Describes a helm release revision, as reported by the helm history command
+type HelmReleaseRevision struct {
+	Revision    int    `json:"revision"`
+	Status      string `json:"status"`
+	Chart       string `json:"chart"`
+	AppVersion  string `json:"app_version"`
+	Description string `json:"description"`
+}
+
+// Collect the revisions of a helm release, ordered by revision number
+func ExecuteChartHistoryRequest(req ChartDeployRequest) ([]HelmReleaseRevision, error) {
+	req.Action = ChartHistoryAction
+	var response = ExecuteChartRequest(req)
+	if response.Error != nil {
+		return nil, errors.New(fmt.Sprintf("Helm history failed with code %v: %v", response.Code, response.Error))
+	}
+	var revisions = make([]HelmReleaseRevision, 0)
+	// Only the first json value is decoded, helm warnings may follow the standard output
+	err := json.NewDecoder(strings.NewReader(response.Output)).Decode(&revisions)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to parse helm history output: %v", err))
 	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision < revisions[j].Revision
+	})
+	return revisions, nil
 }
 
+// Compose the kubectl command line for the given request
+func kubectlCommandArgs(req KubeFileDeployRequest) ([]string, error) {
+	if strings.TrimSpace(req.ManifestPath) == "" {
+		return nil, errors.New(fmt.Sprintf("Manifest path is required for Kubernetes File %s version %s", req.FileName, req.FileVersion))
+	}
+	var args = []string{GetKubectlBinaryPath(), string(req.Action), "-f", req.ManifestPath}
+	switch req.Action {
+	case KubeFileApplyAction, KubeFileDeleteAction:
+		if req.DryRun {
+			args = append(args, "--dry-run=server")
+		}
+		if req.Action == KubeFileApplyAction && req.Force {
+			args = append(args, "--force")
+		}
+	case KubeFileDiffAction:
+	default:
+		return nil, errors.New(fmt.Sprintf("Unsupported kubectl action: %s", req.Action))
+	}
+	if strings.TrimSpace(req.Namespace) != "" {
+		args = append(args, "--namespace", req.Namespace)
+	}
+	if strings.TrimSpace(req.KubeConfig) != "" {
+		args = append(args, "--kubeconfig", req.KubeConfig)
+	}
+	if strings.TrimSpace(req.KubeContext) != "" {
+		args = append(args, "--context", req.KubeContext)
+	}
+	return args, nil
+}
 
+// Execute a kubectl apply, delete or diff request. A diff exit code 1 reports
+// differences found, so it's not considered an error
 func ExecuteKubeRequest(req KubeFileDeployRequest) HelmResponse {
-	return HelmResponse {
-		Code: 404,
-		Error: errors.New("Not Implemented!!"),
+	args, err := kubectlCommandArgs(req)
+	if err != nil {
+		return HelmResponse{
+			Code:  400,
+			Error: err,
+		}
+	}
+	var response = executeCommandRequest(args)
+	if req.Action == KubeFileDiffAction && response.Code == 1 {
+		response.Error = nil
 	}
-}
\ No newline at end of file
+	return response
+}
@@ -0,0 +1,242 @@
+package integration
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Writes a fake helm shell script printing its arguments, the given text on standard error and exiting with the given code
+func writeFakeHelm(t *testing.T, folder string, stderr string, exitCode string) string {
+	var script = "#!/bin/sh\necho \"$@\"\n"
+	if stderr != "" {
+		script += "echo \"" + stderr + "\" >&2\n"
+	}
+	script += "exit " + exitCode + "\n"
+	var file = filepath.Join(folder, "helm")
+	if err := ioutil.WriteFile(file, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestExecuteChartRequestArguments(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "helm-test")
+	defer os.RemoveAll(dir)
+	SetHelmBinaryPath(writeFakeHelm(t, dir, "", "0"))
+	defer SetHelmBinaryPath("")
+	var target = "--namespace apps --kubeconfig /etc/kube/config --kube-context staging"
+	for _, c := range []struct {
+		req      ChartDeployRequest
+		expected string
+	}{
+		{
+			ChartDeployRequest{Action: ChartInstallAction, ReleaseName: "web", ChartPath: "/charts/web", ValuesFile: "/tmp/values.yaml"},
+			"install web /charts/web --values /tmp/values.yaml",
+		},
+		{
+			ChartDeployRequest{Action: ChartInstallAction, ChartName: "web", ChartPath: "/charts/web", Force: true},
+			"install web /charts/web",
+		},
+		{
+			ChartDeployRequest{Action: ChartUpgradeAction, ReleaseName: "web", ChartPath: "/charts/web", Force: true},
+			"upgrade web /charts/web --force",
+		},
+		{
+			ChartDeployRequest{Action: ChartRollbackAction, ReleaseName: "web", Revision: 3, Force: true},
+			"rollback web 3 --force",
+		},
+		{
+			ChartDeployRequest{Action: ChartRollbackAction, ReleaseName: "web"},
+			"rollback web",
+		},
+		{
+			ChartDeployRequest{Action: ChartHistoryAction, ReleaseName: "web"},
+			"history web --output json",
+		},
+		{
+			ChartDeployRequest{Action: ChartUninstallAction, ReleaseName: "web"},
+			"uninstall web",
+		},
+		{
+			ChartDeployRequest{Action: ChartStatusAction, ReleaseName: "web"},
+			"status web",
+		},
+	} {
+		var req = c.req
+		var response = ExecuteChartRequest(req)
+		if response.Code != 0 || response.Error != nil {
+			t.Fatalf("Unexpected failure for %s: %v, %v", req.Action, response.Code, response.Error)
+		}
+		if strings.TrimSpace(response.Output) != c.expected {
+			t.Fatalf("Expected arguments %q, found %q", c.expected, strings.TrimSpace(response.Output))
+		}
+		req.Namespace = "apps"
+		req.KubeConfig = "/etc/kube/config"
+		req.KubeContext = "staging"
+		response = ExecuteChartRequest(req)
+		if strings.TrimSpace(response.Output) != c.expected+" "+target {
+			t.Fatalf("Expected arguments %q, found %q", c.expected+" "+target, strings.TrimSpace(response.Output))
+		}
+	}
+}
+
+func TestExecuteChartRequestInvalid(t *testing.T) {
+	for _, req := range []ChartDeployRequest{
+		{Action: ChartInstallAction, ChartPath: "/charts/web"},
+		{Action: ChartUpgradeAction, ReleaseName: "web"},
+		{Action: ChartAction("delete"), ReleaseName: "web"},
+	} {
+		var response = ExecuteChartRequest(req)
+		if response.Code != 400 || response.Error == nil {
+			t.Fatalf("Expected invalid request for %+v, found code %v", req, response.Code)
+		}
+	}
+}
+
+func TestExecuteChartRequestFailure(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "helm-test")
+	defer os.RemoveAll(dir)
+	SetHelmBinaryPath(writeFakeHelm(t, dir, "Error: release web not found", "3"))
+	defer SetHelmBinaryPath("")
+	var response = ExecuteChartRequest(ChartDeployRequest{Action: ChartStatusAction, ReleaseName: "web"})
+	if response.Code != 3 {
+		t.Fatalf("Expected exit code 3, found %v", response.Code)
+	}
+	if response.Error == nil || response.Error.Error() != "Error: release web not found" {
+		t.Fatalf("Expected standard error as error, found %v", response.Error)
+	}
+	if strings.TrimSpace(response.Output) != "status web" {
+		t.Fatalf("Unexpected output %q", response.Output)
+	}
+	if !strings.HasSuffix(response.Response, "helm status web") {
+		t.Fatalf("Unexpected command line %q", response.Response)
+	}
+}
+
+func TestExecuteChartRequestWarnings(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "helm-test")
+	defer os.RemoveAll(dir)
+	SetHelmBinaryPath(writeFakeHelm(t, dir, "WARNING: This chart is deprecated", "0"))
+	defer SetHelmBinaryPath("")
+	var response = ExecuteChartRequest(ChartDeployRequest{Action: ChartStatusAction, ReleaseName: "web"})
+	if response.Code != 0 || response.Error != nil {
+		t.Fatalf("Unexpected failure: %v, %v", response.Code, response.Error)
+	}
+	if response.Output != "status web\nWARNING: This chart is deprecated\n" {
+		t.Fatalf("Expected standard error following the output, found %q", response.Output)
+	}
+}
+
+func TestExecuteChartRequestMissingBinary(t *testing.T) {
+	SetHelmBinaryPath(filepath.Join(os.TempDir(), "missing-helm-binary"))
+	defer SetHelmBinaryPath("")
+	var response = ExecuteChartRequest(ChartDeployRequest{Action: ChartStatusAction, ReleaseName: "web"})
+	if response.Code != 500 || response.Error == nil {
+		t.Fatalf("Expected code 500 with error, found %v, %v", response.Code, response.Error)
+	}
+}
+
+// Writes a fake kubectl shell script printing its arguments, the given text on standard output and exiting with the given code
+func writeFakeKubectl(t *testing.T, folder string, stdout string, exitCode string) string {
+	var script = "#!/bin/sh\necho \"$@\"\n"
+	if stdout != "" {
+		script += "echo \"" + stdout + "\"\n"
+	}
+	script += "exit " + exitCode + "\n"
+	var file = filepath.Join(folder, "kubectl")
+	if err := ioutil.WriteFile(file, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestExecuteKubeRequestArguments(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "kubectl-test")
+	defer os.RemoveAll(dir)
+	SetKubectlBinaryPath(writeFakeKubectl(t, dir, "", "0"))
+	defer SetKubectlBinaryPath("")
+	var target = "--namespace apps --kubeconfig /etc/kube/config --context staging"
+	for _, c := range []struct {
+		req      KubeFileDeployRequest
+		expected string
+	}{
+		{
+			KubeFileDeployRequest{Action: KubeFileApplyAction, ManifestPath: "/tmp/web.yaml"},
+			"apply -f /tmp/web.yaml",
+		},
+		{
+			KubeFileDeployRequest{Action: KubeFileApplyAction, ManifestPath: "/tmp/web.yaml", DryRun: true, Force: true},
+			"apply -f /tmp/web.yaml --dry-run=server --force",
+		},
+		{
+			KubeFileDeployRequest{Action: KubeFileDeleteAction, ManifestPath: "/tmp/web.yaml", Force: true},
+			"delete -f /tmp/web.yaml",
+		},
+		{
+			KubeFileDeployRequest{Action: KubeFileDeleteAction, ManifestPath: "/tmp/web.yaml", DryRun: true},
+			"delete -f /tmp/web.yaml --dry-run=server",
+		},
+		{
+			KubeFileDeployRequest{Action: KubeFileDiffAction, ManifestPath: "/tmp/web.yaml", DryRun: true, Force: true},
+			"diff -f /tmp/web.yaml",
+		},
+	} {
+		var req = c.req
+		var response = ExecuteKubeRequest(req)
+		if response.Code != 0 || response.Error != nil {
+			t.Fatalf("Unexpected failure for %s: %v, %v", req.Action, response.Code, response.Error)
+		}
+		if strings.TrimSpace(response.Output) != c.expected {
+			t.Fatalf("Expected arguments %q, found %q", c.expected, strings.TrimSpace(response.Output))
+		}
+		req.Namespace = "apps"
+		req.KubeConfig = "/etc/kube/config"
+		req.KubeContext = "staging"
+		response = ExecuteKubeRequest(req)
+		if strings.TrimSpace(response.Output) != c.expected+" "+target {
+			t.Fatalf("Expected arguments %q, found %q", c.expected+" "+target, strings.TrimSpace(response.Output))
+		}
+	}
+}
+
+func TestExecuteKubeRequestInvalid(t *testing.T) {
+	for _, req := range []KubeFileDeployRequest{
+		{Action: KubeFileApplyAction, FileName: "web", FileVersion: "1.0.0"},
+		{Action: KubeFileAction("replace"), ManifestPath: "/tmp/web.yaml"},
+	} {
+		var response = ExecuteKubeRequest(req)
+		if response.Code != 400 || response.Error == nil {
+			t.Fatalf("Expected invalid request for %+v, found code %v", req, response.Code)
+		}
+	}
+}
+
+func TestExecuteKubeRequestDiffExitCodes(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "kubectl-test")
+	defer os.RemoveAll(dir)
+	defer SetKubectlBinaryPath("")
+	// Exit code 1 reports differences found
+	SetKubectlBinaryPath(writeFakeKubectl(t, dir, "-  replicas: 1", "1"))
+	var response = ExecuteKubeRequest(KubeFileDeployRequest{Action: KubeFileDiffAction, ManifestPath: "/tmp/web.yaml"})
+	if response.Code != 1 || response.Error != nil {
+		t.Fatalf("Expected differences found without error, found %v, %v", response.Code, response.Error)
+	}
+	if !strings.Contains(response.Output, "-  replicas: 1") {
+		t.Fatalf("Expected differences in output, found %q", response.Output)
+	}
+	// Greater exit codes report a diff failure
+	SetKubectlBinaryPath(writeFakeKubectl(t, dir, "", "2"))
+	response = ExecuteKubeRequest(KubeFileDeployRequest{Action: KubeFileDiffAction, ManifestPath: "/tmp/web.yaml"})
+	if response.Code != 2 || response.Error == nil {
+		t.Fatalf("Expected diff failure, found %v, %v", response.Code, response.Error)
+	}
+	// Exit code 1 is an error for the other actions
+	SetKubectlBinaryPath(writeFakeKubectl(t, dir, "", "1"))
+	response = ExecuteKubeRequest(KubeFileDeployRequest{Action: KubeFileApplyAction, ManifestPath: "/tmp/web.yaml"})
+	if response.Code != 1 || response.Error == nil {
+		t.Fatalf("Expected apply failure, found %v, %v", response.Code, response.Error)
+	}
+}
@@ -0,0 +1,367 @@
+package integration
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/hellgate75/k8s-deploy/model"
+	"gopkg.in/yaml.v2"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	openApiIntOrStringFormat = "int-or-string"
+	openApiByteFormat        = "byte"
+	openApiReferencePrefix   = "#/definitions/"
+)
+
+var manifestDocumentSeparatorRegexp = regexp.MustCompile(`^---(\s.*)?$`)
+
+var kubernetesNameRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+
+// Describes a single document of a multi-document Kubernetes manifest
+type manifestDocument struct {
+	// 1-based position of the document in the manifest, empty documents are not counted
+	Index int
+	// First line of the document in the manifest
+	Line int
+	Text string
+}
+
+// Split a manifest in its '---' separated documents, skipping documents containing only comments or spaces
+func splitManifestDocuments(manifest string) []manifestDocument {
+	var out = make([]manifestDocument, 0)
+	var lines = strings.Split(strings.ReplaceAll(manifest, "\r\n", "\n"), "\n")
+	var current = make([]string, 0)
+	var start = 1
+	var flush = func() {
+		var text = strings.Join(current, "\n")
+		if !isEmptyManifestDocument(text) {
+			out = append(out, manifestDocument{
+				Index: len(out) + 1,
+				Line:  start,
+				Text:  text,
+			})
+		}
+	}
+	for i, line := range lines {
+		if manifestDocumentSeparatorRegexp.MatchString(line) {
+			flush()
+			current = make([]string, 0)
+			start = i + 2
+			continue
+		}
+		current = append(current, line)
+	}
+	flush()
+	return out
+}
+
+func isEmptyManifestDocument(text string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		var l = strings.TrimSpace(line)
+		if l != "" && !strings.HasPrefix(l, "#") && l != "..." {
+			return false
+		}
+	}
+	return true
+}
+
+// Describes an OpenAPI (swagger v2) schema definition
+type openApiSchema struct {
+	Ref                  string                    `yaml:"$ref,omitempty"`
+	Type                 string                    `yaml:"type,omitempty"`
+	Format               string                    `yaml:"format,omitempty"`
+	Required             []string                  `yaml:"required,omitempty"`
+	Enum                 []string                  `yaml:"enum,omitempty"`
+	Properties           map[string]*openApiSchema `yaml:"properties,omitempty"`
+	Items                *openApiSchema            `yaml:"items,omitempty"`
+	AdditionalProperties *openApiSchema            `yaml:"additionalProperties,omitempty"`
+	GroupVersionKind     []openApiGroupVersionKind `yaml:"x-kubernetes-group-version-kind,omitempty"`
+}
+
+type openApiGroupVersionKind struct {
+	Group   string `yaml:"group"`
+	Version string `yaml:"version"`
+	Kind    string `yaml:"kind"`
+}
+
+// Returns the manifest apiVersion of the group version kind
+func (gvk openApiGroupVersionKind) ApiVersion() string {
+	if gvk.Group == "" {
+		return gvk.Version
+	}
+	return gvk.Group + "/" + gvk.Version
+}
+
+type openApiDocument struct {
+	Definitions map[string]*openApiSchema `yaml:"definitions"`
+}
+
+// Bundled schemas indexed by definition name and by apiVersion/kind
+type kubernetesSchemas struct {
+	definitions map[string]*openApiSchema
+	kinds       map[string]*openApiSchema
+	apiVersions map[string][]string
+}
+
+var bundledSchemas *kubernetesSchemas
+var bundledSchemasOnce sync.Once
+
+// Load the bundled Kubernetes OpenAPI schemas, parsing the definitions only once
+func getKubernetesSchemas() *kubernetesSchemas {
+	bundledSchemasOnce.Do(func() {
+		var doc = openApiDocument{}
+		if err := yaml.Unmarshal([]byte(kubernetesOpenApiDefinitions), &doc); err != nil {
+			panic(fmt.Sprintf("Invalid bundled Kubernetes schemas, Error: %v", err))
+		}
+		bundledSchemas = &kubernetesSchemas{
+			definitions: doc.Definitions,
+			kinds:       make(map[string]*openApiSchema),
+			apiVersions: make(map[string][]string),
+		}
+		for _, schema := range doc.Definitions {
+			for _, gvk := range schema.GroupVersionKind {
+				bundledSchemas.kinds[gvk.ApiVersion()+"/"+gvk.Kind] = schema
+				bundledSchemas.apiVersions[gvk.Kind] = append(bundledSchemas.apiVersions[gvk.Kind], gvk.ApiVersion())
+			}
+		}
+		for kind := range bundledSchemas.apiVersions {
+			sort.Strings(bundledSchemas.apiVersions[kind])
+		}
+	})
+	return bundledSchemas
+}
+
+func (s *kubernetesSchemas) resolve(schema *openApiSchema) *openApiSchema {
+	for schema != nil && schema.Ref != "" {
+		schema = s.definitions[strings.TrimPrefix(schema.Ref, openApiReferencePrefix)]
+	}
+	return schema
+}
+
+// Validate a value against a schema, collecting the problems in the report
+func (s *kubernetesSchemas) validate(value interface{}, schema *openApiSchema, path string, document int, report *model.VerificationReport) {
+	schema = s.resolve(schema)
+	if schema == nil || value == nil {
+		return
+	}
+	if schema.Format == openApiIntOrStringFormat {
+		if !isSchemaInteger(value) {
+			if _, ok := value.(string); !ok {
+				report.AddDocumentIssue(document, path, "expected integer or string, found %s", schemaValueType(value))
+			}
+		}
+		return
+	}
+	switch schema.Type {
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			report.AddDocumentIssue(document, path, "expected string, found %s", schemaValueType(value))
+			return
+		}
+		if len(schema.Enum) > 0 && !containsString(schema.Enum, str) {
+			report.AddDocumentIssue(document, path, "unsupported value %q, expected one of: %s", str, strings.Join(schema.Enum, ", "))
+		}
+		if schema.Format == openApiByteFormat {
+			if _, err := base64.StdEncoding.DecodeString(str); err != nil {
+				report.AddDocumentIssue(document, path, "expected base64 encoded value")
+			}
+		}
+	case "integer":
+		if !isSchemaInteger(value) {
+			report.AddDocumentIssue(document, path, "expected integer, found %s", schemaValueType(value))
+		}
+	case "number":
+		if _, ok := value.(float64); !ok && !isSchemaInteger(value) {
+			report.AddDocumentIssue(document, path, "expected number, found %s", schemaValueType(value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			report.AddDocumentIssue(document, path, "expected boolean, found %s", schemaValueType(value))
+		}
+	case "array":
+		list, ok := value.([]interface{})
+		if !ok {
+			report.AddDocumentIssue(document, path, "expected array, found %s", schemaValueType(value))
+			return
+		}
+		for i, item := range list {
+			s.validate(item, schema.Items, fmt.Sprintf("%s[%d]", path, i), document, report)
+		}
+	case "object":
+		dict, ok := value.(map[string]interface{})
+		if !ok {
+			report.AddDocumentIssue(document, path, "expected object, found %s", schemaValueType(value))
+			return
+		}
+		for _, field := range schema.Required {
+			if v, ok := dict[field]; !ok || v == nil {
+				report.AddDocumentIssue(document, joinSchemaPath(path, field), "required field is missing")
+			}
+		}
+		var keys = make([]string, 0)
+		for k := range dict {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if prop, ok := schema.Properties[k]; ok {
+				s.validate(dict[k], prop, joinSchemaPath(path, k), document, report)
+			} else if schema.AdditionalProperties != nil {
+				s.validate(dict[k], schema.AdditionalProperties, joinSchemaPath(path, k), document, report)
+			}
+		}
+	}
+}
+
+func joinSchemaPath(path string, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+func isSchemaInteger(value interface{}) bool {
+	switch value.(type) {
+	case int, int64, uint64:
+		return true
+	}
+	return false
+}
+
+func schemaValueType(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int64, uint64:
+		return "integer"
+	case float64:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Verify a multi-document Kubernetes manifest, checking each document structure and validating the
+// bundled built-in kinds against their OpenAPI schema. Unknown kinds (e.g.: custom resources) are
+// only checked for apiVersion, kind and metadata.name. Problems are collected per document in the report
+func verifyKubernetesManifest(manifest string, report *model.VerificationReport) {
+	var documents = splitManifestDocuments(manifest)
+	if len(documents) == 0 {
+		report.AddIssue(kubernetesFileManifestName, "manifest doesn't contain any document")
+		return
+	}
+	var schemas = getKubernetesSchemas()
+	for _, document := range documents {
+		var raw = make(map[string]interface{})
+		if err := yaml.Unmarshal([]byte(document.Text), &raw); err != nil {
+			report.AddDocumentIssue(document.Index, fmt.Sprintf("line %d", document.Line), "invalid yaml: %v", err)
+			continue
+		}
+		var object = normalizeYamlMap(raw)
+		apiVersion, _ := object["apiVersion"].(string)
+		kind, _ := object["kind"].(string)
+		if strings.TrimSpace(apiVersion) == "" {
+			report.AddDocumentIssue(document.Index, "apiVersion", "required field is missing")
+		}
+		if strings.TrimSpace(kind) == "" {
+			report.AddDocumentIssue(document.Index, "kind", "required field is missing")
+		}
+		metadata, ok := object["metadata"].(map[string]interface{})
+		if !ok {
+			report.AddDocumentIssue(document.Index, "metadata", "required field is missing")
+		} else {
+			name, _ := metadata["name"].(string)
+			if strings.TrimSpace(name) == "" {
+				report.AddDocumentIssue(document.Index, "metadata.name", "required field is missing")
+			} else if len(name) > 253 || !kubernetesNameRegexp.MatchString(name) {
+				report.AddDocumentIssue(document.Index, "metadata.name", "%q is not a valid DNS-1123 subdomain name", name)
+			}
+		}
+		if apiVersion == "" || kind == "" {
+			continue
+		}
+		schema, ok := schemas.kinds[apiVersion+"/"+kind]
+		if !ok {
+			if versions, known := schemas.apiVersions[kind]; known {
+				report.AddDocumentIssue(document.Index, "apiVersion", "%s is not supported for kind %s, expected one of: %s", apiVersion, kind, strings.Join(versions, ", "))
+			}
+			continue
+		}
+		var metadataSchema = &openApiSchema{Ref: openApiReferencePrefix + "io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"}
+		if metadata != nil {
+			schemas.validate(metadata, metadataSchema, "metadata", document.Index, report)
+		}
+		schemas.validate(object, schema, "", document.Index, report)
+	}
+}
+
+// Compute the sha256 digest of a rendered manifest
+func manifestDigest(manifest string) string {
+	var sum = sha256.Sum256([]byte(manifest))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Collect the resources declared in a multi-document manifest
+func parseManifestResources(manifest string) ([]model.KubernetesResource, error) {
+	var out = make([]model.KubernetesResource, 0)
+	for _, document := range splitManifestDocuments(manifest) {
+		var raw = make(map[string]interface{})
+		if err := yaml.Unmarshal([]byte(document.Text), &raw); err != nil {
+			return out, errors.New(fmt.Sprintf("Invalid yaml in document #%d, Error: %v", document.Index, err))
+		}
+		var object = normalizeYamlMap(raw)
+		var resource = model.KubernetesResource{}
+		resource.ApiVersion, _ = object["apiVersion"].(string)
+		resource.Kind, _ = object["kind"].(string)
+		if metadata, ok := object["metadata"].(map[string]interface{}); ok {
+			resource.Name, _ = metadata["name"].(string)
+			resource.Namespace, _ = metadata["namespace"].(string)
+		}
+		if resource.ApiVersion == "" || resource.Kind == "" || resource.Name == "" {
+			return out, errors.New(fmt.Sprintf("Document #%d requires apiVersion, kind and metadata.name", document.Index))
+		}
+		out = append(out, resource)
+	}
+	return out, nil
+}
+
+// Compose a manifest referencing the given resources, suitable for a kubectl delete
+func resourcesManifest(resources []model.KubernetesResource) string {
+	var documents = make([]string, 0)
+	for _, r := range resources {
+		var metadata = map[string]interface{}{
+			"name": r.Name,
+		}
+		if r.Namespace != "" {
+			metadata["namespace"] = r.Namespace
+		}
+		documents = append(documents, toYaml(map[string]interface{}{
+			"apiVersion": r.ApiVersion,
+			"kind":       r.Kind,
+			"metadata":   metadata,
+		}))
+	}
+	return strings.Join(documents, "\n---\n") + "\n"
+}
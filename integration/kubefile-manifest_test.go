@@ -0,0 +1,121 @@
+package integration
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hellgate75/k8s-deploy/model"
+)
+
+const testDeploymentManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    app: web
+spec:
+  replicas: 2
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: nginx:1.19
+        ports:
+        - containerPort: 80
+          protocol: TCP
+`
+
+func verifyTestManifest(manifest string) *model.VerificationReport {
+	var report = &model.VerificationReport{
+		Name:    "test",
+		Version: "1.0.0",
+		Issues:  make([]model.VerificationIssue, 0),
+	}
+	verifyKubernetesManifest(manifest, report)
+	return report
+}
+
+func TestVerifyKubernetesManifestValidDocuments(t *testing.T) {
+	var manifest = "# leading comment\n---\n" + testKubernetesFileManifest + "---\n" + testDeploymentManifest +
+		"---\napiVersion: v1\nkind: Service\nmetadata:\n  name: web\nspec:\n  selector:\n    app: web\n  ports:\n  - port: 80\n    targetPort: http\n---\n"
+	var report = verifyTestManifest(manifest)
+	if !report.Valid() {
+		t.Fatalf("Unexpected problems: %v", report)
+	}
+	resources, err := parseManifestResources(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resources) != 3 || resources[0].Kind != "ConfigMap" || resources[1].Kind != "Deployment" || resources[2].Kind != "Service" {
+		t.Fatalf("Unexpected resources: %+v", resources)
+	}
+}
+
+func TestVerifyKubernetesManifestUnknownKind(t *testing.T) {
+	// Custom resources are only checked for apiVersion, kind and metadata.name
+	var report = verifyTestManifest("apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: gadget\nspec:\n  size: large\n")
+	if !report.Valid() {
+		t.Fatalf("Unexpected problems for a custom resource: %v", report)
+	}
+	report = verifyTestManifest("apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: Not_Valid\n")
+	if len(report.Issues) != 1 || report.Issues[0].Path != "metadata.name" {
+		t.Fatalf("Expected invalid name problem, found: %+v", report.Issues)
+	}
+	// A built-in kind with an unsupported api version is reported
+	report = verifyTestManifest(strings.Replace(testDeploymentManifest, "apps/v1", "extensions/v1beta1", 1))
+	if len(report.Issues) != 1 || report.Issues[0].Path != "apiVersion" || !strings.Contains(report.Issues[0].Message, "apps/v1") {
+		t.Fatalf("Expected unsupported api version problem, found: %+v", report.Issues)
+	}
+}
+
+func TestVerifyKubernetesManifestMissingRequiredFields(t *testing.T) {
+	var report = verifyTestManifest("kind: ConfigMap\nmetadata:\n  labels:\n    app: web\n")
+	var paths = make([]string, 0)
+	for _, issue := range report.Issues {
+		paths = append(paths, issue.Path)
+	}
+	if strings.Join(paths, ",") != "apiVersion,metadata.name" {
+		t.Fatalf("Unexpected problems: %+v", report.Issues)
+	}
+	var manifest = strings.Replace(testDeploymentManifest, "  selector:\n    matchLabels:\n      app: web\n", "", 1)
+	manifest = strings.Replace(manifest, "      - name: web\n        image", "      - image", 1)
+	report = verifyTestManifest(manifest)
+	paths = make([]string, 0)
+	for _, issue := range report.Issues {
+		paths = append(paths, issue.Path)
+	}
+	if strings.Join(paths, ",") != "spec.selector,spec.template.spec.containers[0].name" {
+		t.Fatalf("Unexpected problems: %+v", report.Issues)
+	}
+}
+
+func TestVerifyKubernetesManifestDocumentIndex(t *testing.T) {
+	// Documents with only comments are not counted, problems refer to the third resource document
+	var manifest = testKubernetesFileManifest + "---\n# comment only\n---\n" + testDeploymentManifest +
+		"---\n" + strings.Replace(testDeploymentManifest, "replicas: 2", "replicas: two", 1)
+	var report = verifyTestManifest(manifest)
+	if len(report.Issues) != 1 {
+		t.Fatalf("Expected a single problem, found: %+v", report.Issues)
+	}
+	var issue = report.Issues[0]
+	if issue.Document != 3 || issue.Path != "spec.replicas" || !strings.Contains(issue.Message, "expected integer") {
+		t.Fatalf("Unexpected problem: %+v", issue)
+	}
+	if len(report.DocumentIssues(2)) != 0 || !strings.Contains(report.Error(), "document #3 spec.replicas") {
+		t.Fatalf("Unexpected report: %v", report)
+	}
+	report = verifyTestManifest(testKubernetesFileManifest + "---\nkind: [unterminated\n")
+	if len(report.Issues) != 1 || report.Issues[0].Document != 2 || report.Issues[0].Path != "line 8" {
+		t.Fatalf("Expected invalid yaml problem in document 2, found: %+v", report.Issues)
+	}
+	report = verifyTestManifest("# nothing here\n---\n")
+	if report.Valid() {
+		t.Fatal("Expected empty manifest problem")
+	}
+}
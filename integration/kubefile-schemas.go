@@ -0,0 +1,655 @@
+package integration
+
+// Bundled subset of the Kubernetes OpenAPI (swagger v2) definitions, used to validate the most
+// common built-in kinds without a cluster connection. Definitions follow the upstream naming and
+// the x-kubernetes-group-version-kind extension is used to bind a definition to the manifest kinds.
+const kubernetesOpenApiDefinitions = `
+definitions:
+  io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta:
+    type: object
+    properties:
+      name:
+        type: string
+      generateName:
+        type: string
+      namespace:
+        type: string
+      labels:
+        type: object
+        additionalProperties:
+          type: string
+      annotations:
+        type: object
+        additionalProperties:
+          type: string
+      finalizers:
+        type: array
+        items:
+          type: string
+      ownerReferences:
+        type: array
+        items:
+          type: object
+          required: [apiVersion, kind, name, uid]
+          properties:
+            apiVersion:
+              type: string
+            kind:
+              type: string
+            name:
+              type: string
+            uid:
+              type: string
+            controller:
+              type: boolean
+            blockOwnerDeletion:
+              type: boolean
+  io.k8s.apimachinery.pkg.apis.meta.v1.LabelSelector:
+    type: object
+    properties:
+      matchLabels:
+        type: object
+        additionalProperties:
+          type: string
+      matchExpressions:
+        type: array
+        items:
+          type: object
+          required: [key, operator]
+          properties:
+            key:
+              type: string
+            operator:
+              type: string
+            values:
+              type: array
+              items:
+                type: string
+  io.k8s.api.core.v1.ResourceRequirements:
+    type: object
+    properties:
+      limits:
+        type: object
+        additionalProperties:
+          format: int-or-string
+      requests:
+        type: object
+        additionalProperties:
+          format: int-or-string
+  io.k8s.api.core.v1.ContainerPort:
+    type: object
+    required: [containerPort]
+    properties:
+      name:
+        type: string
+      containerPort:
+        type: integer
+      hostPort:
+        type: integer
+      hostIP:
+        type: string
+      protocol:
+        type: string
+        enum: [TCP, UDP, SCTP]
+  io.k8s.api.core.v1.EnvVar:
+    type: object
+    required: [name]
+    properties:
+      name:
+        type: string
+      value:
+        type: string
+      valueFrom:
+        type: object
+  io.k8s.api.core.v1.VolumeMount:
+    type: object
+    required: [name, mountPath]
+    properties:
+      name:
+        type: string
+      mountPath:
+        type: string
+      subPath:
+        type: string
+      readOnly:
+        type: boolean
+  io.k8s.api.core.v1.Probe:
+    type: object
+    properties:
+      exec:
+        type: object
+      httpGet:
+        type: object
+        required: [port]
+        properties:
+          path:
+            type: string
+          port:
+            format: int-or-string
+          scheme:
+            type: string
+      tcpSocket:
+        type: object
+        required: [port]
+        properties:
+          port:
+            format: int-or-string
+      initialDelaySeconds:
+        type: integer
+      periodSeconds:
+        type: integer
+      timeoutSeconds:
+        type: integer
+      successThreshold:
+        type: integer
+      failureThreshold:
+        type: integer
+  io.k8s.api.core.v1.Container:
+    type: object
+    required: [name]
+    properties:
+      name:
+        type: string
+      image:
+        type: string
+      imagePullPolicy:
+        type: string
+        enum: [Always, Never, IfNotPresent]
+      command:
+        type: array
+        items:
+          type: string
+      args:
+        type: array
+        items:
+          type: string
+      workingDir:
+        type: string
+      ports:
+        type: array
+        items:
+          $ref: '#/definitions/io.k8s.api.core.v1.ContainerPort'
+      env:
+        type: array
+        items:
+          $ref: '#/definitions/io.k8s.api.core.v1.EnvVar'
+      envFrom:
+        type: array
+        items:
+          type: object
+      resources:
+        $ref: '#/definitions/io.k8s.api.core.v1.ResourceRequirements'
+      volumeMounts:
+        type: array
+        items:
+          $ref: '#/definitions/io.k8s.api.core.v1.VolumeMount'
+      livenessProbe:
+        $ref: '#/definitions/io.k8s.api.core.v1.Probe'
+      readinessProbe:
+        $ref: '#/definitions/io.k8s.api.core.v1.Probe'
+      startupProbe:
+        $ref: '#/definitions/io.k8s.api.core.v1.Probe'
+      securityContext:
+        type: object
+      stdin:
+        type: boolean
+      tty:
+        type: boolean
+  io.k8s.api.core.v1.Volume:
+    type: object
+    required: [name]
+    properties:
+      name:
+        type: string
+      configMap:
+        type: object
+      secret:
+        type: object
+      emptyDir:
+        type: object
+      hostPath:
+        type: object
+        required: [path]
+        properties:
+          path:
+            type: string
+          type:
+            type: string
+      persistentVolumeClaim:
+        type: object
+        required: [claimName]
+        properties:
+          claimName:
+            type: string
+          readOnly:
+            type: boolean
+  io.k8s.api.core.v1.PodSpec:
+    type: object
+    required: [containers]
+    properties:
+      containers:
+        type: array
+        items:
+          $ref: '#/definitions/io.k8s.api.core.v1.Container'
+      initContainers:
+        type: array
+        items:
+          $ref: '#/definitions/io.k8s.api.core.v1.Container'
+      volumes:
+        type: array
+        items:
+          $ref: '#/definitions/io.k8s.api.core.v1.Volume'
+      restartPolicy:
+        type: string
+        enum: [Always, OnFailure, Never]
+      serviceAccountName:
+        type: string
+      nodeSelector:
+        type: object
+        additionalProperties:
+          type: string
+      hostNetwork:
+        type: boolean
+      imagePullSecrets:
+        type: array
+        items:
+          type: object
+          properties:
+            name:
+              type: string
+      terminationGracePeriodSeconds:
+        type: integer
+      tolerations:
+        type: array
+        items:
+          type: object
+      affinity:
+        type: object
+      securityContext:
+        type: object
+  io.k8s.api.core.v1.PodTemplateSpec:
+    type: object
+    properties:
+      metadata:
+        $ref: '#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta'
+      spec:
+        $ref: '#/definitions/io.k8s.api.core.v1.PodSpec'
+  io.k8s.api.core.v1.ServicePort:
+    type: object
+    required: [port]
+    properties:
+      name:
+        type: string
+      protocol:
+        type: string
+        enum: [TCP, UDP, SCTP]
+      port:
+        type: integer
+      targetPort:
+        format: int-or-string
+      nodePort:
+        type: integer
+  io.k8s.api.core.v1.ServiceSpec:
+    type: object
+    properties:
+      type:
+        type: string
+        enum: [ClusterIP, NodePort, LoadBalancer, ExternalName]
+      ports:
+        type: array
+        items:
+          $ref: '#/definitions/io.k8s.api.core.v1.ServicePort'
+      selector:
+        type: object
+        additionalProperties:
+          type: string
+      clusterIP:
+        type: string
+      externalName:
+        type: string
+      externalIPs:
+        type: array
+        items:
+          type: string
+      loadBalancerIP:
+        type: string
+      sessionAffinity:
+        type: string
+        enum: [ClientIP, None]
+  io.k8s.api.core.v1.Service:
+    type: object
+    properties:
+      spec:
+        $ref: '#/definitions/io.k8s.api.core.v1.ServiceSpec'
+    x-kubernetes-group-version-kind:
+    - group: ""
+      version: v1
+      kind: Service
+  io.k8s.api.core.v1.ConfigMap:
+    type: object
+    properties:
+      data:
+        type: object
+        additionalProperties:
+          type: string
+      binaryData:
+        type: object
+        additionalProperties:
+          type: string
+      immutable:
+        type: boolean
+    x-kubernetes-group-version-kind:
+    - group: ""
+      version: v1
+      kind: ConfigMap
+  io.k8s.api.core.v1.Secret:
+    type: object
+    properties:
+      type:
+        type: string
+      data:
+        type: object
+        additionalProperties:
+          type: string
+          format: byte
+      stringData:
+        type: object
+        additionalProperties:
+          type: string
+      immutable:
+        type: boolean
+    x-kubernetes-group-version-kind:
+    - group: ""
+      version: v1
+      kind: Secret
+  io.k8s.api.apps.v1.DeploymentSpec:
+    type: object
+    required: [selector, template]
+    properties:
+      replicas:
+        type: integer
+      selector:
+        $ref: '#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.LabelSelector'
+      template:
+        $ref: '#/definitions/io.k8s.api.core.v1.PodTemplateSpec'
+      strategy:
+        type: object
+        properties:
+          type:
+            type: string
+            enum: [Recreate, RollingUpdate]
+          rollingUpdate:
+            type: object
+            properties:
+              maxSurge:
+                format: int-or-string
+              maxUnavailable:
+                format: int-or-string
+      minReadySeconds:
+        type: integer
+      revisionHistoryLimit:
+        type: integer
+      progressDeadlineSeconds:
+        type: integer
+      paused:
+        type: boolean
+  io.k8s.api.apps.v1.Deployment:
+    type: object
+    properties:
+      spec:
+        $ref: '#/definitions/io.k8s.api.apps.v1.DeploymentSpec'
+    x-kubernetes-group-version-kind:
+    - group: apps
+      version: v1
+      kind: Deployment
+  io.k8s.api.apps.v1.StatefulSetSpec:
+    type: object
+    required: [selector, template, serviceName]
+    properties:
+      replicas:
+        type: integer
+      selector:
+        $ref: '#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.LabelSelector'
+      template:
+        $ref: '#/definitions/io.k8s.api.core.v1.PodTemplateSpec'
+      serviceName:
+        type: string
+      podManagementPolicy:
+        type: string
+        enum: [OrderedReady, Parallel]
+      updateStrategy:
+        type: object
+        properties:
+          type:
+            type: string
+            enum: [OnDelete, RollingUpdate]
+          rollingUpdate:
+            type: object
+            properties:
+              partition:
+                type: integer
+      volumeClaimTemplates:
+        type: array
+        items:
+          type: object
+          properties:
+            metadata:
+              $ref: '#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta'
+            spec:
+              type: object
+      revisionHistoryLimit:
+        type: integer
+  io.k8s.api.apps.v1.StatefulSet:
+    type: object
+    properties:
+      spec:
+        $ref: '#/definitions/io.k8s.api.apps.v1.StatefulSetSpec'
+    x-kubernetes-group-version-kind:
+    - group: apps
+      version: v1
+      kind: StatefulSet
+  io.k8s.api.batch.v1.JobSpec:
+    type: object
+    required: [template]
+    properties:
+      template:
+        $ref: '#/definitions/io.k8s.api.core.v1.PodTemplateSpec'
+      parallelism:
+        type: integer
+      completions:
+        type: integer
+      backoffLimit:
+        type: integer
+      activeDeadlineSeconds:
+        type: integer
+      ttlSecondsAfterFinished:
+        type: integer
+      selector:
+        $ref: '#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.LabelSelector'
+      manualSelector:
+        type: boolean
+  io.k8s.api.batch.v1.Job:
+    type: object
+    properties:
+      spec:
+        $ref: '#/definitions/io.k8s.api.batch.v1.JobSpec'
+    x-kubernetes-group-version-kind:
+    - group: batch
+      version: v1
+      kind: Job
+  io.k8s.api.batch.v1beta1.CronJobSpec:
+    type: object
+    required: [schedule, jobTemplate]
+    properties:
+      schedule:
+        type: string
+      jobTemplate:
+        type: object
+        properties:
+          metadata:
+            $ref: '#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta'
+          spec:
+            $ref: '#/definitions/io.k8s.api.batch.v1.JobSpec'
+      concurrencyPolicy:
+        type: string
+        enum: [Allow, Forbid, Replace]
+      startingDeadlineSeconds:
+        type: integer
+      suspend:
+        type: boolean
+      successfulJobsHistoryLimit:
+        type: integer
+      failedJobsHistoryLimit:
+        type: integer
+  io.k8s.api.batch.v1beta1.CronJob:
+    type: object
+    properties:
+      spec:
+        $ref: '#/definitions/io.k8s.api.batch.v1beta1.CronJobSpec'
+    x-kubernetes-group-version-kind:
+    - group: batch
+      version: v1beta1
+      kind: CronJob
+    - group: batch
+      version: v1
+      kind: CronJob
+  io.k8s.api.networking.v1beta1.IngressBackend:
+    type: object
+    required: [serviceName, servicePort]
+    properties:
+      serviceName:
+        type: string
+      servicePort:
+        format: int-or-string
+  io.k8s.api.networking.v1beta1.IngressSpec:
+    type: object
+    properties:
+      ingressClassName:
+        type: string
+      backend:
+        $ref: '#/definitions/io.k8s.api.networking.v1beta1.IngressBackend'
+      tls:
+        type: array
+        items:
+          type: object
+          properties:
+            hosts:
+              type: array
+              items:
+                type: string
+            secretName:
+              type: string
+      rules:
+        type: array
+        items:
+          type: object
+          properties:
+            host:
+              type: string
+            http:
+              type: object
+              required: [paths]
+              properties:
+                paths:
+                  type: array
+                  items:
+                    type: object
+                    required: [backend]
+                    properties:
+                      path:
+                        type: string
+                      pathType:
+                        type: string
+                        enum: [Exact, Prefix, ImplementationSpecific]
+                      backend:
+                        $ref: '#/definitions/io.k8s.api.networking.v1beta1.IngressBackend'
+  io.k8s.api.networking.v1beta1.Ingress:
+    type: object
+    properties:
+      spec:
+        $ref: '#/definitions/io.k8s.api.networking.v1beta1.IngressSpec'
+    x-kubernetes-group-version-kind:
+    - group: networking.k8s.io
+      version: v1beta1
+      kind: Ingress
+    - group: extensions
+      version: v1beta1
+      kind: Ingress
+  io.k8s.api.networking.v1.IngressBackend:
+    type: object
+    properties:
+      service:
+        type: object
+        required: [name]
+        properties:
+          name:
+            type: string
+          port:
+            type: object
+            properties:
+              name:
+                type: string
+              number:
+                type: integer
+      resource:
+        type: object
+        required: [kind, name]
+        properties:
+          apiGroup:
+            type: string
+          kind:
+            type: string
+          name:
+            type: string
+  io.k8s.api.networking.v1.IngressSpec:
+    type: object
+    properties:
+      ingressClassName:
+        type: string
+      defaultBackend:
+        $ref: '#/definitions/io.k8s.api.networking.v1.IngressBackend'
+      tls:
+        type: array
+        items:
+          type: object
+          properties:
+            hosts:
+              type: array
+              items:
+                type: string
+            secretName:
+              type: string
+      rules:
+        type: array
+        items:
+          type: object
+          properties:
+            host:
+              type: string
+            http:
+              type: object
+              required: [paths]
+              properties:
+                paths:
+                  type: array
+                  items:
+                    type: object
+                    required: [backend, pathType]
+                    properties:
+                      path:
+                        type: string
+                      pathType:
+                        type: string
+                        enum: [Exact, Prefix, ImplementationSpecific]
+                      backend:
+                        $ref: '#/definitions/io.k8s.api.networking.v1.IngressBackend'
+  io.k8s.api.networking.v1.Ingress:
+    type: object
+    properties:
+      spec:
+        $ref: '#/definitions/io.k8s.api.networking.v1.IngressSpec'
+    x-kubernetes-group-version-kind:
+    - group: networking.k8s.io
+      version: v1
+      kind: Ingress
+`
@@ -0,0 +1,86 @@
+package integration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hellgate75/k8s-deploy/model"
+	"regexp"
+	"strings"
+)
+
+// Matches ${VAR} and {{ .Values.x }} placeholders, a $${VAR} placeholder is escaped and kept as literal ${VAR}
+var kubernetesFilePlaceholderRegexp = regexp.MustCompile(`\$?\$\{\s*([A-Za-z_][A-Za-z0-9_.\-]*)\s*\}|\{\{-?\s*\.Values\.([A-Za-z_][A-Za-z0-9_.\-]*)\s*-?\}\}`)
+
+// Compute the Kubernetes File values: variables defaults first, overridden by the values set
+func loadKubernetesFileValues(values model.ValueSet, variables []model.Variable) (map[string]interface{}, error) {
+	var out = make(map[string]interface{})
+	for _, v := range variables {
+		if strings.TrimSpace(v.Name) == "" || v.Default == nil {
+			continue
+		}
+		setValuePath(out, strings.TrimSpace(v.Name), v.Default)
+	}
+	overrides, err := loadValueSet(values)
+	if err != nil {
+		return out, err
+	}
+	return mergeDicts(true, out, overrides), nil
+}
+
+// Lookup a dotted path value (e.g.: image.tag) in a values tree
+func lookupValuePath(values map[string]interface{}, key string) (interface{}, bool) {
+	if v, ok := values[key]; ok {
+		return v, true
+	}
+	var current interface{} = values
+	for _, t := range strings.Split(key, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[t]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// Format a value for the manifest text, structured values are rendered in yaml flow (json) style
+func formatPlaceholderValue(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		if data, err := json.Marshal(v); err == nil {
+			return string(data)
+		}
+	}
+	return toString(v)
+}
+
+// Expand the ${VAR} and {{ .Values.x }} placeholders of a manifest, reporting all unresolved placeholders as error
+func expandKubernetesFileTemplate(manifest string, values map[string]interface{}) (string, error) {
+	var unresolved = make([]string, 0)
+	var lines = strings.Split(manifest, "\n")
+	for i, line := range lines {
+		lines[i] = kubernetesFilePlaceholderRegexp.ReplaceAllStringFunc(line, func(placeholder string) string {
+			if strings.HasPrefix(placeholder, "$$") {
+				return placeholder[1:]
+			}
+			var m = kubernetesFilePlaceholderRegexp.FindStringSubmatch(placeholder)
+			var key = m[1]
+			if key == "" {
+				key = m[2]
+			}
+			v, ok := lookupValuePath(values, key)
+			if !ok || v == nil {
+				unresolved = append(unresolved, fmt.Sprintf("line %d: %s", i+1, placeholder))
+				return placeholder
+			}
+			return formatPlaceholderValue(v)
+		})
+	}
+	if len(unresolved) > 0 {
+		return "", errors.New(fmt.Sprintf("Unresolved placeholders: %s", strings.Join(unresolved, ", ")))
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -1,16 +1,32 @@
 package integration
 
 import (
+	"errors"
 	"fmt"
 	"github.com/hellgate75/k8s-deploy/log"
 	"github.com/hellgate75/k8s-deploy/model"
+	"github.com/hellgate75/k8s-deploy/utils"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
 )
 
+// Charts storage manager, the storage lock is shared by all the managers of the repository, while the chart releases
+// are locked one by one, so long running deploys don't block the storage
 type chartsRepositoryManager struct {
-	repository model.Repository
-	dataFolder string
-	logger     log.Logger
-	charts     []model.ChartInfo
+	*sync.RWMutex
+	targetMutex sync.RWMutex
+	repository  model.Repository
+	dataFolder  string
+	logger      log.Logger
+	charts      []model.ChartInfo
+	target      model.DeployTarget
 }
 
 const (
@@ -19,62 +35,737 @@ const (
 	repositoryChartVersionsDetailsFolderTemplate = "%s%crepositories%c%s%ccharts%c%s%c%s"
 	repositoryChartsIndexTemplate                = "%s%crepositories%c%s%ccharts%cindex.%v"
 	repositoryChartsFolderTemplate               = "%s%crepositories%c%s%ccharts"
+	chartDescriptorFileName                      = "Chart.yaml"
 )
 
 func (c *chartsRepositoryManager) VerifyChart(name string, version string) error {
-	panic("implement me")
+	c.RLock()
+	defer c.RUnlock()
+	var report = &model.VerificationReport{
+		Name:    name,
+		Version: version,
+		Issues:  make([]model.VerificationIssue, 0),
+	}
+	chart, err := loadChartDetails(c.dataFolder, c.logger, c.repository.Name, name)
+	if err != nil {
+		report.AddIssue(name, "%v", err)
+		return report
+	}
+	var registered = false
+	for _, v := range chart.Versions {
+		if v.Name == version {
+			registered = true
+			break
+		}
+	}
+	if !registered {
+		report.AddIssue(name, "version %s not registered in chart index", version)
+	}
+	var versionFolder = getChartVersionFolder(c.dataFolder, c.repository.Name, name, version)
+	if fs, err := os.Stat(versionFolder); err != nil || !fs.IsDir() {
+		report.AddIssue(version, "chart version folder not found")
+		return report
+	}
+	verifyChartFolder(versionFolder, name, version, report)
+	if report.Valid() {
+		return nil
+	}
+	if c.logger != nil {
+		c.logger.Warnf("Chart %s version %s verification failed: %s", name, version, report.Error())
+	}
+	return report
+}
+
+// Verify the chart descriptor, values, dependencies and templates of a chart folder, collecting the problems in the report
+func verifyChartFolder(chartFolder string, name string, version string, report *model.VerificationReport) {
+	descriptor, err := loadChartDescriptor(chartFolder)
+	if err != nil {
+		report.AddIssue(chartDescriptorFileName, "%v", err)
+	}
+	if descriptor != nil {
+		if descriptor.ApiVersion == "" {
+			report.AddIssue(chartDescriptorFileName, "apiVersion is required")
+		} else if descriptor.ApiVersion != "v1" && descriptor.ApiVersion != "v2" {
+			report.AddIssue(chartDescriptorFileName, "apiVersion %s is not supported, expected v1 or v2", descriptor.ApiVersion)
+		}
+		if descriptor.Name == "" {
+			report.AddIssue(chartDescriptorFileName, "name is required")
+		} else if descriptor.Name != name {
+			report.AddIssue(chartDescriptorFileName, "name %s doesn't match chart name %s", descriptor.Name, name)
+		}
+		if descriptor.Version == "" {
+			report.AddIssue(chartDescriptorFileName, "version is required")
+		} else if descriptor.Version != version {
+			report.AddIssue(chartDescriptorFileName, "version %s doesn't match requested version %s", descriptor.Version, version)
+		}
+		for _, dep := range descriptor.Dependencies {
+			if dep.Name == "" {
+				report.AddIssue(chartDescriptorFileName, "dependency without name")
+			} else if !chartDependencyPresent(chartFolder, dep) {
+				report.AddIssue(chartDependenciesFolder, "dependency %s not found", dep.Name)
+			}
+		}
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(chartFolder, chartValuesFileName)); err != nil {
+		report.AddIssue(chartValuesFileName, "values file not found")
+	} else {
+		var values = make(map[string]interface{})
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			report.AddIssue(chartValuesFileName, "unable to parse values: %v", err)
+		}
+	}
+	if fs, err := os.Stat(filepath.Join(chartFolder, chartTemplatesFolderName)); err != nil || !fs.IsDir() {
+		report.AddIssue(chartTemplatesFolderName, "templates folder not found")
+		return
+	}
+	templates, err := listChartTemplates(chartFolder)
+	if err != nil {
+		report.AddIssue(chartTemplatesFolderName, "unable to list templates: %v", err)
+		return
+	}
+	var root = template.New(name)
+	root.Funcs(chartTemplateFuncMap(root))
+	for _, tplFile := range templates {
+		data, err := ioutil.ReadFile(filepath.Join(chartFolder, filepath.FromSlash(tplFile)))
+		if err != nil {
+			report.AddIssue(tplFile, "unable to read template: %v", err)
+			continue
+		}
+		if _, err := root.New(tplFile).Parse(string(data)); err != nil {
+			report.AddIssue(tplFile, "%v", err)
+		}
+	}
 }
 
 func (c *chartsRepositoryManager) InstallChart(name string, version string, archive string, zipArchive bool) error {
-	panic("implement me")
+	c.Lock()
+	defer c.Unlock()
+	name = strings.TrimSpace(name)
+	version = strings.TrimSpace(version)
+	if name == "" || version == "" {
+		return errors.New("Chart name and version cannot be empty or without significant digits or letters")
+	}
+	if err := validateDocumentNameVersion("chart", name, version); err != nil {
+		return err
+	}
+	if chart, err := loadChartDetails(c.dataFolder, c.logger, c.repository.Name, name); err == nil {
+		for _, v := range chart.Versions {
+			if v.Name == version {
+				return errors.New(fmt.Sprintf("Chart %s version %s already present in repository %s", name, version, c.repository.Name))
+			}
+		}
+	}
+	return c.storeChartVersion(name, version, archive, zipArchive)
 }
 
-func (c *chartsRepositoryManager) DeleteChartVersion(name string, version string) error {
-	panic("implement me")
+// Uncompress the chart archive, verify the chart descriptor and store the chart files in the version folder
+func (c *chartsRepositoryManager) storeChartVersion(name string, version string, archive string, zipArchive bool) error {
+	if fs, err := os.Stat(archive); err == nil {
+		if fs.IsDir() {
+			return errors.New(fmt.Sprintf("Archive %s is folder, and not regular file!!", archive))
+		}
+	} else {
+		return errors.New(fmt.Sprintf("Archive file %s doesn't exist!!", archive))
+	}
+	var tmpFolder = utils.GetTempFolder(utils.GetRandPath())
+	err := utils.CleanCreateFolder(tmpFolder)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = utils.DeleteFileOrFolder(tmpFolder)
+	}()
+	if zipArchive {
+		if c.logger != nil {
+			c.logger.Debugf("Decompressing with zip format chart archive %s to folder %s", archive, tmpFolder)
+		}
+		err = utils.ZipUnCompress(archive, tmpFolder)
+	} else {
+		if c.logger != nil {
+			c.logger.Debugf("Decompressing with tar/g-zip format chart archive %s to folder %s", archive, tmpFolder)
+		}
+		err = utils.TarUnCompress(archive, tmpFolder, true)
+	}
+	if err != nil {
+		return err
+	}
+	chartFolder, err := findChartRootFolder(tmpFolder)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Archive %s doesn't contain a valid chart, Error: %v", archive, err))
+	}
+	var versionFolder = getChartVersionFolder(c.dataFolder, c.repository.Name, name, version)
+	if err := checkFolderWithin(getChartsListFolder(c.dataFolder, c.repository.Name), versionFolder); err != nil {
+		return err
+	}
+	if c.logger != nil {
+		c.logger.Infof("Storing chart %s version %s in folder %s", name, version, versionFolder)
+	}
+	err = replaceFolder(chartFolder, versionFolder)
+	if err != nil {
+		return err
+	}
+	digest, err := c.storeChartVersionPackage(name, version)
+	if err != nil {
+		return err
+	}
+	return c.registerChartVersion(name, version, digest)
 }
 
-func getChartIndexFile(baseFolder string, chartName string) string {
-	return fmt.Sprintf(repositoryChartsFolderTemplate, baseFolder, chartName)
+// Add or refresh the version, with the package digest, in the chart index and the chart in the repository chart list
+func (c *chartsRepositoryManager) registerChartVersion(name string, version string, digest string) error {
+	chart, err := loadChartDetails(c.dataFolder, c.logger, c.repository.Name, name)
+	if err != nil {
+		chart = &model.Chart{
+			Id:       utils.NewUniqueIdentifier(),
+			Name:     name,
+			Versions: make([]model.Version, 0),
+		}
+	}
+	var found = false
+	for idx, v := range chart.Versions {
+		if v.Name == version {
+			chart.Versions[idx].State = model.StateReady
+			chart.Versions[idx].Digest = digest
+			found = true
+		}
+	}
+	if !found {
+		chart.Versions = append(chart.Versions, model.Version{
+			Id:     utils.NewUniqueIdentifier(),
+			Name:   version,
+			State:  model.StateReady,
+			Digest: digest,
+		})
+	}
+	chart.State = model.StateReady
+	err = saveChartDetails(c.dataFolder, c.logger, c.repository.Name, *chart)
+	if err != nil {
+		return err
+	}
+	c.reloadCharts()
+	for _, ch := range c.charts {
+		if ch.Name == name {
+			return nil
+		}
+	}
+	c.charts = append(c.charts, model.ChartInfo{
+		Id:   chart.Id,
+		Name: chart.Name,
+	})
+	c.repository.ReplaceCharts(c.charts...)
+	return saveCharts(c.dataFolder, c.logger, c.repository.Name, c.charts)
+}
+
+// Find the folder containing the chart descriptor, in the given folder or in one of its first level sub-folders
+func findChartRootFolder(folder string) (string, error) {
+	if utils.ExistsFileOrFolder(filepath.Join(folder, chartDescriptorFileName)) {
+		return folder, nil
+	}
+	files, err := ioutil.ReadDir(folder)
+	if err != nil {
+		return "", err
+	}
+	for _, f := range files {
+		if f.IsDir() && utils.ExistsFileOrFolder(filepath.Join(folder, f.Name(), chartDescriptorFileName)) {
+			return filepath.Join(folder, f.Name()), nil
+		}
+	}
+	return "", errors.New(fmt.Sprintf("No %s file found", chartDescriptorFileName))
+}
+
+func (c *chartsRepositoryManager) DeleteChartVersion(name string, version string) error {
+	if err := validateDocumentNameVersion("chart", name, version); err != nil {
+		return err
+	}
+	c.Lock()
+	defer c.Unlock()
+	chart, err := loadChartDetails(c.dataFolder, c.logger, c.repository.Name, name)
+	if err != nil {
+		return err
+	}
+	var versions = make([]model.Version, 0)
+	var found = false
+	for _, v := range chart.Versions {
+		if v.Name == version {
+			found = true
+		} else {
+			versions = append(versions, v)
+		}
+	}
+	if !found {
+		return errors.New(fmt.Sprintf("Chart %s version %s not found in repository %s", name, version, c.repository.Name))
+	}
+	var versionFolder = getChartVersionFolder(c.dataFolder, c.repository.Name, name, version)
+	if c.logger != nil {
+		c.logger.Warnf("Deleting chart %s version %s folder %s", name, version, versionFolder)
+	}
+	err = utils.DeleteFileOrFolder(versionFolder)
+	if err != nil {
+		return err
+	}
+	err = utils.DeleteFileOrFolder(getChartVersionPackageFile(c.dataFolder, c.repository.Name, name, version))
+	if err != nil {
+		return err
+	}
+	chart.Versions = versions
+	return saveChartDetails(c.dataFolder, c.logger, c.repository.Name, *chart)
 }
-func (c *chartsRepositoryManager) DeleteEntireChart(name string, version string) error {
 
-	panic("implement me")
+func (c *chartsRepositoryManager) DeleteEntireChart(name string, version string) error {
+	c.Lock()
+	defer c.Unlock()
+	c.reloadCharts()
+	var charts = make([]model.ChartInfo, 0)
+	var found = false
+	for _, ch := range c.charts {
+		if ch.Name == name {
+			found = true
+		} else {
+			charts = append(charts, ch)
+		}
+	}
+	if !found {
+		return errors.New(fmt.Sprintf("Chart %s not found in repository %s", name, c.repository.Name))
+	}
+	var folder = getChartDetailsFolder(c.dataFolder, c.repository.Name, name)
+	if c.logger != nil {
+		c.logger.Warnf("Deleting chart %s folder %s", name, folder)
+	}
+	err := utils.DeleteFileOrFolder(folder)
+	if err != nil {
+		return err
+	}
+	c.charts = charts
+	c.repository.ReplaceCharts(c.charts...)
+	return saveCharts(c.dataFolder, c.logger, c.repository.Name, c.charts)
 }
 
 func (c *chartsRepositoryManager) GetChartVersionTemplate(name string, version string, values model.ValueSet) (string, error) {
-	panic("implement me")
+	if err := validateDocumentNameVersion("chart", name, version); err != nil {
+		return "", err
+	}
+	c.RLock()
+	defer c.RUnlock()
+	var versionFolder = getChartVersionFolder(c.dataFolder, c.repository.Name, name, version)
+	if fs, err := os.Stat(versionFolder); err != nil || !fs.IsDir() {
+		return "", errors.New(fmt.Sprintf("Chart %s version %s not found in repository %s", name, version, c.repository.Name))
+	}
+	overrides, err := loadValueSet(values)
+	if err != nil {
+		return "", err
+	}
+	if c.logger != nil {
+		c.logger.Debugf("Rendering chart %s version %s templates with %v override value(s)", name, version, len(values.Value))
+	}
+	return renderChartTemplates(versionFolder, name, defaultReleaseNamespace, overrides)
 }
 
 func (c *chartsRepositoryManager) UpdateExistingChart(name string, version string, archive string, zipArchive bool, forceCreate bool) error {
-	panic("implement me")
+	c.Lock()
+	defer c.Unlock()
+	name = strings.TrimSpace(name)
+	version = strings.TrimSpace(version)
+	if name == "" || version == "" {
+		return errors.New("Chart name and version cannot be empty or without significant digits or letters")
+	}
+	if err := validateDocumentNameVersion("chart", name, version); err != nil {
+		return err
+	}
+	if !forceCreate {
+		chart, err := loadChartDetails(c.dataFolder, c.logger, c.repository.Name, name)
+		if err != nil {
+			return err
+		}
+		var found = false
+		for _, v := range chart.Versions {
+			if v.Name == version {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New(fmt.Sprintf("Chart %s version %s not found in repository %s", name, version, c.repository.Name))
+		}
+	}
+	return c.storeChartVersion(name, version, archive, zipArchive)
 }
 
 func (c *chartsRepositoryManager) GetChartVersions(name string) ([]model.Version, error) {
-	panic("implement me")
+	c.RLock()
+	defer c.RUnlock()
+	_, versions, err := c.listChartVersions(name)
+	return versions, err
+}
+
+// Collect the available versions of a chart, sorted by semantic version, verifying the version folders
+func (c *chartsRepositoryManager) listChartVersions(name string) (*model.Chart, []model.Version, error) {
+	chart, err := loadChartDetails(c.dataFolder, c.logger, c.repository.Name, name)
+	if err != nil {
+		return nil, make([]model.Version, 0), err
+	}
+	var versions = make([]model.Version, 0)
+	for _, v := range chart.Versions {
+		if v.State == model.StateDeleted || v.State == model.StatePutged {
+			continue
+		}
+		var versionFolder = getChartVersionFolder(c.dataFolder, c.repository.Name, name, v.Name)
+		if fs, err := os.Stat(versionFolder); err != nil || !fs.IsDir() {
+			if c.logger != nil {
+				c.logger.Warnf("Chart %s version %s folder %s not found", name, v.Name, versionFolder)
+			}
+			v.State = model.StateError
+		}
+		versions = append(versions, v)
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		return utils.CompareSemVerStrings(versions[i].Name, versions[j].Name) < 0
+	})
+	return chart, versions, nil
 }
 
 func (c *chartsRepositoryManager) GetChartProjectVersions(name string) ([]model.ProjectChart, error) {
-	panic("implement me")
+	c.RLock()
+	defer c.RUnlock()
+	var out = make([]model.ProjectChart, 0)
+	chart, versions, err := c.listChartVersions(name)
+	if err != nil {
+		return out, err
+	}
+	for _, v := range versions {
+		out = append(out, model.ProjectChart{
+			Id:      v.Id,
+			Name:    chart.Name,
+			Version: v.Name,
+			State:   v.State,
+		})
+	}
+	return out, nil
+}
+
+func (c *chartsRepositoryManager) GetLatestChartVersion(name string) (model.Version, error) {
+	c.RLock()
+	defer c.RUnlock()
+	_, versions, err := c.listChartVersions(name)
+	if err != nil {
+		return model.Version{}, err
+	}
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].State == model.StateError {
+			continue
+		}
+		if sv, err := utils.ParseSemVer(versions[i].Name); err == nil && !sv.IsPreRelease() {
+			return versions[i], nil
+		}
+	}
+	return model.Version{}, errors.New(fmt.Sprintf("No stable version found for chart %s in repository %s", name, c.repository.Name))
+}
+
+func (c *chartsRepositoryManager) GetLatestChartVersionMatching(name string, constraint string) (model.Version, error) {
+	c.RLock()
+	defer c.RUnlock()
+	cs, err := utils.ParseSemVerConstraint(constraint)
+	if err != nil {
+		return model.Version{}, err
+	}
+	_, versions, err := c.listChartVersions(name)
+	if err != nil {
+		return model.Version{}, err
+	}
+	for i := len(versions) - 1; i >= 0; i-- {
+		if versions[i].State == model.StateError {
+			continue
+		}
+		if sv, err := utils.ParseSemVer(versions[i].Name); err == nil && cs.Check(sv) {
+			return versions[i], nil
+		}
+	}
+	return model.Version{}, errors.New(fmt.Sprintf("No version matching %s found for chart %s in repository %s", constraint, name, c.repository.Name))
+}
+
+func (c *chartsRepositoryManager) SetDeployTarget(target model.DeployTarget) {
+	c.targetMutex.Lock()
+	defer c.targetMutex.Unlock()
+	c.target = normalizeDeployTarget(target)
+}
+
+func (c *chartsRepositoryManager) GetDeployTarget() model.DeployTarget {
+	c.targetMutex.RLock()
+	defer c.targetMutex.RUnlock()
+	return c.target
+}
+
+// Locks the deploy target and the chart release on it, returning the unlock function
+func (c *chartsRepositoryManager) lockRelease(name string) func() {
+	c.targetMutex.RLock()
+	var lock = getStorageLock(getChartReleaseFile(c.dataFolder, c.repository.Name, c.target, name, repositoryFormatExtension))
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		c.targetMutex.RUnlock()
+	}
 }
 
 func (c *chartsRepositoryManager) DeployInstallChart(name string, version string, values model.ValueSet) (string, error) {
-	panic("implement me")
+	defer c.lockRelease(name)()
+	return c.deployChart(model.ChartReleaseInstall, name, version, values, false)
 }
 
 func (c *chartsRepositoryManager) DeployUpgradeChart(name string, version string, values model.ValueSet, force bool) (string, error) {
-	panic("implement me")
+	defer c.lockRelease(name)()
+	return c.deployChart(model.ChartReleaseUpgrade, name, version, values, force)
+}
+
+// Install or upgrade a chart version release on the deploy target, recording the outcome in the release state
+func (c *chartsRepositoryManager) deployChart(action string, name string, version string, values model.ValueSet, force bool) (string, error) {
+	release, err := loadChartRelease(c.dataFolder, c.logger, c.repository.Name, c.target, name)
+	if err != nil {
+		return "", err
+	}
+	if action == model.ChartReleaseInstall && release.IsInstalled() {
+		return "", errors.New(fmt.Sprintf("Chart %s version %s already installed on target %s, upgrade required", name, release.Current.Name, c.target.Key()))
+	}
+	if action == model.ChartReleaseUpgrade && !release.IsInstalled() {
+		return "", errors.New(fmt.Sprintf("Chart %s not installed on target %s, install required", name, c.target.Key()))
+	}
+	chartVersion, versionFolder, err := c.getDeployableChartVersion(name, version)
+	if err != nil {
+		return "", err
+	}
+	overrides, err := loadValueSet(values)
+	if err != nil {
+		return "", err
+	}
+	var valuesFile = ""
+	if len(overrides) > 0 {
+		valuesFile = utils.GetTempFolder(utils.GetRandPath() + ".yaml")
+		data, err := yaml.Marshal(overrides)
+		if err != nil {
+			return "", err
+		}
+		err = ioutil.WriteFile(valuesFile, data, 0666)
+		if err != nil {
+			return "", err
+		}
+		defer func() {
+			_ = utils.DeleteFileOrFolder(valuesFile)
+		}()
+	}
+	if c.logger != nil {
+		c.logger.Infof("Executing helm %s of chart %s version %s on target %s", action, name, version, c.target.Key())
+	}
+	var response = ExecuteChartRequest(ChartDeployRequest{
+		Action:       ChartAction(action),
+		ReleaseName:  release.Name,
+		ChartName:    name,
+		ChartVersion: version,
+		ChartPath:    versionFolder,
+		ValuesFile:   valuesFile,
+		Force:        force,
+		Namespace:    c.target.Namespace,
+		KubeConfig:   c.target.KubeConfig,
+		KubeContext:  c.target.Context,
+	})
+	var revision = model.ChartReleaseRevision{
+		Action:  action,
+		Version: chartVersion,
+		Force:   force,
+		Date:    time.Now(),
+	}
+	if response.Error != nil {
+		revision.Version.State = model.StateFailed
+		revision.Message = response.Error.Error()
+	} else {
+		revision.Revision = release.LastRevision() + 1
+		revision.Version.State = model.StateReady
+		release.Current = revision.Version
+	}
+	release.Revisions = append(release.Revisions, revision)
+	err = saveChartRelease(c.dataFolder, c.logger, c.repository.Name, *release)
+	if response.Error != nil {
+		return response.Output, errors.New(fmt.Sprintf("Helm %s of chart %s version %s failed with code %v: %v", action, name, version, response.Code, response.Error))
+	}
+	return response.Output, err
+}
+
+// Collect a chart version ready to be deployed, with its version folder
+func (c *chartsRepositoryManager) getDeployableChartVersion(name string, version string) (model.Version, string, error) {
+	c.RLock()
+	defer c.RUnlock()
+	_, versions, err := c.listChartVersions(name)
+	if err != nil {
+		return model.Version{}, "", err
+	}
+	for _, v := range versions {
+		if v.Name == version {
+			if v.State == model.StateError {
+				return v, "", errors.New(fmt.Sprintf("Chart %s version %s is in error state and cannot be deployed", name, version))
+			}
+			return v, getChartVersionFolder(c.dataFolder, c.repository.Name, name, version), nil
+		}
+	}
+	return model.Version{}, "", errors.New(fmt.Sprintf("Chart %s version %s not found in repository %s", name, version, c.repository.Name))
 }
 
 func (c *chartsRepositoryManager) GetInstalledChartVersion(name string) (model.Version, error) {
-	panic("implement me")
+	defer c.lockRelease(name)()
+	release, err := loadChartRelease(c.dataFolder, c.logger, c.repository.Name, c.target, name)
+	if err != nil {
+		return model.Version{}, err
+	}
+	if !release.IsInstalled() {
+		return model.Version{}, errors.New(fmt.Sprintf("Chart %s not installed on target %s", name, c.target.Key()))
+	}
+	return release.Current, nil
 }
 
 func (c *chartsRepositoryManager) GetInstalledChartVersionDetails(name string, version string) (model.Version, error) {
-	panic("implement me")
+	defer c.lockRelease(name)()
+	release, err := loadChartRelease(c.dataFolder, c.logger, c.repository.Name, c.target, name)
+	if err != nil {
+		return model.Version{}, err
+	}
+	if release.Current.Name == version {
+		return release.Current, nil
+	}
+	for i := len(release.Revisions) - 1; i >= 0; i-- {
+		if release.Revisions[i].Version.Name == version {
+			return release.Revisions[i].Version, nil
+		}
+	}
+	return model.Version{}, errors.New(fmt.Sprintf("Chart %s version %s never deployed on target %s", name, version, c.target.Key()))
+}
+
+// Get the release state of a chart on the deploy target
+func (c *chartsRepositoryManager) GetChartRelease(name string) (*model.ChartRelease, error) {
+	defer c.lockRelease(name)()
+	return loadChartRelease(c.dataFolder, c.logger, c.repository.Name, c.target, name)
+}
+
+// Find in the helm release history the deployed revision and the successful revision preceding it
+func previousHelmRevision(history []HelmReleaseRevision) (HelmReleaseRevision, HelmReleaseRevision, bool) {
+	var current, previous HelmReleaseRevision
+	for _, rev := range history {
+		if rev.Status == "deployed" && rev.Revision > current.Revision {
+			current = rev
+		}
+	}
+	for _, rev := range history {
+		if rev.Status == "superseded" && rev.Revision < current.Revision && rev.Revision > previous.Revision {
+			previous = rev
+		}
+	}
+	return current, previous, current.Revision > 0 && previous.Revision > 0
+}
+
+// Roll back a chart release to the revision preceding the deployed one, as reported by the helm release history,
+// recording the outcome in the release state
+func (c *chartsRepositoryManager) DeployRollbackChart(name string, force bool) (string, error) {
+	defer c.lockRelease(name)()
+	release, err := loadChartRelease(c.dataFolder, c.logger, c.repository.Name, c.target, name)
+	if err != nil {
+		return "", err
+	}
+	if !release.IsInstalled() {
+		return "", errors.New(fmt.Sprintf("Chart %s not installed on target %s", name, c.target.Key()))
+	}
+	var request = ChartDeployRequest{
+		Action:      ChartRollbackAction,
+		ReleaseName: release.Name,
+		ChartName:   name,
+		Force:       force,
+		Namespace:   c.target.Namespace,
+		KubeConfig:  c.target.KubeConfig,
+		KubeContext: c.target.Context,
+	}
+	history, err := ExecuteChartHistoryRequest(request)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Unable to read chart %s release history on target %s: %v", name, c.target.Key(), err))
+	}
+	_, previous, ok := previousHelmRevision(history)
+	if !ok {
+		return "", errors.New(fmt.Sprintf("Chart %s has no previous revision on target %s", name, c.target.Key()))
+	}
+	var version = model.Version{Name: strings.TrimPrefix(previous.Chart, name+"-")}
+	for i := len(release.Revisions) - 1; i >= 0; i-- {
+		if release.Revisions[i].Version.Name != "" && strings.HasSuffix(previous.Chart, "-"+release.Revisions[i].Version.Name) {
+			version = release.Revisions[i].Version
+			break
+		}
+	}
+	if c.logger != nil {
+		c.logger.Infof("Executing helm rollback of chart %s to revision %v, version %s, on target %s", name, previous.Revision, version.Name, c.target.Key())
+	}
+	request.ChartVersion = version.Name
+	request.Revision = previous.Revision
+	var response = ExecuteChartRequest(request)
+	var revision = model.ChartReleaseRevision{
+		Action:  model.ChartReleaseRollback,
+		Version: version,
+		Force:   force,
+		Date:    time.Now(),
+	}
+	if response.Error != nil {
+		revision.Version.State = model.StateFailed
+		revision.Message = response.Error.Error()
+	} else {
+		// Helm records the rollback as a new revision, following the latest one in the history
+		revision.Revision = history[len(history)-1].Revision + 1
+		revision.Version.State = model.StateReady
+		revision.Message = fmt.Sprintf("Rolled back to revision %v", previous.Revision)
+		release.Current = revision.Version
+	}
+	release.Revisions = append(release.Revisions, revision)
+	err = saveChartRelease(c.dataFolder, c.logger, c.repository.Name, *release)
+	if response.Error != nil {
+		return response.Output, errors.New(fmt.Sprintf("Helm rollback of chart %s to revision %v failed with code %v: %v", name, previous.Revision, response.Code, response.Error))
+	}
+	return response.Output, err
 }
 
 func (c *chartsRepositoryManager) UnDeployInstalledChart(name string) (model.Version, error) {
-	panic("implement me")
+	defer c.lockRelease(name)()
+	release, err := loadChartRelease(c.dataFolder, c.logger, c.repository.Name, c.target, name)
+	if err != nil {
+		return model.Version{}, err
+	}
+	if !release.IsInstalled() {
+		return model.Version{}, errors.New(fmt.Sprintf("Chart %s not installed on target %s", name, c.target.Key()))
+	}
+	if c.logger != nil {
+		c.logger.Infof("Executing helm uninstall of chart %s version %s on target %s", name, release.Current.Name, c.target.Key())
+	}
+	var response = ExecuteChartRequest(ChartDeployRequest{
+		Action:       ChartUninstallAction,
+		ReleaseName:  release.Name,
+		ChartName:    name,
+		ChartVersion: release.Current.Name,
+		Namespace:    c.target.Namespace,
+		KubeConfig:   c.target.KubeConfig,
+		KubeContext:  c.target.Context,
+	})
+	var revision = model.ChartReleaseRevision{
+		Action:  model.ChartReleaseUninstall,
+		Version: release.Current,
+		Date:    time.Now(),
+	}
+	if response.Error != nil {
+		revision.Version.State = model.StateFailed
+		revision.Message = response.Error.Error()
+		release.Revisions = append(release.Revisions, revision)
+		_ = saveChartRelease(c.dataFolder, c.logger, c.repository.Name, *release)
+		return release.Current, errors.New(fmt.Sprintf("Helm uninstall of chart %s failed with code %v: %v", name, response.Code, response.Error))
+	}
+	revision.Version.State = model.StateDeleted
+	release.Current = revision.Version
+	release.Revisions = append(release.Revisions, revision)
+	return release.Current, saveChartRelease(c.dataFolder, c.logger, c.repository.Name, *release)
+}
+
+// Refresh the repository chart list, changed by the other managers of the repository
+func (c *chartsRepositoryManager) reloadCharts() {
+	if charts, err := loadCharts(c.dataFolder, c.logger, c.repository.Name); err == nil {
+		c.charts = charts
+	}
 }
 
 func (c *chartsRepositoryManager) init() (model.RepositoryChartManager, error) {
@@ -88,9 +779,11 @@ func (c *chartsRepositoryManager) init() (model.RepositoryChartManager, error) {
 
 func NewRepositoryChartManager(repository model.Repository, dataFolder string, logger log.Logger) (model.RepositoryChartManager, error) {
 	return (&chartsRepositoryManager{
+		RWMutex:    getStorageLock(getChartsListFolder(dataFolder, repository.Name)),
 		repository: repository,
 		dataFolder: dataFolder,
 		logger:     logger,
 		charts:     make([]model.ChartInfo, 0),
+		target:     normalizeDeployTarget(model.DeployTarget{}),
 	}).init()
 }
@@ -0,0 +1,303 @@
+package integration
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hellgate75/k8s-deploy/model"
+	"github.com/hellgate75/k8s-deploy/utils"
+)
+
+// Chart created by helm create 3.x, used to verify and render a complete chart
+var testHelmCreateChart = filepath.Join("testdata", "mychart")
+
+// Writes a minimal chart archive in the given folder, returning the archive path
+func writeTestChartArchive(t *testing.T, folder string, name string, version string) string {
+	var chartFolder = filepath.Join(folder, fmt.Sprintf("%s-%s-src", name, version), name)
+	if err := os.MkdirAll(filepath.Join(chartFolder, "templates"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	var files = map[string]string{
+		"Chart.yaml":               fmt.Sprintf("apiVersion: v2\nname: %s\nversion: %s\n", name, version),
+		"values.yaml":              "replicaCount: 1\n",
+		"templates/configmap.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: {{ .Release.Name }}\n",
+	}
+	for f, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(chartFolder, filepath.FromSlash(f)), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var archive = filepath.Join(folder, fmt.Sprintf("%s-%s.tgz", name, version))
+	if err := utils.TarCompress(chartFolder, archive, true); err != nil {
+		t.Fatal(err)
+	}
+	return archive
+}
+
+func TestInstallChartConcurrentManagers(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "charts-test")
+	defer os.RemoveAll(dir)
+	var repo = model.Repository{Name: "test"}
+	var count = 8
+	var archives = make([]string, count)
+	for i := 0; i < count; i++ {
+		archives[i] = writeTestChartArchive(t, dir, fmt.Sprintf("chart%v", i), "1.0.0")
+	}
+	var wg sync.WaitGroup
+	var errs = make(chan error, count)
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cm, err := NewRepositoryChartManager(repo, dir, nil)
+			if err != nil && cm == nil {
+				errs <- err
+				return
+			}
+			errs <- cm.InstallChart(fmt.Sprintf("chart%v", i), "1.0.0", archives[i], false)
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	charts, err := loadCharts(dir, nil, repo.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(charts) != count {
+		t.Fatalf("Expected %v charts in the repository index, found %v", count, len(charts))
+	}
+}
+
+func TestReplaceFolderKeepsDestinationOnFailure(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "charts-test")
+	defer os.RemoveAll(dir)
+	var dst = filepath.Join(dir, "1.0.0")
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dst, "Chart.yaml"), []byte("name: stored\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := replaceFolder(filepath.Join(dir, "missing"), dst); err == nil {
+		t.Fatal("Expected error copying a missing folder")
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dst, "Chart.yaml"))
+	if err != nil || string(data) != "name: stored\n" {
+		t.Fatalf("Stored folder changed by a failed replace: %q, error: %v", string(data), err)
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("Staging folders left in place: %v", len(files))
+	}
+	var src = filepath.Join(dir, "src")
+	_ = os.MkdirAll(src, 0755)
+	_ = ioutil.WriteFile(filepath.Join(src, "Chart.yaml"), []byte("name: new\n"), 0644)
+	if err := replaceFolder(src, dst); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, _ = ioutil.ReadFile(filepath.Join(dst, "Chart.yaml"))
+	if string(data) != "name: new\n" {
+		t.Fatalf("Folder not replaced: %q", string(data))
+	}
+}
+
+func TestVerifyChartFolderHelmCreateChart(t *testing.T) {
+	var report = &model.VerificationReport{Name: "mychart", Version: "0.1.0"}
+	verifyChartFolder(testHelmCreateChart, "mychart", "0.1.0", report)
+	if !report.Valid() {
+		t.Fatalf("Unexpected verification issues: %s", report.Error())
+	}
+}
+
+func TestVerifyChartFolderReportsTemplateErrors(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "charts-test")
+	defer os.RemoveAll(dir)
+	if err := copyFolderContent(testHelmCreateChart, dir); err != nil {
+		t.Fatal(err)
+	}
+	var broken = filepath.Join(dir, "templates", "broken.yaml")
+	if err := ioutil.WriteFile(broken, []byte("name: {{ unknownFunction .Release.Name }}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var report = &model.VerificationReport{Name: "mychart", Version: "0.1.0"}
+	verifyChartFolder(dir, "mychart", "0.1.0", report)
+	if len(report.Issues) != 1 || report.Issues[0].Path != "templates/broken.yaml" {
+		t.Fatalf("Expected one issue on templates/broken.yaml, found: %+v", report.Issues)
+	}
+}
+
+func TestVerifyChartHelmCreateArchive(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "charts-test")
+	defer os.RemoveAll(dir)
+	var archive = filepath.Join(dir, "mychart-0.1.0.tgz")
+	if err := utils.TarCompress(testHelmCreateChart, archive, true); err != nil {
+		t.Fatal(err)
+	}
+	cm, err := NewRepositoryChartManager(model.Repository{Name: "test"}, dir, nil)
+	if err != nil && cm == nil {
+		t.Fatal(err)
+	}
+	if err := cm.InstallChart("mychart", "0.1.0", archive, false); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := cm.VerifyChart("mychart", "0.1.0"); err != nil {
+		t.Fatalf("Unexpected verification error: %v", err)
+	}
+}
+
+func TestGetLatestChartVersionMatching(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "charts-test")
+	defer os.RemoveAll(dir)
+	var data = filepath.Join(dir, "data")
+	cm, err := NewRepositoryChartManager(model.Repository{Name: "test"}, data, nil)
+	if err != nil && cm == nil {
+		t.Fatal(err)
+	}
+	for _, version := range []string{"1.10.0", "1.2.0", "1.2.5", "2.0.0-rc.1", "0.9.0", "1.11.0-beta.1"} {
+		if err := cm.InstallChart("web", version, writeTestChartArchive(t, dir, "web", version), false); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	for _, c := range []struct {
+		constraint string
+		expected   string
+	}{
+		{"^1.2.0", "1.10.0"},
+		{"~1.2", "1.2.5"},
+		{"1.2.0 - 1.5", "1.2.5"},
+		{"<1.0 || >=3.0", "0.9.0"},
+		{">=2.0.0-rc.0", "2.0.0-rc.1"},
+		{"*", "1.10.0"},
+	} {
+		v, err := cm.GetLatestChartVersionMatching("web", c.constraint)
+		if err != nil {
+			t.Fatalf("Unexpected error for %q: %v", c.constraint, err)
+		}
+		if v.Name != c.expected {
+			t.Fatalf("Expected version %s for %q, found %s", c.expected, c.constraint, v.Name)
+		}
+	}
+	if _, err := cm.GetLatestChartVersionMatching("web", "^3.0"); err == nil {
+		t.Fatal("Expected no matching version error")
+	}
+	if _, err := cm.GetLatestChartVersionMatching("web", "not-a-constraint"); err == nil {
+		t.Fatal("Expected invalid constraint error")
+	}
+}
+
+func TestInstallChartRejectsUnsafeNames(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "charts-test")
+	defer os.RemoveAll(dir)
+	var data = filepath.Join(dir, "data")
+	var archive = writeTestChartArchive(t, dir, "web", "1.0.0")
+	cm, err := NewRepositoryChartManager(model.Repository{Name: "test"}, data, nil)
+	if err != nil && cm == nil {
+		t.Fatal(err)
+	}
+	var invalid = [][]string{
+		{"../../outside", "1.0.0"},
+		{"web", "../../../outside"},
+		{"web", ".."},
+		{"..", "1.0.0"},
+		{".", "1.0.0"},
+		{"web", "."},
+		{"web/sub", "1.0.0"},
+	}
+	for _, nv := range invalid {
+		if err := cm.InstallChart(nv[0], nv[1], archive, false); err == nil {
+			t.Fatalf("Expected chart %s version %s rejected", nv[0], nv[1])
+		}
+		if err := cm.DeleteChartVersion(nv[0], nv[1]); err == nil || !strings.Contains(err.Error(), "Invalid chart") {
+			t.Fatalf("Expected chart %s version %s delete rejected, found: %v", nv[0], nv[1], err)
+		}
+		if _, err := cm.GetChartVersionTemplate(nv[0], nv[1], model.ValueSet{}); err == nil || !strings.Contains(err.Error(), "Invalid chart") {
+			t.Fatalf("Expected chart %s version %s template rejected, found: %v", nv[0], nv[1], err)
+		}
+		if err := cm.UpdateExistingChart(nv[0], nv[1], archive, false, true); err == nil {
+			t.Fatalf("Expected chart %s version %s update rejected", nv[0], nv[1])
+		}
+	}
+	if utils.ExistsFileOrFolder(filepath.Join(dir, "outside")) || utils.ExistsFileOrFolder(filepath.Join(data, "outside")) {
+		t.Fatal("Expected no chart stored outside the repository charts folder")
+	}
+	// Semantic versions with build metadata are accepted, as by the chart version route
+	if err := cm.InstallChart("web", "1.0.0+build.1", archive, false); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+func TestCheckFolderWithin(t *testing.T) {
+	var base = filepath.Join("data", "repositories", "test", "charts")
+	if err := checkFolderWithin(base, filepath.Join(base, "web", "1.0.0")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, folder := range []string{base, filepath.Join(base, ".."), filepath.Join(base, "..", "other"), filepath.Join(base, "web", "..", "..", "x")} {
+		if err := checkFolderWithin(base, folder); err == nil {
+			t.Fatalf("Expected folder %s outside of %s", folder, base)
+		}
+	}
+}
+
+func TestDeployRollbackChartUsesHelmHistory(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "charts-test")
+	defer os.RemoveAll(dir)
+	// Revision 3 is a failed upgrade not counted by the local release state, helm rolls back to the superseded revision 2
+	var history = `[{"revision":1,"status":"superseded","chart":"web-0.1.0"},{"revision":2,"status":"superseded","chart":"web-0.2.0"},` +
+		`{"revision":3,"status":"failed","chart":"web-0.3.0"},{"revision":4,"status":"deployed","chart":"web-0.3.1"}]`
+	var script = "#!/bin/sh\nif [ \"$1\" = \"history\" ]; then\necho '" + history + "'\necho 'WARNING: kubeconfig is group-readable' >&2\nelse\necho \"$@\"\nfi\n"
+	var helm = filepath.Join(dir, "helm")
+	if err := ioutil.WriteFile(helm, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	SetHelmBinaryPath(helm)
+	defer SetHelmBinaryPath("")
+	var data = filepath.Join(dir, "data")
+	var repo = model.Repository{Name: "test"}
+	cm, err := NewRepositoryChartManager(repo, data, nil)
+	if err != nil && cm == nil {
+		t.Fatal(err)
+	}
+	var target = normalizeDeployTarget(model.DeployTarget{})
+	cm.SetDeployTarget(target)
+	var release = model.ChartRelease{
+		Name:       "web",
+		Chart:      "web",
+		Repository: repo.Name,
+		Target:     target,
+		Current:    model.Version{Name: "0.3.1", State: model.StateReady},
+		Revisions: []model.ChartReleaseRevision{
+			{Revision: 1, Action: model.ChartReleaseInstall, Version: model.Version{Name: "0.1.0", State: model.StateReady}},
+			{Revision: 2, Action: model.ChartReleaseUpgrade, Version: model.Version{Name: "0.2.0", State: model.StateReady}},
+			{Action: model.ChartReleaseUpgrade, Version: model.Version{Name: "0.3.0", State: model.StateFailed}},
+			{Revision: 3, Action: model.ChartReleaseUpgrade, Version: model.Version{Name: "0.3.1", State: model.StateReady}},
+		},
+	}
+	if err := saveChartRelease(data, nil, repo.Name, release); err != nil {
+		t.Fatal(err)
+	}
+	output, err := cm.DeployRollbackChart("web", false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.TrimSpace(output) != "rollback web 2 --namespace "+target.Namespace {
+		t.Fatalf("Unexpected helm arguments %q", strings.TrimSpace(output))
+	}
+	stored, err := cm.GetChartRelease("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var last = stored.Revisions[len(stored.Revisions)-1]
+	if stored.Current.Name != "0.2.0" || last.Revision != 5 || last.Action != model.ChartReleaseRollback {
+		t.Fatalf("Unexpected release state after rollback: %+v", stored)
+	}
+}
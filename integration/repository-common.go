@@ -1,13 +1,63 @@
 package integration
 
 import (
+	"errors"
 	"fmt"
 	"github.com/hellgate75/k8s-deploy/log"
 	"github.com/hellgate75/k8s-deploy/model"
 	"github.com/hellgate75/k8s-deploy/utils"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
 )
 
+// Document names and versions are used as storage folder names, so only the characters accepted by the
+// repository REST routes are allowed, starting with a letter or a digit so "." and ".." are never accepted
+var (
+	documentNameRegexp    = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+	documentVersionRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9.+_-]*$`)
+)
+
+// Verify a document name and version are safe to be used as storage folder names
+func validateDocumentNameVersion(kind string, name string, version string) error {
+	if !documentNameRegexp.MatchString(name) || strings.Contains(name, "..") {
+		return errors.New(fmt.Sprintf("Invalid %s name: %s, it must start with a letter or a digit, followed by letters, digits, '.', '_' and '-'", kind, name))
+	}
+	if !documentVersionRegexp.MatchString(version) || strings.Contains(version, "..") {
+		return errors.New(fmt.Sprintf("Invalid %s version: %s, it must start with a letter or a digit, followed by letters, digits, '.', '+', '_' and '-'", kind, version))
+	}
+	return nil
+}
+
+// Verify a folder is contained in the given parent folder, once both paths are cleaned
+func checkFolderWithin(parent string, folder string) error {
+	rel, err := filepath.Rel(filepath.Clean(parent), filepath.Clean(folder))
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(os.PathSeparator)) || filepath.IsAbs(rel) {
+		return errors.New(fmt.Sprintf("Folder %s is outside of folder %s", folder, parent))
+	}
+	return nil
+}
+
+var storageLocksMutex sync.Mutex
+var storageLocks = make(map[string]*sync.RWMutex)
+
+// Recovers the lock shared by all the managers accessing a storage folder or file. Managers are created on request,
+// so the shared lock serializes the concurrent changes of the same index and release files
+func getStorageLock(path string) *sync.RWMutex {
+	storageLocksMutex.Lock()
+	defer storageLocksMutex.Unlock()
+	path = filepath.Clean(path)
+	lock, ok := storageLocks[path]
+	if !ok {
+		lock = &sync.RWMutex{}
+		storageLocks[path] = lock
+	}
+	return lock
+}
+
 func getChartsListFolder(baseFolder string, repoName string) string {
 	return fmt.Sprintf(repositoryChartsFolderTemplate, baseFolder, os.PathSeparator, os.PathSeparator, repoName, os.PathSeparator)
 }
@@ -29,7 +79,12 @@ func getChartDetailsIndex(baseFolder string, repoName string, chartName string,
 
 func getChartVersionFolder(baseFolder string, repoName string, chartName string, version string) string {
 	//repositoryChartVersionsDetailsFolderTemplate = "%s%crepositories%c%s%ccharts%c%s%c%s"
-	return fmt.Sprintf(repositoryChartDetailsFolderTemplate, baseFolder, os.PathSeparator, os.PathSeparator, repoName, os.PathSeparator, os.PathSeparator, chartName, os.PathSeparator, version)
+	return fmt.Sprintf(repositoryChartVersionsDetailsFolderTemplate, baseFolder, os.PathSeparator, os.PathSeparator, repoName, os.PathSeparator, os.PathSeparator, chartName, os.PathSeparator, version)
+}
+
+// Get the stored Helm package file of a chart version, next to the version folder
+func getChartVersionPackageFile(baseFolder string, repoName string, chartName string, version string) string {
+	return filepath.Join(getChartDetailsFolder(baseFolder, repoName, chartName), getHelmPackageFileName(chartName, version))
 }
 
 func saveRepository(dataFolder string, logger log.Logger, repoName string, repo model.Repository) error {
@@ -158,3 +213,153 @@ func loadKubernetesFiles(dataFolder string, logger log.Logger, repoName string)
 	}
 	return filesList, nil
 }
+
+func saveChartDetails(dataFolder string, logger log.Logger, repoName string, chart model.Chart) error {
+	// Create Chart Details File
+	var file = getChartDetailsIndex(dataFolder, repoName, chart.Name, repositoryFormatExtension)
+	if logger != nil {
+		logger.Warnf("Saving chart details file %s for chart %s in repository %s", file, chart.Name, repoName)
+		logger.Warnf("Number of saved versions %v for chart %s in repository %s", len(chart.Versions), chart.Name, repoName)
+	}
+	var folder = getChartDetailsFolder(dataFolder, repoName, chart.Name)
+	if !utils.ExistsFileOrFolder(folder) {
+		err := utils.CreateFolder(folder)
+		if err != nil {
+			return err
+		}
+	}
+	return utils.SaveStructureByType(file, &chart, repositoryFormatExtension)
+}
+
+func loadChartDetails(dataFolder string, logger log.Logger, repoName string, chartName string) (*model.Chart, error) {
+	// Load Chart Details File
+	var file = getChartDetailsIndex(dataFolder, repoName, chartName, repositoryFormatExtension)
+	if logger != nil {
+		logger.Debugf("Loading chart details file %s for chart %s in repository %s", file, chartName, repoName)
+	}
+	if !utils.ExistsFileOrFolder(file) {
+		return nil, errors.New(fmt.Sprintf("Chart %s not found in repository %s", chartName, repoName))
+	}
+	var chart = model.Chart{
+		Versions: make([]model.Version, 0),
+	}
+	err := utils.LoadStructureByType(file, &chart, repositoryFormatExtension)
+	if err != nil {
+		return nil, err
+	}
+	return &chart, nil
+}
+
+// Copy the content of a folder into another one, without creating the source folder in the destination one
+func copyFolderContent(srcFolder string, dstFolder string) error {
+	if !utils.ExistsFileOrFolder(dstFolder) {
+		err := utils.CreateFolder(dstFolder)
+		if err != nil {
+			return err
+		}
+	}
+	files, err := ioutil.ReadDir(srcFolder)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		_, _, err = utils.CopyFileToFolder(filepath.Join(srcFolder, f.Name()), dstFolder)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Replace the content of a folder with the content of another one. The content is copied in a staging folder, next
+// to the destination folder, then renamed in place, so the destination folder is left untouched when the copy fails
+func replaceFolder(srcFolder string, dstFolder string) error {
+	var stagingFolder = fmt.Sprintf("%s.%s.staging", dstFolder, utils.GetRandPath())
+	var backupFolder = fmt.Sprintf("%s.%s.backup", dstFolder, utils.GetRandPath())
+	err := utils.CleanCreateFolder(stagingFolder)
+	if err != nil {
+		return err
+	}
+	err = copyFolderContent(srcFolder, stagingFolder)
+	if err != nil {
+		_ = utils.DeleteFileOrFolder(stagingFolder)
+		return err
+	}
+	var exists = utils.ExistsFileOrFolder(dstFolder)
+	if exists {
+		if err := os.Rename(dstFolder, backupFolder); err != nil {
+			_ = utils.DeleteFileOrFolder(stagingFolder)
+			return err
+		}
+	}
+	if err := os.Rename(stagingFolder, dstFolder); err != nil {
+		_ = utils.DeleteFileOrFolder(stagingFolder)
+		if exists {
+			_ = os.Rename(backupFolder, dstFolder)
+		}
+		return err
+	}
+	if exists {
+		_ = utils.DeleteFileOrFolder(backupFolder)
+	}
+	return nil
+}
+
+func getKubernetesFilesListFolder(baseFolder string, repoName string) string {
+	return fmt.Sprintf(repositoryKubernetesFilesFolderTemplate, baseFolder, os.PathSeparator, os.PathSeparator, repoName, os.PathSeparator)
+}
+
+func getKubernetesFileDetailsFolder(baseFolder string, repoName string, fileName string) string {
+	//repositoryKubernetesFileDetailsFolderTemplate         = "%s%crepositories%c%s%ckubefiles%c%s"
+	return fmt.Sprintf(repositoryKubernetesFileDetailsFolderTemplate, baseFolder, os.PathSeparator, os.PathSeparator, repoName, os.PathSeparator, os.PathSeparator, fileName)
+}
+
+func getKubernetesFileDetailsIndex(baseFolder string, repoName string, fileName string, extension utils.FormatType) string {
+	//repositoryKubernetesFileDetailsIndexTemplate          = "%s%crepositories%c%s%ckubefiles%c%s%cindex.%v"
+	return fmt.Sprintf(repositoryKubernetesFileDetailsIndexTemplate, baseFolder, os.PathSeparator, os.PathSeparator, repoName, os.PathSeparator, os.PathSeparator, fileName, os.PathSeparator, extension)
+}
+
+func getKubernetesFileVersionFolder(baseFolder string, repoName string, fileName string, version string) string {
+	//repositoryKubernetesFileVersionsDetailsFolderTemplate = "%s%crepositories%c%s%ckubefiles%c%s%c%s"
+	return fmt.Sprintf(repositoryKubernetesFileVersionsDetailsFolderTemplate, baseFolder, os.PathSeparator, os.PathSeparator, repoName, os.PathSeparator, os.PathSeparator, fileName, os.PathSeparator, version)
+}
+
+func getKubernetesFileVersionManifest(baseFolder string, repoName string, fileName string, version string) string {
+	return filepath.Join(getKubernetesFileVersionFolder(baseFolder, repoName, fileName, version), kubernetesFileManifestName)
+}
+
+func saveKubernetesFileDetails(dataFolder string, logger log.Logger, repoName string, kubeFile model.KubernetesFile) error {
+	// Create Kubernetes File Details File
+	var file = getKubernetesFileDetailsIndex(dataFolder, repoName, kubeFile.Name, repositoryFormatExtension)
+	if logger != nil {
+		logger.Warnf("Saving Kubernetes File details file %s for Kubernetes File %s in repository %s", file, kubeFile.Name, repoName)
+		logger.Warnf("Number of saved versions %v for Kubernetes File %s in repository %s", len(kubeFile.Versions), kubeFile.Name, repoName)
+	}
+	var folder = getKubernetesFileDetailsFolder(dataFolder, repoName, kubeFile.Name)
+	if !utils.ExistsFileOrFolder(folder) {
+		err := utils.CreateFolder(folder)
+		if err != nil {
+			return err
+		}
+	}
+	return utils.SaveStructureByType(file, &kubeFile, repositoryFormatExtension)
+}
+
+func loadKubernetesFileDetails(dataFolder string, logger log.Logger, repoName string, fileName string) (*model.KubernetesFile, error) {
+	// Load Kubernetes File Details File
+	var file = getKubernetesFileDetailsIndex(dataFolder, repoName, fileName, repositoryFormatExtension)
+	if logger != nil {
+		logger.Debugf("Loading Kubernetes File details file %s for Kubernetes File %s in repository %s", file, fileName, repoName)
+	}
+	if !utils.ExistsFileOrFolder(file) {
+		return nil, errors.New(fmt.Sprintf("Kubernetes File %s not found in repository %s", fileName, repoName))
+	}
+	var kubeFile = model.KubernetesFile{
+		Versions: make([]model.Version, 0),
+	}
+	err := utils.LoadStructureByType(file, &kubeFile, repositoryFormatExtension)
+	if err != nil {
+		return nil, err
+	}
+	return &kubeFile, nil
+}
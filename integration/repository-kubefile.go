@@ -1,15 +1,29 @@
 package integration
 
 import (
+	"errors"
+	"fmt"
 	"github.com/hellgate75/k8s-deploy/log"
 	"github.com/hellgate75/k8s-deploy/model"
+	"github.com/hellgate75/k8s-deploy/utils"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
+// Kubernetes Files storage manager, the storage lock is shared by all the managers of the repository, while the
+// Kubernetes File releases are locked one by one, so long running deploys don't block the storage
 type kubernetesFilesRepositoryManager struct {
-	repository model.Repository
-	dataFolder string
-	logger     log.Logger
-	files      []model.KubernetesFileInfo
+	*sync.RWMutex
+	targetMutex sync.RWMutex
+	repository  model.Repository
+	dataFolder  string
+	logger      log.Logger
+	files       []model.KubernetesFileInfo
+	target      model.DeployTarget
 }
 
 const (
@@ -18,58 +32,630 @@ const (
 	repositoryKubernetesFileDetailsIndexTemplate          = "%s%crepositories%c%s%ckubefiles%c%s%cindex.%v"
 	repositoryKubernetesFileDetailsFolderTemplate         = "%s%crepositories%c%s%ckubefiles%c%s"
 	repositoryKubernetesFileVersionsDetailsFolderTemplate = "%s%crepositories%c%s%ckubefiles%c%s%c%s"
+	kubernetesFileManifestName                            = "manifest.yaml"
 )
 
 func (k *kubernetesFilesRepositoryManager) VerifyKubernetesFile(name string, version string) error {
-	panic("implement me")
+	k.RLock()
+	defer k.RUnlock()
+	var report = &model.VerificationReport{
+		Name:    name,
+		Version: version,
+		Issues:  make([]model.VerificationIssue, 0),
+	}
+	kubeFile, err := loadKubernetesFileDetails(k.dataFolder, k.logger, k.repository.Name, name)
+	if err != nil {
+		report.AddIssue(name, "%v", err)
+		return report
+	}
+	var registered = false
+	for _, v := range kubeFile.Versions {
+		if v.Name == version {
+			registered = true
+			break
+		}
+	}
+	if !registered {
+		report.AddIssue(name, "version %s not registered in Kubernetes File index", version)
+	}
+	var manifest = getKubernetesFileVersionManifest(k.dataFolder, k.repository.Name, name, version)
+	data, err := ioutil.ReadFile(manifest)
+	if err != nil {
+		report.AddIssue(version, "Kubernetes File version manifest not found")
+		return report
+	}
+	verifyKubernetesManifest(string(data), report)
+	if report.Valid() {
+		return nil
+	}
+	if k.logger != nil {
+		k.logger.Warnf("Kubernetes File %s version %s verification failed: %s", name, version, report.Error())
+	}
+	return report
 }
 
 func (k *kubernetesFilesRepositoryManager) InstallKubernetesFile(name string, version string, file string) error {
-	panic("implement me")
+	k.Lock()
+	defer k.Unlock()
+	name = strings.TrimSpace(name)
+	version = strings.TrimSpace(version)
+	if name == "" || version == "" {
+		return errors.New("Kubernetes File name and version cannot be empty or without significant digits or letters")
+	}
+	if err := validateDocumentNameVersion("Kubernetes File", name, version); err != nil {
+		return err
+	}
+	kubeFile, err := loadKubernetesFileDetails(k.dataFolder, k.logger, k.repository.Name, name)
+	if err != nil {
+		kubeFile = &model.KubernetesFile{
+			Id:       utils.NewUniqueIdentifier(),
+			Name:     name,
+			Versions: make([]model.Version, 0),
+		}
+	}
+	return k.storeKubernetesFileVersion(kubeFile, version, file)
+}
+
+// Store the manifest as a new immutable version and keep the Kubernetes File and repository indexes in sync
+func (k *kubernetesFilesRepositoryManager) storeKubernetesFileVersion(kubeFile *model.KubernetesFile, version string, file string) error {
+	for _, v := range kubeFile.Versions {
+		if v.Name == version && v.State != model.StateDeleted {
+			return errors.New(fmt.Sprintf("Kubernetes File %s version %s already present in repository %s, versions are immutable", kubeFile.Name, version, k.repository.Name))
+		}
+	}
+	if fs, err := os.Stat(file); err == nil {
+		if fs.IsDir() {
+			return errors.New(fmt.Sprintf("Kubernetes File %s is folder, and not regular file!!", file))
+		}
+	} else {
+		return errors.New(fmt.Sprintf("Kubernetes File %s doesn't exist!!", file))
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var versionFolder = getKubernetesFileVersionFolder(k.dataFolder, k.repository.Name, kubeFile.Name, version)
+	if err := checkFolderWithin(getKubernetesFilesListFolder(k.dataFolder, k.repository.Name), versionFolder); err != nil {
+		return err
+	}
+	if k.logger != nil {
+		k.logger.Infof("Storing Kubernetes File %s version %s in folder %s", kubeFile.Name, version, versionFolder)
+	}
+	err = utils.CleanCreateFolder(versionFolder)
+	if err != nil {
+		return err
+	}
+	var manifest = getKubernetesFileVersionManifest(k.dataFolder, k.repository.Name, kubeFile.Name, version)
+	err = ioutil.WriteFile(manifest, data, 0666)
+	if err != nil {
+		return err
+	}
+	var versions = make([]model.Version, 0)
+	for _, v := range kubeFile.Versions {
+		if v.Name != version {
+			versions = append(versions, v)
+		}
+	}
+	kubeFile.Versions = append(versions, model.Version{
+		Id:    utils.NewUniqueIdentifier(),
+		Name:  version,
+		State: model.StateReady,
+	})
+	kubeFile.State = model.StateReady
+	err = saveKubernetesFileDetails(k.dataFolder, k.logger, k.repository.Name, *kubeFile)
+	if err != nil {
+		return err
+	}
+	k.reloadKubernetesFiles()
+	for _, f := range k.files {
+		if f.Name == kubeFile.Name {
+			return nil
+		}
+	}
+	k.files = append(k.files, model.KubernetesFileInfo{
+		Id:   kubeFile.Id,
+		Name: kubeFile.Name,
+	})
+	k.repository.ReplaceKubernetesFiles(k.files...)
+	return saveKubernetesFiles(k.dataFolder, k.logger, k.repository.Name, k.files)
 }
 
 func (k *kubernetesFilesRepositoryManager) DeleteKubernetesFileVersion(name string, version string) error {
-	panic("implement me")
+	if err := validateDocumentNameVersion("Kubernetes File", name, version); err != nil {
+		return err
+	}
+	k.Lock()
+	defer k.Unlock()
+	kubeFile, err := loadKubernetesFileDetails(k.dataFolder, k.logger, k.repository.Name, name)
+	if err != nil {
+		return err
+	}
+	var versions = make([]model.Version, 0)
+	var found = false
+	for _, v := range kubeFile.Versions {
+		if v.Name == version {
+			found = true
+		} else {
+			versions = append(versions, v)
+		}
+	}
+	if !found {
+		return errors.New(fmt.Sprintf("Kubernetes File %s version %s not found in repository %s", name, version, k.repository.Name))
+	}
+	var versionFolder = getKubernetesFileVersionFolder(k.dataFolder, k.repository.Name, name, version)
+	if k.logger != nil {
+		k.logger.Warnf("Deleting Kubernetes File %s version %s folder %s", name, version, versionFolder)
+	}
+	err = utils.DeleteFileOrFolder(versionFolder)
+	if err != nil {
+		return err
+	}
+	kubeFile.Versions = versions
+	return saveKubernetesFileDetails(k.dataFolder, k.logger, k.repository.Name, *kubeFile)
 }
 
 func (k *kubernetesFilesRepositoryManager) DeleteEntireKubernetesFile(name string, version string) error {
-	panic("implement me")
+	k.Lock()
+	defer k.Unlock()
+	k.reloadKubernetesFiles()
+	var files = make([]model.KubernetesFileInfo, 0)
+	var found = false
+	for _, f := range k.files {
+		if f.Name == name {
+			found = true
+		} else {
+			files = append(files, f)
+		}
+	}
+	if !found {
+		return errors.New(fmt.Sprintf("Kubernetes File %s not found in repository %s", name, k.repository.Name))
+	}
+	var folder = getKubernetesFileDetailsFolder(k.dataFolder, k.repository.Name, name)
+	if k.logger != nil {
+		k.logger.Warnf("Deleting Kubernetes File %s folder %s", name, folder)
+	}
+	err := utils.DeleteFileOrFolder(folder)
+	if err != nil {
+		return err
+	}
+	k.files = files
+	k.repository.ReplaceKubernetesFiles(k.files...)
+	return saveKubernetesFiles(k.dataFolder, k.logger, k.repository.Name, k.files)
 }
 
 func (k *kubernetesFilesRepositoryManager) GetKubernetesFileVersionTemplate(name string, version string) (string, error) {
-	panic("implement me")
+	k.RLock()
+	defer k.RUnlock()
+	return k.readKubernetesFileManifest(name, version)
+}
+
+func (k *kubernetesFilesRepositoryManager) GetKubernetesFileVersionTemplateWithValues(name string, version string, values model.ValueSet, variables []model.Variable) (string, error) {
+	k.RLock()
+	defer k.RUnlock()
+	if k.logger != nil {
+		k.logger.Debugf("Expanding Kubernetes File %s version %s with %v value(s) and %v variable(s)", name, version, len(values.Value), len(variables))
+	}
+	return k.expandKubernetesFileManifest(name, version, values, variables)
+}
+
+// Read the stored manifest of a Kubernetes File version
+func (k *kubernetesFilesRepositoryManager) readKubernetesFileManifest(name string, version string) (string, error) {
+	if err := validateDocumentNameVersion("Kubernetes File", name, version); err != nil {
+		return "", err
+	}
+	var manifest = getKubernetesFileVersionManifest(k.dataFolder, k.repository.Name, name, version)
+	if !utils.ExistsFileOrFolder(manifest) {
+		return "", errors.New(fmt.Sprintf("Kubernetes File %s version %s not found in repository %s", name, version, k.repository.Name))
+	}
+	data, err := ioutil.ReadFile(manifest)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
 }
 
 func (k *kubernetesFilesRepositoryManager) UpdateExistingKubernetesFile(name string, version string, file string) error {
-	panic("implement me")
+	k.Lock()
+	defer k.Unlock()
+	name = strings.TrimSpace(name)
+	version = strings.TrimSpace(version)
+	if name == "" || version == "" {
+		return errors.New("Kubernetes File name and version cannot be empty or without significant digits or letters")
+	}
+	if err := validateDocumentNameVersion("Kubernetes File", name, version); err != nil {
+		return err
+	}
+	kubeFile, err := loadKubernetesFileDetails(k.dataFolder, k.logger, k.repository.Name, name)
+	if err != nil {
+		return err
+	}
+	return k.storeKubernetesFileVersion(kubeFile, version, file)
 }
 
 func (k *kubernetesFilesRepositoryManager) GetKubernetesFileVersions(name string) ([]model.Version, error) {
-	panic("implement me")
+	k.RLock()
+	defer k.RUnlock()
+	_, versions, err := k.listKubernetesFileVersions(name)
+	return versions, err
+}
+
+// Collect the available versions of a Kubernetes File, sorted by semantic version, verifying the stored manifests
+func (k *kubernetesFilesRepositoryManager) listKubernetesFileVersions(name string) (*model.KubernetesFile, []model.Version, error) {
+	kubeFile, err := loadKubernetesFileDetails(k.dataFolder, k.logger, k.repository.Name, name)
+	if err != nil {
+		return nil, make([]model.Version, 0), err
+	}
+	var versions = make([]model.Version, 0)
+	for _, v := range kubeFile.Versions {
+		if v.State == model.StateDeleted || v.State == model.StatePutged {
+			continue
+		}
+		var manifest = getKubernetesFileVersionManifest(k.dataFolder, k.repository.Name, name, v.Name)
+		if !utils.ExistsFileOrFolder(manifest) {
+			if k.logger != nil {
+				k.logger.Warnf("Kubernetes File %s version %s manifest %s not found", name, v.Name, manifest)
+			}
+			v.State = model.StateError
+		}
+		versions = append(versions, v)
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		return utils.CompareSemVerStrings(versions[i].Name, versions[j].Name) < 0
+	})
+	return kubeFile, versions, nil
 }
 
 func (k *kubernetesFilesRepositoryManager) GetKubernetesFileProjectVersions(name string) ([]model.ProjectKubeFile, error) {
-	panic("implement me")
+	k.RLock()
+	defer k.RUnlock()
+	var out = make([]model.ProjectKubeFile, 0)
+	kubeFile, versions, err := k.listKubernetesFileVersions(name)
+	if err != nil {
+		return out, err
+	}
+	for _, v := range versions {
+		out = append(out, model.ProjectKubeFile{
+			Id:      v.Id,
+			Name:    kubeFile.Name,
+			Version: v.Name,
+			State:   v.State,
+		})
+	}
+	return out, nil
+}
+
+func (k *kubernetesFilesRepositoryManager) SetDeployTarget(target model.DeployTarget) {
+	k.targetMutex.Lock()
+	defer k.targetMutex.Unlock()
+	k.target = normalizeDeployTarget(target)
+}
+
+func (k *kubernetesFilesRepositoryManager) GetDeployTarget() model.DeployTarget {
+	k.targetMutex.RLock()
+	defer k.targetMutex.RUnlock()
+	return k.target
+}
+
+// Locks the deploy target and the Kubernetes File release on it, returning the unlock function
+func (k *kubernetesFilesRepositoryManager) lockRelease(name string) func() {
+	k.targetMutex.RLock()
+	var lock = getStorageLock(getKubernetesFileReleaseFile(k.dataFolder, k.repository.Name, k.target, name, repositoryFormatExtension))
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		k.targetMutex.RUnlock()
+	}
 }
 
 func (k *kubernetesFilesRepositoryManager) DeployInstallKubernetesFile(name string, version string) (string, error) {
-	panic("implement me")
+	defer k.lockRelease(name)()
+	k.RLock()
+	manifest, err := k.expandKubernetesFileManifest(name, version, model.ValueSet{}, nil)
+	k.RUnlock()
+	if err != nil {
+		return "", err
+	}
+	return k.applyKubernetesFile(model.KubernetesFileReleaseInstall, name, version, manifest, false)
 }
 
 func (k *kubernetesFilesRepositoryManager) DeployUpgradeKubernetesFile(name string, version string, force bool) (string, error) {
-	panic("implement me")
+	defer k.lockRelease(name)()
+	k.RLock()
+	manifest, err := k.expandKubernetesFileManifest(name, version, model.ValueSet{}, nil)
+	k.RUnlock()
+	if err != nil {
+		return "", err
+	}
+	return k.applyKubernetesFile(model.KubernetesFileReleaseUpgrade, name, version, manifest, force)
+}
+
+func (k *kubernetesFilesRepositoryManager) DeployInstallKubernetesFileWithValues(name string, version string, values model.ValueSet, variables []model.Variable) (string, error) {
+	defer k.lockRelease(name)()
+	k.RLock()
+	manifest, err := k.expandKubernetesFileManifest(name, version, values, variables)
+	k.RUnlock()
+	if err != nil {
+		return "", err
+	}
+	return k.applyKubernetesFile(model.KubernetesFileReleaseInstall, name, version, manifest, false)
+}
+
+func (k *kubernetesFilesRepositoryManager) DeployUpgradeKubernetesFileWithValues(name string, version string, values model.ValueSet, variables []model.Variable, force bool) (string, error) {
+	defer k.lockRelease(name)()
+	k.RLock()
+	manifest, err := k.expandKubernetesFileManifest(name, version, values, variables)
+	k.RUnlock()
+	if err != nil {
+		return "", err
+	}
+	return k.applyKubernetesFile(model.KubernetesFileReleaseUpgrade, name, version, manifest, force)
+}
+
+func (k *kubernetesFilesRepositoryManager) DeployRollbackKubernetesFile(name string) (string, error) {
+	return k.DeployRollbackKubernetesFileWithValues(name, model.ValueSet{}, nil)
+}
+
+// Roll back to the version applied before the current one, re-applying the manifest recorded in the release ledger.
+// Given values and variables are used only to render ledger records without a recorded manifest
+func (k *kubernetesFilesRepositoryManager) DeployRollbackKubernetesFileWithValues(name string, values model.ValueSet, variables []model.Variable) (string, error) {
+	defer k.lockRelease(name)()
+	previous, err := k.previousKubernetesFileRecord(name)
+	if err != nil {
+		return "", err
+	}
+	var manifest = previous.Manifest
+	if manifest == "" {
+		k.RLock()
+		manifest, err = k.expandKubernetesFileManifest(name, previous.Version.Name, values, variables)
+		k.RUnlock()
+		if err != nil {
+			return "", err
+		}
+	}
+	return k.applyKubernetesFile(model.KubernetesFileReleaseRollback, name, previous.Version.Name, manifest, false)
+}
+
+// Collect the ledger record of the version applied before the current one
+func (k *kubernetesFilesRepositoryManager) previousKubernetesFileRecord(name string) (model.KubernetesFileReleaseRecord, error) {
+	release, err := loadKubernetesFileRelease(k.dataFolder, k.logger, k.repository.Name, k.target, name)
+	if err != nil {
+		return model.KubernetesFileReleaseRecord{}, err
+	}
+	if !release.IsInstalled() {
+		return model.KubernetesFileReleaseRecord{}, errors.New(fmt.Sprintf("Kubernetes File %s not applied on target %s", name, k.target.Key()))
+	}
+	previous, ok := release.PreviousRecord()
+	if !ok {
+		return model.KubernetesFileReleaseRecord{}, errors.New(fmt.Sprintf("Kubernetes File %s has no previous version on target %s", name, k.target.Key()))
+	}
+	return previous, nil
+}
+
+// Read the stored manifest of a Kubernetes File version, expanding the placeholders with given values and variables defaults
+func (k *kubernetesFilesRepositoryManager) expandKubernetesFileManifest(name string, version string, values model.ValueSet, variables []model.Variable) (string, error) {
+	manifest, err := k.readKubernetesFileManifest(name, version)
+	if err != nil {
+		return "", err
+	}
+	fileValues, err := loadKubernetesFileValues(values, variables)
+	if err != nil {
+		return "", err
+	}
+	out, err := expandKubernetesFileTemplate(manifest, fileValues)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("Kubernetes File %s version %s: %v", name, version, err))
+	}
+	return out, nil
+}
+
+// Apply a rendered manifest of a Kubernetes File version on the deploy target and record it in the release ledger.
+// On upgrade and rollback the resources of the previous version not declared anymore are deleted
+func (k *kubernetesFilesRepositoryManager) applyKubernetesFile(action string, name string, version string, manifest string, force bool) (string, error) {
+	release, err := loadKubernetesFileRelease(k.dataFolder, k.logger, k.repository.Name, k.target, name)
+	if err != nil {
+		return "", err
+	}
+	if action == model.KubernetesFileReleaseInstall && release.IsInstalled() {
+		return "", errors.New(fmt.Sprintf("Kubernetes File %s version %s already applied on target %s, upgrade required", name, release.Current.Name, k.target.Key()))
+	}
+	if action != model.KubernetesFileReleaseInstall && !release.IsInstalled() {
+		return "", errors.New(fmt.Sprintf("Kubernetes File %s not applied on target %s, install required", name, k.target.Key()))
+	}
+	k.RLock()
+	kubeFileVersion, err := k.getKubernetesFileVersion(name, version)
+	k.RUnlock()
+	if err != nil {
+		return "", err
+	}
+	var report = &model.VerificationReport{
+		Name:    name,
+		Version: version,
+		Issues:  make([]model.VerificationIssue, 0),
+	}
+	verifyKubernetesManifest(manifest, report)
+	if !report.Valid() {
+		return "", report
+	}
+	resources, err := parseManifestResources(manifest)
+	if err != nil {
+		return "", err
+	}
+	var record = model.KubernetesFileReleaseRecord{
+		Action:    action,
+		Version:   kubeFileVersion,
+		Digest:    manifestDigest(manifest),
+		Manifest:  manifest,
+		Resources: resources,
+		Date:      time.Now(),
+	}
+	if k.logger != nil {
+		k.logger.Infof("Executing kubectl apply of Kubernetes File %s version %s on target %s", name, version, k.target.Key())
+	}
+	var response = k.executeKubectl(KubeFileApplyAction, name, version, manifest, force)
+	var output = response.Output
+	if response.Error == nil && action != model.KubernetesFileReleaseInstall {
+		var stale = staleKubernetesResources(release.Resources, resources)
+		if len(stale) > 0 {
+			if k.logger != nil {
+				k.logger.Infof("Deleting %v resource(s) not declared anymore by Kubernetes File %s version %s", len(stale), name, version)
+			}
+			var pruneResponse = k.executeKubectl(KubeFileDeleteAction, name, version, resourcesManifest(stale), false)
+			output += pruneResponse.Output
+			if pruneResponse.Error != nil {
+				response = pruneResponse
+			}
+		}
+	}
+	if response.Error != nil {
+		record.Version.State = model.StateFailed
+		record.Message = response.Error.Error()
+	} else {
+		record.Version.State = model.StateReady
+		release.Current = record.Version
+		release.Digest = record.Digest
+		release.Resources = resources
+	}
+	release.Updated = record.Date
+	release.History = append(release.History, record)
+	err = saveKubernetesFileRelease(k.dataFolder, k.logger, k.repository.Name, *release)
+	if response.Error != nil {
+		return output, errors.New(fmt.Sprintf("Kubectl %s of Kubernetes File %s version %s failed with code %v: %v", action, name, version, response.Code, response.Error))
+	}
+	return output, err
+}
+
+// Execute a kubectl request on the deploy target, using a temporary copy of the given manifest
+func (k *kubernetesFilesRepositoryManager) executeKubectl(action KubeFileAction, name string, version string, manifest string, force bool) HelmResponse {
+	var manifestFile = utils.GetTempFolder(utils.GetRandPath() + ".yaml")
+	if err := ioutil.WriteFile(manifestFile, []byte(manifest), 0666); err != nil {
+		return HelmResponse{
+			Code:  500,
+			Error: err,
+		}
+	}
+	defer func() {
+		_ = utils.DeleteFileOrFolder(manifestFile)
+	}()
+	return ExecuteKubeRequest(KubeFileDeployRequest{
+		Action:       action,
+		FileName:     name,
+		FileVersion:  version,
+		ManifestPath: manifestFile,
+		Force:        force,
+		Namespace:    k.target.Namespace,
+		KubeConfig:   k.target.KubeConfig,
+		KubeContext:  k.target.Context,
+	})
+}
+
+// Collect the resources of the previous list not present in the current list
+func staleKubernetesResources(previous []model.KubernetesResource, current []model.KubernetesResource) []model.KubernetesResource {
+	var keys = make(map[string]bool)
+	for _, r := range current {
+		keys[r.Key()] = true
+	}
+	var out = make([]model.KubernetesResource, 0)
+	for _, r := range previous {
+		if !keys[r.Key()] {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Collect a registered Kubernetes File version ready to be applied
+func (k *kubernetesFilesRepositoryManager) getKubernetesFileVersion(name string, version string) (model.Version, error) {
+	_, versions, err := k.listKubernetesFileVersions(name)
+	if err != nil {
+		return model.Version{}, err
+	}
+	for _, v := range versions {
+		if v.Name == version {
+			if v.State == model.StateError {
+				return v, errors.New(fmt.Sprintf("Kubernetes File %s version %s is in error state and cannot be applied", name, version))
+			}
+			return v, nil
+		}
+	}
+	return model.Version{}, errors.New(fmt.Sprintf("Kubernetes File %s version %s not found in repository %s", name, version, k.repository.Name))
 }
 
 func (k *kubernetesFilesRepositoryManager) GetInstalledKubernetesFileVersion(name string) (model.Version, error) {
-	panic("implement me")
+	defer k.lockRelease(name)()
+	release, err := loadKubernetesFileRelease(k.dataFolder, k.logger, k.repository.Name, k.target, name)
+	if err != nil {
+		return model.Version{}, err
+	}
+	if !release.IsInstalled() {
+		return model.Version{}, errors.New(fmt.Sprintf("Kubernetes File %s not applied on target %s", name, k.target.Key()))
+	}
+	return release.Current, nil
 }
 
 func (k *kubernetesFilesRepositoryManager) GetInstalledKubernetesFileVersionDetails(name string, version string) (model.Version, error) {
-	panic("implement me")
+	defer k.lockRelease(name)()
+	release, err := loadKubernetesFileRelease(k.dataFolder, k.logger, k.repository.Name, k.target, name)
+	if err != nil {
+		return model.Version{}, err
+	}
+	if release.Current.Name == version {
+		return release.Current, nil
+	}
+	for i := len(release.History) - 1; i >= 0; i-- {
+		if release.History[i].Version.Name == version {
+			return release.History[i].Version, nil
+		}
+	}
+	return model.Version{}, errors.New(fmt.Sprintf("Kubernetes File %s version %s never applied on target %s", name, version, k.target.Key()))
+}
+
+// Get the release ledger of a Kubernetes File on the deploy target
+func (k *kubernetesFilesRepositoryManager) GetKubernetesFileRelease(name string) (*model.KubernetesFileRelease, error) {
+	defer k.lockRelease(name)()
+	return loadKubernetesFileRelease(k.dataFolder, k.logger, k.repository.Name, k.target, name)
 }
 
 func (k *kubernetesFilesRepositoryManager) UnDeployInstalledKubernetesFile(name string) (model.Version, error) {
-	panic("implement me")
+	defer k.lockRelease(name)()
+	release, err := loadKubernetesFileRelease(k.dataFolder, k.logger, k.repository.Name, k.target, name)
+	if err != nil {
+		return model.Version{}, err
+	}
+	if !release.IsInstalled() {
+		return model.Version{}, errors.New(fmt.Sprintf("Kubernetes File %s not applied on target %s", name, k.target.Key()))
+	}
+	if k.logger != nil {
+		k.logger.Infof("Deleting %v resource(s) of Kubernetes File %s version %s on target %s", len(release.Resources), name, release.Current.Name, k.target.Key())
+	}
+	var response = k.executeKubectl(KubeFileDeleteAction, name, release.Current.Name, resourcesManifest(release.Resources), false)
+	var record = model.KubernetesFileReleaseRecord{
+		Action:    model.KubernetesFileReleaseUninstall,
+		Version:   release.Current,
+		Digest:    release.Digest,
+		Resources: release.Resources,
+		Date:      time.Now(),
+	}
+	release.Updated = record.Date
+	if response.Error != nil {
+		record.Version.State = model.StateFailed
+		record.Message = response.Error.Error()
+		release.History = append(release.History, record)
+		_ = saveKubernetesFileRelease(k.dataFolder, k.logger, k.repository.Name, *release)
+		return release.Current, errors.New(fmt.Sprintf("Kubectl delete of Kubernetes File %s failed with code %v: %v", name, response.Code, response.Error))
+	}
+	record.Version.State = model.StateDeleted
+	release.Current = record.Version
+	release.Digest = ""
+	release.Resources = make([]model.KubernetesResource, 0)
+	release.History = append(release.History, record)
+	return release.Current, saveKubernetesFileRelease(k.dataFolder, k.logger, k.repository.Name, *release)
+}
+
+// Refresh the repository Kubernetes File list, changed by the other managers of the repository
+func (k *kubernetesFilesRepositoryManager) reloadKubernetesFiles() {
+	if files, err := loadKubernetesFiles(k.dataFolder, k.logger, k.repository.Name); err == nil {
+		k.files = files
+	}
 }
 
 func (k *kubernetesFilesRepositoryManager) init() (model.RepositoryKubernetesFilesManager, error) {
@@ -83,9 +669,11 @@ func (k *kubernetesFilesRepositoryManager) init() (model.RepositoryKubernetesFil
 
 func NewRepositoryKubernetesFilesManager(repository model.Repository, dataFolder string, logger log.Logger) (model.RepositoryKubernetesFilesManager, error) {
 	return (&kubernetesFilesRepositoryManager{
+		RWMutex:    getStorageLock(fmt.Sprintf(repositoryKubernetesFilesFolderTemplate, dataFolder, os.PathSeparator, os.PathSeparator, repository.Name, os.PathSeparator)),
 		repository: repository,
 		dataFolder: dataFolder,
 		logger:     logger,
 		files:      make([]model.KubernetesFileInfo, 0),
+		target:     normalizeDeployTarget(model.DeployTarget{}),
 	}).init()
 }
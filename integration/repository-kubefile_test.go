@@ -0,0 +1,149 @@
+package integration
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hellgate75/k8s-deploy/model"
+	"github.com/hellgate75/k8s-deploy/utils"
+)
+
+const testKubernetesFileManifest = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\ndata:\n  key: value\n"
+
+func TestGetKubernetesFileProjectVersions(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "kubefiles-test")
+	defer os.RemoveAll(dir)
+	var file = filepath.Join(dir, "configmap.yaml")
+	if err := ioutil.WriteFile(file, []byte(testKubernetesFileManifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	km, err := NewRepositoryKubernetesFilesManager(model.Repository{Name: "test"}, dir, nil)
+	if err != nil && km == nil {
+		t.Fatal(err)
+	}
+	for _, version := range []string{"1.10.0", "1.2.0"} {
+		if err := km.InstallKubernetesFile("config", version, file); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	versions, err := km.GetKubernetesFileProjectVersions("config")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(versions) != 2 || versions[0].Version != "1.2.0" || versions[1].Version != "1.10.0" {
+		t.Fatalf("Unexpected project versions: %+v", versions)
+	}
+	for _, v := range versions {
+		if v.Name != "config" || v.Id == "" || v.State != model.StateReady {
+			t.Fatalf("Unexpected project version: %+v", v)
+		}
+	}
+	if _, err := km.GetKubernetesFileProjectVersions("missing"); err == nil {
+		t.Fatal("Expected error for a missing Kubernetes File")
+	}
+}
+
+func TestDeployKubernetesFileRejectsUnresolvedPlaceholders(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "kubefiles-test")
+	defer os.RemoveAll(dir)
+	SetKubectlBinaryPath("/bin/true")
+	defer SetKubectlBinaryPath("")
+	var file = filepath.Join(dir, "configmap.yaml")
+	var manifest = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: ${NAME}\n"
+	if err := ioutil.WriteFile(file, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	km, err := NewRepositoryKubernetesFilesManager(model.Repository{Name: "test"}, dir, nil)
+	if err != nil && km == nil {
+		t.Fatal(err)
+	}
+	if err := km.InstallKubernetesFile("config", "1.0.0", file); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := km.DeployInstallKubernetesFile("config", "1.0.0"); err == nil {
+		t.Fatal("Expected unresolved placeholder error installing without values")
+	}
+	if _, err := km.DeployUpgradeKubernetesFile("config", "1.0.0", false); err == nil {
+		t.Fatal("Expected unresolved placeholder error upgrading without values")
+	}
+	var variables = []model.Variable{{Name: "NAME", Default: "test"}}
+	if _, err := km.DeployInstallKubernetesFileWithValues("config", "1.0.0", model.ValueSet{}, variables); err != nil {
+		t.Fatalf("Unexpected error installing with values: %v", err)
+	}
+}
+
+func TestInstallKubernetesFileRejectsUnsafeNames(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "kubefiles-test")
+	defer os.RemoveAll(dir)
+	var data = filepath.Join(dir, "data")
+	var file = filepath.Join(dir, "configmap.yaml")
+	if err := ioutil.WriteFile(file, []byte(testKubernetesFileManifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	km, err := NewRepositoryKubernetesFilesManager(model.Repository{Name: "test"}, data, nil)
+	if err != nil && km == nil {
+		t.Fatal(err)
+	}
+	for _, nv := range [][]string{{"../../outside", "1.0.0"}, {"config", "../../../outside"}, {"config/sub", "1.0.0"}, {"config", ".."}, {".", "1.0.0"}, {"config", "."}} {
+		if err := km.InstallKubernetesFile(nv[0], nv[1], file); err == nil {
+			t.Fatalf("Expected Kubernetes file %s version %s rejected", nv[0], nv[1])
+		}
+		if err := km.DeleteKubernetesFileVersion(nv[0], nv[1]); err == nil || !strings.Contains(err.Error(), "Invalid Kubernetes File") {
+			t.Fatalf("Expected Kubernetes file %s version %s delete rejected, found: %v", nv[0], nv[1], err)
+		}
+		if _, err := km.GetKubernetesFileVersionTemplate(nv[0], nv[1]); err == nil || !strings.Contains(err.Error(), "Invalid Kubernetes File") {
+			t.Fatalf("Expected Kubernetes file %s version %s template rejected, found: %v", nv[0], nv[1], err)
+		}
+	}
+	if utils.ExistsFileOrFolder(filepath.Join(data, "repositories", "test", "kubefiles", "index.yaml")) {
+		t.Fatal("Expected Kubernetes files index not overwritten by a rejected file")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "outside")); !os.IsNotExist(err) {
+		t.Fatal("Expected no Kubernetes file stored outside the repository folder")
+	}
+}
+
+func TestDeployRollbackKubernetesFileReappliesRecordedManifest(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "kubefiles-test")
+	defer os.RemoveAll(dir)
+	SetKubectlBinaryPath("/bin/true")
+	defer SetKubectlBinaryPath("")
+	var file = filepath.Join(dir, "configmap.yaml")
+	var manifest = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: ${NAME}\n"
+	if err := ioutil.WriteFile(file, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	km, err := NewRepositoryKubernetesFilesManager(model.Repository{Name: "test"}, dir, nil)
+	if err != nil && km == nil {
+		t.Fatal(err)
+	}
+	for _, version := range []string{"1.0.0", "2.0.0"} {
+		if err := km.InstallKubernetesFile("config", version, file); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if _, err := km.DeployInstallKubernetesFileWithValues("config", "1.0.0", model.ValueSet{}, []model.Variable{{Name: "NAME", Default: "first"}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := km.DeployUpgradeKubernetesFileWithValues("config", "2.0.0", model.ValueSet{}, []model.Variable{{Name: "NAME", Default: "second"}}, false); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// The values used by the first install aren't available anymore, the recorded manifest is applied as it was
+	if _, err := km.DeployRollbackKubernetesFile("config"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	release, err := km.GetKubernetesFileRelease("config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var last = release.History[len(release.History)-1]
+	if release.Current.Name != "1.0.0" || last.Action != model.KubernetesFileReleaseRollback || last.Manifest != release.History[0].Manifest {
+		t.Fatalf("Unexpected release after rollback: %+v", release)
+	}
+	if len(release.Resources) != 1 || release.Resources[0].Name != "first" || release.Digest != release.History[0].Digest {
+		t.Fatalf("Expected first install resources restored, found: %+v", release.Resources)
+	}
+}
@@ -0,0 +1,121 @@
+package integration
+
+import (
+	"fmt"
+	"github.com/hellgate75/k8s-deploy/log"
+	"github.com/hellgate75/k8s-deploy/model"
+	"github.com/hellgate75/k8s-deploy/utils"
+	"os"
+	"strings"
+)
+
+const (
+	repositoryReleasesTargetFolderTemplate = "%s%crepositories%c%s%creleases%c%s"
+	repositoryChartReleaseFileTemplate     = "%s%ccharts%c%s.%v"
+	repositoryKubeFileReleaseFileTemplate  = "%s%ckubefiles%c%s.%v"
+	defaultReleaseTargetNamespace          = defaultReleaseNamespace
+)
+
+// Normalize the deploy target, applying the default namespace
+func normalizeDeployTarget(target model.DeployTarget) model.DeployTarget {
+	target.KubeConfig = strings.TrimSpace(target.KubeConfig)
+	target.Context = strings.TrimSpace(target.Context)
+	target.Namespace = strings.TrimSpace(target.Namespace)
+	if target.Namespace == "" {
+		target.Namespace = defaultReleaseTargetNamespace
+	}
+	return target
+}
+
+func getReleasesTargetFolder(baseFolder string, repoName string, target model.DeployTarget) string {
+	//repositoryReleasesTargetFolderTemplate = "%s%crepositories%c%s%creleases%c%s"
+	return fmt.Sprintf(repositoryReleasesTargetFolderTemplate, baseFolder, os.PathSeparator, os.PathSeparator, repoName, os.PathSeparator, os.PathSeparator, target.Key())
+}
+
+func getChartReleaseFile(baseFolder string, repoName string, target model.DeployTarget, chartName string, extension utils.FormatType) string {
+	//repositoryChartReleaseFileTemplate     = "%s%ccharts%c%s.%v"
+	return fmt.Sprintf(repositoryChartReleaseFileTemplate, getReleasesTargetFolder(baseFolder, repoName, target), os.PathSeparator, os.PathSeparator, chartName, extension)
+}
+
+// Load the chart release recorded on a target, or a new empty release when the chart has never been deployed
+func loadChartRelease(dataFolder string, logger log.Logger, repoName string, target model.DeployTarget, chartName string) (*model.ChartRelease, error) {
+	var file = getChartReleaseFile(dataFolder, repoName, target, chartName, repositoryFormatExtension)
+	var release = model.ChartRelease{
+		Name:       chartName,
+		Chart:      chartName,
+		Repository: repoName,
+		Target:     target,
+		Revisions:  make([]model.ChartReleaseRevision, 0),
+	}
+	if !utils.ExistsFileOrFolder(file) {
+		return &release, nil
+	}
+	if logger != nil {
+		logger.Debugf("Loading chart %s release file %s for target %s in repository %s", chartName, file, target.Key(), repoName)
+	}
+	err := utils.LoadStructureByType(file, &release, repositoryFormatExtension)
+	if err != nil {
+		return nil, err
+	}
+	return &release, nil
+}
+
+// Save the chart release state recorded on a target
+func saveChartRelease(dataFolder string, logger log.Logger, repoName string, release model.ChartRelease) error {
+	var file = getChartReleaseFile(dataFolder, repoName, release.Target, release.Chart, repositoryFormatExtension)
+	if logger != nil {
+		logger.Debugf("Saving chart %s release file %s for target %s in repository %s", release.Chart, file, release.Target.Key(), repoName)
+	}
+	var folder = fmt.Sprintf("%s%ccharts", getReleasesTargetFolder(dataFolder, repoName, release.Target), os.PathSeparator)
+	if !utils.ExistsFileOrFolder(folder) {
+		err := utils.CreateFolder(folder)
+		if err != nil {
+			return err
+		}
+	}
+	return utils.SaveStructureByType(file, &release, repositoryFormatExtension)
+}
+
+func getKubernetesFileReleaseFile(baseFolder string, repoName string, target model.DeployTarget, fileName string, extension utils.FormatType) string {
+	//repositoryKubeFileReleaseFileTemplate  = "%s%ckubefiles%c%s.%v"
+	return fmt.Sprintf(repositoryKubeFileReleaseFileTemplate, getReleasesTargetFolder(baseFolder, repoName, target), os.PathSeparator, os.PathSeparator, fileName, extension)
+}
+
+// Load the Kubernetes File release ledger recorded on a target, or a new empty ledger when the file has never been applied
+func loadKubernetesFileRelease(dataFolder string, logger log.Logger, repoName string, target model.DeployTarget, fileName string) (*model.KubernetesFileRelease, error) {
+	var file = getKubernetesFileReleaseFile(dataFolder, repoName, target, fileName, repositoryFormatExtension)
+	var release = model.KubernetesFileRelease{
+		Name:       fileName,
+		Repository: repoName,
+		Target:     target,
+		Resources:  make([]model.KubernetesResource, 0),
+		History:    make([]model.KubernetesFileReleaseRecord, 0),
+	}
+	if !utils.ExistsFileOrFolder(file) {
+		return &release, nil
+	}
+	if logger != nil {
+		logger.Debugf("Loading Kubernetes File %s release file %s for target %s in repository %s", fileName, file, target.Key(), repoName)
+	}
+	err := utils.LoadStructureByType(file, &release, repositoryFormatExtension)
+	if err != nil {
+		return nil, err
+	}
+	return &release, nil
+}
+
+// Save the Kubernetes File release ledger recorded on a target
+func saveKubernetesFileRelease(dataFolder string, logger log.Logger, repoName string, release model.KubernetesFileRelease) error {
+	var file = getKubernetesFileReleaseFile(dataFolder, repoName, release.Target, release.Name, repositoryFormatExtension)
+	if logger != nil {
+		logger.Debugf("Saving Kubernetes File %s release file %s for target %s in repository %s", release.Name, file, release.Target.Key(), repoName)
+	}
+	var folder = fmt.Sprintf("%s%ckubefiles", getReleasesTargetFolder(dataFolder, repoName, release.Target), os.PathSeparator)
+	if !utils.ExistsFileOrFolder(folder) {
+		err := utils.CreateFolder(folder)
+		if err != nil {
+			return err
+		}
+	}
+	return utils.SaveStructureByType(file, &release, repositoryFormatExtension)
+}
@@ -7,7 +7,9 @@ import (
 	"github.com/hellgate75/k8s-deploy/model"
 	"github.com/hellgate75/k8s-deploy/utils"
 	umodel "github.com/hellgate75/k8s-deploy/utils/model"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -666,7 +668,7 @@ func (s *repositoryStorageManager) ListRepositoryCharts(id string) ([]model.Char
 						return outList, errors.New(fmt.Sprintf("No repository id: %s contains folder instead file path: %s", id, file))
 					} else {
 						ext, err := utils.GetPathExtension(file)
-						if err == nil {
+						if err != nil {
 							if s.logger != nil {
 								s.logger.Errorf("Error recovering file %s extension for repository: %s, ", file, repo.Name)
 							}
@@ -684,7 +686,7 @@ func (s *repositoryStorageManager) ListRepositoryCharts(id string) ([]model.Char
 							Charts:   make([]model.ChartInfo, 0),
 						}
 						err = utils.LoadStructureByType(file, &chartsList, repositoryFormatExtension)
-						if err == nil {
+						if err != nil {
 							if s.logger != nil {
 								s.logger.Errorf("Error loading file %s for repository: %s, ", file, chartsList.RepoName)
 							}
@@ -738,7 +740,7 @@ func (s *repositoryStorageManager) ListRepositoryKubernetesFiles(id string) ([]m
 						return outList, errors.New(fmt.Sprintf("No repository id: %s contains folder instead file path: %s", id, file))
 					} else {
 						ext, err := utils.GetPathExtension(file)
-						if err == nil {
+						if err != nil {
 							if s.logger != nil {
 								s.logger.Errorf("Error recovering file %s extension for repository: %s, ", file, repo.Name)
 							}
@@ -756,7 +758,7 @@ func (s *repositoryStorageManager) ListRepositoryKubernetesFiles(id string) ([]m
 							Files:    make([]model.KubernetesFileInfo, 0),
 						}
 						err = utils.LoadStructureByType(file, &chartsList, repositoryFormatExtension)
-						if err == nil {
+						if err != nil {
 							if s.logger != nil {
 								s.logger.Errorf("Error loading file %s for repository: %s, ", file, chartsList.RepoName)
 							}
@@ -797,7 +799,7 @@ func (s *repositoryStorageManager) BackupRepository(id string, archiveFile strin
 		if repo.Id == id {
 			var folder = fmt.Sprintf(repositoryDetailsFolderTemplate, s.dataFolder, os.PathSeparator, os.PathSeparator, repo.Name)
 
-			if fs, errS := os.Stat(folder); err == nil {
+			if fs, errS := os.Stat(folder); errS == nil {
 				found = true
 				if !fs.IsDir() {
 					if s.logger != nil {
@@ -857,6 +859,9 @@ func (s *repositoryStorageManager) RestoreRepository(archiveFile string, useZipF
 		return err
 	}
 	uncompressed = true
+	defer func() {
+		_ = utils.DeleteFileOrFolder(tmpFolder)
+	}()
 	if useZipFormat {
 		if s.logger != nil {
 			s.logger.Warnf("Decompressing with zip format archive %s to folder %s", archiveFile, tmpFolder)
@@ -875,9 +880,82 @@ func (s *repositoryStorageManager) RestoreRepository(archiveFile string, useZipF
 	if !uncompressed {
 		return errors.New(fmt.Sprintf("Not able to restore archive: %s", archiveFile))
 	}
+	folder, err := findRepositoryRootFolder(tmpFolder)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Archive %s doesn't contain a valid repository, Error: %v", archiveFile, err))
+	}
+	var repository = model.Repository{}
+	err = utils.LoadStructureByType(filepath.Join(folder, fmt.Sprintf("index.%v", repositoryFormatExtension)), &repository, repositoryFormatExtension)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Archive %s contains an invalid repository index, Error: %v", archiveFile, err))
+	}
+	var repoName = utils.ConvertName(repository.Name)
+	if strings.TrimSpace(repoName) == "" {
+		return errors.New(fmt.Sprintf("Archive %s contains a repository without name", archiveFile))
+	}
+	repository.Name = repoName
+	// An existing repository keeps its identifier, a new one is created only when forced
+	var found = false
+	for _, ref := range s.repositories.Repositories {
+		if ref.Name == repoName {
+			found = true
+			repository.Id = ref.Id
+			break
+		}
+	}
+	if !found {
+		if !forceCreate {
+			return errors.New(fmt.Sprintf("Repository %s not present, restore with force create option to create it", repoName))
+		}
+		if repository.Id == "" || s.containsRepositoryId(repository.Id) {
+			repository.Id = utils.NewUniqueIdentifier()
+		}
+	}
+	var target = fmt.Sprintf(repositoryDetailsFolderTemplate, s.dataFolder, os.PathSeparator, os.PathSeparator, repoName)
+	if s.logger != nil {
+		s.logger.Warnf("Restoring repository %s from archive %s to folder %s", repoName, archiveFile, target)
+	}
+	err = utils.CleanCreateFolder(target)
+	if err != nil {
+		return err
+	}
+	err = copyFolderContent(folder, target)
+	if err != nil {
+		return err
+	}
+	err = saveRepository(s.dataFolder, s.logger, repoName, repository)
+	if err != nil {
+		return err
+	}
+	if !found {
+		s.repositories.Repositories = append(s.repositories.Repositories, model.RepositoryRef{
+			Id:   repository.Id,
+			Name: repository.Name,
+		})
+		s.repositories.Updated = time.Now()
+		err = s.savePoint()
+	}
 	return err
 }
 
+// Find the folder containing the repository index, in the given folder or in one of its first level sub-folders
+func findRepositoryRootFolder(folder string) (string, error) {
+	var index = fmt.Sprintf("index.%v", repositoryFormatExtension)
+	if utils.ExistsFileOrFolder(filepath.Join(folder, index)) {
+		return folder, nil
+	}
+	files, err := ioutil.ReadDir(folder)
+	if err != nil {
+		return "", err
+	}
+	for _, f := range files {
+		if f.IsDir() && utils.ExistsFileOrFolder(filepath.Join(folder, f.Name(), index)) {
+			return filepath.Join(folder, f.Name()), nil
+		}
+	}
+	return "", errors.New(fmt.Sprintf("No repository %s file found", index))
+}
+
 func (s *repositoryStorageManager) GetRepositoryChartsManager(id string) (model.RepositoryChartManager, error) {
 	r, err := s.GetRepositoryById(id)
 	if err != nil {
@@ -946,12 +1024,17 @@ func (s *repositoryStorageManager) SavePoint() error {
 		s.Unlock()
 	}()
 	s.Lock()
+	err = s.savePoint()
+	return err
+}
+
+// Saves the repositories index, the caller must hold the lock
+func (s *repositoryStorageManager) savePoint() error {
 	var file = fmt.Sprintf(repositoryIndexTemplate, s.dataFolder, os.PathSeparator, repositoryFormatExtension)
 	if utils.ExistsFileOrFolder(file) {
 		_ = utils.DeleteFileOrFolder(file)
 	}
-	err = utils.SaveStructureByType(file, s.repositories, repositoryFormatExtension)
-	return err
+	return utils.SaveStructureByType(file, s.repositories, repositoryFormatExtension)
 }
 
 func (s *repositoryStorageManager) Refresh() error {
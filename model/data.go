@@ -142,6 +142,10 @@ type JobsDataManager interface {
 	GetJobByName(name string) *Job
 	//Override existing job data in the Deploy
 	OverrideJob(id string, j Job) DataResponse
+	//Query over Deploy jobs
+	QueryJobs(q ...Query) DataResponse
+	//Change the state of a Deploy job, only allowed state transitions are accepted
+	UpdateJobState(id string, s State) DataResponse
 }
 
 // Represents the deploys data storage manager
@@ -166,6 +170,10 @@ type DeployDataManager interface {
 	AccessDeploy(d Deploy) *JobsDataManager
 	// Override existing Deploy selecting by Id
 	OverrideDeploy(id string, d Deploy) DataResponse
+	// Query over Deploys
+	QueryDeploys(q ...Query) DataResponse
+	// Change the state of a Deploy selecting by id, only allowed state transitions are accepted
+	UpdateDeployState(id string, s State) DataResponse
 }
 
 // Represents the global data storage manager
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"time"
+)
+
+const (
+	// Job instance parameter selecting the repository containing the job chart or Kubernetes file
+	JobParamRepository = "repository"
+	// Job instance parameter selecting the deploy target namespace
+	JobParamNamespace = "namespace"
+	// Job instance parameter selecting the deploy target kubeconfig file
+	JobParamKubeConfig = "kubeConfig"
+	// Job instance parameter selecting the deploy target kubeconfig context
+	JobParamContext = "context"
+	// Job instance parameter forcing resources update on upgrade
+	JobParamForce = "force"
+)
+
+// Describes a job execution, run by the executor. Rollback marks the executions rolling back a completed job
+type JobExecution struct {
+	DeployId   string    `yaml:"deployId" json:"deployId" xml:"deploy-id"`
+	DeployName string    `yaml:"deployName" json:"deployName" xml:"deploy-name"`
+	JobId      string    `yaml:"jobId" json:"jobId" xml:"job-id"`
+	JobName    string    `yaml:"jobName" json:"jobName" xml:"job-name"`
+	Repository string    `yaml:"repository" json:"repository" xml:"repository"`
+	Document   string    `yaml:"document" json:"document" xml:"document"`
+	Version    string    `yaml:"version" json:"version" xml:"version"`
+	IsChart    bool      `yaml:"isChart" json:"isChart" xml:"is-chart"`
+	Target     string    `yaml:"target" json:"target" xml:"target"`
+	State      State     `yaml:"state" json:"state" xml:"state"`
+	Rollback   bool      `yaml:"rollback,omitempty" json:"rollback,omitempty" xml:"rollback,omitempty"`
+	Output     string    `yaml:"output,omitempty" json:"output,omitempty" xml:"output,omitempty"`
+	Message    string    `yaml:"message,omitempty" json:"message,omitempty" xml:"message,omitempty"`
+	Started    time.Time `yaml:"started" json:"started" xml:"started"`
+	Finished   time.Time `yaml:"finished" json:"finished" xml:"finished"`
+}
+
+// Represents the jobs executor, pulling the ready jobs from the scheduler and running them on the deploy targets
+type JobExecutor interface {
+	// Pull the ready jobs from the scheduler and execute them, collecting the number of executed jobs
+	Poll() (int, error)
+	// Roll back the completed jobs of a failed Deploy, collecting the number of rolled back jobs
+	Rollback(deployId string) (int, error)
+	// List the latest job executions, newest first
+	Executions() []JobExecution
+	// Start the periodic jobs polling
+	Start() error
+	// Stop the periodic jobs polling
+	Stop()
+	// Verify if the periodic jobs polling is running
+	IsRunning() bool
+}
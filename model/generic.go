@@ -61,12 +61,41 @@ const (
 	StateComplete State = "complete"
 	StateFailed   State = "failed"
 	StateRollback State = "rolled-back"
+	StateSkipped  State = "skipped"
 	StateDeleting State = "deleting"
 	StateDeleted  State = "deleted"
 	StatePurging  State = "purging"
 	StatePutged   State = "purged"
 )
 
+// Allowed state transitions, staying in the same state is always allowed
+var stateTransitions = map[State][]State{
+	StateCreated:  {StateReady, StateRunning, StateError, StateDeleting, StateDeleted},
+	StateReady:    {StateRunning, StateSkipped, StateError, StateDeleting, StateDeleted},
+	StateRunning:  {StateComplete, StateFailed, StateError},
+	StateComplete: {StateReady, StateRollback, StateDeleting, StateDeleted},
+	StateFailed:   {StateReady, StateRunning, StateRollback, StateDeleting, StateDeleted},
+	StateError:    {StateReady, StateRunning, StateDeleting, StateDeleted},
+	StateRollback: {StateReady, StateDeleting, StateDeleted},
+	StateSkipped:  {StateReady, StateDeleting, StateDeleted},
+	StateDeleting: {StateDeleted, StateError},
+	StateDeleted:  {StatePurging, StatePutged},
+	StatePurging:  {StatePutged, StateError},
+}
+
+// Verify if the state can change to the given state
+func (s State) CanTransitionTo(t State) bool {
+	if s == t {
+		return true
+	}
+	for _, st := range stateTransitions[s] {
+		if st == t {
+			return true
+		}
+	}
+	return false
+}
+
 type QueryItem struct {
 	Key        string     `yaml:"key" json:"key" xml:"key"`
 	Value      string     `yaml:"value" json:"value" xml:"value"`
@@ -138,6 +167,8 @@ type Version struct {
 	Id    string `yaml:"id" json:"id" xml:"id"`
 	Name  string `yaml:"name" json:"name" xml:"name"`
 	State State  `yaml:"state" json:"state" xml:"state"`
+	// Sha256 digest of the stored package, only for chart versions
+	Digest string `yaml:"digest,omitempty" json:"digest,omitempty" xml:"digest,omitempty"`
 }
 
 func (ver *Version) ToJson() (string, error) {
@@ -492,7 +523,7 @@ type Project struct {
 	Id       string           `yaml:"id" json:"id" xml:"id"`
 	Name     string           `yaml:"name" json:"name" xml:"name"`
 	Version  string           `yaml:"version" json:"version" xml:"version"`
-	Versions []ProjectVersion `yaml:"versions" json:"versions" xml:"version"`
+	Versions []ProjectVersion `yaml:"versions" json:"versions" xml:"project-version"`
 	State    State            `yaml:"state" json:"state" xml:"state"`
 	ReadOnly bool             `yaml:"readOnly" json:"readOnly" xml:"read-only"`
 }
@@ -709,6 +740,7 @@ type Job struct {
 	VersionId  string   `yaml:"versionId" json:"versionId" xml:"version-id"`
 	DocumentId string   `yaml:"documentId" json:"documentId" xml:"document-id"`
 	IsChart    bool     `yaml:"isChart" json:"isChart" xml:"is-chart"`
+	DependsOn  []string `yaml:"dependsOn,omitempty" json:"dependsOn,omitempty" xml:"depends-on,omitempty"`
 	State      State    `yaml:"state" json:"state" xml:"state"`
 	Instance   Instance `yaml:"instance" json:"instance" xml:"instance"`
 }
@@ -738,10 +770,12 @@ func (j *Job) LoadJson(path string) error {
 }
 
 type Deploy struct {
-	Id    string `yaml:"id" json:"id" xml:"id"`
-	Name  string `yaml:"name" json:"name" xml:"name"`
-	Job   []Job  `yaml:"jobs" json:"jobs" xml:"job"`
-	State State  `yaml:"state" json:"state" xml:"state"`
+	Id       string         `yaml:"id" json:"id" xml:"id"`
+	Name     string         `yaml:"name" json:"name" xml:"name"`
+	Job      []Job          `yaml:"jobs" json:"jobs" xml:"job"`
+	State    State          `yaml:"state" json:"state" xml:"state"`
+	Trigger  DeployTrigger  `yaml:"trigger" json:"trigger" xml:"trigger"`
+	Rollback RollbackPolicy `yaml:"rollback,omitempty" json:"rollback,omitempty" xml:"rollback,omitempty"`
 }
 
 func (d *Deploy) ToJson() (string, error) {
@@ -1,6 +1,10 @@
 package model
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 type RepositoryRef struct {
 	Id   string
@@ -22,3 +26,255 @@ type KubernetesFileList struct {
 	RepoName string               `yaml:"repository" json:"repository" xml:"repository"`
 	Files    []KubernetesFileInfo `yaml:"files" json:"files" xml:"file"`
 }
+
+// Describes a single problem found verifying a chart or a Kubernetes file.
+// Document is the 1-based index of the multi-document manifest entry, zero when not related to a document
+type VerificationIssue struct {
+	Document int    `yaml:"document,omitempty" json:"document,omitempty" xml:"document,omitempty"`
+	Path     string `yaml:"path" json:"path" xml:"path"`
+	Message  string `yaml:"message" json:"message" xml:"message"`
+}
+
+// Describes the outcome of a chart or a Kubernetes file verification, listing all problems found
+type VerificationReport struct {
+	Name    string              `yaml:"name" json:"name" xml:"name"`
+	Version string              `yaml:"version" json:"version" xml:"version"`
+	Issues  []VerificationIssue `yaml:"issues" json:"issues" xml:"issue"`
+}
+
+// Add a new problem to the report
+func (r *VerificationReport) AddIssue(path string, format string, in ...interface{}) {
+	r.Issues = append(r.Issues, VerificationIssue{
+		Path:    path,
+		Message: fmt.Sprintf(format, in...),
+	})
+}
+
+// Add a new problem related to a document of a multi-document manifest to the report
+func (r *VerificationReport) AddDocumentIssue(document int, path string, format string, in ...interface{}) {
+	r.Issues = append(r.Issues, VerificationIssue{
+		Document: document,
+		Path:     path,
+		Message:  fmt.Sprintf(format, in...),
+	})
+}
+
+// Collect the problems related to a document of a multi-document manifest
+func (r *VerificationReport) DocumentIssues(document int) []VerificationIssue {
+	var out = make([]VerificationIssue, 0)
+	for _, issue := range r.Issues {
+		if issue.Document == document {
+			out = append(out, issue)
+		}
+	}
+	return out
+}
+
+// Verify if the report contains any problem
+func (r *VerificationReport) Valid() bool {
+	return len(r.Issues) == 0
+}
+
+func (r *VerificationReport) Error() string {
+	var messages = make([]string, 0)
+	for _, issue := range r.Issues {
+		if issue.Document > 0 {
+			messages = append(messages, fmt.Sprintf("document #%d %s: %s", issue.Document, issue.Path, issue.Message))
+		} else {
+			messages = append(messages, fmt.Sprintf("%s: %s", issue.Path, issue.Message))
+		}
+	}
+	return fmt.Sprintf("%s version %s has %v problem(s): %s", r.Name, r.Version, len(r.Issues), strings.Join(messages, "; "))
+}
+
+// Describes a deploy target: the kubeconfig file, the kubeconfig context and the namespace
+type DeployTarget struct {
+	KubeConfig string `yaml:"kubeConfig,omitempty" json:"kubeConfig,omitempty" xml:"kube-config,omitempty"`
+	Context    string `yaml:"context,omitempty" json:"context,omitempty" xml:"context,omitempty"`
+	Namespace  string `yaml:"namespace" json:"namespace" xml:"namespace"`
+}
+
+// Unique key of the target, composed by kubeconfig context and namespace
+func (t DeployTarget) Key() string {
+	var context = strings.TrimSpace(t.Context)
+	if context == "" {
+		context = "default"
+	}
+	var namespace = strings.TrimSpace(t.Namespace)
+	if namespace == "" {
+		namespace = "default"
+	}
+	return strings.NewReplacer("/", "-", "\\", "-", ":", "-", " ", "-").Replace(context + "_" + namespace)
+}
+
+const (
+	ChartReleaseInstall   = "install"
+	ChartReleaseUpgrade   = "upgrade"
+	ChartReleaseUninstall = "uninstall"
+	ChartReleaseRollback  = "rollback"
+)
+
+// Describes a single action executed on a chart release, failed actions have no revision number
+type ChartReleaseRevision struct {
+	Revision int       `yaml:"revision" json:"revision" xml:"revision"`
+	Action   string    `yaml:"action" json:"action" xml:"action"`
+	Version  Version   `yaml:"version" json:"version" xml:"version"`
+	Force    bool      `yaml:"force,omitempty" json:"force,omitempty" xml:"force,omitempty"`
+	Date     time.Time `yaml:"date" json:"date" xml:"date"`
+	Message  string    `yaml:"message,omitempty" json:"message,omitempty" xml:"message,omitempty"`
+}
+
+// Describes the locally recorded state of a chart release on a deploy target
+type ChartRelease struct {
+	Name       string                 `yaml:"name" json:"name" xml:"name"`
+	Chart      string                 `yaml:"chart" json:"chart" xml:"chart"`
+	Repository string                 `yaml:"repository" json:"repository" xml:"repository"`
+	Target     DeployTarget           `yaml:"target" json:"target" xml:"target"`
+	Current    Version                `yaml:"current" json:"current" xml:"current"`
+	Revisions  []ChartReleaseRevision `yaml:"revisions" json:"revisions" xml:"revision"`
+}
+
+// Verify if the release has a version currently installed
+func (r *ChartRelease) IsInstalled() bool {
+	return r.Current.Name != "" && r.Current.State == StateReady
+}
+
+// Get the latest successful release revision number, revisions restart after an uninstall
+func (r *ChartRelease) LastRevision() int {
+	var last = 0
+	for _, rev := range r.Revisions {
+		if rev.Action == ChartReleaseUninstall && rev.Version.State == StateDeleted {
+			last = 0
+		} else if rev.Revision > last {
+			last = rev.Revision
+		}
+	}
+	return last
+}
+
+// Get the successful release revision preceding the latest one, if any. Revisions before an uninstall are not considered
+func (r *ChartRelease) PreviousRevision() (ChartReleaseRevision, bool) {
+	var last = r.LastRevision()
+	var previous ChartReleaseRevision
+	var found = false
+	for _, rev := range r.Revisions {
+		if rev.Action == ChartReleaseUninstall && rev.Version.State == StateDeleted {
+			found = false
+		} else if rev.Version.State == StateReady && rev.Revision > 0 && rev.Revision < last && (!found || rev.Revision > previous.Revision) {
+			previous = rev
+			found = true
+		}
+	}
+	return previous, found
+}
+
+const (
+	KubernetesFileReleaseInstall   = "install"
+	KubernetesFileReleaseUpgrade   = "upgrade"
+	KubernetesFileReleaseUninstall = "uninstall"
+	KubernetesFileReleaseRollback  = "rollback"
+)
+
+// Describes a Kubernetes resource declared in a manifest document
+type KubernetesResource struct {
+	ApiVersion string `yaml:"apiVersion" json:"apiVersion" xml:"api-version"`
+	Kind       string `yaml:"kind" json:"kind" xml:"kind"`
+	Namespace  string `yaml:"namespace,omitempty" json:"namespace,omitempty" xml:"namespace,omitempty"`
+	Name       string `yaml:"name" json:"name" xml:"name"`
+}
+
+// Unique key of the resource, composed by api group, kind, namespace and name
+func (r KubernetesResource) Key() string {
+	var group = r.ApiVersion
+	if idx := strings.LastIndex(group, "/"); idx >= 0 {
+		group = group[:idx]
+	} else {
+		group = ""
+	}
+	return fmt.Sprintf("%s/%s/%s/%s", group, r.Kind, r.Namespace, r.Name)
+}
+
+// Describes a single action executed on a Kubernetes File release, with the rendered manifest applied by the action
+type KubernetesFileReleaseRecord struct {
+	Action    string               `yaml:"action" json:"action" xml:"action"`
+	Version   Version              `yaml:"version" json:"version" xml:"version"`
+	Digest    string               `yaml:"digest,omitempty" json:"digest,omitempty" xml:"digest,omitempty"`
+	Manifest  string               `yaml:"manifest,omitempty" json:"manifest,omitempty" xml:"manifest,omitempty"`
+	Resources []KubernetesResource `yaml:"resources,omitempty" json:"resources,omitempty" xml:"resource,omitempty"`
+	Date      time.Time            `yaml:"date" json:"date" xml:"date"`
+	Message   string               `yaml:"message,omitempty" json:"message,omitempty" xml:"message,omitempty"`
+}
+
+// Describes the ledger of a Kubernetes File on a deploy target: the applied version,
+// the rendered manifest sha256 digest, the created resources and the history of the actions
+type KubernetesFileRelease struct {
+	Name       string                        `yaml:"name" json:"name" xml:"name"`
+	Repository string                        `yaml:"repository" json:"repository" xml:"repository"`
+	Target     DeployTarget                  `yaml:"target" json:"target" xml:"target"`
+	Current    Version                       `yaml:"current" json:"current" xml:"current"`
+	Digest     string                        `yaml:"digest,omitempty" json:"digest,omitempty" xml:"digest,omitempty"`
+	Resources  []KubernetesResource          `yaml:"resources" json:"resources" xml:"resource"`
+	Updated    time.Time                     `yaml:"updated" json:"updated" xml:"updated"`
+	History    []KubernetesFileReleaseRecord `yaml:"history" json:"history" xml:"history"`
+}
+
+// Verify if the Kubernetes File has a version currently applied
+func (r *KubernetesFileRelease) IsInstalled() bool {
+	return r.Current.Name != "" && r.Current.State == StateReady
+}
+
+// Get the version applied before the current one, if any. Versions applied before an uninstall are not considered
+func (r *KubernetesFileRelease) PreviousVersion() (Version, bool) {
+	record, ok := r.PreviousRecord()
+	return record.Version, ok
+}
+
+// Get the ledger record of the version applied before the current one, if any. Versions applied before an uninstall are not considered
+func (r *KubernetesFileRelease) PreviousRecord() (KubernetesFileReleaseRecord, bool) {
+	var found = false
+	for i := len(r.History) - 1; i >= 0; i-- {
+		var record = r.History[i]
+		if record.Action == KubernetesFileReleaseUninstall && record.Version.State == StateDeleted {
+			break
+		}
+		if record.Version.State != StateReady || record.Action == KubernetesFileReleaseUninstall {
+			continue
+		}
+		if !found {
+			found = record.Version.Name == r.Current.Name
+			if found {
+				continue
+			}
+		}
+		if found && record.Version.Name != r.Current.Name {
+			return record, true
+		}
+	}
+	return KubernetesFileReleaseRecord{}, false
+}
+
+// Describes a chart version entry of a Helm chart repository index, the urls are relative to the repository url
+type HelmIndexEntry struct {
+	ApiVersion  string    `yaml:"apiVersion" json:"apiVersion" xml:"api-version"`
+	Name        string    `yaml:"name" json:"name" xml:"name"`
+	Version     string    `yaml:"version" json:"version" xml:"version"`
+	AppVersion  string    `yaml:"appVersion,omitempty" json:"appVersion,omitempty" xml:"app-version,omitempty"`
+	KubeVersion string    `yaml:"kubeVersion,omitempty" json:"kubeVersion,omitempty" xml:"kube-version,omitempty"`
+	Description string    `yaml:"description,omitempty" json:"description,omitempty" xml:"description,omitempty"`
+	Type        string    `yaml:"type,omitempty" json:"type,omitempty" xml:"type,omitempty"`
+	Keywords    []string  `yaml:"keywords,omitempty" json:"keywords,omitempty" xml:"keyword,omitempty"`
+	Home        string    `yaml:"home,omitempty" json:"home,omitempty" xml:"home,omitempty"`
+	Sources     []string  `yaml:"sources,omitempty" json:"sources,omitempty" xml:"source,omitempty"`
+	Icon        string    `yaml:"icon,omitempty" json:"icon,omitempty" xml:"icon,omitempty"`
+	Deprecated  bool      `yaml:"deprecated,omitempty" json:"deprecated,omitempty" xml:"deprecated,omitempty"`
+	Created     time.Time `yaml:"created" json:"created" xml:"created"`
+	Digest      string    `yaml:"digest" json:"digest" xml:"digest"`
+	Urls        []string  `yaml:"urls" json:"urls" xml:"url"`
+}
+
+// Describes a Helm chart repository index.yaml file, entries are grouped by chart name
+type HelmIndex struct {
+	ApiVersion string                      `yaml:"apiVersion" json:"apiVersion" xml:"api-version"`
+	Entries    map[string][]HelmIndexEntry `yaml:"entries" json:"entries" xml:"-"`
+	Generated  time.Time                   `yaml:"generated" json:"generated" xml:"generated"`
+}
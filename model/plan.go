@@ -0,0 +1,134 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Describes a job in a Deploy execution plan, dependencies are resolved to job instance names
+type PlanJob struct {
+	JobId     string   `yaml:"jobId" json:"jobId" xml:"job-id"`
+	JobName   string   `yaml:"jobName" json:"jobName" xml:"job-name"`
+	IsChart   bool     `yaml:"isChart" json:"isChart" xml:"is-chart"`
+	DependsOn []string `yaml:"dependsOn" json:"dependsOn" xml:"depends-on"`
+	State     State    `yaml:"state" json:"state" xml:"state"`
+}
+
+// Describes a Deploy execution plan stage, the jobs depend only on jobs of the previous stages so they can run in parallel
+type PlanStage struct {
+	Stage int       `yaml:"stage" json:"stage" xml:"stage"`
+	Jobs  []PlanJob `yaml:"jobs" json:"jobs" xml:"job"`
+}
+
+// Describes a Deploy execution plan: the jobs sorted in topological order, grouped by stage
+type DeployPlan struct {
+	DeployId   string      `yaml:"deployId" json:"deployId" xml:"deploy-id"`
+	DeployName string      `yaml:"deployName" json:"deployName" xml:"deploy-name"`
+	Stages     []PlanStage `yaml:"stages" json:"stages" xml:"stage"`
+}
+
+// Computes the Deploy execution plan, sorting the jobs in dependency stages. Dependencies refer jobs by instance
+// name or id, duplicate job names, self dependencies, unknown dependencies and dependency cycles are reported as error
+func BuildDeployPlan(d Deploy) (*DeployPlan, error) {
+	var names = make(map[string]string)
+	var unique = make(map[string]bool)
+	for _, j := range d.Job {
+		if unique[j.Instance.Name] {
+			return nil, errors.New(fmt.Sprintf("Duplicate job name %s, job names must be unique in a Deploy", j.Instance.Name))
+		}
+		unique[j.Instance.Name] = true
+		names[j.Instance.Name] = j.Instance.Name
+		if j.Instance.Id != "" {
+			names[j.Instance.Id] = j.Instance.Name
+		}
+	}
+	var jobs = make([]PlanJob, 0)
+	for _, j := range d.Job {
+		var pj = PlanJob{
+			JobId:     j.Instance.Id,
+			JobName:   j.Instance.Name,
+			IsChart:   j.IsChart,
+			DependsOn: make([]string, 0),
+			State:     j.State,
+		}
+		var seen = make(map[string]bool)
+		for _, dep := range j.DependsOn {
+			name, ok := names[strings.TrimSpace(dep)]
+			if !ok {
+				return nil, errors.New(fmt.Sprintf("Job %s depends on unknown job %s", j.Instance.Name, dep))
+			}
+			if name == j.Instance.Name {
+				return nil, errors.New(fmt.Sprintf("Job %s depends on itself", j.Instance.Name))
+			}
+			if !seen[name] {
+				seen[name] = true
+				pj.DependsOn = append(pj.DependsOn, name)
+			}
+		}
+		jobs = append(jobs, pj)
+	}
+	var stages = make(map[string]int)
+	for len(stages) < len(jobs) {
+		var progress = false
+		for _, pj := range jobs {
+			if _, ok := stages[pj.JobName]; ok {
+				continue
+			}
+			var stage = 0
+			var resolved = true
+			for _, dep := range pj.DependsOn {
+				s, ok := stages[dep]
+				if !ok {
+					resolved = false
+					break
+				}
+				if s+1 > stage {
+					stage = s + 1
+				}
+			}
+			if resolved {
+				stages[pj.JobName] = stage
+				progress = true
+			}
+		}
+		if !progress {
+			var cycle = make([]string, 0)
+			for _, pj := range jobs {
+				if _, ok := stages[pj.JobName]; !ok {
+					cycle = append(cycle, pj.JobName)
+				}
+			}
+			sort.Strings(cycle)
+			return nil, errors.New(fmt.Sprintf("Dependency cycle between jobs: %s", strings.Join(cycle, ", ")))
+		}
+	}
+	var plan = DeployPlan{
+		DeployId:   d.Id,
+		DeployName: d.Name,
+		Stages:     make([]PlanStage, 0),
+	}
+	for _, pj := range jobs {
+		var stage = stages[pj.JobName]
+		for len(plan.Stages) <= stage {
+			plan.Stages = append(plan.Stages, PlanStage{
+				Stage: len(plan.Stages),
+				Jobs:  make([]PlanJob, 0),
+			})
+		}
+		plan.Stages[stage].Jobs = append(plan.Stages[stage].Jobs, pj)
+	}
+	return &plan, nil
+}
+
+// Collects the plan jobs dependencies, by job instance name
+func (p *DeployPlan) Dependencies() map[string][]string {
+	var deps = make(map[string][]string)
+	for _, s := range p.Stages {
+		for _, j := range s.Jobs {
+			deps[j.JobName] = j.DependsOn
+		}
+	}
+	return deps
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildDeployPlanRejectsDuplicateJobNames(t *testing.T) {
+	var d = Deploy{
+		Name: "deploy",
+		Job: []Job{
+			{IsChart: true, Instance: Instance{Id: "1", Name: "app-web"}},
+			{IsChart: false, Instance: Instance{Id: "2", Name: "app-web"}},
+		},
+	}
+	_, err := BuildDeployPlan(d)
+	if err == nil || !strings.Contains(err.Error(), "Duplicate job name app-web") {
+		t.Fatalf("Expected duplicate job name error, found: %v", err)
+	}
+	d.Job[1].Instance.Name = "app-web-kubefile"
+	d.Job[1].DependsOn = []string{"app-web"}
+	plan, err := BuildDeployPlan(d)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(plan.Stages) != 2 || plan.Stages[1].Jobs[0].JobName != "app-web-kubefile" {
+		t.Fatalf("Unexpected plan: %+v", plan.Stages)
+	}
+}
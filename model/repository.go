@@ -48,7 +48,7 @@ type RepositoryStorageManager interface {
 
 //Describes the Repository Charts Manager interface
 type RepositoryChartManager interface {
-	//Verify presence and correctness of a chart
+	//Verify presence and correctness of a chart, any found problem is reported via a *VerificationReport error
 	VerifyChart(name string, version string) error
 	//Install chart version via archive
 	InstallChart(name string, version string, archive string, zipArchive bool) error
@@ -64,21 +64,39 @@ type RepositoryChartManager interface {
 	GetChartVersions(name string) ([]Version, error)
 	// Collects project versions of a Chart, ready for job scheduling
 	GetChartProjectVersions(name string) ([]ProjectChart, error)
-	// Execute deploy of a chart and collects the output
+	// Get the chart version packaged as Helm chart archive (tar/g-zip), packaging is reproducible
+	GetChartVersionPackage(name string, version string) ([]byte, error)
+	// Get the Helm chart repository index of all available chart versions
+	GetHelmRepositoryIndex() (*HelmIndex, error)
+	// Get a Helm chart archive by package file name (<name>-<version>.tgz), resolved against the stored chart versions
+	GetHelmPackage(fileName string) ([]byte, error)
+	// Collects the latest stable (not pre-release) version of a Chart
+	GetLatestChartVersion(name string) (Version, error)
+	// Collects the latest version of a Chart matching a semantic version constraint (e.g.: ^1.2, ~2.0.x, >=1.0 <2.0)
+	GetLatestChartVersionMatching(name string, constraint string) (Version, error)
+	// Set the deploy target (kubeconfig, context and namespace) used by the deploy operations
+	SetDeployTarget(target DeployTarget)
+	// Get the deploy target used by the deploy operations
+	GetDeployTarget() DeployTarget
+	// Execute deploy of a chart and collects the output, the installed version is recorded for the deploy target
 	DeployInstallChart(name string, version string, values ValueSet) (string, error)
 	// Execute upgrade of a chart and collects the output
 	DeployUpgradeChart(name string, version string, values ValueSet, force bool) (string, error)
-	// Verify and return chart version, or an error in case chart is not installed
+	// Verify and return chart version installed on the deploy target, or an error in case chart is not installed
 	GetInstalledChartVersion(name string) (Version, error)
 	// Get isntalled Chart version details
 	GetInstalledChartVersionDetails(name string, version string) (Version, error)
+	// Get the release state of a chart on the deploy target: installed version and revisions history
+	GetChartRelease(name string) (*ChartRelease, error)
+	// Execute rollback of a chart to the revision preceding the latest one and collects the output, an error is reported when there is no previous revision
+	DeployRollbackChart(name string, force bool) (string, error)
 	// Un-deploy installed chart, and collects latest installed version
 	UnDeployInstalledChart(name string) (Version, error)
 }
 
 //Describes the Repository Kubernetes yaml files Manager interface
 type RepositoryKubernetesFilesManager interface {
-	//Verify presence and correctness of a Kubernetes yaml file
+	//Verify presence and correctness of a Kubernetes yaml file, any found problem is reported per document via a *VerificationReport error
 	VerifyKubernetesFile(name string, version string) error
 	//Install Kubernetes yaml file version via file
 	InstallKubernetesFile(name string, version string, file string) error
@@ -88,20 +106,37 @@ type RepositoryKubernetesFilesManager interface {
 	DeleteEntireKubernetesFile(name string, version string) error
 	// Get a yaml template build from a Kubernetes yaml file version
 	GetKubernetesFileVersionTemplate(name string, version string) (string, error)
-	// Update and existing Kubernetes yaml file, or createa new Kubernetes yaml file deployment
+	// Get a yaml template build from a Kubernetes yaml file version, expanding ${VAR} and {{ .Values.x }} placeholders
+	// with given values, using project variables defaults for missing values. Unresolved placeholders are reported as error
+	GetKubernetesFileVersionTemplateWithValues(name string, version string, values ValueSet, variables []Variable) (string, error)
+	// Add a new version to an existing Kubernetes yaml file, stored versions are immutable
 	UpdateExistingKubernetesFile(name string, version string, file string) error
 	// Collects versions of a Kubernetes yaml file
 	GetKubernetesFileVersions(name string) ([]Version, error)
 	// Collects project versions of a Kubernetes yaml file, ready for job scheduling
 	GetKubernetesFileProjectVersions(name string) ([]ProjectKubeFile, error)
-	// Execute deploy of a Kubernetes yaml file and collects the output
+	// Set the deploy target (kubeconfig, context and namespace) used by the deploy operations
+	SetDeployTarget(target DeployTarget)
+	// Get the deploy target used by the deploy operations
+	GetDeployTarget() DeployTarget
+	// Get the release ledger of a Kubernetes yaml file on the deploy target: applied version, rendered manifest digest and created resources
+	GetKubernetesFileRelease(name string) (*KubernetesFileRelease, error)
+	// Execute deploy of a Kubernetes yaml file and collects the output, the applied version is recorded in the deploy target ledger
 	DeployInstallKubernetesFile(name string, version string) (string, error)
-	// Execute upgrade of a Kubernetes yaml file and collects the output
+	// Execute upgrade of a Kubernetes yaml file and collects the output, resources not declared anymore are deleted
 	DeployUpgradeKubernetesFile(name string, version string, force bool) (string, error)
+	// Execute deploy of a Kubernetes yaml file, expanding placeholders with given values and project variables defaults, and collects the output
+	DeployInstallKubernetesFileWithValues(name string, version string, values ValueSet, variables []Variable) (string, error)
+	// Execute upgrade of a Kubernetes yaml file, expanding placeholders with given values and project variables defaults, and collects the output
+	DeployUpgradeKubernetesFileWithValues(name string, version string, values ValueSet, variables []Variable, force bool) (string, error)
+	// Execute rollback of a Kubernetes yaml file to the version applied before the current one and collects the output, an error is reported when there is no previous version
+	DeployRollbackKubernetesFile(name string) (string, error)
+	// Execute rollback of a Kubernetes yaml file to the version applied before the current one, re-applying the manifest recorded on the target, and collects the output. Given values and project variables defaults expand placeholders only when no manifest was recorded
+	DeployRollbackKubernetesFileWithValues(name string, values ValueSet, variables []Variable) (string, error)
 	// Verify and return Kubernetes yaml file version, or an error in case Kubernetes yaml file is not installed
 	GetInstalledKubernetesFileVersion(name string) (Version, error)
 	// Get installed Kubernetes yaml file version details
 	GetInstalledKubernetesFileVersionDetails(name string, version string) (Version, error)
-	// Un-deploy installed Kubernetes yaml file, and collects latest installed version
+	// Un-deploy installed Kubernetes yaml file deleting the recorded resources, and collects latest installed version
 	UnDeployInstalledKubernetesFile(name string) (Version, error)
 }
@@ -12,7 +12,7 @@ const (
 	DefaultDatabaseNamePrefix  		string = "default-k8s-deploy"
 	DefaultRepositoryStorageFolder  string = "/var/k8s-deploy/k8srepo"
 	DefaultSchedulerStorageFolder   string = "/var/k8s-deploy/scheduler"
-	DefaultExecutorStorageFolder    string = "/var/k8s-deploy/scheduler"
+	DefaultExecutorStorageFolder    string = "/var/k8s-deploy/executor"
 	DefaultConfigFolder             string = "/etc/k8s-deploy"
 	DefaultLogFileFolder            string = "/var/log/k8s-deploy"
 	DefaultLogFileLevel             string = "DEBUG"
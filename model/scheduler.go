@@ -0,0 +1,86 @@
+package model
+
+import (
+	"time"
+)
+
+type TriggerType string
+
+const (
+	// Deploy is queued until it's explicitly triggered
+	TriggerTypeManual TriggerType = "manual"
+	// Deploy is queued until the trigger time
+	TriggerTypeTime TriggerType = "time"
+	// Deploy stays queued and runs a copy of itself at any occurrence of the cron expression
+	TriggerTypeCron TriggerType = "cron"
+)
+
+type RollbackPolicy string
+
+const (
+	// Completed jobs of a failed Deploy are rolled back by the executor, default policy
+	RollbackPolicyAuto RollbackPolicy = "auto"
+	// Completed jobs of a failed Deploy are rolled back on request
+	RollbackPolicyManual RollbackPolicy = "manual"
+	// Completed jobs of a failed Deploy are never rolled back
+	RollbackPolicyNone RollbackPolicy = "none"
+)
+
+// Verify the rollback policy is known, an empty policy stands for the default auto policy
+func (p RollbackPolicy) IsValid() bool {
+	switch p {
+	case "", RollbackPolicyAuto, RollbackPolicyManual, RollbackPolicyNone:
+		return true
+	}
+	return false
+}
+
+// Describes a weekly or daily period when no Deploy is released, in the trigger timezone.
+// Weekly bounds have format "<weekday> HH:MM" (e.g.: "Fri 16:00"), daily bounds have format "HH:MM"
+type BlackoutWindow struct {
+	From string `yaml:"from" json:"from" xml:"from"`
+	To   string `yaml:"to" json:"to" xml:"to"`
+}
+
+// Describes when a Deploy leaves the scheduler queue, becoming ready for execution.
+// Time triggers falling in a blackout window are postponed to the window end, cron occurrences falling in a
+// blackout window are skipped. Next is the persisted due time, Last is the latest cron occurrence fired
+type DeployTrigger struct {
+	Type      TriggerType      `yaml:"type" json:"type" xml:"type"`
+	At        time.Time        `yaml:"at" json:"at" xml:"at"`
+	Cron      string           `yaml:"cron,omitempty" json:"cron,omitempty" xml:"cron,omitempty"`
+	Timezone  string           `yaml:"timezone,omitempty" json:"timezone,omitempty" xml:"timezone,omitempty"`
+	Blackouts []BlackoutWindow `yaml:"blackouts,omitempty" json:"blackouts,omitempty" xml:"blackout,omitempty"`
+	Next      time.Time        `yaml:"next" json:"next" xml:"next"`
+	Last      time.Time        `yaml:"last" json:"last" xml:"last"`
+}
+
+// Describes a Deploy waiting in the scheduler queue
+type QueueItem struct {
+	DeployId   string      `yaml:"deployId" json:"deployId" xml:"deploy-id"`
+	DeployName string      `yaml:"deployName" json:"deployName" xml:"deploy-name"`
+	Trigger    TriggerType `yaml:"trigger" json:"trigger" xml:"trigger"`
+	DueTime    time.Time   `yaml:"dueTime" json:"dueTime" xml:"due-time"`
+	Jobs       int         `yaml:"jobs" json:"jobs" xml:"jobs"`
+}
+
+// Represents the Deploy scheduler, expanding Deploy definitions into jobs and queueing them for execution
+type DeployScheduler interface {
+	// Expand the Deploy jobs from the project versions and store the Deploy in the queue
+	Submit(d Deploy) (*Deploy, error)
+	// Expand a job definition from the project version and add the resulting jobs to an existing Deploy
+	AddJobs(deployId string, j Job) ([]Job, error)
+	// Trigger immediately a queued Deploy, the Deploy and its jobs become ready for execution
+	Trigger(deployId string) (*Deploy, error)
+	// List the queued Deploys, sorted by due time, Deploys waiting for a manual trigger come last
+	Queue() ([]QueueItem, error)
+	// List the next planned executions of a queued Deploy, or of all the queued Deploys when the Deploy id is empty,
+	// sorted by due time
+	Planned(deployId string, count int) ([]QueueItem, error)
+	// Start the queue processing
+	Start() error
+	// Stop the queue processing
+	Stop()
+	// Verify if the queue processing is running
+	IsRunning() bool
+}
@@ -10,37 +10,60 @@ import (
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
 	"net/http"
+	"strings"
+	"time"
 )
 
 type ResponseHandler func(code int, message string, mdiaType common.MediaType,
 	content []byte)
 
-type ApiClient interface{}
+// Describes a Rest Api client, sending requests to a base url
+type ApiClient interface {
+	// Send a request to the given path, encoding the body accordingly to the content type (no body when nil),
+	// and pass the response to the response handler
+	Send(method common.WebMethod, path string,
+		acceptType common.MediaType, contentType common.MediaType,
+		body interface{}, respnseHandler ResponseHandler) error
+	// Encode an element accordingly to the media type
+	EncodeElement(mType common.MediaType, body interface{}) ([]byte, error)
+	// Decode a content accordingly to the media type
+	DecodeElement(mType common.MediaType, content []byte, body interface{}) (interface{}, error)
+}
 
 type apiClient struct {
 	baseUrl string
+	client  *http.Client
 }
 
 func (c *apiClient) Send(method common.WebMethod, path string,
 	acceptType common.MediaType, contentType common.MediaType,
 	body interface{}, respnseHandler ResponseHandler) error {
-	jsonValue, err := encodeMediaType(contentType, body)
+	var payload = make([]byte, 0)
+	if body != nil {
+		var err error
+		payload, err = encodeMediaType(contentType, body)
+		if err != nil {
+			return err
+		}
+	}
+	request, err := http.NewRequest(string(method), fmt.Sprintf("%s%s", c.baseUrl, path),
+		bytes.NewBuffer(payload))
 	if err != nil {
 		return err
 	}
-	request, _ := http.NewRequest("POST", fmt.Sprintf("%s%s", c.baseUrl, path),
-		bytes.NewBuffer(jsonValue))
-	if contentType != "" {
+	if contentType != "" && body != nil {
 		request.Header.Set("Content-Type", string(contentType))
 	}
 	if acceptType != "" {
-		request.Header.Set("Accepts", string(acceptType))
+		request.Header.Set("Accept", string(acceptType))
 	}
-	client := &http.Client{}
-	response, err := client.Do(request)
+	response, err := c.client.Do(request)
 	if err != nil {
 		return err
 	} else {
+		defer func() {
+			_ = response.Body.Close()
+		}()
 		data, rErr := ioutil.ReadAll(response.Body)
 		if rErr != nil {
 			return rErr
@@ -66,6 +89,19 @@ func (c *apiClient) Send(method common.WebMethod, path string,
 	return nil
 }
 
+// Creates a new Rest Api client for the given base url (e.g.: http://127.0.0.1:8089), using the given
+// requests timeout, or no timeout when the timeout isn't positive
+func NewApiClient(baseUrl string, timeout time.Duration) ApiClient {
+	var client = &http.Client{}
+	if timeout > 0 {
+		client.Timeout = timeout
+	}
+	return &apiClient{
+		baseUrl: strings.TrimSuffix(baseUrl, "/"),
+		client:  client,
+	}
+}
+
 func (c *apiClient) EncodeElement(mType common.MediaType, body interface{}) ([]byte, error) {
 	return encodeMediaType(mType, body)
 }
@@ -29,11 +29,25 @@ func CreateApiEndpoints(router *mux.Router,
 	hostBaseUrl string,
 	epType EndPointType,
 	configuration interface{},
-	dataManager model.RepositoryDataManager,
-	repositoryStorageManager model.RepositoryStorageManager) error {
+	dataManager model.DataManager,
+	repositoryStorageManager model.RepositoryStorageManager,
+	deployScheduler model.DeployScheduler,
+	jobExecutor model.JobExecutor) error {
 	switch epType {
 	case RepositoryEndpoint:
-		addV1RepositoryApiEndpoints(router, authFunc, dnsHandler, logger, hostBaseUrl, configuration.(model.KubeRepoConfig), dataManager, repositoryStorageManager)
+		addV1RepositoryApiEndpoints(router, authFunc, dnsHandler, logger, hostBaseUrl, configuration.(model.KubeRepoConfig), dataManager.Repos, repositoryStorageManager)
+		return nil
+	case SchedulerEndpoint:
+		if deployScheduler == nil {
+			return errors.New("Deploy scheduler is required by scheduler endpoints")
+		}
+		addV1SchedulerApiEndpoints(router, authFunc, dnsHandler, logger, hostBaseUrl, configuration.(model.KubeRepoConfig), dataManager.Deploys, deployScheduler)
+		return nil
+	case ExecutorEndpoint:
+		if jobExecutor == nil {
+			return errors.New("Job executor is required by executor endpoints")
+		}
+		addV1ExecutorApiEndpoints(router, authFunc, dnsHandler, logger, hostBaseUrl, configuration.(model.KubeRepoConfig), jobExecutor)
 		return nil
 	default:
 		return errors.New("Not implemented")
@@ -52,6 +66,34 @@ func addV1RepositoryApiEndpoints(router *mux.Router,
 	repositoryStorageManager model.RepositoryStorageManager) {
 	v1RegistryRootRest := NewV1RegistryRootRestService(logger, hostBaseUrl, config, dataManager, repositoryStorageManager)
 	router.HandleFunc("/v1/repositories", authFunc(restHandler(v1RegistryRootRest))).Methods("GET", "POST", "PUT", "DELETE")
+	v1RepositoryChartsRest := NewV1RepositoryChartsRestService(logger, hostBaseUrl, config, dataManager, repositoryStorageManager)
+	v1RepositoryChartRest := NewV1RepositoryChartRestService(logger, hostBaseUrl, config, dataManager, repositoryStorageManager)
+	v1RepositoryChartVersionRest := NewV1RepositoryChartVersionRestService(logger, hostBaseUrl, config, dataManager, repositoryStorageManager)
+	//Adding entry point for repository charts list queries (POST, GET)
+	router.HandleFunc("/v1/repositories/{repo:[a-zA-Z0-9._-]+}/charts", authFunc(restHandler(v1RepositoryChartsRest))).Methods("GET", "POST", "PUT", "DELETE")
+	//Adding entry point for specific chart queries (PUT, DEL, GET)
+	router.HandleFunc("/v1/repositories/{repo:[a-zA-Z0-9._-]+}/charts/{chart:[a-zA-Z0-9._-]+}", authFunc(restHandler(v1RepositoryChartRest))).Methods("GET", "POST", "PUT", "DELETE")
+	//Adding entry point for specific chart version queries (PUT, DEL, GET)
+	router.HandleFunc("/v1/repositories/{repo:[a-zA-Z0-9._-]+}/charts/{chart:[a-zA-Z0-9._-]+}/versions/{version:[a-zA-Z0-9.+_-]+}", authFunc(restHandler(v1RepositoryChartVersionRest))).Methods("GET", "POST", "PUT", "DELETE")
+	v1RepositoryKubeFilesRest := NewV1RepositoryKubeFilesRestService(logger, hostBaseUrl, config, dataManager, repositoryStorageManager)
+	v1RepositoryKubeFileRest := NewV1RepositoryKubeFileRestService(logger, hostBaseUrl, config, dataManager, repositoryStorageManager)
+	v1RepositoryKubeFileVersionRest := NewV1RepositoryKubeFileVersionRestService(logger, hostBaseUrl, config, dataManager, repositoryStorageManager)
+	//Adding entry point for repository Kubernetes files list queries (POST, GET)
+	router.HandleFunc("/v1/repositories/{repo:[a-zA-Z0-9._-]+}/kubefiles", authFunc(restHandler(v1RepositoryKubeFilesRest))).Methods("GET", "POST", "PUT", "DELETE")
+	//Adding entry point for specific Kubernetes file queries (PUT, DEL, GET)
+	router.HandleFunc("/v1/repositories/{repo:[a-zA-Z0-9._-]+}/kubefiles/{kubefile:[a-zA-Z0-9._-]+}", authFunc(restHandler(v1RepositoryKubeFileRest))).Methods("GET", "POST", "PUT", "DELETE")
+	//Adding entry point for specific Kubernetes file version queries (DEL, GET)
+	router.HandleFunc("/v1/repositories/{repo:[a-zA-Z0-9._-]+}/kubefiles/{kubefile:[a-zA-Z0-9._-]+}/versions/{version:[a-zA-Z0-9.+_-]+}", authFunc(restHandler(v1RepositoryKubeFileVersionRest))).Methods("GET", "POST", "PUT", "DELETE")
+	v1RepositoryBackupRest := NewV1RepositoryBackupRestService(logger, hostBaseUrl, config, repositoryStorageManager)
+	//Adding entry point for repository restore from uploaded archive (POST)
+	router.HandleFunc("/v1/repositories/restore", authFunc(restHandler(v1RepositoryBackupRest))).Methods("POST")
+	//Adding entry point for repository backup download (GET)
+	router.HandleFunc("/v1/repositories/{repo:[a-zA-Z0-9._-]+}/backup", authFunc(restHandler(v1RepositoryBackupRest))).Methods("GET")
+	v1HelmRepositoryRest := NewV1HelmRepositoryRestService(logger, hostBaseUrl, config, repositoryStorageManager)
+	//Adding entry point for Helm chart repository index (GET)
+	router.HandleFunc("/helm/{repo:[a-zA-Z0-9._-]+}/index.yaml", authFunc(restHandler(v1HelmRepositoryRest))).Methods("GET")
+	//Adding entry point for Helm chart repository packaged charts (GET)
+	router.HandleFunc("/helm/{repo:[a-zA-Z0-9._-]+}/charts/{package:[a-zA-Z0-9.+_-]+}.tgz", authFunc(restHandler(v1HelmRepositoryRest))).Methods("GET")
 	//Adding entry point for groups queries (PUT, POST, DEL, GET)
 	//router.HandleFunc("/v1/dns/groups", authFunc(restHandler(v1GroupsRest))).Methods("GET", "POST", "PUT", "DELETE")
 	////Adding entry point for spcific group queries (PUT, POST, DEL, GET)
@@ -61,3 +103,48 @@ func addV1RepositoryApiEndpoints(router *mux.Router,
 	////Adding entry point for specific group queries (PUT, POST, DEL, GET)
 	//router.HandleFunc("/v1/dns/group/{group:[a-zA-Z0-9.-]+}/resources/{resource:[a-zA-Z0-9.-]+}", authFunc(restHandler(v1DnsGroupResourceDetailsRest))).Methods("GET", "POST", "PUT", "DELETE")
 }
+
+// Add V1 Scheduler Endpoints to the Godzilla Router
+func addV1SchedulerApiEndpoints(router *mux.Router,
+	authFunc func(h http.HandlerFunc) http.HandlerFunc,
+	restHandler func(serv RestService) http.HandlerFunc,
+	logger log.Logger,
+	hostBaseUrl string,
+	config model.KubeRepoConfig,
+	dataManager model.DeployDataManager,
+	deployScheduler model.DeployScheduler) {
+	v1DeploysRest := NewV1DeploysRestService(logger, hostBaseUrl, config, dataManager, deployScheduler)
+	v1DeployRest := NewV1DeployRestService(logger, hostBaseUrl, config, dataManager, deployScheduler)
+	v1DeployJobsRest := NewV1DeployJobsRestService(logger, hostBaseUrl, config, dataManager, deployScheduler)
+	v1DeployJobRest := NewV1DeployJobRestService(logger, hostBaseUrl, config, dataManager)
+	v1DeployPlanRest := NewV1DeployPlanRestService(logger, hostBaseUrl, config, dataManager)
+	v1SchedulerQueueRest := NewV1SchedulerQueueRestService(logger, hostBaseUrl, config, deployScheduler)
+	v1SchedulerScheduleRest := NewV1SchedulerScheduleRestService(logger, hostBaseUrl, config, dataManager, deployScheduler)
+	//Adding entry point for deploys list queries (POST, GET)
+	router.HandleFunc("/v1/deploys", authFunc(restHandler(v1DeploysRest))).Methods("GET", "POST", "PUT", "DELETE")
+	//Adding entry point for specific deploy queries (PUT, DEL, GET)
+	router.HandleFunc("/v1/deploys/{deploy:[a-zA-Z0-9._-]+}", authFunc(restHandler(v1DeployRest))).Methods("GET", "POST", "PUT", "DELETE")
+	//Adding entry point for deploy jobs list queries (POST, GET)
+	router.HandleFunc("/v1/deploys/{deploy:[a-zA-Z0-9._-]+}/jobs", authFunc(restHandler(v1DeployJobsRest))).Methods("GET", "POST", "PUT", "DELETE")
+	//Adding entry point for specific deploy job queries (PUT, DEL, GET)
+	router.HandleFunc("/v1/deploys/{deploy:[a-zA-Z0-9._-]+}/jobs/{job:[a-zA-Z0-9._-]+}", authFunc(restHandler(v1DeployJobRest))).Methods("GET", "POST", "PUT", "DELETE")
+	//Adding entry point for deploy execution plan queries (GET)
+	router.HandleFunc("/v1/deploys/{deploy:[a-zA-Z0-9._-]+}/plan", authFunc(restHandler(v1DeployPlanRest))).Methods("GET", "POST", "PUT", "DELETE")
+	//Adding entry point for scheduler queue queries (GET)
+	router.HandleFunc("/v1/queue", authFunc(restHandler(v1SchedulerQueueRest))).Methods("GET", "POST", "PUT", "DELETE")
+	//Adding entry point for scheduler planned executions queries (GET)
+	router.HandleFunc("/v1/schedule", authFunc(restHandler(v1SchedulerScheduleRest))).Methods("GET", "POST", "PUT", "DELETE")
+}
+
+// Add V1 Executor Endpoints to the Godzilla Router
+func addV1ExecutorApiEndpoints(router *mux.Router,
+	authFunc func(h http.HandlerFunc) http.HandlerFunc,
+	restHandler func(serv RestService) http.HandlerFunc,
+	logger log.Logger,
+	hostBaseUrl string,
+	config model.KubeRepoConfig,
+	jobExecutor model.JobExecutor) {
+	v1ExecutionsRest := NewV1ExecutionsRestService(logger, hostBaseUrl, config, jobExecutor)
+	//Adding entry point for job executions queries (POST, GET)
+	router.HandleFunc("/v1/executions", authFunc(restHandler(v1ExecutionsRest))).Methods("GET", "POST", "PUT", "DELETE")
+}
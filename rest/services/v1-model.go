@@ -28,3 +28,215 @@ func NewV1RegistryRootRestService(logger log.Logger, hostBaseUrl string,
 		RepositoryStorageManager: repositoryStorageManager,
 	}
 }
+
+// Creates a V1 API Rest Service Instance for the repository charts list
+func NewV1RepositoryChartsRestService(logger log.Logger, hostBaseUrl string,
+	configuration model.KubeRepoConfig,
+	dataManager model.RepositoryDataManager,
+	repositoryStorageManager model.RepositoryStorageManager) RestService {
+	return &v1.RestV1RepositoryChartsService{
+		Log:                      logger,
+		BaseUrl:                  hostBaseUrl,
+		Configuration:            configuration,
+		DataManager:              dataManager,
+		RepositoryStorageManager: repositoryStorageManager,
+	}
+}
+
+// Creates a V1 API Rest Service Instance for a repository chart
+func NewV1RepositoryChartRestService(logger log.Logger, hostBaseUrl string,
+	configuration model.KubeRepoConfig,
+	dataManager model.RepositoryDataManager,
+	repositoryStorageManager model.RepositoryStorageManager) RestService {
+	return &v1.RestV1RepositoryChartService{
+		Log:                      logger,
+		BaseUrl:                  hostBaseUrl,
+		Configuration:            configuration,
+		DataManager:              dataManager,
+		RepositoryStorageManager: repositoryStorageManager,
+	}
+}
+
+// Creates a V1 API Rest Service Instance for a repository chart version
+func NewV1RepositoryChartVersionRestService(logger log.Logger, hostBaseUrl string,
+	configuration model.KubeRepoConfig,
+	dataManager model.RepositoryDataManager,
+	repositoryStorageManager model.RepositoryStorageManager) RestService {
+	return &v1.RestV1RepositoryChartVersionService{
+		Log:                      logger,
+		BaseUrl:                  hostBaseUrl,
+		Configuration:            configuration,
+		DataManager:              dataManager,
+		RepositoryStorageManager: repositoryStorageManager,
+	}
+}
+
+// Creates a V1 API Rest Service Instance for the repository Kubernetes files list
+func NewV1RepositoryKubeFilesRestService(logger log.Logger, hostBaseUrl string,
+	configuration model.KubeRepoConfig,
+	dataManager model.RepositoryDataManager,
+	repositoryStorageManager model.RepositoryStorageManager) RestService {
+	return &v1.RestV1RepositoryKubeFilesService{
+		Log:                      logger,
+		BaseUrl:                  hostBaseUrl,
+		Configuration:            configuration,
+		DataManager:              dataManager,
+		RepositoryStorageManager: repositoryStorageManager,
+	}
+}
+
+// Creates a V1 API Rest Service Instance for a repository Kubernetes file
+func NewV1RepositoryKubeFileRestService(logger log.Logger, hostBaseUrl string,
+	configuration model.KubeRepoConfig,
+	dataManager model.RepositoryDataManager,
+	repositoryStorageManager model.RepositoryStorageManager) RestService {
+	return &v1.RestV1RepositoryKubeFileService{
+		Log:                      logger,
+		BaseUrl:                  hostBaseUrl,
+		Configuration:            configuration,
+		DataManager:              dataManager,
+		RepositoryStorageManager: repositoryStorageManager,
+	}
+}
+
+// Creates a V1 API Rest Service Instance for a repository Kubernetes file version
+func NewV1RepositoryKubeFileVersionRestService(logger log.Logger, hostBaseUrl string,
+	configuration model.KubeRepoConfig,
+	dataManager model.RepositoryDataManager,
+	repositoryStorageManager model.RepositoryStorageManager) RestService {
+	return &v1.RestV1RepositoryKubeFileVersionService{
+		Log:                      logger,
+		BaseUrl:                  hostBaseUrl,
+		Configuration:            configuration,
+		DataManager:              dataManager,
+		RepositoryStorageManager: repositoryStorageManager,
+	}
+}
+
+// Creates a V1 API Rest Service Instance exposing repositories as Helm chart repositories
+func NewV1HelmRepositoryRestService(logger log.Logger, hostBaseUrl string,
+	configuration model.KubeRepoConfig,
+	repositoryStorageManager model.RepositoryStorageManager) RestService {
+	return &v1.RestV1HelmRepositoryService{
+		Log:                      logger,
+		BaseUrl:                  hostBaseUrl,
+		Configuration:            configuration,
+		RepositoryStorageManager: repositoryStorageManager,
+	}
+}
+
+// Creates a V1 API Rest Service Instance for the repositories backup and restore
+func NewV1RepositoryBackupRestService(logger log.Logger, hostBaseUrl string,
+	configuration model.KubeRepoConfig,
+	repositoryStorageManager model.RepositoryStorageManager) RestService {
+	return &v1.RestV1RepositoryBackupService{
+		Log:                      logger,
+		BaseUrl:                  hostBaseUrl,
+		Configuration:            configuration,
+		RepositoryStorageManager: repositoryStorageManager,
+	}
+}
+
+// Creates a V1 API Rest Service Instance for the scheduler deploys list
+func NewV1DeploysRestService(logger log.Logger, hostBaseUrl string,
+	configuration model.KubeRepoConfig,
+	dataManager model.DeployDataManager,
+	deployScheduler model.DeployScheduler) RestService {
+	return &v1.RestV1DeploysService{
+		Log:           logger,
+		BaseUrl:       hostBaseUrl,
+		Configuration: configuration,
+		DataManager:   dataManager,
+		Scheduler:     deployScheduler,
+	}
+}
+
+// Creates a V1 API Rest Service Instance for a specific scheduler deploy
+func NewV1DeployRestService(logger log.Logger, hostBaseUrl string,
+	configuration model.KubeRepoConfig,
+	dataManager model.DeployDataManager,
+	deployScheduler model.DeployScheduler) RestService {
+	return &v1.RestV1DeployService{
+		Log:           logger,
+		BaseUrl:       hostBaseUrl,
+		Configuration: configuration,
+		DataManager:   dataManager,
+		Scheduler:     deployScheduler,
+	}
+}
+
+// Creates a V1 API Rest Service Instance for the scheduler deploy jobs list
+func NewV1DeployJobsRestService(logger log.Logger, hostBaseUrl string,
+	configuration model.KubeRepoConfig,
+	dataManager model.DeployDataManager,
+	deployScheduler model.DeployScheduler) RestService {
+	return &v1.RestV1DeployJobsService{
+		Log:           logger,
+		BaseUrl:       hostBaseUrl,
+		Configuration: configuration,
+		DataManager:   dataManager,
+		Scheduler:     deployScheduler,
+	}
+}
+
+// Creates a V1 API Rest Service Instance for a specific scheduler deploy job
+func NewV1DeployJobRestService(logger log.Logger, hostBaseUrl string,
+	configuration model.KubeRepoConfig,
+	dataManager model.DeployDataManager) RestService {
+	return &v1.RestV1DeployJobService{
+		Log:           logger,
+		BaseUrl:       hostBaseUrl,
+		Configuration: configuration,
+		DataManager:   dataManager,
+	}
+}
+
+// Creates a V1 API Rest Service Instance for a scheduler deploy execution plan
+func NewV1DeployPlanRestService(logger log.Logger, hostBaseUrl string,
+	configuration model.KubeRepoConfig,
+	dataManager model.DeployDataManager) RestService {
+	return &v1.RestV1DeployPlanService{
+		Log:           logger,
+		BaseUrl:       hostBaseUrl,
+		Configuration: configuration,
+		DataManager:   dataManager,
+	}
+}
+
+// Creates a V1 API Rest Service Instance for the scheduler queue
+func NewV1SchedulerQueueRestService(logger log.Logger, hostBaseUrl string,
+	configuration model.KubeRepoConfig,
+	deployScheduler model.DeployScheduler) RestService {
+	return &v1.RestV1SchedulerQueueService{
+		Log:           logger,
+		BaseUrl:       hostBaseUrl,
+		Configuration: configuration,
+		Scheduler:     deployScheduler,
+	}
+}
+
+// Creates a V1 API Rest Service Instance for the scheduler planned executions
+func NewV1SchedulerScheduleRestService(logger log.Logger, hostBaseUrl string,
+	configuration model.KubeRepoConfig,
+	dataManager model.DeployDataManager,
+	deployScheduler model.DeployScheduler) RestService {
+	return &v1.RestV1SchedulerScheduleService{
+		Log:           logger,
+		BaseUrl:       hostBaseUrl,
+		Configuration: configuration,
+		DataManager:   dataManager,
+		Scheduler:     deployScheduler,
+	}
+}
+
+// Creates a V1 API Rest Service Instance for the job executor executions
+func NewV1ExecutionsRestService(logger log.Logger, hostBaseUrl string,
+	configuration model.KubeRepoConfig,
+	jobExecutor model.JobExecutor) RestService {
+	return &v1.RestV1ExecutionsService{
+		Log:           logger,
+		BaseUrl:       hostBaseUrl,
+		Configuration: configuration,
+		Executor:      jobExecutor,
+	}
+}
@@ -0,0 +1,201 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/hellgate75/k8s-deploy/log"
+	"github.com/hellgate75/k8s-deploy/model"
+	"github.com/hellgate75/k8s-deploy/model/rest"
+	"github.com/hellgate75/k8s-deploy/utils"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// Maximum memory used to parse multipart requests, exceeding parts are stored in temporary files
+	multipartMaxMemory int64 = 32 << 20
+)
+
+// Creates an Api reference for given url, containing given methods
+func getApiReference(url string, method string, methods ...string) model.ApiReference {
+	var items = make([]model.ApiReferenceItem, 0)
+	for _, m := range methods {
+		items = append(items, model.ApiReferenceItem{
+			Name: m,
+			Url:  url,
+		})
+	}
+	return model.ApiReference{
+		CurrentUrl:    url,
+		CurrentMethod: method,
+		Urls:          items,
+	}
+}
+
+// Response writer delaying the status header to the first body write, so the content type
+// chosen by the response encoder is still sent
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status  int
+	written bool
+}
+
+func (w *statusResponseWriter) Write(b []byte) (int, error) {
+	if !w.written {
+		w.written = true
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Writes status header and encoded response envelope
+func writeResponse(logger log.Logger, w http.ResponseWriter, r *http.Request, status int, message string, reference model.ApiReference, data interface{}) {
+	var sw = &statusResponseWriter{
+		ResponseWriter: w,
+		status:         status,
+	}
+	var response = model.Response{
+		Status:    status,
+		Message:   message,
+		Reference: reference,
+		Data:      data,
+	}
+	err := utils.RestParseResponse(sw, r, &response)
+	if err != nil {
+		logger.Errorf("Error encoding response: %v", err)
+	}
+	if !sw.written {
+		w.WriteHeader(status)
+	}
+}
+
+// Renders API request templates for the given methods
+func writeRequestTemplates(logger log.Logger, w http.ResponseWriter, r *http.Request, templates ...rest.TemplateDataType) {
+	var method = strings.ToLower(r.URL.Query().Get("method"))
+	var list = make([]rest.TemplateDataType, 0)
+	for _, t := range templates {
+		if method == "" || method == strings.ToLower(t.Method) {
+			list = append(list, t)
+		}
+	}
+	tErr := utils.RestParseResponse(w, r,
+		&rest.TemplateResponse{
+			Templates: list,
+		})
+	if tErr != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		logger.Errorf("Error encoding template(s) summary response, Error: %v", tErr)
+	}
+}
+
+// Recovers a repository from the path variable, by name or by id
+func getPathRepository(manager model.RepositoryStorageManager, r *http.Request) (*model.Repository, error) {
+	var key = strings.TrimSpace(mux.Vars(r)["repo"])
+	if key == "" {
+		return nil, errors.New("Repository name or id must be valid and not empty")
+	}
+	if repo, err := manager.GetRepository(key); err == nil {
+		return repo, nil
+	}
+	if repo, err := manager.GetRepositoryById(key); err == nil {
+		return repo, nil
+	}
+	return nil, errors.New(fmt.Sprintf("Repository %s not found", key))
+}
+
+// Recovers a request parameter from query string or, as fallback, from request header
+func getRequestParameter(r *http.Request, name string) string {
+	if s := r.URL.Query().Get(name); s != "" {
+		return s
+	}
+	return r.Header.Get(strings.ToUpper(name))
+}
+
+// Recovers values overrides from the query string, expressed as multiple set=name=value parameters
+func getRequestValueSet(r *http.Request) model.ValueSet {
+	var values = model.ValueSet{
+		File:  "",
+		Value: make([]model.Value, 0),
+	}
+	for _, s := range r.URL.Query()["set"] {
+		var idx = strings.Index(s, "=")
+		if idx <= 0 {
+			continue
+		}
+		values.Value = append(values.Value, model.Value{
+			Name:  strings.TrimSpace(s[:idx]),
+			Value: s[idx+1:],
+			Valid: true,
+		})
+	}
+	return values
+}
+
+// Stores the uploaded multipart file field in a temporary folder, returning the temporary
+// folder to be removed after use, the stored file path and the uploaded file name
+func storeUploadedFile(r *http.Request, field string) (string, string, string, error) {
+	err := r.ParseMultipartForm(multipartMaxMemory)
+	if err != nil {
+		return "", "", "", errors.New(fmt.Sprintf("Error parsing multipart request: %v", err))
+	}
+	in, header, err := r.FormFile(field)
+	if err != nil {
+		return "", "", "", errors.New(fmt.Sprintf("Error reading multipart file field %s: %v", field, err))
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+	var tmpFolder = utils.GetTempFolder(utils.GetRandPath())
+	err = utils.CleanCreateFolder(tmpFolder)
+	if err != nil {
+		return "", "", "", err
+	}
+	var fileName = filepath.Base(header.Filename)
+	var file = filepath.Join(tmpFolder, fileName)
+	out, err := os.Create(file)
+	if err != nil {
+		_ = utils.DeleteFileOrFolder(tmpFolder)
+		return "", "", "", err
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+	if _, err = io.Copy(out, in); err != nil {
+		_ = utils.DeleteFileOrFolder(tmpFolder)
+		return "", "", "", err
+	}
+	return tmpFolder, file, fileName, nil
+}
+
+// Stores the raw request body in a temporary folder, returning the temporary folder to be removed
+// after use and the stored file path
+func storeRequestBody(r *http.Request, fileName string) (string, string, error) {
+	var tmpFolder = utils.GetTempFolder(utils.GetRandPath())
+	err := utils.CleanCreateFolder(tmpFolder)
+	if err != nil {
+		return "", "", err
+	}
+	var file = filepath.Join(tmpFolder, filepath.Base(fileName))
+	out, err := os.Create(file)
+	if err != nil {
+		_ = utils.DeleteFileOrFolder(tmpFolder)
+		return "", "", err
+	}
+	defer func() {
+		_ = out.Close()
+	}()
+	if _, err = io.Copy(out, r.Body); err != nil {
+		_ = utils.DeleteFileOrFolder(tmpFolder)
+		return "", "", err
+	}
+	return tmpFolder, file, nil
+}
+
+// Verifies the request accepts a yaml response
+func acceptsYaml(r *http.Request) bool {
+	return strings.Contains(strings.ToLower(r.Header.Get("Accept")), "yaml")
+}
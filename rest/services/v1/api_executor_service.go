@@ -0,0 +1,99 @@
+package v1
+
+import (
+	"fmt"
+	"github.com/hellgate75/k8s-deploy/log"
+	"github.com/hellgate75/k8s-deploy/model"
+	"net/http"
+	"strings"
+)
+
+const executionsDeployParameter = "deploy"
+
+func getRestV1ExecutionsApiReference(method string) model.ApiReference {
+	return getApiReference("/v1/executions", method, "GET", "POST")
+}
+
+type RestV1ExecutionsResponse struct {
+	Executions []model.JobExecution `yaml:"executions" json:"executions" xml:"execution"`
+}
+
+type RestV1ExecutionsPollResponse struct {
+	Executed   int                  `yaml:"executed" json:"executed" xml:"executed"`
+	Executions []model.JobExecution `yaml:"executions" json:"executions" xml:"execution"`
+}
+
+type RestV1ExecutionsRollbackResponse struct {
+	RolledBack int                  `yaml:"rolledBack" json:"rolledBack" xml:"rolled-back"`
+	Executions []model.JobExecution `yaml:"executions" json:"executions" xml:"execution"`
+}
+
+// RestV1ExecutionsService is an implementation of RestService interface, exposing the job executor executions.
+type RestV1ExecutionsService struct {
+	Log           log.Logger
+	BaseUrl       string
+	Configuration model.KubeRepoConfig
+	Executor      model.JobExecutor
+}
+
+// Create is HTTP handler of POST model.Request.
+// Use for polling the scheduler immediately, executing the ready jobs, or for rolling back the completed jobs of a
+// failed deploy (action=rollback, deploy=<deploy id>).
+func (s *RestV1ExecutionsService) Create(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1ExecutionsService.Create() - Path: %s ...", r.URL.Path)
+	if strings.ToLower(getRequestParameter(r, "action")) == "rollback" {
+		var deployId = strings.TrimSpace(getRequestParameter(r, executionsDeployParameter))
+		if deployId == "" {
+			writeResponse(s.Log, w, r, http.StatusBadRequest, "Deploy id is required by rollback action", getRestV1ExecutionsApiReference("POST"), nil)
+			return
+		}
+		n, err := s.Executor.Rollback(deployId)
+		if err != nil {
+			writeResponse(s.Log, w, r, http.StatusConflict, fmt.Sprintf("Error rolling back deploy: %s, message: %v", deployId, err), getRestV1ExecutionsApiReference("POST"), RestV1ExecutionsRollbackResponse{
+				RolledBack: n,
+				Executions: s.Executor.Executions(),
+			})
+			return
+		}
+		writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1ExecutionsApiReference("POST"), RestV1ExecutionsRollbackResponse{
+			RolledBack: n,
+			Executions: s.Executor.Executions(),
+		})
+		return
+	}
+	n, err := s.Executor.Poll()
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusBadGateway, fmt.Sprintf("Error executing jobs: %v", err), getRestV1ExecutionsApiReference("POST"), RestV1ExecutionsPollResponse{
+			Executed:   n,
+			Executions: s.Executor.Executions(),
+		})
+		return
+	}
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1ExecutionsApiReference("POST"), RestV1ExecutionsPollResponse{
+		Executed:   n,
+		Executions: s.Executor.Executions(),
+	})
+}
+
+// Read is HTTP handler of GET model.Request.
+// Use for listing the latest job executions, newest first.
+func (s *RestV1ExecutionsService) Read(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1ExecutionsService.Read() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1ExecutionsApiReference("GET"), RestV1ExecutionsResponse{
+		Executions: s.Executor.Executions(),
+	})
+}
+
+// Update is HTTP handler of PUT model.Request.
+// Not allowed on job executions.
+func (s *RestV1ExecutionsService) Update(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1ExecutionsService.Update() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusMethodNotAllowed, "Method PUT not allowed on job executions", getRestV1ExecutionsApiReference("PUT"), nil)
+}
+
+// Delete is HTTP handler of DELETE model.Request.
+// Not allowed on job executions.
+func (s *RestV1ExecutionsService) Delete(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1ExecutionsService.Delete() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusMethodNotAllowed, "Method DELETE not allowed on job executions", getRestV1ExecutionsApiReference("DELETE"), nil)
+}
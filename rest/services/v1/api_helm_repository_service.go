@@ -0,0 +1,93 @@
+package v1
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/hellgate75/k8s-deploy/log"
+	"github.com/hellgate75/k8s-deploy/model"
+	"gopkg.in/yaml.v2"
+	"net/http"
+)
+
+const (
+	helmPackageSuffix = ".tgz"
+)
+
+// RestV1HelmRepositoryService is an implementation of RestService interface, exposing a repository as a standard Helm chart repository.
+// It serves the index.yaml file and the packaged chart archives, only GET requests are allowed.
+type RestV1HelmRepositoryService struct {
+	Log                      log.Logger
+	BaseUrl                  string
+	Configuration            model.KubeRepoConfig
+	RepositoryStorageManager model.RepositoryStorageManager
+}
+
+func (s *RestV1HelmRepositoryService) notAllowed(w http.ResponseWriter, r *http.Request) {
+	s.Log.Warnf("RestV1HelmRepositoryService - Method %s not allowed on path: %s", r.Method, r.URL.Path)
+	http.Error(w, fmt.Sprintf("Method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+}
+
+// Create is HTTP handler of POST model.Request.
+// Not allowed on Helm chart repositories.
+func (s *RestV1HelmRepositoryService) Create(w http.ResponseWriter, r *http.Request) {
+	s.notAllowed(w, r)
+}
+
+// Read is HTTP handler of GET model.Request.
+// Use for reading the Helm repository index.yaml or a packaged chart archive.
+func (s *RestV1HelmRepositoryService) Read(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1HelmRepositoryService.Read() - Path: %s ...", r.URL.Path)
+	repo, err := getPathRepository(s.RepositoryStorageManager, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	cm, err := s.RepositoryStorageManager.GetRepositoryChartsManager(repo.Id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error accessing repository %s charts: %v", repo.Name, err), http.StatusInternalServerError)
+		return
+	}
+	var pkg = mux.Vars(r)["package"]
+	if pkg == "" {
+		index, err := cm.GetHelmRepositoryIndex()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error creating repository %s Helm index: %v", repo.Name, err), http.StatusInternalServerError)
+			return
+		}
+		data, err := yaml.Marshal(index)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error encoding repository %s Helm index: %v", repo.Name, err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/yaml")
+		w.WriteHeader(http.StatusOK)
+		if _, err = w.Write(data); err != nil {
+			s.Log.Errorf("Error writing response: %v", err)
+		}
+		return
+	}
+	var fileName = pkg + helmPackageSuffix
+	data, err := cm.GetHelmPackage(fileName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(data); err != nil {
+		s.Log.Errorf("Error writing response: %v", err)
+	}
+}
+
+// Update is HTTP handler of PUT model.Request.
+// Not allowed on Helm chart repositories.
+func (s *RestV1HelmRepositoryService) Update(w http.ResponseWriter, r *http.Request) {
+	s.notAllowed(w, r)
+}
+
+// Delete is HTTP handler of DELETE model.Request.
+// Not allowed on Helm chart repositories.
+func (s *RestV1HelmRepositoryService) Delete(w http.ResponseWriter, r *http.Request) {
+	s.notAllowed(w, r)
+}
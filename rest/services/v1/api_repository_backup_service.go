@@ -0,0 +1,158 @@
+package v1
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/hellgate75/k8s-deploy/log"
+	"github.com/hellgate75/k8s-deploy/model"
+	"github.com/hellgate75/k8s-deploy/model/rest"
+	"github.com/hellgate75/k8s-deploy/utils"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	backupArchiveField     = "archive"
+	backupFormatField      = "format"
+	backupForceCreateField = "forceCreate"
+	backupZipFormat        = "zip"
+)
+
+func getRestV1RepositoryBackupApiReference(r *http.Request, method string) model.ApiReference {
+	var repo = mux.Vars(r)["repo"]
+	if repo == "" {
+		repo = "{repo}"
+	}
+	var reference = getApiReference(fmt.Sprintf("/v1/repositories/%s/backup", repo), method, "GET")
+	reference.Urls = append(reference.Urls, model.ApiReferenceItem{
+		Name: "POST",
+		Url:  "/v1/repositories/restore",
+	})
+	if method == "POST" {
+		reference.CurrentUrl = "/v1/repositories/restore"
+	}
+	return reference
+}
+
+// Multipart form fields accepted by the repository restore request
+type RestV1RepositoryRestoreRequest struct {
+	Archive     string `yaml:"archive,omitempty" json:"archive,omitempty" xml:"archive,omitempty"`
+	Format      string `yaml:"format,omitempty" json:"format,omitempty" xml:"format,omitempty"`
+	ForceCreate string `yaml:"forceCreate,omitempty" json:"forceCreate,omitempty" xml:"force-create,omitempty"`
+}
+
+// RestV1RepositoryBackupService is an implementation of RestService interface, managing the repositories backup and restore.
+type RestV1RepositoryBackupService struct {
+	Log                      log.Logger
+	BaseUrl                  string
+	Configuration            model.KubeRepoConfig
+	RepositoryStorageManager model.RepositoryStorageManager
+}
+
+// Create is HTTP handler of POST model.Request.
+// Use for restoring a repository from an uploaded zip or tar/g-zip archive. A repository not present yet
+// is created only using the forceCreate option.
+func (s *RestV1RepositoryBackupService) Create(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1RepositoryBackupService.Create() - Path: %s ...", r.URL.Path)
+	tmpFolder, file, fileName, err := storeUploadedFile(r, backupArchiveField)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusBadRequest, err.Error(), getRestV1RepositoryBackupApiReference(r, "POST"), nil)
+		return
+	}
+	defer func() {
+		_ = utils.DeleteFileOrFolder(tmpFolder)
+	}()
+	var zipArchive = strings.ToLower(strings.TrimSpace(r.FormValue(backupFormatField))) == backupZipFormat ||
+		strings.HasSuffix(strings.ToLower(fileName), ".zip")
+	var forceCreate = parseBool(r.FormValue(backupForceCreateField)) || parseBool(getRequestParameter(r, backupForceCreateField))
+	err = s.RepositoryStorageManager.RestoreRepository(file, zipArchive, forceCreate)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusBadRequest, fmt.Sprintf("Error restoring repository archive: %s, message: %v", fileName, err), getRestV1RepositoryBackupApiReference(r, "POST"), nil)
+		return
+	}
+	var list = make([]string, 0)
+	for _, repo := range s.RepositoryStorageManager.GetRepositoryList() {
+		list = append(list, fmt.Sprintf("%s:%s", repo.Id, repo.Name))
+	}
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1RepositoryBackupApiReference(r, "POST"), RestV1RepositoryRootResponse{Repositories: list})
+}
+
+// Read is HTTP handler of GET model.Request.
+// Use for downloading a repository backup, as tar/g-zip archive or, with format=zip, as zip archive.
+func (s *RestV1RepositoryBackupService) Read(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1RepositoryBackupService.Read() - Path: %s ...", r.URL.Path)
+	if strings.ToLower(r.URL.Query().Get("action")) == "template" {
+		writeRequestTemplates(s.Log, w, r,
+			rest.TemplateDataType{
+				Method:  "GET",
+				Header:  []string{"FORMAT: zip|tgz"},
+				Query:   []string{"action=template", "format=zip|tgz"},
+				Request: nil,
+			},
+			rest.TemplateDataType{
+				Method:  "POST",
+				Header:  []string{"Content-Type: multipart/form-data", "FORCECREATE: true|false"},
+				Query:   []string{"forceCreate=true|false"},
+				Request: RestV1RepositoryRestoreRequest{Format: "zip|tgz", ForceCreate: "true|false"},
+			})
+		return
+	}
+	repo, err := getPathRepository(s.RepositoryStorageManager, r)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusNotFound, err.Error(), getRestV1RepositoryBackupApiReference(r, "GET"), nil)
+		return
+	}
+	var zipArchive = strings.ToLower(strings.TrimSpace(getRequestParameter(r, backupFormatField))) == backupZipFormat
+	var fileName = fmt.Sprintf("%s.tar.gz", repo.Name)
+	var contentType = "application/gzip"
+	if zipArchive {
+		fileName = fmt.Sprintf("%s.zip", repo.Name)
+		contentType = "application/zip"
+	}
+	var tmpFolder = utils.GetTempFolder(utils.GetRandPath())
+	err = utils.CleanCreateFolder(tmpFolder)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusInternalServerError, fmt.Sprintf("Error creating backup folder: %v", err), getRestV1RepositoryBackupApiReference(r, "GET"), nil)
+		return
+	}
+	defer func() {
+		_ = utils.DeleteFileOrFolder(tmpFolder)
+	}()
+	var file = filepath.Join(tmpFolder, fileName)
+	err = s.RepositoryStorageManager.BackupRepository(repo.Id, file, zipArchive)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusInternalServerError, fmt.Sprintf("Error creating repository %s backup: %v", repo.Name, err), getRestV1RepositoryBackupApiReference(r, "GET"), nil)
+		return
+	}
+	in, err := os.Open(file)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusInternalServerError, fmt.Sprintf("Error reading repository %s backup: %v", repo.Name, err), getRestV1RepositoryBackupApiReference(r, "GET"), nil)
+		return
+	}
+	defer func() {
+		_ = in.Close()
+	}()
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	w.WriteHeader(http.StatusOK)
+	if _, err = io.Copy(w, in); err != nil {
+		s.Log.Errorf("Error streaming repository %s backup: %v", repo.Name, err)
+	}
+}
+
+// Update is HTTP handler of PUT model.Request.
+// Not allowed on repository backups.
+func (s *RestV1RepositoryBackupService) Update(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1RepositoryBackupService.Update() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusMethodNotAllowed, "Method PUT not allowed on repository backups", getRestV1RepositoryBackupApiReference(r, "PUT"), nil)
+}
+
+// Delete is HTTP handler of DELETE model.Request.
+// Not allowed on repository backups.
+func (s *RestV1RepositoryBackupService) Delete(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1RepositoryBackupService.Delete() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusMethodNotAllowed, "Method DELETE not allowed on repository backups", getRestV1RepositoryBackupApiReference(r, "DELETE"), nil)
+}
@@ -0,0 +1,167 @@
+package v1
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/hellgate75/k8s-deploy/integration"
+	"github.com/hellgate75/k8s-deploy/log"
+	"github.com/hellgate75/k8s-deploy/model"
+	"github.com/hellgate75/k8s-deploy/utils"
+)
+
+const testBackupManifest = "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\ndata:\n  key: value\n"
+
+func newTestBackupServer(t *testing.T, dataFolder string) (*httptest.Server, model.RepositoryStorageManager) {
+	if err := os.MkdirAll(dataFolder, 0755); err != nil {
+		t.Fatal(err)
+	}
+	var logger = log.NewLogger("backup-test", log.ERROR)
+	manager, err := integration.NewRepositoryStorageManager(dataFolder, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var service = &RestV1RepositoryBackupService{
+		Log:                      logger,
+		RepositoryStorageManager: manager,
+	}
+	var router = mux.NewRouter()
+	router.HandleFunc("/v1/repositories/restore", service.Create).Methods("POST")
+	router.HandleFunc("/v1/repositories/{repo:[a-zA-Z0-9._-]+}/backup", service.Read).Methods("GET")
+	return httptest.NewServer(router), manager
+}
+
+func postTestRestore(t *testing.T, url string, fileName string, archive []byte, forceCreate bool) (int, model.Response) {
+	var body = &bytes.Buffer{}
+	var mw = multipart.NewWriter(body)
+	fw, err := mw.CreateFormFile(backupArchiveField, fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = fw.Write(archive); err != nil {
+		t.Fatal(err)
+	}
+	if forceCreate {
+		_ = mw.WriteField(backupForceCreateField, "true")
+	}
+	_ = mw.Close()
+	resp, err := http.Post(url+"/v1/repositories/restore", mw.FormDataContentType(), body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var response model.Response
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, response
+}
+
+func TestRepositoryBackupRestoreRoundTrip(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "backup-test")
+	defer os.RemoveAll(dir)
+	var file = filepath.Join(dir, "configmap.yaml")
+	if err := ioutil.WriteFile(file, []byte(testBackupManifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	source, sourceManager := newTestBackupServer(t, filepath.Join(dir, "source"))
+	defer source.Close()
+	repo, err := sourceManager.CreateRepository("web-repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	km, err := sourceManager.GetRepositoryKubernetesFilesManager(repo.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := km.InstallKubernetesFile("config", "1.0.0", file); err != nil {
+		t.Fatal(err)
+	}
+	for _, format := range []string{"tgz", backupZipFormat} {
+		resp, err := http.Get(source.URL + "/v1/repositories/web-repo/backup?format=" + format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		archive, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Unexpected %s backup status %v: %s", format, resp.StatusCode, string(archive))
+		}
+		var fileName = "web-repo.tar.gz"
+		if format == backupZipFormat {
+			fileName = "web-repo.zip"
+		}
+		if !strings.Contains(resp.Header.Get("Content-Disposition"), fileName) {
+			t.Fatalf("Expected %s attachment, found: %s", fileName, resp.Header.Get("Content-Disposition"))
+		}
+		// The archive is restored into a clean data folder, creating the missing repository only when forced
+		target, targetManager := newTestBackupServer(t, filepath.Join(dir, "target-"+format))
+		if status, _ := postTestRestore(t, target.URL, fileName, archive, false); status != http.StatusBadRequest {
+			t.Fatalf("Expected missing repository restore refused, found status %v", status)
+		}
+		status, response := postTestRestore(t, target.URL, fileName, archive, true)
+		target.Close()
+		if status != http.StatusOK || !strings.Contains(response.Message, "OK") {
+			t.Fatalf("Unexpected %s restore status %v: %s", format, status, response.Message)
+		}
+		restored, err := targetManager.GetRepository("web-repo")
+		if err != nil {
+			t.Fatalf("Expected repository restored from %s archive: %v", format, err)
+		}
+		tkm, err := targetManager.GetRepositoryKubernetesFilesManager(restored.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		manifest, err := tkm.GetKubernetesFileVersionTemplate("config", "1.0.0")
+		if err != nil || manifest != testBackupManifest {
+			t.Fatalf("Expected restored Kubernetes file manifest, found: %q, %v", manifest, err)
+		}
+	}
+}
+
+func TestRepositoryRestoreRejectsUnsafeArchiveEntries(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "backup-test")
+	defer os.RemoveAll(dir)
+	target, targetManager := newTestBackupServer(t, filepath.Join(dir, "target"))
+	defer target.Close()
+	var escaped = filepath.Base(dir) + "-escaped.yaml"
+	var buf = &bytes.Buffer{}
+	var gw = gzip.NewWriter(buf)
+	var tw = tar.NewWriter(gw)
+	for _, e := range [][]string{
+		{"web-repo/index.yaml", "name: web-repo\n"},
+		{"../" + escaped, "kind: ConfigMap\n"},
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: e[0], Mode: 0644, Size: int64(len(e[1])), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(tw, e[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = tw.Close()
+	_ = gw.Close()
+	status, response := postTestRestore(t, target.URL, "web-repo.tar.gz", buf.Bytes(), true)
+	if status != http.StatusBadRequest || !strings.Contains(response.Message, "outside of the target folder") {
+		t.Fatalf("Expected unsafe archive rejected, found status %v: %s", status, response.Message)
+	}
+	if utils.ExistsFileOrFolder(filepath.Join(os.TempDir(), escaped)) {
+		_ = os.Remove(filepath.Join(os.TempDir(), escaped))
+		t.Fatal("Expected no archive entry extracted outside of the restore folder")
+	}
+	if _, err := targetManager.GetRepository("web-repo"); err == nil {
+		t.Fatal("Expected no repository restored from the unsafe archive")
+	}
+}
@@ -0,0 +1,431 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/hellgate75/k8s-deploy/log"
+	"github.com/hellgate75/k8s-deploy/model"
+	"github.com/hellgate75/k8s-deploy/model/rest"
+	"github.com/hellgate75/k8s-deploy/utils"
+	"net/http"
+	"strings"
+)
+
+const (
+	chartArchiveField = "archive"
+	chartNameField    = "name"
+	chartVersionField = "version"
+	chartFormatField  = "format"
+	chartForceField   = "force"
+)
+
+func getRestV1RepositoryChartsApiReference(r *http.Request, method string) model.ApiReference {
+	return getApiReference(fmt.Sprintf("/v1/repositories/%s/charts", mux.Vars(r)["repo"]), method, "GET", "POST")
+}
+
+func getRestV1RepositoryChartApiReference(r *http.Request, method string) model.ApiReference {
+	var vars = mux.Vars(r)
+	return getApiReference(fmt.Sprintf("/v1/repositories/%s/charts/%s", vars["repo"], vars["chart"]), method, "GET", "PUT", "DELETE")
+}
+
+func getRestV1RepositoryChartVersionApiReference(r *http.Request, method string) model.ApiReference {
+	var vars = mux.Vars(r)
+	return getApiReference(fmt.Sprintf("/v1/repositories/%s/charts/%s/versions/%s", vars["repo"], vars["chart"], vars["version"]), method, "GET", "PUT", "DELETE")
+}
+
+type RestV1RepositoryChartsResponse struct {
+	Repository string            `yaml:"repository" json:"repository" xml:"repository"`
+	Charts     []model.ChartInfo `yaml:"charts" json:"charts" xml:"chart"`
+}
+
+type RestV1RepositoryChartResponse struct {
+	Repository string          `yaml:"repository" json:"repository" xml:"repository"`
+	Chart      string          `yaml:"chart" json:"chart" xml:"chart"`
+	Versions   []model.Version `yaml:"versions" json:"versions" xml:"version"`
+}
+
+type RestV1RepositoryChartVersionResponse struct {
+	Repository string        `yaml:"repository" json:"repository" xml:"repository"`
+	Chart      string        `yaml:"chart" json:"chart" xml:"chart"`
+	Version    model.Version `yaml:"version" json:"version" xml:"version"`
+	Template   string        `yaml:"template,omitempty" json:"template,omitempty" xml:"template,omitempty"`
+}
+
+// Multipart form fields accepted by the chart archive upload requests
+type RestV1RepositoryChartUploadRequest struct {
+	Name    string `yaml:"name,omitempty" json:"name,omitempty" xml:"name,omitempty"`
+	Version string `yaml:"version,omitempty" json:"version,omitempty" xml:"version,omitempty"`
+	Archive string `yaml:"archive,omitempty" json:"archive,omitempty" xml:"archive,omitempty"`
+	Format  string `yaml:"format,omitempty" json:"format,omitempty" xml:"format,omitempty"`
+	Force   string `yaml:"force,omitempty" json:"force,omitempty" xml:"force,omitempty"`
+}
+
+// Stores the uploaded chart archive and calls the given install function, the archive format is
+// zip when required by the format field or by the archive file extension, tar/g-zip otherwise
+func uploadChartArchive(r *http.Request, install func(archive string, zipArchive bool) error) error {
+	tmpFolder, file, fileName, err := storeUploadedFile(r, chartArchiveField)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = utils.DeleteFileOrFolder(tmpFolder)
+	}()
+	var zipArchive = strings.ToLower(strings.TrimSpace(r.FormValue(chartFormatField))) == "zip" ||
+		strings.HasSuffix(strings.ToLower(fileName), ".zip")
+	return install(file, zipArchive)
+}
+
+// RestV1RepositoryChartsService is an implementation of RestService interface, managing the charts of a repository.
+type RestV1RepositoryChartsService struct {
+	Log                      log.Logger
+	BaseUrl                  string
+	Configuration            model.KubeRepoConfig
+	DataManager              model.RepositoryDataManager
+	RepositoryStorageManager model.RepositoryStorageManager
+}
+
+// Create is HTTP handler of POST model.Request.
+// Use for installing a new chart version from a multipart archive upload.
+func (s *RestV1RepositoryChartsService) Create(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1RepositoryChartsService.Create() - Path: %s ...", r.URL.Path)
+	repo, err := getPathRepository(s.RepositoryStorageManager, r)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusNotFound, err.Error(), getRestV1RepositoryChartsApiReference(r, "POST"), nil)
+		return
+	}
+	cm, err := s.RepositoryStorageManager.GetRepositoryChartsManager(repo.Id)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusInternalServerError, fmt.Sprintf("Error accessing repository %s charts: %v", repo.Name, err), getRestV1RepositoryChartsApiReference(r, "POST"), nil)
+		return
+	}
+	var name, version string
+	err = uploadChartArchive(r, func(archive string, zipArchive bool) error {
+		name = strings.TrimSpace(r.FormValue(chartNameField))
+		version = strings.TrimSpace(r.FormValue(chartVersionField))
+		return cm.InstallChart(name, version, archive, zipArchive)
+	})
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusBadRequest, fmt.Sprintf("Error installing chart: %s version: %s, message: %v", name, version, err), getRestV1RepositoryChartsApiReference(r, "POST"), nil)
+		return
+	}
+	versions, err := cm.GetChartVersions(name)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusInternalServerError, fmt.Sprintf("Error reading chart: %s versions, message: %v", name, err), getRestV1RepositoryChartsApiReference(r, "POST"), nil)
+		return
+	}
+	if err := getChartDocuments(s.DataManager, *repo).sync(name, versions); err != nil {
+		writeResponse(s.Log, w, r, http.StatusInternalServerError, documentsSyncMessage("chart", name, repo.Name, err), getRestV1RepositoryChartsApiReference(r, "POST"), nil)
+		return
+	}
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1RepositoryChartsApiReference(r, "POST"), RestV1RepositoryChartResponse{
+		Repository: repo.Name,
+		Chart:      name,
+		Versions:   versions,
+	})
+}
+
+// Read is HTTP handler of GET model.Request.
+// Use for listing the repository charts.
+func (s *RestV1RepositoryChartsService) Read(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1RepositoryChartsService.Read() - Path: %s ...", r.URL.Path)
+	if strings.ToLower(r.URL.Query().Get("action")) == "template" {
+		writeRequestTemplates(s.Log, w, r,
+			rest.TemplateDataType{
+				Method:  "GET",
+				Header:  []string{},
+				Query:   []string{"action=template"},
+				Request: nil,
+			},
+			rest.TemplateDataType{
+				Method:  "POST",
+				Header:  []string{"Content-Type: multipart/form-data"},
+				Query:   []string{},
+				Request: RestV1RepositoryChartUploadRequest{Format: "zip|tgz"},
+			})
+		return
+	}
+	repo, err := getPathRepository(s.RepositoryStorageManager, r)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusNotFound, err.Error(), getRestV1RepositoryChartsApiReference(r, "GET"), nil)
+		return
+	}
+	charts, err := s.RepositoryStorageManager.ListRepositoryCharts(repo.Id)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusInternalServerError, fmt.Sprintf("Error listing repository %s charts: %v", repo.Name, err), getRestV1RepositoryChartsApiReference(r, "GET"), nil)
+		return
+	}
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1RepositoryChartsApiReference(r, "GET"), RestV1RepositoryChartsResponse{
+		Repository: repo.Name,
+		Charts:     charts,
+	})
+}
+
+// Update is HTTP handler of PUT model.Request.
+// Not allowed on the charts list, use the chart endpoint.
+func (s *RestV1RepositoryChartsService) Update(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1RepositoryChartsService.Update() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusMethodNotAllowed, "Method PUT not allowed, use the chart endpoint", getRestV1RepositoryChartsApiReference(r, "PUT"), nil)
+}
+
+// Delete is HTTP handler of DELETE model.Request.
+// Not allowed on the charts list, use the chart endpoint.
+func (s *RestV1RepositoryChartsService) Delete(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1RepositoryChartsService.Delete() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusMethodNotAllowed, "Method DELETE not allowed, use the chart endpoint", getRestV1RepositoryChartsApiReference(r, "DELETE"), nil)
+}
+
+// RestV1RepositoryChartService is an implementation of RestService interface, managing a single chart of a repository.
+type RestV1RepositoryChartService struct {
+	Log                      log.Logger
+	BaseUrl                  string
+	Configuration            model.KubeRepoConfig
+	DataManager              model.RepositoryDataManager
+	RepositoryStorageManager model.RepositoryStorageManager
+}
+
+func (s *RestV1RepositoryChartService) chartsManager(r *http.Request) (*model.Repository, model.RepositoryChartManager, int, error) {
+	repo, err := getPathRepository(s.RepositoryStorageManager, r)
+	if err != nil {
+		return nil, nil, http.StatusNotFound, err
+	}
+	cm, err := s.RepositoryStorageManager.GetRepositoryChartsManager(repo.Id)
+	if err != nil {
+		return nil, nil, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error accessing repository %s charts: %v", repo.Name, err))
+	}
+	return repo, cm, http.StatusOK, nil
+}
+
+// Create is HTTP handler of POST model.Request.
+// Not allowed on a chart, use the charts list endpoint.
+func (s *RestV1RepositoryChartService) Create(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1RepositoryChartService.Create() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusMethodNotAllowed, "Method POST not allowed, use the charts list endpoint", getRestV1RepositoryChartApiReference(r, "POST"), nil)
+}
+
+// Read is HTTP handler of GET model.Request.
+// Use for listing the chart versions.
+func (s *RestV1RepositoryChartService) Read(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1RepositoryChartService.Read() - Path: %s ...", r.URL.Path)
+	if strings.ToLower(r.URL.Query().Get("action")) == "template" {
+		writeRequestTemplates(s.Log, w, r,
+			rest.TemplateDataType{
+				Method:  "GET",
+				Header:  []string{},
+				Query:   []string{"action=template"},
+				Request: nil,
+			},
+			rest.TemplateDataType{
+				Method:  "PUT",
+				Header:  []string{"Content-Type: multipart/form-data", "FORCE: true|false"},
+				Query:   []string{"force=true|false"},
+				Request: RestV1RepositoryChartUploadRequest{Format: "zip|tgz", Force: "true|false"},
+			},
+			rest.TemplateDataType{
+				Method:  "DELETE",
+				Header:  []string{},
+				Query:   []string{},
+				Request: nil,
+			})
+		return
+	}
+	repo, cm, status, err := s.chartsManager(r)
+	if err != nil {
+		writeResponse(s.Log, w, r, status, err.Error(), getRestV1RepositoryChartApiReference(r, "GET"), nil)
+		return
+	}
+	var name = mux.Vars(r)["chart"]
+	versions, err := cm.GetChartVersions(name)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusNotFound, fmt.Sprintf("Error reading chart: %s versions, message: %v", name, err), getRestV1RepositoryChartApiReference(r, "GET"), nil)
+		return
+	}
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1RepositoryChartApiReference(r, "GET"), RestV1RepositoryChartResponse{
+		Repository: repo.Name,
+		Chart:      name,
+		Versions:   versions,
+	})
+}
+
+// Update is HTTP handler of PUT model.Request.
+// Use for updating a chart version from a multipart archive upload, creating it when forced.
+func (s *RestV1RepositoryChartService) Update(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1RepositoryChartService.Update() - Path: %s ...", r.URL.Path)
+	repo, cm, status, err := s.chartsManager(r)
+	if err != nil {
+		writeResponse(s.Log, w, r, status, err.Error(), getRestV1RepositoryChartApiReference(r, "PUT"), nil)
+		return
+	}
+	var name = mux.Vars(r)["chart"]
+	var version string
+	err = uploadChartArchive(r, func(archive string, zipArchive bool) error {
+		version = strings.TrimSpace(r.FormValue(chartVersionField))
+		var force = parseBool(r.FormValue(chartForceField)) || parseBool(getRequestParameter(r, chartForceField))
+		return cm.UpdateExistingChart(name, version, archive, zipArchive, force)
+	})
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusBadRequest, fmt.Sprintf("Error updating chart: %s version: %s, message: %v", name, version, err), getRestV1RepositoryChartApiReference(r, "PUT"), nil)
+		return
+	}
+	versions, err := cm.GetChartVersions(name)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusInternalServerError, fmt.Sprintf("Error reading chart: %s versions, message: %v", name, err), getRestV1RepositoryChartApiReference(r, "PUT"), nil)
+		return
+	}
+	if err := getChartDocuments(s.DataManager, *repo).sync(name, versions); err != nil {
+		writeResponse(s.Log, w, r, http.StatusInternalServerError, documentsSyncMessage("chart", name, repo.Name, err), getRestV1RepositoryChartApiReference(r, "PUT"), nil)
+		return
+	}
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1RepositoryChartApiReference(r, "PUT"), RestV1RepositoryChartResponse{
+		Repository: repo.Name,
+		Chart:      name,
+		Versions:   versions,
+	})
+}
+
+// Delete is HTTP handler of DELETE model.Request.
+// Use for deleting an entire chart, including all versions.
+func (s *RestV1RepositoryChartService) Delete(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1RepositoryChartService.Delete() - Path: %s ...", r.URL.Path)
+	repo, cm, status, err := s.chartsManager(r)
+	if err != nil {
+		writeResponse(s.Log, w, r, status, err.Error(), getRestV1RepositoryChartApiReference(r, "DELETE"), nil)
+		return
+	}
+	var name = mux.Vars(r)["chart"]
+	versions, _ := cm.GetChartVersions(name)
+	err = cm.DeleteEntireChart(name, "")
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusNotFound, fmt.Sprintf("Error deleting chart: %s, message: %v", name, err), getRestV1RepositoryChartApiReference(r, "DELETE"), nil)
+		return
+	}
+	if err := getChartDocuments(s.DataManager, *repo).remove(name); err != nil {
+		writeResponse(s.Log, w, r, http.StatusInternalServerError, documentsSyncMessage("chart", name, repo.Name, err), getRestV1RepositoryChartApiReference(r, "DELETE"), nil)
+		return
+	}
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1RepositoryChartApiReference(r, "DELETE"), RestV1RepositoryChartResponse{
+		Repository: repo.Name,
+		Chart:      name,
+		Versions:   versions,
+	})
+}
+
+// RestV1RepositoryChartVersionService is an implementation of RestService interface, managing a single chart version of a repository.
+type RestV1RepositoryChartVersionService struct {
+	Log                      log.Logger
+	BaseUrl                  string
+	Configuration            model.KubeRepoConfig
+	DataManager              model.RepositoryDataManager
+	RepositoryStorageManager model.RepositoryStorageManager
+}
+
+func (s *RestV1RepositoryChartVersionService) chartVersion(r *http.Request) (*model.Repository, model.RepositoryChartManager, *model.Version, int, error) {
+	repo, err := getPathRepository(s.RepositoryStorageManager, r)
+	if err != nil {
+		return nil, nil, nil, http.StatusNotFound, err
+	}
+	cm, err := s.RepositoryStorageManager.GetRepositoryChartsManager(repo.Id)
+	if err != nil {
+		return nil, nil, nil, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error accessing repository %s charts: %v", repo.Name, err))
+	}
+	var vars = mux.Vars(r)
+	versions, err := cm.GetChartVersions(vars["chart"])
+	if err != nil {
+		return nil, nil, nil, http.StatusNotFound, errors.New(fmt.Sprintf("Error reading chart: %s versions, message: %v", vars["chart"], err))
+	}
+	for _, v := range versions {
+		if v.Name == vars["version"] {
+			return repo, cm, &v, http.StatusOK, nil
+		}
+	}
+	return nil, nil, nil, http.StatusNotFound, errors.New(fmt.Sprintf("Chart %s version %s not found in repository %s", vars["chart"], vars["version"], repo.Name))
+}
+
+// Create is HTTP handler of POST model.Request.
+// Not allowed on a chart version, use the charts list endpoint.
+func (s *RestV1RepositoryChartVersionService) Create(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1RepositoryChartVersionService.Create() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusMethodNotAllowed, "Method POST not allowed, use the charts list endpoint", getRestV1RepositoryChartVersionApiReference(r, "POST"), nil)
+}
+
+// Read is HTTP handler of GET model.Request.
+// Use for reading the chart version details or, with action=template, for rendering the chart version templates.
+func (s *RestV1RepositoryChartVersionService) Read(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1RepositoryChartVersionService.Read() - Path: %s ...", r.URL.Path)
+	repo, cm, version, status, err := s.chartVersion(r)
+	if err != nil {
+		writeResponse(s.Log, w, r, status, err.Error(), getRestV1RepositoryChartVersionApiReference(r, "GET"), nil)
+		return
+	}
+	var name = mux.Vars(r)["chart"]
+	var response = RestV1RepositoryChartVersionResponse{
+		Repository: repo.Name,
+		Chart:      name,
+		Version:    *version,
+	}
+	if strings.ToLower(r.URL.Query().Get("action")) == "template" {
+		response.Template, err = cm.GetChartVersionTemplate(name, version.Name, getRequestValueSet(r))
+		if err != nil {
+			writeResponse(s.Log, w, r, http.StatusBadRequest, fmt.Sprintf("Error rendering chart: %s version: %s templates, message: %v", name, version.Name, err), getRestV1RepositoryChartVersionApiReference(r, "GET"), nil)
+			return
+		}
+	}
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1RepositoryChartVersionApiReference(r, "GET"), response)
+}
+
+// Update is HTTP handler of PUT model.Request.
+// Use for replacing the chart version content from a multipart archive upload.
+func (s *RestV1RepositoryChartVersionService) Update(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1RepositoryChartVersionService.Update() - Path: %s ...", r.URL.Path)
+	repo, cm, version, status, err := s.chartVersion(r)
+	if err != nil {
+		writeResponse(s.Log, w, r, status, err.Error(), getRestV1RepositoryChartVersionApiReference(r, "PUT"), nil)
+		return
+	}
+	var name = mux.Vars(r)["chart"]
+	err = uploadChartArchive(r, func(archive string, zipArchive bool) error {
+		return cm.UpdateExistingChart(name, version.Name, archive, zipArchive, false)
+	})
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusBadRequest, fmt.Sprintf("Error updating chart: %s version: %s, message: %v", name, version.Name, err), getRestV1RepositoryChartVersionApiReference(r, "PUT"), nil)
+		return
+	}
+	versions, _ := cm.GetChartVersions(name)
+	if err := getChartDocuments(s.DataManager, *repo).sync(name, versions); err != nil {
+		writeResponse(s.Log, w, r, http.StatusInternalServerError, documentsSyncMessage("chart", name, repo.Name, err), getRestV1RepositoryChartVersionApiReference(r, "PUT"), nil)
+		return
+	}
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1RepositoryChartVersionApiReference(r, "PUT"), RestV1RepositoryChartVersionResponse{
+		Repository: repo.Name,
+		Chart:      name,
+		Version:    *version,
+	})
+}
+
+// Delete is HTTP handler of DELETE model.Request.
+// Use for deleting the chart version.
+func (s *RestV1RepositoryChartVersionService) Delete(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1RepositoryChartVersionService.Delete() - Path: %s ...", r.URL.Path)
+	repo, cm, version, status, err := s.chartVersion(r)
+	if err != nil {
+		writeResponse(s.Log, w, r, status, err.Error(), getRestV1RepositoryChartVersionApiReference(r, "DELETE"), nil)
+		return
+	}
+	var name = mux.Vars(r)["chart"]
+	err = cm.DeleteChartVersion(name, version.Name)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusInternalServerError, fmt.Sprintf("Error deleting chart: %s version: %s, message: %v", name, version.Name, err), getRestV1RepositoryChartVersionApiReference(r, "DELETE"), nil)
+		return
+	}
+	versions, _ := cm.GetChartVersions(name)
+	if err := getChartDocuments(s.DataManager, *repo).sync(name, versions); err != nil {
+		writeResponse(s.Log, w, r, http.StatusInternalServerError, documentsSyncMessage("chart", name, repo.Name, err), getRestV1RepositoryChartVersionApiReference(r, "DELETE"), nil)
+		return
+	}
+	version.State = model.StateDeleted
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1RepositoryChartVersionApiReference(r, "DELETE"), RestV1RepositoryChartVersionResponse{
+		Repository: repo.Name,
+		Chart:      name,
+		Version:    *version,
+	})
+}
@@ -0,0 +1,160 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hellgate75/k8s-deploy/model"
+)
+
+// Charts or Kubernetes files metadata of a repository in the data manager, kept aligned with
+// the repository storage after every successful upload or delete
+type repositoryDocuments struct {
+	query         func(q ...model.Query) model.DataResponse
+	add           func(d model.Chart) model.DataResponse
+	addVersion    func(d model.Chart, v model.Version) model.DataResponse
+	updateVersion func(d model.Chart, v model.Version, q ...model.Query) model.DataResponse
+	purge         func(q ...model.Query) model.DataResponse
+	purgeVersions func(d model.Chart, q ...model.Query) model.DataResponse
+}
+
+// Gets the repository charts metadata, nil when no data manager is available
+func getChartDocuments(dataManager model.RepositoryDataManager, repo model.Repository) *repositoryDocuments {
+	if dataManager == nil {
+		return nil
+	}
+	var dm = *dataManager.AccessRepository(repo)
+	return &repositoryDocuments{
+		query:         dm.QueryCharts,
+		add:           dm.AddChart,
+		addVersion:    dm.AddChartVersion,
+		updateVersion: dm.UpdateChartVersions,
+		purge:         dm.PurgeCharts,
+		purgeVersions: dm.PurgeChartVersions,
+	}
+}
+
+// Gets the repository Kubernetes files metadata, nil when no data manager is available
+func getKubeFileDocuments(dataManager model.RepositoryDataManager, repo model.Repository) *repositoryDocuments {
+	if dataManager == nil {
+		return nil
+	}
+	var dm = *dataManager.AccessRepository(repo)
+	return &repositoryDocuments{
+		query: dm.QueryKubeFiles,
+		add: func(d model.Chart) model.DataResponse {
+			return dm.AddKubeFile(model.KubernetesFile(d))
+		},
+		addVersion: func(d model.Chart, v model.Version) model.DataResponse {
+			return dm.AddKubeFileVersion(model.KubernetesFile(d), v)
+		},
+		updateVersion: func(d model.Chart, v model.Version, q ...model.Query) model.DataResponse {
+			return dm.UpdateKubeFileVersions(model.KubernetesFile(d), v, q...)
+		},
+		purge: dm.PurgeKubeFiles,
+		purgeVersions: func(d model.Chart, q ...model.Query) model.DataResponse {
+			return dm.PurgeKubeFileVersions(model.KubernetesFile(d), q...)
+		},
+	}
+}
+
+func nameQuery(name string) model.Query {
+	return model.Query{
+		Oper: model.OperAnd,
+		Items: []model.QueryItem{
+			{Key: "name", Value: name, Aggregator: model.AggregatorEq},
+		},
+	}
+}
+
+func responseError(resp model.DataResponse) error {
+	if resp.Success {
+		return nil
+	}
+	return errors.New(resp.Message)
+}
+
+// Find the document with given name, nil when missing
+func (rd *repositoryDocuments) find(name string) (*model.Chart, error) {
+	var resp = rd.query(nameQuery(name))
+	if err := responseError(resp); err != nil {
+		return nil, err
+	}
+	for _, o := range resp.ResponseObjects {
+		switch d := o.(type) {
+		case model.Chart:
+			return &d, nil
+		case model.KubernetesFile:
+			var c = model.Chart(d)
+			return &c, nil
+		}
+	}
+	return nil, nil
+}
+
+// Align the document metadata to the versions in the repository storage, adding the missing
+// document or versions, updating the versions state and purging the versions not stored anymore.
+// The document is purged when no version is stored
+func (rd *repositoryDocuments) sync(name string, versions []model.Version) error {
+	if rd == nil {
+		return nil
+	}
+	if len(versions) == 0 {
+		return rd.remove(name)
+	}
+	doc, err := rd.find(name)
+	if err != nil {
+		return err
+	}
+	if doc == nil {
+		return responseError(rd.add(model.Chart{
+			Name:     name,
+			Versions: versions,
+			State:    model.StateCreated,
+		}))
+	}
+	for _, v := range versions {
+		var current *model.Version
+		for i := range doc.Versions {
+			if doc.Versions[i].Name == v.Name {
+				current = &doc.Versions[i]
+				break
+			}
+		}
+		if current == nil {
+			err = responseError(rd.addVersion(*doc, v))
+		} else if current.State != v.State && v.State != "" {
+			err = responseError(rd.updateVersion(*doc, v, nameQuery(v.Name)))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, current := range doc.Versions {
+		var stored = false
+		for _, v := range versions {
+			if v.Name == current.Name {
+				stored = true
+				break
+			}
+		}
+		if !stored {
+			if err := responseError(rd.purgeVersions(*doc, nameQuery(current.Name))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Purge the document metadata, including all versions
+func (rd *repositoryDocuments) remove(name string) error {
+	if rd == nil {
+		return nil
+	}
+	return responseError(rd.purge(nameQuery(name)))
+}
+
+// Error message of a failed metadata alignment
+func documentsSyncMessage(kind string, name string, repo string, err error) string {
+	return fmt.Sprintf("Error saving %s: %s metadata in repository %s, message: %v", kind, name, repo, err)
+}
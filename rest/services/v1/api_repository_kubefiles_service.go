@@ -0,0 +1,436 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/hellgate75/k8s-deploy/log"
+	"github.com/hellgate75/k8s-deploy/model"
+	"github.com/hellgate75/k8s-deploy/model/rest"
+	"github.com/hellgate75/k8s-deploy/utils"
+	"net/http"
+	"strings"
+)
+
+const (
+	kubeFileNameParameter    = "name"
+	kubeFileVersionParameter = "version"
+)
+
+func getRestV1RepositoryKubeFilesApiReference(r *http.Request, method string) model.ApiReference {
+	return getApiReference(fmt.Sprintf("/v1/repositories/%s/kubefiles", mux.Vars(r)["repo"]), method, "GET", "POST")
+}
+
+func getRestV1RepositoryKubeFileApiReference(r *http.Request, method string) model.ApiReference {
+	var vars = mux.Vars(r)
+	return getApiReference(fmt.Sprintf("/v1/repositories/%s/kubefiles/%s", vars["repo"], vars["kubefile"]), method, "GET", "PUT", "DELETE")
+}
+
+func getRestV1RepositoryKubeFileVersionApiReference(r *http.Request, method string) model.ApiReference {
+	var vars = mux.Vars(r)
+	return getApiReference(fmt.Sprintf("/v1/repositories/%s/kubefiles/%s/versions/%s", vars["repo"], vars["kubefile"], vars["version"]), method, "GET", "DELETE")
+}
+
+type RestV1RepositoryKubeFilesResponse struct {
+	Repository string                     `yaml:"repository" json:"repository" xml:"repository"`
+	KubeFiles  []model.KubernetesFileInfo `yaml:"kubefiles" json:"kubefiles" xml:"kubefile"`
+}
+
+type RestV1RepositoryKubeFileResponse struct {
+	Repository string          `yaml:"repository" json:"repository" xml:"repository"`
+	KubeFile   string          `yaml:"kubefile" json:"kubefile" xml:"kubefile"`
+	Versions   []model.Version `yaml:"versions" json:"versions" xml:"version"`
+}
+
+type RestV1RepositoryKubeFileVersionResponse struct {
+	Repository string                    `yaml:"repository" json:"repository" xml:"repository"`
+	KubeFile   string                    `yaml:"kubefile" json:"kubefile" xml:"kubefile"`
+	Version    model.Version             `yaml:"version" json:"version" xml:"version"`
+	Manifest   string                    `yaml:"manifest,omitempty" json:"manifest,omitempty" xml:"manifest,omitempty"`
+	Report     *model.VerificationReport `yaml:"report,omitempty" json:"report,omitempty" xml:"report,omitempty"`
+}
+
+// Stores the raw yaml request body and calls the given install function
+func uploadKubeFile(r *http.Request, name string, version string, install func(file string) error) error {
+	var contentType = strings.ToLower(r.Header.Get("Content-Type"))
+	if contentType != "" && !strings.Contains(contentType, "yaml") && !strings.Contains(contentType, "text/plain") {
+		return errors.New(fmt.Sprintf("Unsupported media type: %s, expected yaml body", contentType))
+	}
+	tmpFolder, file, err := storeRequestBody(r, fmt.Sprintf("%s-%s.%v", name, version, utils.YAML_FORMAT))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = utils.DeleteFileOrFolder(tmpFolder)
+	}()
+	return install(file)
+}
+
+func getKubeFilesManager(manager model.RepositoryStorageManager, r *http.Request) (*model.Repository, model.RepositoryKubernetesFilesManager, int, error) {
+	repo, err := getPathRepository(manager, r)
+	if err != nil {
+		return nil, nil, http.StatusNotFound, err
+	}
+	km, err := manager.GetRepositoryKubernetesFilesManager(repo.Id)
+	if err != nil {
+		return nil, nil, http.StatusInternalServerError, errors.New(fmt.Sprintf("Error accessing repository %s Kubernetes files: %v", repo.Name, err))
+	}
+	return repo, km, http.StatusOK, nil
+}
+
+// RestV1RepositoryKubeFilesService is an implementation of RestService interface, managing the Kubernetes files of a repository.
+type RestV1RepositoryKubeFilesService struct {
+	Log                      log.Logger
+	BaseUrl                  string
+	Configuration            model.KubeRepoConfig
+	DataManager              model.RepositoryDataManager
+	RepositoryStorageManager model.RepositoryStorageManager
+}
+
+// Create is HTTP handler of POST model.Request.
+// Use for installing a new Kubernetes file version from a raw yaml body.
+func (s *RestV1RepositoryKubeFilesService) Create(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1RepositoryKubeFilesService.Create() - Path: %s ...", r.URL.Path)
+	repo, km, status, err := getKubeFilesManager(s.RepositoryStorageManager, r)
+	if err != nil {
+		writeResponse(s.Log, w, r, status, err.Error(), getRestV1RepositoryKubeFilesApiReference(r, "POST"), nil)
+		return
+	}
+	var name = strings.TrimSpace(getRequestParameter(r, kubeFileNameParameter))
+	var version = strings.TrimSpace(getRequestParameter(r, kubeFileVersionParameter))
+	if name == "" || version == "" {
+		writeResponse(s.Log, w, r, http.StatusBadRequest, "Kubernetes file name and version parameters must be valid and not empty", getRestV1RepositoryKubeFilesApiReference(r, "POST"), nil)
+		return
+	}
+	err = uploadKubeFile(r, name, version, func(file string) error {
+		return km.InstallKubernetesFile(name, version, file)
+	})
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusBadRequest, fmt.Sprintf("Error installing Kubernetes file: %s version: %s, message: %v", name, version, err), getRestV1RepositoryKubeFilesApiReference(r, "POST"), nil)
+		return
+	}
+	versions, err := km.GetKubernetesFileVersions(name)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusInternalServerError, fmt.Sprintf("Error reading Kubernetes file: %s versions, message: %v", name, err), getRestV1RepositoryKubeFilesApiReference(r, "POST"), nil)
+		return
+	}
+	if err := getKubeFileDocuments(s.DataManager, *repo).sync(name, versions); err != nil {
+		writeResponse(s.Log, w, r, http.StatusInternalServerError, documentsSyncMessage("Kubernetes file", name, repo.Name, err), getRestV1RepositoryKubeFilesApiReference(r, "POST"), nil)
+		return
+	}
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1RepositoryKubeFilesApiReference(r, "POST"), RestV1RepositoryKubeFileResponse{
+		Repository: repo.Name,
+		KubeFile:   name,
+		Versions:   versions,
+	})
+}
+
+// Read is HTTP handler of GET model.Request.
+// Use for listing the repository Kubernetes files.
+func (s *RestV1RepositoryKubeFilesService) Read(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1RepositoryKubeFilesService.Read() - Path: %s ...", r.URL.Path)
+	if strings.ToLower(r.URL.Query().Get("action")) == "template" {
+		writeRequestTemplates(s.Log, w, r,
+			rest.TemplateDataType{
+				Method:  "GET",
+				Header:  []string{},
+				Query:   []string{"action=template"},
+				Request: nil,
+			},
+			rest.TemplateDataType{
+				Method:  "POST",
+				Header:  []string{"Content-Type: text/yaml", "NAME: <name>", "VERSION: <version>"},
+				Query:   []string{"name=<name>", "version=<version>"},
+				Request: "<Kubernetes yaml manifest>",
+			})
+		return
+	}
+	repo, err := getPathRepository(s.RepositoryStorageManager, r)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusNotFound, err.Error(), getRestV1RepositoryKubeFilesApiReference(r, "GET"), nil)
+		return
+	}
+	files, err := s.RepositoryStorageManager.ListRepositoryKubernetesFiles(repo.Id)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusInternalServerError, fmt.Sprintf("Error listing repository %s Kubernetes files: %v", repo.Name, err), getRestV1RepositoryKubeFilesApiReference(r, "GET"), nil)
+		return
+	}
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1RepositoryKubeFilesApiReference(r, "GET"), RestV1RepositoryKubeFilesResponse{
+		Repository: repo.Name,
+		KubeFiles:  files,
+	})
+}
+
+// Update is HTTP handler of PUT model.Request.
+// Not allowed on the Kubernetes files list, use the Kubernetes file endpoint.
+func (s *RestV1RepositoryKubeFilesService) Update(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1RepositoryKubeFilesService.Update() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusMethodNotAllowed, "Method PUT not allowed, use the Kubernetes file endpoint", getRestV1RepositoryKubeFilesApiReference(r, "PUT"), nil)
+}
+
+// Delete is HTTP handler of DELETE model.Request.
+// Not allowed on the Kubernetes files list, use the Kubernetes file endpoint.
+func (s *RestV1RepositoryKubeFilesService) Delete(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1RepositoryKubeFilesService.Delete() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusMethodNotAllowed, "Method DELETE not allowed, use the Kubernetes file endpoint", getRestV1RepositoryKubeFilesApiReference(r, "DELETE"), nil)
+}
+
+// RestV1RepositoryKubeFileService is an implementation of RestService interface, managing a single Kubernetes file of a repository.
+type RestV1RepositoryKubeFileService struct {
+	Log                      log.Logger
+	BaseUrl                  string
+	Configuration            model.KubeRepoConfig
+	DataManager              model.RepositoryDataManager
+	RepositoryStorageManager model.RepositoryStorageManager
+}
+
+// Create is HTTP handler of POST model.Request.
+// Not allowed on a Kubernetes file, use the Kubernetes files list endpoint.
+func (s *RestV1RepositoryKubeFileService) Create(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1RepositoryKubeFileService.Create() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusMethodNotAllowed, "Method POST not allowed, use the Kubernetes files list endpoint", getRestV1RepositoryKubeFileApiReference(r, "POST"), nil)
+}
+
+// Read is HTTP handler of GET model.Request.
+// Use for listing the Kubernetes file versions.
+func (s *RestV1RepositoryKubeFileService) Read(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1RepositoryKubeFileService.Read() - Path: %s ...", r.URL.Path)
+	if strings.ToLower(r.URL.Query().Get("action")) == "template" {
+		writeRequestTemplates(s.Log, w, r,
+			rest.TemplateDataType{
+				Method:  "GET",
+				Header:  []string{},
+				Query:   []string{"action=template"},
+				Request: nil,
+			},
+			rest.TemplateDataType{
+				Method:  "PUT",
+				Header:  []string{"Content-Type: text/yaml", "VERSION: <version>"},
+				Query:   []string{"version=<version>"},
+				Request: "<Kubernetes yaml manifest>",
+			},
+			rest.TemplateDataType{
+				Method:  "DELETE",
+				Header:  []string{},
+				Query:   []string{},
+				Request: nil,
+			})
+		return
+	}
+	repo, km, status, err := getKubeFilesManager(s.RepositoryStorageManager, r)
+	if err != nil {
+		writeResponse(s.Log, w, r, status, err.Error(), getRestV1RepositoryKubeFileApiReference(r, "GET"), nil)
+		return
+	}
+	var name = mux.Vars(r)["kubefile"]
+	versions, err := km.GetKubernetesFileVersions(name)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusNotFound, fmt.Sprintf("Error reading Kubernetes file: %s versions, message: %v", name, err), getRestV1RepositoryKubeFileApiReference(r, "GET"), nil)
+		return
+	}
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1RepositoryKubeFileApiReference(r, "GET"), RestV1RepositoryKubeFileResponse{
+		Repository: repo.Name,
+		KubeFile:   name,
+		Versions:   versions,
+	})
+}
+
+// Update is HTTP handler of PUT model.Request.
+// Use for adding a new Kubernetes file version from a raw yaml body, stored versions are immutable.
+func (s *RestV1RepositoryKubeFileService) Update(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1RepositoryKubeFileService.Update() - Path: %s ...", r.URL.Path)
+	repo, km, status, err := getKubeFilesManager(s.RepositoryStorageManager, r)
+	if err != nil {
+		writeResponse(s.Log, w, r, status, err.Error(), getRestV1RepositoryKubeFileApiReference(r, "PUT"), nil)
+		return
+	}
+	var name = mux.Vars(r)["kubefile"]
+	var version = strings.TrimSpace(getRequestParameter(r, kubeFileVersionParameter))
+	if version == "" {
+		writeResponse(s.Log, w, r, http.StatusBadRequest, "Kubernetes file version parameter must be valid and not empty", getRestV1RepositoryKubeFileApiReference(r, "PUT"), nil)
+		return
+	}
+	err = uploadKubeFile(r, name, version, func(file string) error {
+		return km.UpdateExistingKubernetesFile(name, version, file)
+	})
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusBadRequest, fmt.Sprintf("Error updating Kubernetes file: %s version: %s, message: %v", name, version, err), getRestV1RepositoryKubeFileApiReference(r, "PUT"), nil)
+		return
+	}
+	versions, err := km.GetKubernetesFileVersions(name)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusInternalServerError, fmt.Sprintf("Error reading Kubernetes file: %s versions, message: %v", name, err), getRestV1RepositoryKubeFileApiReference(r, "PUT"), nil)
+		return
+	}
+	if err := getKubeFileDocuments(s.DataManager, *repo).sync(name, versions); err != nil {
+		writeResponse(s.Log, w, r, http.StatusInternalServerError, documentsSyncMessage("Kubernetes file", name, repo.Name, err), getRestV1RepositoryKubeFileApiReference(r, "PUT"), nil)
+		return
+	}
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1RepositoryKubeFileApiReference(r, "PUT"), RestV1RepositoryKubeFileResponse{
+		Repository: repo.Name,
+		KubeFile:   name,
+		Versions:   versions,
+	})
+}
+
+// Delete is HTTP handler of DELETE model.Request.
+// Use for deleting an entire Kubernetes file, including all versions.
+func (s *RestV1RepositoryKubeFileService) Delete(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1RepositoryKubeFileService.Delete() - Path: %s ...", r.URL.Path)
+	repo, km, status, err := getKubeFilesManager(s.RepositoryStorageManager, r)
+	if err != nil {
+		writeResponse(s.Log, w, r, status, err.Error(), getRestV1RepositoryKubeFileApiReference(r, "DELETE"), nil)
+		return
+	}
+	var name = mux.Vars(r)["kubefile"]
+	versions, _ := km.GetKubernetesFileVersions(name)
+	err = km.DeleteEntireKubernetesFile(name, "")
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusNotFound, fmt.Sprintf("Error deleting Kubernetes file: %s, message: %v", name, err), getRestV1RepositoryKubeFileApiReference(r, "DELETE"), nil)
+		return
+	}
+	if err := getKubeFileDocuments(s.DataManager, *repo).remove(name); err != nil {
+		writeResponse(s.Log, w, r, http.StatusInternalServerError, documentsSyncMessage("Kubernetes file", name, repo.Name, err), getRestV1RepositoryKubeFileApiReference(r, "DELETE"), nil)
+		return
+	}
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1RepositoryKubeFileApiReference(r, "DELETE"), RestV1RepositoryKubeFileResponse{
+		Repository: repo.Name,
+		KubeFile:   name,
+		Versions:   versions,
+	})
+}
+
+// RestV1RepositoryKubeFileVersionService is an implementation of RestService interface, managing a single Kubernetes file version of a repository.
+type RestV1RepositoryKubeFileVersionService struct {
+	Log                      log.Logger
+	BaseUrl                  string
+	Configuration            model.KubeRepoConfig
+	DataManager              model.RepositoryDataManager
+	RepositoryStorageManager model.RepositoryStorageManager
+}
+
+func (s *RestV1RepositoryKubeFileVersionService) kubeFileVersion(r *http.Request) (*model.Repository, model.RepositoryKubernetesFilesManager, *model.Version, int, error) {
+	repo, km, status, err := getKubeFilesManager(s.RepositoryStorageManager, r)
+	if err != nil {
+		return nil, nil, nil, status, err
+	}
+	var vars = mux.Vars(r)
+	versions, err := km.GetKubernetesFileVersions(vars["kubefile"])
+	if err != nil {
+		return nil, nil, nil, http.StatusNotFound, errors.New(fmt.Sprintf("Error reading Kubernetes file: %s versions, message: %v", vars["kubefile"], err))
+	}
+	for _, v := range versions {
+		if v.Name == vars["version"] {
+			return repo, km, &v, http.StatusOK, nil
+		}
+	}
+	return nil, nil, nil, http.StatusNotFound, errors.New(fmt.Sprintf("Kubernetes file %s version %s not found in repository %s", vars["kubefile"], vars["version"], repo.Name))
+}
+
+// Create is HTTP handler of POST model.Request.
+// Not allowed on a Kubernetes file version, stored versions are immutable.
+func (s *RestV1RepositoryKubeFileVersionService) Create(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1RepositoryKubeFileVersionService.Create() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusMethodNotAllowed, "Method POST not allowed, use the Kubernetes files list endpoint", getRestV1RepositoryKubeFileVersionApiReference(r, "POST"), nil)
+}
+
+// Read is HTTP handler of GET model.Request.
+// Use for reading the Kubernetes file version manifest, as raw yaml when the request accepts yaml, or
+// with action=verify for verifying the Kubernetes file version.
+func (s *RestV1RepositoryKubeFileVersionService) Read(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1RepositoryKubeFileVersionService.Read() - Path: %s ...", r.URL.Path)
+	var action = strings.ToLower(r.URL.Query().Get("action"))
+	if action == "template" {
+		writeRequestTemplates(s.Log, w, r,
+			rest.TemplateDataType{
+				Method:  "GET",
+				Header:  []string{"Accept: application/json|application/xml|text/yaml"},
+				Query:   []string{"action=template|verify"},
+				Request: nil,
+			},
+			rest.TemplateDataType{
+				Method:  "DELETE",
+				Header:  []string{},
+				Query:   []string{},
+				Request: nil,
+			})
+		return
+	}
+	repo, km, version, status, err := s.kubeFileVersion(r)
+	if err != nil {
+		writeResponse(s.Log, w, r, status, err.Error(), getRestV1RepositoryKubeFileVersionApiReference(r, "GET"), nil)
+		return
+	}
+	var name = mux.Vars(r)["kubefile"]
+	var response = RestV1RepositoryKubeFileVersionResponse{
+		Repository: repo.Name,
+		KubeFile:   name,
+		Version:    *version,
+	}
+	if action == "verify" {
+		err = km.VerifyKubernetesFile(name, version.Name)
+		if err == nil {
+			response.Report = &model.VerificationReport{
+				Name:    name,
+				Version: version.Name,
+				Issues:  make([]model.VerificationIssue, 0),
+			}
+			writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1RepositoryKubeFileVersionApiReference(r, "GET"), response)
+		} else if report, ok := err.(*model.VerificationReport); ok {
+			response.Report = report
+			writeResponse(s.Log, w, r, http.StatusUnprocessableEntity, fmt.Sprintf("Kubernetes file: %s version: %s verification failed", name, version.Name), getRestV1RepositoryKubeFileVersionApiReference(r, "GET"), response)
+		} else {
+			writeResponse(s.Log, w, r, http.StatusInternalServerError, fmt.Sprintf("Error verifying Kubernetes file: %s version: %s, message: %v", name, version.Name, err), getRestV1RepositoryKubeFileVersionApiReference(r, "GET"), nil)
+		}
+		return
+	}
+	response.Manifest, err = km.GetKubernetesFileVersionTemplate(name, version.Name)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusInternalServerError, fmt.Sprintf("Error reading Kubernetes file: %s version: %s manifest, message: %v", name, version.Name, err), getRestV1RepositoryKubeFileVersionApiReference(r, "GET"), nil)
+		return
+	}
+	if acceptsYaml(r) {
+		w.Header().Set("Content-Type", "text/yaml")
+		w.WriteHeader(http.StatusOK)
+		if _, err = w.Write([]byte(response.Manifest)); err != nil {
+			s.Log.Errorf("Error writing response: %v", err)
+		}
+		return
+	}
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1RepositoryKubeFileVersionApiReference(r, "GET"), response)
+}
+
+// Update is HTTP handler of PUT model.Request.
+// Not allowed on a Kubernetes file version, stored versions are immutable.
+func (s *RestV1RepositoryKubeFileVersionService) Update(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1RepositoryKubeFileVersionService.Update() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusMethodNotAllowed, "Method PUT not allowed, Kubernetes file versions are immutable", getRestV1RepositoryKubeFileVersionApiReference(r, "PUT"), nil)
+}
+
+// Delete is HTTP handler of DELETE model.Request.
+// Use for deleting the Kubernetes file version.
+func (s *RestV1RepositoryKubeFileVersionService) Delete(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1RepositoryKubeFileVersionService.Delete() - Path: %s ...", r.URL.Path)
+	repo, km, version, status, err := s.kubeFileVersion(r)
+	if err != nil {
+		writeResponse(s.Log, w, r, status, err.Error(), getRestV1RepositoryKubeFileVersionApiReference(r, "DELETE"), nil)
+		return
+	}
+	var name = mux.Vars(r)["kubefile"]
+	err = km.DeleteKubernetesFileVersion(name, version.Name)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusInternalServerError, fmt.Sprintf("Error deleting Kubernetes file: %s version: %s, message: %v", name, version.Name, err), getRestV1RepositoryKubeFileVersionApiReference(r, "DELETE"), nil)
+		return
+	}
+	versions, _ := km.GetKubernetesFileVersions(name)
+	if err := getKubeFileDocuments(s.DataManager, *repo).sync(name, versions); err != nil {
+		writeResponse(s.Log, w, r, http.StatusInternalServerError, documentsSyncMessage("Kubernetes file", name, repo.Name, err), getRestV1RepositoryKubeFileVersionApiReference(r, "DELETE"), nil)
+		return
+	}
+	version.State = model.StateDeleted
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1RepositoryKubeFileVersionApiReference(r, "DELETE"), RestV1RepositoryKubeFileVersionResponse{
+		Repository: repo.Name,
+		KubeFile:   name,
+		Version:    *version,
+	})
+}
@@ -13,20 +13,20 @@ import (
 func getRestV1RepositoryRootApiReference(method string) model.ApiReference {
 	var items = make([]model.ApiReferenceItem, 0)
 	items = append(items, model.ApiReferenceItem{
-		"GET",
-		"/v1/repositories",
+		Name: "GET",
+		Url:  "/v1/repositories",
 	})
 	items = append(items, model.ApiReferenceItem{
-		"POST",
-		"/v1/repositories",
+		Name: "POST",
+		Url:  "/v1/repositories",
 	})
 	items = append(items, model.ApiReferenceItem{
-		"PUT",
-		"/v1/repositories",
+		Name: "PUT",
+		Url:  "/v1/repositories",
 	})
 	items = append(items, model.ApiReferenceItem{
-		"DELETE",
-		"/v1/repositories",
+		Name: "DELETE",
+		Url:  "/v1/repositories",
 	})
 	return model.ApiReference{
 		CurrentUrl:    "/v1/repositories",
@@ -0,0 +1,438 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/hellgate75/k8s-deploy/log"
+	"github.com/hellgate75/k8s-deploy/model"
+	"github.com/hellgate75/k8s-deploy/model/rest"
+	"github.com/hellgate75/k8s-deploy/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	deployStateParameter    = "state"
+	deployPurgeParameter    = "purge"
+	scheduleCountParameter  = "count"
+	scheduleDeployParameter = "deploy"
+	// Default number of planned executions listed by the schedule
+	defaultScheduleCount = 10
+	// Maximum number of planned executions listed by the schedule
+	maxScheduleCount = 1000
+)
+
+func getRestV1DeploysApiReference(method string) model.ApiReference {
+	return getApiReference("/v1/deploys", method, "GET", "POST")
+}
+
+func getRestV1DeployApiReference(r *http.Request, method string) model.ApiReference {
+	return getApiReference(fmt.Sprintf("/v1/deploys/%s", mux.Vars(r)["deploy"]), method, "GET", "PUT", "DELETE")
+}
+
+func getRestV1SchedulerQueueApiReference(method string) model.ApiReference {
+	return getApiReference("/v1/queue", method, "GET")
+}
+
+func getRestV1SchedulerScheduleApiReference(method string) model.ApiReference {
+	return getApiReference("/v1/schedule", method, "GET")
+}
+
+type RestV1DeploysResponse struct {
+	Deploys []model.Deploy `yaml:"deploys" json:"deploys" xml:"deploy"`
+}
+
+type RestV1SchedulerQueueResponse struct {
+	Queue []model.QueueItem `yaml:"queue" json:"queue" xml:"queue-item"`
+}
+
+type RestV1SchedulerScheduleResponse struct {
+	Schedule []model.QueueItem `yaml:"schedule" json:"schedule" xml:"schedule-item"`
+}
+
+// Recovers a deploy from the path variable, by id or by name
+func getPathDeploy(manager model.DeployDataManager, r *http.Request) (*model.Deploy, error) {
+	var key = strings.TrimSpace(mux.Vars(r)["deploy"])
+	if key == "" {
+		return nil, errors.New("Deploy id or name must be valid and not empty")
+	}
+	if d := manager.GetDeploy(key); d != nil {
+		return d, nil
+	}
+	if d := manager.GetDeployByName(key); d != nil {
+		return d, nil
+	}
+	return nil, errors.New(fmt.Sprintf("Deploy %s not found", key))
+}
+
+// Creates a query matching all the given keys provided as query string parameters
+func getRequestQuery(r *http.Request, keys ...string) []model.Query {
+	var items = make([]model.QueryItem, 0)
+	for _, k := range keys {
+		if v := strings.TrimSpace(r.URL.Query().Get(k)); v != "" {
+			items = append(items, model.QueryItem{
+				Key:        k,
+				Value:      v,
+				Aggregator: model.AggregatorEq,
+			})
+		}
+	}
+	if len(items) == 0 {
+		return []model.Query{}
+	}
+	return []model.Query{
+		{
+			Items: items,
+			Oper:  model.OperAnd,
+		},
+	}
+}
+
+// Collects the deploys from a data response
+func responseDeploys(resp model.DataResponse) []model.Deploy {
+	var list = make([]model.Deploy, 0)
+	for _, o := range resp.ResponseObjects {
+		if d, ok := o.(model.Deploy); ok {
+			list = append(list, d)
+		}
+	}
+	return list
+}
+
+// Sample deploy definition, used in request templates
+func getDeployTemplate() model.Deploy {
+	return model.Deploy{
+		Name: "<name>",
+		Job: []model.Job{
+			{
+				ProjectId: "<project id or name>",
+				VersionId: "<project version, current version when empty>",
+				DependsOn: []string{"<job name or id, or job definition name>"},
+				Instance: model.Instance{
+					Name:       "<job name prefix, project name when empty>",
+					Parameters: []model.Param{{Name: "<name>", Value: "<value>"}},
+					Values: model.ValueSet{
+						Value: []model.Value{{Name: "<name>", Value: "<value>", Valid: true}},
+					},
+				},
+			},
+		},
+		Trigger: model.DeployTrigger{
+			Type:     model.TriggerTypeCron,
+			At:       time.Now().UTC().Truncate(time.Minute),
+			Cron:     "<minute hour day-of-month month day-of-week, used by cron triggers>",
+			Timezone: "<IANA timezone, UTC when empty>",
+			Blackouts: []model.BlackoutWindow{
+				{From: "Fri 16:00", To: "Mon 08:00"},
+			},
+		},
+		Rollback: model.RollbackPolicy("<auto|manual|none, auto when empty>"),
+	}
+}
+
+// RestV1DeploysService is an implementation of RestService interface, managing the scheduler deploys.
+type RestV1DeploysService struct {
+	Log           log.Logger
+	BaseUrl       string
+	Configuration model.KubeRepoConfig
+	DataManager   model.DeployDataManager
+	Scheduler     model.DeployScheduler
+}
+
+// Create is HTTP handler of POST model.Request.
+// Use for submitting a new deploy, jobs referring a project are expanded to the project version charts and Kubernetes files.
+func (s *RestV1DeploysService) Create(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1DeploysService.Create() - Path: %s ...", r.URL.Path)
+	var request = model.Deploy{}
+	err := utils.RestParseRequest(w, r, &request)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusBadRequest, fmt.Sprintf("Error parsing request: %v", err), getRestV1DeploysApiReference("POST"), nil)
+		return
+	}
+	d, err := s.Scheduler.Submit(request)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusBadRequest, fmt.Sprintf("Error submitting deploy: %s, message: %v", request.Name, err), getRestV1DeploysApiReference("POST"), nil)
+		return
+	}
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1DeploysApiReference("POST"), *d)
+}
+
+// Read is HTTP handler of GET model.Request.
+// Use for listing the deploys, optionally filtered by name and state.
+func (s *RestV1DeploysService) Read(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1DeploysService.Read() - Path: %s ...", r.URL.Path)
+	if strings.ToLower(r.URL.Query().Get("action")) == "template" {
+		writeRequestTemplates(s.Log, w, r,
+			rest.TemplateDataType{
+				Method:  "GET",
+				Header:  []string{},
+				Query:   []string{"action=template", "name=<name>", "state=<state>"},
+				Request: nil,
+			},
+			rest.TemplateDataType{
+				Method:  "POST",
+				Header:  []string{"Content-Type: application/json|text/yaml|application/xml"},
+				Query:   []string{},
+				Request: getDeployTemplate(),
+			})
+		return
+	}
+	resp := s.DataManager.QueryDeploys(getRequestQuery(r, "name", "state")...)
+	if !resp.Success {
+		writeResponse(s.Log, w, r, http.StatusInternalServerError, resp.Message, getRestV1DeploysApiReference("GET"), nil)
+		return
+	}
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1DeploysApiReference("GET"), RestV1DeploysResponse{
+		Deploys: responseDeploys(resp),
+	})
+}
+
+// Update is HTTP handler of PUT model.Request.
+// Not allowed on the deploys list.
+func (s *RestV1DeploysService) Update(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1DeploysService.Update() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusMethodNotAllowed, "Method PUT not allowed on deploys list", getRestV1DeploysApiReference("PUT"), nil)
+}
+
+// Delete is HTTP handler of DELETE model.Request.
+// Not allowed on the deploys list.
+func (s *RestV1DeploysService) Delete(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1DeploysService.Delete() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusMethodNotAllowed, "Method DELETE not allowed on deploys list", getRestV1DeploysApiReference("DELETE"), nil)
+}
+
+// RestV1DeployService is an implementation of RestService interface, managing a scheduler deploy.
+type RestV1DeployService struct {
+	Log           log.Logger
+	BaseUrl       string
+	Configuration model.KubeRepoConfig
+	DataManager   model.DeployDataManager
+	Scheduler     model.DeployScheduler
+}
+
+// Create is HTTP handler of POST model.Request.
+// Not allowed on a deploy, use PUT with action=trigger to start a queued deploy.
+func (s *RestV1DeployService) Create(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1DeployService.Create() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusMethodNotAllowed, "Method POST not allowed on deploy", getRestV1DeployApiReference(r, "POST"), nil)
+}
+
+// Read is HTTP handler of GET model.Request.
+// Use for reading a deploy and its jobs.
+func (s *RestV1DeployService) Read(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1DeployService.Read() - Path: %s ...", r.URL.Path)
+	if strings.ToLower(r.URL.Query().Get("action")) == "template" {
+		writeRequestTemplates(s.Log, w, r,
+			rest.TemplateDataType{
+				Method:  "GET",
+				Header:  []string{},
+				Query:   []string{"action=template"},
+				Request: nil,
+			},
+			rest.TemplateDataType{
+				Method:  "PUT",
+				Header:  []string{"Content-Type: application/json|text/yaml|application/xml", "ACTION: trigger", "STATE: <state>"},
+				Query:   []string{"action=trigger", "state=<state>"},
+				Request: getDeployTemplate(),
+			},
+			rest.TemplateDataType{
+				Method:  "DELETE",
+				Header:  []string{"PURGE: true|false"},
+				Query:   []string{"purge=true|false"},
+				Request: nil,
+			})
+		return
+	}
+	d, err := getPathDeploy(s.DataManager, r)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusNotFound, err.Error(), getRestV1DeployApiReference(r, "GET"), nil)
+		return
+	}
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1DeployApiReference(r, "GET"), *d)
+}
+
+// Update is HTTP handler of PUT model.Request.
+// Use for triggering a queued deploy (action=trigger), changing the deploy state (state=<state>) or overriding
+// the deploy with the request body.
+func (s *RestV1DeployService) Update(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1DeployService.Update() - Path: %s ...", r.URL.Path)
+	d, err := getPathDeploy(s.DataManager, r)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusNotFound, err.Error(), getRestV1DeployApiReference(r, "PUT"), nil)
+		return
+	}
+	if strings.ToLower(getRequestParameter(r, "action")) == "trigger" {
+		td, err := s.Scheduler.Trigger(d.Id)
+		if err != nil {
+			writeResponse(s.Log, w, r, http.StatusConflict, fmt.Sprintf("Error triggering deploy: %s, message: %v", d.Name, err), getRestV1DeployApiReference(r, "PUT"), nil)
+			return
+		}
+		writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1DeployApiReference(r, "PUT"), *td)
+		return
+	}
+	var resp model.DataResponse
+	if state := strings.TrimSpace(getRequestParameter(r, deployStateParameter)); state != "" {
+		resp = s.DataManager.UpdateDeployState(d.Id, model.State(state))
+	} else {
+		var request = model.Deploy{}
+		err = utils.RestParseRequest(w, r, &request)
+		if err != nil {
+			writeResponse(s.Log, w, r, http.StatusBadRequest, fmt.Sprintf("Error parsing request: %v", err), getRestV1DeployApiReference(r, "PUT"), nil)
+			return
+		}
+		if _, err := model.BuildDeployPlan(request); err != nil {
+			writeResponse(s.Log, w, r, http.StatusBadRequest, fmt.Sprintf("Invalid job dependencies: %v", err), getRestV1DeployApiReference(r, "PUT"), nil)
+			return
+		}
+		if !request.Rollback.IsValid() {
+			writeResponse(s.Log, w, r, http.StatusBadRequest, fmt.Sprintf("Invalid rollback policy: %s", request.Rollback), getRestV1DeployApiReference(r, "PUT"), nil)
+			return
+		}
+		resp = s.DataManager.OverrideDeploy(d.Id, request)
+	}
+	if !resp.Success {
+		writeResponse(s.Log, w, r, http.StatusBadRequest, resp.Message, getRestV1DeployApiReference(r, "PUT"), nil)
+		return
+	}
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1DeployApiReference(r, "PUT"), resp.ResponseObjects[0])
+}
+
+// Delete is HTTP handler of DELETE model.Request.
+// Use for deleting a deploy or, with purge=true, for removing it permanently.
+func (s *RestV1DeployService) Delete(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1DeployService.Delete() - Path: %s ...", r.URL.Path)
+	d, err := getPathDeploy(s.DataManager, r)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusNotFound, err.Error(), getRestV1DeployApiReference(r, "DELETE"), nil)
+		return
+	}
+	var query = model.Query{
+		Items: []model.QueryItem{{Key: "id", Value: d.Id, Aggregator: model.AggregatorEq}},
+		Oper:  model.OperAnd,
+	}
+	var resp model.DataResponse
+	if parseBool(getRequestParameter(r, deployPurgeParameter)) {
+		resp = s.DataManager.PurgeDeploys(query)
+	} else {
+		resp = s.DataManager.DeleteDeploys(query)
+	}
+	if !resp.Success || resp.Changes == 0 {
+		writeResponse(s.Log, w, r, http.StatusConflict, fmt.Sprintf("Error deleting deploy: %s, message: %s", d.Name, resp.Message), getRestV1DeployApiReference(r, "DELETE"), nil)
+		return
+	}
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1DeployApiReference(r, "DELETE"), RestV1DeploysResponse{
+		Deploys: responseDeploys(resp),
+	})
+}
+
+// RestV1SchedulerQueueService is an implementation of RestService interface, exposing the scheduler queue.
+type RestV1SchedulerQueueService struct {
+	Log           log.Logger
+	BaseUrl       string
+	Configuration model.KubeRepoConfig
+	Scheduler     model.DeployScheduler
+}
+
+// Create is HTTP handler of POST model.Request.
+// Not allowed on the scheduler queue.
+func (s *RestV1SchedulerQueueService) Create(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1SchedulerQueueService.Create() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusMethodNotAllowed, "Method POST not allowed on scheduler queue", getRestV1SchedulerQueueApiReference("POST"), nil)
+}
+
+// Read is HTTP handler of GET model.Request.
+// Use for listing the queued deploys, sorted by due time.
+func (s *RestV1SchedulerQueueService) Read(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1SchedulerQueueService.Read() - Path: %s ...", r.URL.Path)
+	items, err := s.Scheduler.Queue()
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusInternalServerError, fmt.Sprintf("Error reading scheduler queue: %v", err), getRestV1SchedulerQueueApiReference("GET"), nil)
+		return
+	}
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1SchedulerQueueApiReference("GET"), RestV1SchedulerQueueResponse{
+		Queue: items,
+	})
+}
+
+// Update is HTTP handler of PUT model.Request.
+// Not allowed on the scheduler queue.
+func (s *RestV1SchedulerQueueService) Update(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1SchedulerQueueService.Update() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusMethodNotAllowed, "Method PUT not allowed on scheduler queue", getRestV1SchedulerQueueApiReference("PUT"), nil)
+}
+
+// Delete is HTTP handler of DELETE model.Request.
+// Not allowed on the scheduler queue.
+func (s *RestV1SchedulerQueueService) Delete(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1SchedulerQueueService.Delete() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusMethodNotAllowed, "Method DELETE not allowed on scheduler queue", getRestV1SchedulerQueueApiReference("DELETE"), nil)
+}
+
+// RestV1SchedulerScheduleService is an implementation of RestService interface, exposing the planned executions of
+// the queued deploys.
+type RestV1SchedulerScheduleService struct {
+	Log           log.Logger
+	BaseUrl       string
+	Configuration model.KubeRepoConfig
+	DataManager   model.DeployDataManager
+	Scheduler     model.DeployScheduler
+}
+
+// Create is HTTP handler of POST model.Request.
+// Not allowed on the scheduler schedule.
+func (s *RestV1SchedulerScheduleService) Create(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1SchedulerScheduleService.Create() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusMethodNotAllowed, "Method POST not allowed on scheduler schedule", getRestV1SchedulerScheduleApiReference("POST"), nil)
+}
+
+// Read is HTTP handler of GET model.Request.
+// Use for listing the next planned executions (count=<n>), optionally of a single deploy (deploy=<id or name>).
+func (s *RestV1SchedulerScheduleService) Read(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1SchedulerScheduleService.Read() - Path: %s ...", r.URL.Path)
+	var count = defaultScheduleCount
+	if c := strings.TrimSpace(getRequestParameter(r, scheduleCountParameter)); c != "" {
+		n, err := strconv.Atoi(c)
+		if err != nil || n <= 0 || n > maxScheduleCount {
+			writeResponse(s.Log, w, r, http.StatusBadRequest, fmt.Sprintf("Invalid count: %s, expected a number between 1 and %v", c, maxScheduleCount), getRestV1SchedulerScheduleApiReference("GET"), nil)
+			return
+		}
+		count = n
+	}
+	var deployId = ""
+	if key := strings.TrimSpace(getRequestParameter(r, scheduleDeployParameter)); key != "" {
+		d := s.DataManager.GetDeploy(key)
+		if d == nil {
+			d = s.DataManager.GetDeployByName(key)
+		}
+		if d == nil {
+			writeResponse(s.Log, w, r, http.StatusNotFound, fmt.Sprintf("Deploy %s not found", key), getRestV1SchedulerScheduleApiReference("GET"), nil)
+			return
+		}
+		deployId = d.Id
+	}
+	items, err := s.Scheduler.Planned(deployId, count)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusInternalServerError, fmt.Sprintf("Error reading scheduler schedule: %v", err), getRestV1SchedulerScheduleApiReference("GET"), nil)
+		return
+	}
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1SchedulerScheduleApiReference("GET"), RestV1SchedulerScheduleResponse{
+		Schedule: items,
+	})
+}
+
+// Update is HTTP handler of PUT model.Request.
+// Not allowed on the scheduler schedule.
+func (s *RestV1SchedulerScheduleService) Update(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1SchedulerScheduleService.Update() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusMethodNotAllowed, "Method PUT not allowed on scheduler schedule", getRestV1SchedulerScheduleApiReference("PUT"), nil)
+}
+
+// Delete is HTTP handler of DELETE model.Request.
+// Not allowed on the scheduler schedule.
+func (s *RestV1SchedulerScheduleService) Delete(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1SchedulerScheduleService.Delete() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusMethodNotAllowed, "Method DELETE not allowed on scheduler schedule", getRestV1SchedulerScheduleApiReference("DELETE"), nil)
+}
@@ -0,0 +1,287 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/hellgate75/k8s-deploy/log"
+	"github.com/hellgate75/k8s-deploy/model"
+	"github.com/hellgate75/k8s-deploy/model/rest"
+	"github.com/hellgate75/k8s-deploy/utils"
+	"net/http"
+	"strings"
+)
+
+func getRestV1DeployJobsApiReference(r *http.Request, method string) model.ApiReference {
+	return getApiReference(fmt.Sprintf("/v1/deploys/%s/jobs", mux.Vars(r)["deploy"]), method, "GET", "POST")
+}
+
+func getRestV1DeployJobApiReference(r *http.Request, method string) model.ApiReference {
+	var vars = mux.Vars(r)
+	return getApiReference(fmt.Sprintf("/v1/deploys/%s/jobs/%s", vars["deploy"], vars["job"]), method, "GET", "PUT", "DELETE")
+}
+
+type RestV1DeployJobsResponse struct {
+	Deploy string      `yaml:"deploy" json:"deploy" xml:"deploy"`
+	Jobs   []model.Job `yaml:"jobs" json:"jobs" xml:"job"`
+}
+
+// Collects the jobs from a data response
+func responseJobs(resp model.DataResponse) []model.Job {
+	var list = make([]model.Job, 0)
+	for _, o := range resp.ResponseObjects {
+		if j, ok := o.(model.Job); ok {
+			list = append(list, j)
+		}
+	}
+	return list
+}
+
+func getDeployJobsManager(manager model.DeployDataManager, r *http.Request) (*model.Deploy, model.JobsDataManager, error) {
+	d, err := getPathDeploy(manager, r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return d, *manager.AccessDeploy(*d), nil
+}
+
+// Recovers a deploy job from the path variable, by id or by name
+func getPathJob(jm model.JobsDataManager, r *http.Request) (*model.Job, error) {
+	var key = strings.TrimSpace(mux.Vars(r)["job"])
+	if key == "" {
+		return nil, errors.New("Job id or name must be valid and not empty")
+	}
+	if j := jm.GetJob(key); j != nil {
+		return j, nil
+	}
+	if j := jm.GetJobByName(key); j != nil {
+		return j, nil
+	}
+	return nil, errors.New(fmt.Sprintf("Job %s not found", key))
+}
+
+// RestV1DeployJobsService is an implementation of RestService interface, managing the jobs of a scheduler deploy.
+type RestV1DeployJobsService struct {
+	Log           log.Logger
+	BaseUrl       string
+	Configuration model.KubeRepoConfig
+	DataManager   model.DeployDataManager
+	Scheduler     model.DeployScheduler
+}
+
+// Create is HTTP handler of POST model.Request.
+// Use for adding jobs to a deploy, a job referring a project is expanded to the project version charts and Kubernetes files.
+func (s *RestV1DeployJobsService) Create(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1DeployJobsService.Create() - Path: %s ...", r.URL.Path)
+	d, err := getPathDeploy(s.DataManager, r)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusNotFound, err.Error(), getRestV1DeployJobsApiReference(r, "POST"), nil)
+		return
+	}
+	var request = model.Job{}
+	err = utils.RestParseRequest(w, r, &request)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusBadRequest, fmt.Sprintf("Error parsing request: %v", err), getRestV1DeployJobsApiReference(r, "POST"), nil)
+		return
+	}
+	jobs, err := s.Scheduler.AddJobs(d.Id, request)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusBadRequest, fmt.Sprintf("Error adding jobs to deploy: %s, message: %v", d.Name, err), getRestV1DeployJobsApiReference(r, "POST"), RestV1DeployJobsResponse{
+			Deploy: d.Name,
+			Jobs:   jobs,
+		})
+		return
+	}
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1DeployJobsApiReference(r, "POST"), RestV1DeployJobsResponse{
+		Deploy: d.Name,
+		Jobs:   jobs,
+	})
+}
+
+// Read is HTTP handler of GET model.Request.
+// Use for listing the deploy jobs, optionally filtered by name, state, project and document.
+func (s *RestV1DeployJobsService) Read(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1DeployJobsService.Read() - Path: %s ...", r.URL.Path)
+	if strings.ToLower(r.URL.Query().Get("action")) == "template" {
+		writeRequestTemplates(s.Log, w, r,
+			rest.TemplateDataType{
+				Method:  "GET",
+				Header:  []string{},
+				Query:   []string{"action=template", "name=<name>", "state=<state>", "project=<project id>", "document=<document id>"},
+				Request: nil,
+			},
+			rest.TemplateDataType{
+				Method:  "POST",
+				Header:  []string{"Content-Type: application/json|text/yaml|application/xml"},
+				Query:   []string{},
+				Request: getDeployTemplate().Job[0],
+			})
+		return
+	}
+	d, jm, err := getDeployJobsManager(s.DataManager, r)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusNotFound, err.Error(), getRestV1DeployJobsApiReference(r, "GET"), nil)
+		return
+	}
+	resp := jm.QueryJobs(getRequestQuery(r, "name", "state", "project", "document")...)
+	if !resp.Success {
+		writeResponse(s.Log, w, r, http.StatusInternalServerError, resp.Message, getRestV1DeployJobsApiReference(r, "GET"), nil)
+		return
+	}
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1DeployJobsApiReference(r, "GET"), RestV1DeployJobsResponse{
+		Deploy: d.Name,
+		Jobs:   responseJobs(resp),
+	})
+}
+
+// Update is HTTP handler of PUT model.Request.
+// Not allowed on the deploy jobs list.
+func (s *RestV1DeployJobsService) Update(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1DeployJobsService.Update() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusMethodNotAllowed, "Method PUT not allowed on deploy jobs list", getRestV1DeployJobsApiReference(r, "PUT"), nil)
+}
+
+// Delete is HTTP handler of DELETE model.Request.
+// Not allowed on the deploy jobs list.
+func (s *RestV1DeployJobsService) Delete(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1DeployJobsService.Delete() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusMethodNotAllowed, "Method DELETE not allowed on deploy jobs list", getRestV1DeployJobsApiReference(r, "DELETE"), nil)
+}
+
+// RestV1DeployJobService is an implementation of RestService interface, managing a job of a scheduler deploy.
+type RestV1DeployJobService struct {
+	Log           log.Logger
+	BaseUrl       string
+	Configuration model.KubeRepoConfig
+	DataManager   model.DeployDataManager
+}
+
+// Create is HTTP handler of POST model.Request.
+// Not allowed on a deploy job.
+func (s *RestV1DeployJobService) Create(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1DeployJobService.Create() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusMethodNotAllowed, "Method POST not allowed on deploy job", getRestV1DeployJobApiReference(r, "POST"), nil)
+}
+
+// Read is HTTP handler of GET model.Request.
+// Use for reading a deploy job.
+func (s *RestV1DeployJobService) Read(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1DeployJobService.Read() - Path: %s ...", r.URL.Path)
+	if strings.ToLower(r.URL.Query().Get("action")) == "template" {
+		writeRequestTemplates(s.Log, w, r,
+			rest.TemplateDataType{
+				Method:  "GET",
+				Header:  []string{},
+				Query:   []string{"action=template"},
+				Request: nil,
+			},
+			rest.TemplateDataType{
+				Method:  "PUT",
+				Header:  []string{"Content-Type: application/json|text/yaml|application/xml", "STATE: <state>"},
+				Query:   []string{"state=<state>"},
+				Request: getDeployTemplate().Job[0],
+			},
+			rest.TemplateDataType{
+				Method:  "DELETE",
+				Header:  []string{"PURGE: true|false"},
+				Query:   []string{"purge=true|false"},
+				Request: nil,
+			})
+		return
+	}
+	_, jm, err := getDeployJobsManager(s.DataManager, r)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusNotFound, err.Error(), getRestV1DeployJobApiReference(r, "GET"), nil)
+		return
+	}
+	j, err := getPathJob(jm, r)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusNotFound, err.Error(), getRestV1DeployJobApiReference(r, "GET"), nil)
+		return
+	}
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1DeployJobApiReference(r, "GET"), *j)
+}
+
+// Update is HTTP handler of PUT model.Request.
+// Use for changing the job state (state=<state>) or overriding the job with the request body.
+func (s *RestV1DeployJobService) Update(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1DeployJobService.Update() - Path: %s ...", r.URL.Path)
+	d, jm, err := getDeployJobsManager(s.DataManager, r)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusNotFound, err.Error(), getRestV1DeployJobApiReference(r, "PUT"), nil)
+		return
+	}
+	j, err := getPathJob(jm, r)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusNotFound, err.Error(), getRestV1DeployJobApiReference(r, "PUT"), nil)
+		return
+	}
+	var resp model.DataResponse
+	if state := strings.TrimSpace(getRequestParameter(r, deployStateParameter)); state != "" {
+		resp = jm.UpdateJobState(j.Instance.Id, model.State(state))
+	} else {
+		var request = model.Job{}
+		err = utils.RestParseRequest(w, r, &request)
+		if err != nil {
+			writeResponse(s.Log, w, r, http.StatusBadRequest, fmt.Sprintf("Error parsing request: %v", err), getRestV1DeployJobApiReference(r, "PUT"), nil)
+			return
+		}
+		var check = *d
+		check.Job = make([]model.Job, 0)
+		for _, cj := range d.Job {
+			if cj.Instance.Id == j.Instance.Id {
+				var nj = request
+				nj.Instance.Id = j.Instance.Id
+				if nj.Instance.Name == "" {
+					nj.Instance.Name = j.Instance.Name
+				}
+				cj = nj
+			}
+			check.Job = append(check.Job, cj)
+		}
+		if _, err := model.BuildDeployPlan(check); err != nil {
+			writeResponse(s.Log, w, r, http.StatusBadRequest, fmt.Sprintf("Invalid job dependencies: %v", err), getRestV1DeployJobApiReference(r, "PUT"), nil)
+			return
+		}
+		resp = jm.OverrideJob(j.Instance.Id, request)
+	}
+	if !resp.Success || len(resp.ResponseObjects) == 0 {
+		writeResponse(s.Log, w, r, http.StatusBadRequest, resp.Message, getRestV1DeployJobApiReference(r, "PUT"), nil)
+		return
+	}
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1DeployJobApiReference(r, "PUT"), resp.ResponseObjects[0])
+}
+
+// Delete is HTTP handler of DELETE model.Request.
+// Use for deleting a deploy job or, with purge=true, for removing it permanently.
+func (s *RestV1DeployJobService) Delete(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1DeployJobService.Delete() - Path: %s ...", r.URL.Path)
+	d, jm, err := getDeployJobsManager(s.DataManager, r)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusNotFound, err.Error(), getRestV1DeployJobApiReference(r, "DELETE"), nil)
+		return
+	}
+	j, err := getPathJob(jm, r)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusNotFound, err.Error(), getRestV1DeployJobApiReference(r, "DELETE"), nil)
+		return
+	}
+	var query = model.Query{
+		Items: []model.QueryItem{{Key: "id", Value: j.Instance.Id, Aggregator: model.AggregatorEq}},
+		Oper:  model.OperAnd,
+	}
+	var resp model.DataResponse
+	if parseBool(getRequestParameter(r, deployPurgeParameter)) {
+		resp = jm.PurgeJobs(query)
+	} else {
+		resp = jm.DeleteJobs(query)
+	}
+	if !resp.Success || resp.Changes == 0 {
+		writeResponse(s.Log, w, r, http.StatusConflict, fmt.Sprintf("Error deleting job: %s, message: %s", j.Instance.Name, resp.Message), getRestV1DeployJobApiReference(r, "DELETE"), nil)
+		return
+	}
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1DeployJobApiReference(r, "DELETE"), RestV1DeployJobsResponse{
+		Deploy: d.Name,
+		Jobs:   responseJobs(resp),
+	})
+}
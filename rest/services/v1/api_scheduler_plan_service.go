@@ -0,0 +1,59 @@
+package v1
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/hellgate75/k8s-deploy/log"
+	"github.com/hellgate75/k8s-deploy/model"
+	"net/http"
+)
+
+func getRestV1DeployPlanApiReference(r *http.Request, method string) model.ApiReference {
+	return getApiReference(fmt.Sprintf("/v1/deploys/%s/plan", mux.Vars(r)["deploy"]), method, "GET")
+}
+
+// RestV1DeployPlanService is an implementation of RestService interface, exposing the deploy execution plan.
+type RestV1DeployPlanService struct {
+	Log           log.Logger
+	BaseUrl       string
+	Configuration model.KubeRepoConfig
+	DataManager   model.DeployDataManager
+}
+
+// Create is HTTP handler of POST model.Request.
+// Not allowed on a deploy plan.
+func (s *RestV1DeployPlanService) Create(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1DeployPlanService.Create() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusMethodNotAllowed, "Method POST not allowed on deploy plan", getRestV1DeployPlanApiReference(r, "POST"), nil)
+}
+
+// Read is HTTP handler of GET model.Request.
+// Use for reading the deploy jobs in topological order, grouped by stages of jobs running in parallel.
+func (s *RestV1DeployPlanService) Read(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1DeployPlanService.Read() - Path: %s ...", r.URL.Path)
+	d, err := getPathDeploy(s.DataManager, r)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusNotFound, err.Error(), getRestV1DeployPlanApiReference(r, "GET"), nil)
+		return
+	}
+	plan, err := model.BuildDeployPlan(*d)
+	if err != nil {
+		writeResponse(s.Log, w, r, http.StatusConflict, fmt.Sprintf("Error planning deploy: %s, message: %v", d.Name, err), getRestV1DeployPlanApiReference(r, "GET"), nil)
+		return
+	}
+	writeResponse(s.Log, w, r, http.StatusOK, "OK", getRestV1DeployPlanApiReference(r, "GET"), *plan)
+}
+
+// Update is HTTP handler of PUT model.Request.
+// Not allowed on a deploy plan.
+func (s *RestV1DeployPlanService) Update(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1DeployPlanService.Update() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusMethodNotAllowed, "Method PUT not allowed on deploy plan", getRestV1DeployPlanApiReference(r, "PUT"), nil)
+}
+
+// Delete is HTTP handler of DELETE model.Request.
+// Not allowed on a deploy plan.
+func (s *RestV1DeployPlanService) Delete(w http.ResponseWriter, r *http.Request) {
+	s.Log.Infof("RestV1DeployPlanService.Delete() - Path: %s ...", r.URL.Path)
+	writeResponse(s.Log, w, r, http.StatusMethodNotAllowed, "Method DELETE not allowed on deploy plan", getRestV1DeployPlanApiReference(r, "DELETE"), nil)
+}
@@ -0,0 +1,222 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Maximum search range of the next cron occurrence
+const cronSearchYears = 5
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var cronMonthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var cronDayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// Cron expression, in the standard five fields format: minute hour day-of-month month day-of-week.
+// Fields accept *, values, names (months and week days), ranges, lists and steps. As in Vixie cron, fixed time
+// expressions (minute and hour not starting with *) run once on daylight saving time changes
+type cronSchedule struct {
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+	domAny bool
+	dowAny bool
+	fixed  bool
+}
+
+// Parses a value or a name, when names are available for the field
+func parseCronValue(s string, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	return strconv.Atoi(s)
+}
+
+// Parses a cron field into a bit set, reporting if the field is unrestricted: as in Vixie cron, a field starting
+// with * (e.g. */2) is unrestricted for the day of month and day of week matching
+func parseCronField(field string, min int, max int, names map[string]int) (uint64, bool, error) {
+	var bits uint64
+	var any = strings.HasPrefix(field, "*")
+	for _, part := range strings.Split(field, ",") {
+		var step = 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return 0, false, errors.New(fmt.Sprintf("Invalid step in cron field: %s", field))
+			}
+			part = part[:i]
+		}
+		var from, to int
+		switch {
+		case part == "*":
+			from, to = min, max
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err1, err2 error
+			from, err1 = parseCronValue(bounds[0], names)
+			to, err2 = parseCronValue(bounds[1], names)
+			if err1 != nil || err2 != nil {
+				return 0, false, errors.New(fmt.Sprintf("Invalid range in cron field: %s", field))
+			}
+		default:
+			v, err := parseCronValue(part, names)
+			if err != nil {
+				return 0, false, errors.New(fmt.Sprintf("Invalid value in cron field: %s", field))
+			}
+			from, to = v, v
+			if step > 1 {
+				to = max
+			}
+		}
+		if from < min || to > max || from > to {
+			return 0, false, errors.New(fmt.Sprintf("Cron field %s out of range %v-%v", field, min, max))
+		}
+		for v := from; v <= to; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, any, nil
+}
+
+// Parses a cron expression or a descriptor (@yearly, @monthly, @weekly, @daily, @hourly)
+func parseCron(expr string) (*cronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if d, ok := cronDescriptors[strings.ToLower(expr)]; ok {
+		expr = d
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, errors.New(fmt.Sprintf("Cron expression %s must have 5 fields: minute hour day-of-month month day-of-week", expr))
+	}
+	var c = cronSchedule{}
+	var minuteAny, hourAny bool
+	var err error
+	if c.minute, minuteAny, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, err
+	}
+	if c.hour, hourAny, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, err
+	}
+	c.fixed = !minuteAny && !hourAny
+	if c.dom, c.domAny, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, err
+	}
+	if c.month, _, err = parseCronField(fields[3], 1, 12, cronMonthNames); err != nil {
+		return nil, err
+	}
+	if c.dow, c.dowAny, err = parseCronField(fields[4], 0, 7, cronDayNames); err != nil {
+		return nil, err
+	}
+	// Sunday is both 0 and 7
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	return &c, nil
+}
+
+// Verifies the day matches: when both day of month and day of week are restricted, any of them matches,
+// otherwise both must match
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	var domMatch = c.dom&(1<<uint(t.Day())) != 0
+	var dowMatch = c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// Computes the first time of a day, on days starting with a daylight saving time gap midnight doesn't exist and
+// the day starts at the gap end
+func dayStart(year int, month time.Month, day int, loc *time.Location) time.Time {
+	var t = time.Date(year, month, day, 0, 0, 0, 0, loc)
+	if noon := time.Date(year, month, day, 12, 0, 0, 0, loc); t.Day() != noon.Day() {
+		_, before := t.Zone()
+		_, after := noon.Zone()
+		t = t.Add(time.Duration(after-before) * time.Second)
+	}
+	return t
+}
+
+// Verifies a daylight saving time gap, right before the given time, skipped a matching hour of the day
+func (c *cronSchedule) gapMatches(t time.Time) bool {
+	var before = t.Add(-time.Minute)
+	var from = 0
+	if before.Day() == t.Day() {
+		from = before.Hour() + 1
+	}
+	for h := from; h < t.Hour(); h++ {
+		if c.hour&(1<<uint(h)) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Verifies the wall clock time already occurred, before a daylight saving time change in the previous 3 hours
+func repeatedWallClock(t time.Time) bool {
+	_, offset := t.Zone()
+	_, previous := t.Add(-3 * time.Hour).Zone()
+	if previous <= offset {
+		return false
+	}
+	var first = t.Add(-time.Duration(previous-offset) * time.Second)
+	_, firstOffset := first.Zone()
+	return firstOffset == previous && first.Hour() == t.Hour() && first.Minute() == t.Minute()
+}
+
+// Computes the first occurrence strictly after the given time, in the given time location. Fixed time occurrences
+// skipped by a daylight saving time gap run at the gap end, the repeated ones run only the first time.
+// A zero time is returned when there is no occurrence in the search range
+func (c *cronSchedule) next(t time.Time) time.Time {
+	var loc = t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).Add(time.Minute)
+	var limit = t.AddDate(cronSearchYears, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = dayStart(t.Year(), t.Month()+1, 1, loc)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = dayStart(t.Year(), t.Month(), t.Day()+1, loc)
+			continue
+		}
+		if c.fixed && c.gapMatches(t) {
+			return t
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			var n = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			if !n.After(t) {
+				n = t.Add(time.Hour).Truncate(time.Hour)
+			}
+			t = n
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 || (c.fixed && repeatedWallClock(t)) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
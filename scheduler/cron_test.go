@@ -0,0 +1,86 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+const testTimeLayout = "2006-01-02 15:04 -0700"
+
+func parseTestTime(t *testing.T, value string, location string) time.Time {
+	loc, err := time.LoadLocation(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tm, err := time.Parse(testTimeLayout, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tm.In(loc)
+}
+
+func formatTestTime(tm time.Time) string {
+	if tm.IsZero() {
+		return ""
+	}
+	return tm.Format(testTimeLayout)
+}
+
+func TestCronNext(t *testing.T) {
+	for _, c := range []struct {
+		expr     string
+		location string
+		from     string
+		expected string
+	}{
+		{"*/15 * * * *", "UTC", "2020-06-01 10:30 +0000", "2020-06-01 10:45 +0000"},
+		{"0 9-17/4 * * *", "UTC", "2020-06-01 10:30 +0000", "2020-06-01 13:00 +0000"},
+		{"5,10 8-9 * * *", "UTC", "2020-06-01 08:10 +0000", "2020-06-01 09:05 +0000"},
+		{"30 8 * * mon-fri", "UTC", "2020-06-05 09:00 +0000", "2020-06-08 08:30 +0000"},
+		{"0 0 1 jan *", "UTC", "2020-06-01 10:30 +0000", "2021-01-01 00:00 +0000"},
+		{"0 12 * * 7", "UTC", "2020-06-01 10:30 +0000", "2020-06-07 12:00 +0000"},
+		{"0 12 * * SUN", "UTC", "2020-06-01 10:30 +0000", "2020-06-07 12:00 +0000"},
+		{"@hourly", "UTC", "2020-06-01 10:30 +0000", "2020-06-01 11:00 +0000"},
+		{"@weekly", "UTC", "2020-06-01 10:30 +0000", "2020-06-07 00:00 +0000"},
+		{"0 0 29 2 *", "UTC", "2020-06-01 10:30 +0000", "2024-02-29 00:00 +0000"},
+		{"0 0 31 2 *", "UTC", "2020-06-01 10:30 +0000", ""},
+		// Restricted day of month and day of week: any of them matches
+		{"0 0 13 * 5", "UTC", "2020-06-01 10:30 +0000", "2020-06-05 00:00 +0000"},
+		{"0 0 13 * 5", "UTC", "2020-06-12 00:00 +0000", "2020-06-13 00:00 +0000"},
+		// A field starting with * is unrestricted: both of them match
+		{"0 0 */2 * 1", "UTC", "2020-06-01 10:30 +0000", "2020-06-15 00:00 +0000"},
+		{"0 0 13 * */2", "UTC", "2020-06-01 10:30 +0000", "2020-06-13 00:00 +0000"},
+		{"0 0 * * 1", "UTC", "2020-06-01 10:30 +0000", "2020-06-08 00:00 +0000"},
+		// Occurrences are computed in the time location
+		{"0 9 * * *", "Europe/Rome", "2020-06-01 10:30 +0200", "2020-06-02 09:00 +0200"},
+		{"0 9 * * *", "Europe/Rome", "2020-03-28 09:00 +0100", "2020-03-29 09:00 +0200"},
+		// Fixed time occurrences skipped by a daylight saving time gap run at the gap end
+		{"30 2 * * *", "America/New_York", "2020-03-07 03:00 -0500", "2020-03-08 03:00 -0400"},
+		{"30 2 * * *", "America/New_York", "2020-03-08 03:00 -0400", "2020-03-09 02:30 -0400"},
+		{"0 2 * * *", "America/New_York", "2020-03-08 01:59 -0500", "2020-03-08 03:00 -0400"},
+		{"*/30 * * * *", "America/New_York", "2020-03-08 01:45 -0500", "2020-03-08 03:00 -0400"},
+		{"0 0 * * *", "America/Sao_Paulo", "2018-11-03 10:00 -0300", "2018-11-04 01:00 -0200"},
+		{"0 12 * * 0", "America/Sao_Paulo", "2018-11-03 10:00 -0300", "2018-11-04 12:00 -0200"},
+		// Repeated fixed time occurrences run only the first time, the others run in the repeated hour too
+		{"30 1 * * *", "America/New_York", "2020-11-01 00:00 -0400", "2020-11-01 01:30 -0400"},
+		{"30 1 * * *", "America/New_York", "2020-11-01 01:30 -0400", "2020-11-02 01:30 -0500"},
+		{"30 * * * *", "America/New_York", "2020-11-01 01:30 -0400", "2020-11-01 01:30 -0500"},
+	} {
+		cron, err := parseCron(c.expr)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %q: %v", c.expr, err)
+		}
+		if n := formatTestTime(cron.next(parseTestTime(t, c.from, c.location))); n != c.expected {
+			t.Fatalf("Expected %q next occurrence from %s %q, found %q", c.expr, c.from, c.expected, n)
+		}
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "* * * * * *", "61 * * * *", "0 24 * * *", "0 0 32 * *", "0 0 0 * *",
+		"0 0 * 13 *", "0 0 * * 8", "*/0 * * * *", "5-1 * * * *", "0 0 * foo *", "0 0 * * mon-", "@reboot"} {
+		if _, err := parseCron(expr); err == nil {
+			t.Fatalf("Expected error parsing %q", expr)
+		}
+	}
+}
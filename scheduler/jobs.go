@@ -0,0 +1,177 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hellgate75/k8s-deploy/model"
+	"strings"
+)
+
+// Recovers a project by id or, as fallback, by name
+func (s *deployScheduler) getProject(key string) (*model.Project, error) {
+	var k = strings.TrimSpace(key)
+	if k == "" {
+		return nil, errors.New("Project id or name is required")
+	}
+	if p := s.projects.GetProject(k); p != nil {
+		return p, nil
+	}
+	if p := s.projects.GetProjectByName(k); p != nil {
+		return p, nil
+	}
+	return nil, errors.New(fmt.Sprintf("Project %s not found", k))
+}
+
+// Recovers a project version, when no version is provided the project current version or, as fallback, the latest one is used
+func getProjectVersion(p *model.Project, version string) (*model.ProjectVersion, error) {
+	var v = strings.TrimSpace(version)
+	if v == "" {
+		v = strings.TrimSpace(p.Version)
+	}
+	if v == "" && len(p.Versions) > 0 {
+		v = p.Versions[len(p.Versions)-1].Version
+	}
+	for _, pv := range p.Versions {
+		if pv.Version == v {
+			if pv.State == model.StateDeleted || pv.State == model.StateError {
+				return nil, errors.New(fmt.Sprintf("Project %s version %s is in state %s", p.Name, v, pv.State))
+			}
+			return &pv, nil
+		}
+	}
+	return nil, errors.New(fmt.Sprintf("Project %s version %s not found", p.Name, v))
+}
+
+// Get a job name not used yet, a chart and a Kubernetes file with the same name get the document kind as suffix
+func uniqueJobName(used map[string]bool, name string, isChart bool) string {
+	var kind = "kubefile"
+	if isChart {
+		kind = "chart"
+	}
+	var unique = name
+	if used[unique] {
+		unique = fmt.Sprintf("%s-%s", name, kind)
+	}
+	for i := 2; used[unique]; i++ {
+		unique = fmt.Sprintf("%s-%s-%v", name, kind, i)
+	}
+	used[unique] = true
+	return unique
+}
+
+// Create a job for a project version document, inheriting the definition parameters and values
+func newDocumentJob(def model.Job, p *model.Project, pv *model.ProjectVersion, used map[string]bool, base string, id string, name string, isChart bool) model.Job {
+	return model.Job{
+		ProjectId:  p.Id,
+		VersionId:  pv.Version,
+		DocumentId: id,
+		IsChart:    isChart,
+		DependsOn:  def.DependsOn,
+		Instance: model.Instance{
+			Name:       uniqueJobName(used, fmt.Sprintf("%s-%s", base, name), isChart),
+			Version:    *pv,
+			Parameters: def.Instance.Parameters,
+			Values:     def.Instance.Values,
+		},
+	}
+}
+
+// Expand a job definition into the jobs of the project version charts and Kubernetes files.
+// A job referring a document is already expanded, it only gets the project version instance
+func (s *deployScheduler) expandJob(def model.Job) ([]model.Job, error) {
+	if strings.TrimSpace(def.ProjectId) == "" {
+		if strings.TrimSpace(def.DocumentId) == "" {
+			return nil, errors.New(fmt.Sprintf("Job %s requires a project or a document", def.Instance.Name))
+		}
+		return []model.Job{def}, nil
+	}
+	p, err := s.getProject(def.ProjectId)
+	if err != nil {
+		return nil, err
+	}
+	pv, err := getProjectVersion(p, def.VersionId)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(def.DocumentId) != "" {
+		def.ProjectId = p.Id
+		def.VersionId = pv.Version
+		def.Instance.Version = *pv
+		return []model.Job{def}, nil
+	}
+	var base = strings.TrimSpace(def.Instance.Name)
+	if base == "" {
+		base = p.Name
+	}
+	var jobs = make([]model.Job, 0)
+	var used = make(map[string]bool)
+	for _, c := range pv.Charts {
+		if c.State == model.StateDeleted || c.State == model.StateError {
+			continue
+		}
+		jobs = append(jobs, newDocumentJob(def, p, pv, used, base, c.Id, c.Name, true))
+	}
+	for _, f := range pv.KubeFiles {
+		if f.State == model.StateDeleted || f.State == model.StateError {
+			continue
+		}
+		jobs = append(jobs, newDocumentJob(def, p, pv, used, base, f.Id, f.Name, false))
+	}
+	if len(jobs) == 0 {
+		return nil, errors.New(fmt.Sprintf("Project %s version %s has no charts or Kubernetes files to deploy", p.Name, pv.Version))
+	}
+	return jobs, nil
+}
+
+// Expand all the Deploy job definitions. Dependencies referring a job definition name, expanded in many jobs,
+// are replaced by the names of the expanded jobs
+func (s *deployScheduler) expandJobs(d model.Deploy) ([]model.Job, error) {
+	var jobs = make([]model.Job, 0)
+	var groups = make(map[string][]string)
+	for _, def := range d.Job {
+		list, err := s.expandJob(def)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Deploy %s, Error: %v", d.Name, err))
+		}
+		var base = strings.TrimSpace(def.Instance.Name)
+		if base != "" && (len(list) != 1 || list[0].Instance.Name != base) {
+			for _, j := range list {
+				groups[base] = append(groups[base], j.Instance.Name)
+			}
+		}
+		jobs = append(jobs, list...)
+	}
+	var names = make(map[string]bool)
+	for _, j := range jobs {
+		names[j.Instance.Name] = true
+	}
+	for i := range jobs {
+		var deps = make([]string, 0)
+		for _, dep := range jobs[i].DependsOn {
+			dep = strings.TrimSpace(dep)
+			if g, ok := groups[dep]; ok && !names[dep] {
+				deps = append(deps, g...)
+			} else {
+				deps = append(deps, dep)
+			}
+		}
+		jobs[i].DependsOn = deps
+	}
+	return jobs, nil
+}
+
+// Validates the Deploy jobs dependencies, replacing the dependencies by job id with the job names, so Deploy runs
+// created with new job ids keep the dependencies
+func validateDependencies(d *model.Deploy) error {
+	plan, err := model.BuildDeployPlan(*d)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Deploy %s, Error: %v", d.Name, err))
+	}
+	var deps = plan.Dependencies()
+	for i := range d.Job {
+		if len(d.Job[i].DependsOn) > 0 {
+			d.Job[i].DependsOn = deps[d.Job[i].Instance.Name]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package scheduler
+
+import (
+	"testing"
+)
+
+func TestUniqueJobName(t *testing.T) {
+	var used = make(map[string]bool)
+	var names = []string{
+		uniqueJobName(used, "app-web", true),
+		uniqueJobName(used, "app-web", false),
+		uniqueJobName(used, "app-web", false),
+		uniqueJobName(used, "app-db", false),
+	}
+	var expected = []string{"app-web", "app-web-kubefile", "app-web-kubefile-2", "app-db"}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Fatalf("Expected job names %v, found %v", expected, names)
+		}
+	}
+}
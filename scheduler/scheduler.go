@@ -0,0 +1,433 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hellgate75/k8s-deploy/log"
+	"github.com/hellgate75/k8s-deploy/model"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Default interval between two queue checks
+	DefaultQueueCheckInterval = 10 * time.Second
+)
+
+// Deploy scheduler, the queue is the list of stored Deploys in created state, so it survives restarts and
+// a Deploy is released only once, changing its state to ready
+type deployScheduler struct {
+	sync.Mutex
+	deploys  model.DeployDataManager
+	projects model.ProjectDataManager
+	interval time.Duration
+	logger   log.Logger
+	stop     chan bool
+	running  bool
+}
+
+func responseError(r model.DataResponse) error {
+	if r.Success {
+		return nil
+	}
+	return errors.New(r.Message)
+}
+
+func (s *deployScheduler) getDeploy(id string) (*model.Deploy, error) {
+	if d := s.deploys.GetDeploy(id); d != nil {
+		return d, nil
+	}
+	return nil, errors.New(fmt.Sprintf("Deploy id: %s not found", id))
+}
+
+func (s *deployScheduler) Submit(d model.Deploy) (*model.Deploy, error) {
+	s.Lock()
+	defer s.Unlock()
+	var err error
+	d.Trigger, err = prepareTrigger(d.Trigger, time.Now())
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Deploy %s, Error: %v", d.Name, err))
+	}
+	if !d.Rollback.IsValid() {
+		return nil, errors.New(fmt.Sprintf("Deploy %s, Error: unknown rollback policy: %s", d.Name, d.Rollback))
+	}
+	if d.Rollback == "" {
+		d.Rollback = model.RollbackPolicyAuto
+	}
+	d.Job, err = s.expandJobs(d)
+	if err != nil {
+		return nil, err
+	}
+	d.State = model.StateCreated
+	for i := range d.Job {
+		d.Job[i].State = model.StateCreated
+		d.Job[i].Instance.State = model.StateCreated
+	}
+	if err := validateDependencies(&d); err != nil {
+		return nil, err
+	}
+	resp := s.deploys.AddDeploy(d)
+	if err := responseError(resp); err != nil {
+		return nil, err
+	}
+	var out = resp.ResponseObjects[0].(model.Deploy)
+	if s.logger != nil {
+		s.logger.Infof("DeployScheduler::Submit() - Deploy %s queued with %v jobs, trigger: %s", out.Name, len(out.Job), out.Trigger.Type)
+	}
+	return &out, nil
+}
+
+func (s *deployScheduler) AddJobs(deployId string, j model.Job) ([]model.Job, error) {
+	s.Lock()
+	defer s.Unlock()
+	d, err := s.getDeploy(deployId)
+	if err != nil {
+		return nil, err
+	}
+	if d.State != model.StateCreated && d.State != model.StateReady {
+		return nil, errors.New(fmt.Sprintf("Deploy %s is in state %s, jobs can be added only to created or ready deploys", d.Name, d.State))
+	}
+	jobs, err := s.expandJob(j)
+	if err != nil {
+		return nil, err
+	}
+	var check = *d
+	check.Job = append(append(make([]model.Job, 0), d.Job...), jobs...)
+	if err := validateDependencies(&check); err != nil {
+		return nil, err
+	}
+	jobs = check.Job[len(d.Job):]
+	var jm = *s.deploys.AccessDeploy(*d)
+	var out = make([]model.Job, 0)
+	for _, job := range jobs {
+		job.State = d.State
+		job.Instance.State = d.State
+		resp := jm.AddJob(job)
+		if err := responseError(resp); err != nil {
+			return out, err
+		}
+		out = append(out, resp.ResponseObjects[0].(model.Job))
+	}
+	return out, nil
+}
+
+// Change the Deploy and its created jobs state to ready, making them available for execution
+func (s *deployScheduler) release(d *model.Deploy) error {
+	if d.State != model.StateCreated {
+		return errors.New(fmt.Sprintf("Deploy %s is not queued, state: %s", d.Name, d.State))
+	}
+	var jm = *s.deploys.AccessDeploy(*d)
+	for _, j := range d.Job {
+		if j.State != model.StateCreated {
+			continue
+		}
+		if err := responseError(jm.UpdateJobState(j.Instance.Id, model.StateReady)); err != nil {
+			return err
+		}
+	}
+	if err := responseError(s.deploys.UpdateDeployState(d.Id, model.StateReady)); err != nil {
+		return err
+	}
+	if s.logger != nil {
+		s.logger.Infof("DeployScheduler - Deploy %s released for execution", d.Name)
+	}
+	return nil
+}
+
+// Persists the Deploy trigger, with the updated due times
+func (s *deployScheduler) saveTrigger(d *model.Deploy, t model.DeployTrigger) error {
+	var u = *d
+	u.Trigger = t
+	if err := responseError(s.deploys.OverrideDeploy(d.Id, u)); err != nil {
+		return err
+	}
+	d.Trigger = t
+	return nil
+}
+
+// Name of the Deploy run created by a cron Deploy occurrence, unique per occurrence
+func runName(d *model.Deploy, due time.Time) string {
+	return fmt.Sprintf("%s-%s", d.Name, due.UTC().Format("20060102-1504"))
+}
+
+// Creates and releases the Deploy run of a cron Deploy occurrence. The run is created in the queue with a time
+// trigger at the due time, so a run not released yet is released by the queue processing. An occurrence creates
+// only one run, because run names are unique per occurrence
+func (s *deployScheduler) run(d *model.Deploy, due time.Time) (*model.Deploy, error) {
+	var r = model.Deploy{
+		Name:     runName(d, due),
+		State:    model.StateCreated,
+		Job:      make([]model.Job, 0),
+		Rollback: d.Rollback,
+		Trigger: model.DeployTrigger{
+			Type:     model.TriggerTypeTime,
+			At:       due,
+			Timezone: d.Trigger.Timezone,
+			Next:     due,
+		},
+	}
+	for _, j := range d.Job {
+		if j.State == model.StateDeleted {
+			continue
+		}
+		j.State = model.StateCreated
+		j.Instance.Id = ""
+		j.Instance.State = model.StateCreated
+		r.Job = append(r.Job, j)
+	}
+	if s.deploys.GetDeployByName(r.Name) != nil {
+		return nil, errors.New(fmt.Sprintf("Deploy %s occurrence %v already fired", d.Name, due))
+	}
+	resp := s.deploys.AddDeploy(r)
+	if err := responseError(resp); err != nil {
+		return nil, err
+	}
+	var out = resp.ResponseObjects[0].(model.Deploy)
+	if err := s.release(&out); err != nil {
+		return nil, err
+	}
+	return s.getDeploy(out.Id)
+}
+
+// Trigger immediately a queued Deploy, refused during blackout windows. Cron Deploys stay queued, creating a
+// Deploy run that is released for execution
+func (s *deployScheduler) Trigger(deployId string) (*model.Deploy, error) {
+	s.Lock()
+	defer s.Unlock()
+	d, err := s.getDeploy(deployId)
+	if err != nil {
+		return nil, err
+	}
+	if d.State != model.StateCreated {
+		return nil, errors.New(fmt.Sprintf("Deploy %s is not queued, state: %s", d.Name, d.State))
+	}
+	sch, err := newTriggerSchedule(d.Trigger)
+	if err != nil {
+		return nil, err
+	}
+	var now = time.Now()
+	if end, in := sch.blackoutEnd(now); in {
+		return nil, errors.New(fmt.Sprintf("Deploy %s cannot be triggered during a blackout window, until %v", d.Name, end))
+	}
+	if d.Trigger.Type == model.TriggerTypeCron {
+		return s.run(d, now)
+	}
+	if err := s.release(d); err != nil {
+		return nil, err
+	}
+	return s.getDeploy(deployId)
+}
+
+// Lists the queued Deploys
+func (s *deployScheduler) queued() ([]model.Deploy, error) {
+	resp := s.deploys.QueryDeploys(model.Query{
+		Items: []model.QueryItem{
+			{
+				Key:        "state",
+				Value:      string(model.StateCreated),
+				Aggregator: model.AggregatorEq,
+			},
+		},
+		Oper: model.OperAnd,
+	})
+	if err := responseError(resp); err != nil {
+		return nil, err
+	}
+	var list = make([]model.Deploy, 0)
+	for _, o := range resp.ResponseObjects {
+		list = append(list, o.(model.Deploy))
+	}
+	return list, nil
+}
+
+func (s *deployScheduler) Queue() ([]model.QueueItem, error) {
+	list, err := s.queued()
+	if err != nil {
+		return nil, err
+	}
+	var items = make([]model.QueueItem, 0)
+	for _, d := range list {
+		var item = model.QueueItem{
+			DeployId:   d.Id,
+			DeployName: d.Name,
+			Trigger:    d.Trigger.Type,
+			Jobs:       len(d.Job),
+		}
+		if d.Trigger.Type != model.TriggerTypeManual {
+			item.DueTime = dueTime(d.Trigger)
+		}
+		items = append(items, item)
+	}
+	sortQueueItems(items)
+	return items, nil
+}
+
+func (s *deployScheduler) Planned(deployId string, count int) ([]model.QueueItem, error) {
+	list, err := s.queued()
+	if err != nil {
+		return nil, err
+	}
+	var items = make([]model.QueueItem, 0)
+	for _, d := range list {
+		if deployId != "" && d.Id != deployId {
+			continue
+		}
+		sch, err := newTriggerSchedule(d.Trigger)
+		if err != nil {
+			if s.logger != nil {
+				s.logger.Errorf("DeployScheduler - Deploy %s has invalid trigger, Error: %v", d.Name, err)
+			}
+			continue
+		}
+		for _, due := range sch.planned(d.Trigger, count) {
+			items = append(items, model.QueueItem{
+				DeployId:   d.Id,
+				DeployName: d.Name,
+				Trigger:    d.Trigger.Type,
+				DueTime:    due,
+				Jobs:       len(d.Job),
+			})
+		}
+	}
+	sortQueueItems(items)
+	if len(items) > count {
+		items = items[:count]
+	}
+	return items, nil
+}
+
+// Sorts the queue items by due time, items without due time come last
+func sortQueueItems(items []model.QueueItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].DueTime.IsZero() != items[j].DueTime.IsZero() {
+			return !items[i].DueTime.IsZero()
+		}
+		if !items[i].DueTime.Equal(items[j].DueTime) {
+			return items[i].DueTime.Before(items[j].DueTime)
+		}
+		return strings.Compare(items[i].DeployName, items[j].DeployName) < 0
+	})
+}
+
+// Release a time Deploy that reached its due time, postponing it when the processing happens in a blackout window
+func (s *deployScheduler) processTime(d *model.Deploy, sch *triggerSchedule, now time.Time) error {
+	if dueTime(d.Trigger).After(now) {
+		return nil
+	}
+	if end, in := sch.blackoutEnd(now); in {
+		var t = d.Trigger
+		t.Next = end
+		return s.saveTrigger(d, t)
+	}
+	return s.release(d)
+}
+
+// Fire a cron Deploy that reached its due time and persist the next due time. Missed occurrences are fired once,
+// occurrences in blackout windows are skipped
+func (s *deployScheduler) processCron(d *model.Deploy, sch *triggerSchedule, now time.Time) error {
+	var due = d.Trigger.Next
+	if due.After(now) {
+		return nil
+	}
+	var t = d.Trigger
+	if _, in := sch.blackoutEnd(now); !in && !due.IsZero() && !due.Equal(t.Last) {
+		if _, err := s.run(d, due); err != nil && s.logger != nil {
+			s.logger.Errorf("DeployScheduler - Unable to run deploy %s, Error: %v", d.Name, err)
+		}
+		t.Last = due
+	}
+	t.Next = sch.nextOccurrence(now)
+	return s.saveTrigger(d, t)
+}
+
+// Release the queued Deploys that reached their due time
+func (s *deployScheduler) process() {
+	s.Lock()
+	defer s.Unlock()
+	list, err := s.queued()
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Errorf("DeployScheduler - Unable to read the queue, Error: %v", err)
+		}
+		return
+	}
+	var now = time.Now()
+	for _, d := range list {
+		if d.Trigger.Type == model.TriggerTypeManual {
+			continue
+		}
+		sch, err := newTriggerSchedule(d.Trigger)
+		if err == nil {
+			if d.Trigger.Type == model.TriggerTypeCron {
+				err = s.processCron(&d, sch, now)
+			} else {
+				err = s.processTime(&d, sch, now)
+			}
+		}
+		if err != nil && s.logger != nil {
+			s.logger.Errorf("DeployScheduler - Unable to process deploy %s, Error: %v", d.Name, err)
+		}
+	}
+}
+
+func (s *deployScheduler) Start() error {
+	s.Lock()
+	defer s.Unlock()
+	if s.running {
+		return errors.New("Deploy scheduler is already running")
+	}
+	s.stop = make(chan bool)
+	s.running = true
+	go func(stop chan bool) {
+		var ticker = time.NewTicker(s.interval)
+		defer ticker.Stop()
+		s.process()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				s.process()
+			}
+		}
+	}(s.stop)
+	if s.logger != nil {
+		s.logger.Infof("DeployScheduler - Queue processing started, check interval: %v", s.interval)
+	}
+	return nil
+}
+
+func (s *deployScheduler) Stop() {
+	s.Lock()
+	defer s.Unlock()
+	if !s.running {
+		return
+	}
+	close(s.stop)
+	s.running = false
+	if s.logger != nil {
+		s.logger.Infof("DeployScheduler - Queue processing stopped")
+	}
+}
+
+func (s *deployScheduler) IsRunning() bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.running
+}
+
+// Create a Deploy scheduler, expanding jobs from the projects data and storing Deploys and jobs via the deploys data.
+// The queue is checked at the given interval, or at the default one when the interval isn't positive
+func NewDeployScheduler(deploys model.DeployDataManager, projects model.ProjectDataManager, interval time.Duration, logger log.Logger) model.DeployScheduler {
+	if interval <= 0 {
+		interval = DefaultQueueCheckInterval
+	}
+	return &deployScheduler{
+		deploys:  deploys,
+		projects: projects,
+		interval: interval,
+		logger:   logger,
+	}
+}
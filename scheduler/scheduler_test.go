@@ -0,0 +1,236 @@
+package scheduler
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hellgate75/k8s-deploy/data/device"
+	"github.com/hellgate75/k8s-deploy/model"
+)
+
+func newTestScheduler(t *testing.T) (*deployScheduler, func()) {
+	dir, _ := ioutil.TempDir("", "scheduler-test")
+	var projects = device.GetProjectDataManager(dir, nil)
+	var resp = projects.AddProject(model.Project{
+		Name:    "shop",
+		Version: "1.0.0",
+		Versions: []model.ProjectVersion{
+			{
+				Version:   "1.0.0",
+				Charts:    []model.ProjectChart{{Id: "db-chart", Name: "db", Version: "1.0.0"}},
+				KubeFiles: []model.ProjectKubeFile{{Id: "web-file", Name: "web", Version: "1.0.0"}},
+			},
+		},
+	})
+	if !resp.Success {
+		os.RemoveAll(dir)
+		t.Fatalf("Unexpected error: %s", resp.Message)
+	}
+	var s = NewDeployScheduler(device.GetDeployDataManager(dir, nil), projects, time.Hour, nil).(*deployScheduler)
+	return s, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func TestSubmitExpandsAndQueuesDeploy(t *testing.T) {
+	s, clean := newTestScheduler(t)
+	defer clean()
+	d, err := s.Submit(model.Deploy{
+		Name: "release",
+		Job: []model.Job{
+			{Instance: model.Instance{Name: "app"}, ProjectId: "shop"},
+			{Instance: model.Instance{Name: "check"}, DocumentId: "smoke-test", DependsOn: []string{"app"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.State != model.StateCreated || d.Rollback != model.RollbackPolicyAuto || d.Trigger.Type != model.TriggerTypeManual {
+		t.Fatalf("Unexpected deploy: %+v", d)
+	}
+	var names = make([]string, 0)
+	for _, j := range d.Job {
+		if j.State != model.StateCreated || j.Instance.Id == "" {
+			t.Fatalf("Expected created job with id, found: %+v", j)
+		}
+		names = append(names, j.Instance.Name)
+	}
+	if strings.Join(names, ",") != "app-db,app-web,check" {
+		t.Fatalf("Unexpected jobs: %v", names)
+	}
+	// A dependency on a job definition refers to all the expanded jobs
+	if strings.Join(d.Job[2].DependsOn, ",") != "app-db,app-web" {
+		t.Fatalf("Unexpected dependencies: %v", d.Job[2].DependsOn)
+	}
+	items, err := s.Queue()
+	if err != nil || len(items) != 1 || items[0].DeployId != d.Id || items[0].Jobs != 3 || !items[0].DueTime.IsZero() {
+		t.Fatalf("Unexpected queue: %+v, %v", items, err)
+	}
+}
+
+func TestSubmitRejectsInvalidDeploys(t *testing.T) {
+	s, clean := newTestScheduler(t)
+	defer clean()
+	for _, d := range []model.Deploy{
+		{Name: "missing-project", Job: []model.Job{{ProjectId: "missing"}}},
+		{Name: "missing-document", Job: []model.Job{{Instance: model.Instance{Name: "job"}}}},
+		{Name: "rollback", Rollback: "sometimes", Job: []model.Job{{ProjectId: "shop"}}},
+		{Name: "cron", Trigger: model.DeployTrigger{Cron: "61 * * * *"}, Job: []model.Job{{ProjectId: "shop"}}},
+		{Name: "cycle", Job: []model.Job{
+			{Instance: model.Instance{Name: "a"}, DocumentId: "a", DependsOn: []string{"b"}},
+			{Instance: model.Instance{Name: "b"}, DocumentId: "b", DependsOn: []string{"a"}},
+		}},
+	} {
+		if _, err := s.Submit(d); err == nil {
+			t.Fatalf("Expected deploy %s rejected", d.Name)
+		}
+	}
+	if items, err := s.Queue(); err != nil || len(items) != 0 {
+		t.Fatalf("Expected empty queue, found: %+v, %v", items, err)
+	}
+}
+
+func TestSubmitConcurrentDeploys(t *testing.T) {
+	s, clean := newTestScheduler(t)
+	defer clean()
+	var wg sync.WaitGroup
+	var errs = make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var name = fmt.Sprintf("release-%v", i%5)
+			if _, err := s.Submit(model.Deploy{Name: name, Job: []model.Job{{ProjectId: "shop"}}}); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	// Each name is queued once, the duplicate submissions are refused
+	if len(errs) != 5 {
+		t.Fatalf("Expected 5 duplicate deploys refused, found %v", len(errs))
+	}
+	if items, err := s.Queue(); err != nil || len(items) != 5 {
+		t.Fatalf("Expected 5 queued deploys, found: %+v, %v", items, err)
+	}
+}
+
+func TestTriggerReleasesQueuedDeploy(t *testing.T) {
+	s, clean := newTestScheduler(t)
+	defer clean()
+	d, err := s.Submit(model.Deploy{Name: "release", Job: []model.Job{{ProjectId: "shop"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	released, err := s.Trigger(d.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if released.State != model.StateReady {
+		t.Fatalf("Expected ready deploy, found: %s", released.State)
+	}
+	for _, j := range released.Job {
+		if j.State != model.StateReady {
+			t.Fatalf("Expected ready job, found: %+v", j)
+		}
+	}
+	if _, err := s.Trigger(d.Id); err == nil || !strings.Contains(err.Error(), "not queued") {
+		t.Fatalf("Expected deploy released only once, found: %v", err)
+	}
+	if items, err := s.Queue(); err != nil || len(items) != 0 {
+		t.Fatalf("Expected empty queue, found: %+v, %v", items, err)
+	}
+	// Jobs can still be added to a ready deploy, in the deploy state
+	jobs, err := s.AddJobs(d.Id, model.Job{Instance: model.Instance{Name: "check"}, DocumentId: "smoke-test"})
+	if err != nil || len(jobs) != 1 || jobs[0].State != model.StateReady {
+		t.Fatalf("Unexpected added jobs: %+v, %v", jobs, err)
+	}
+}
+
+func TestReleaseSkipsJobsNotCreated(t *testing.T) {
+	s, clean := newTestScheduler(t)
+	defer clean()
+	d, err := s.Submit(model.Deploy{Name: "release", Job: []model.Job{{ProjectId: "shop"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var jm = *s.deploys.AccessDeploy(*d)
+	if resp := jm.UpdateJobState(d.Job[0].Instance.Id, model.StateDeleted); !resp.Success {
+		t.Fatalf("Unexpected error: %s", resp.Message)
+	}
+	d, _ = s.getDeploy(d.Id)
+	if err := s.release(d); err != nil {
+		t.Fatal(err)
+	}
+	d, _ = s.getDeploy(d.Id)
+	if d.State != model.StateReady || d.Job[0].State != model.StateDeleted || d.Job[1].State != model.StateReady {
+		t.Fatalf("Unexpected released deploy: %+v", d)
+	}
+	if err := s.release(d); err == nil {
+		t.Fatal("Expected ready deploy release refused")
+	}
+}
+
+func TestProcessReleasesDueDeploys(t *testing.T) {
+	s, clean := newTestScheduler(t)
+	defer clean()
+	var now = time.Now()
+	due, err := s.Submit(model.Deploy{Name: "due", Trigger: model.DeployTrigger{At: now.Add(-time.Minute)}, Job: []model.Job{{ProjectId: "shop"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	future, err := s.Submit(model.Deploy{Name: "future", Trigger: model.DeployTrigger{At: now.Add(time.Hour)}, Job: []model.Job{{ProjectId: "shop"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manual, err := s.Submit(model.Deploy{Name: "manual", Job: []model.Job{{ProjectId: "shop"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, _ := s.Queue()
+	if len(items) != 3 || items[0].DeployName != "due" || items[1].DeployName != "future" || items[2].DeployName != "manual" {
+		t.Fatalf("Expected queue sorted by due time, found: %+v", items)
+	}
+	s.process()
+	for id, state := range map[string]model.State{due.Id: model.StateReady, future.Id: model.StateCreated, manual.Id: model.StateCreated} {
+		if d, _ := s.getDeploy(id); d == nil || d.State != state {
+			t.Fatalf("Expected deploy %s in state %s, found: %+v", id, state, d)
+		}
+	}
+}
+
+func TestProcessFiresCronDeployRuns(t *testing.T) {
+	s, clean := newTestScheduler(t)
+	defer clean()
+	d, err := s.Submit(model.Deploy{Name: "nightly", Trigger: model.DeployTrigger{Cron: "@daily"}, Job: []model.Job{{ProjectId: "shop"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Moves the due time in the past, as for a missed occurrence
+	var missed = d.Trigger.Next.AddDate(0, 0, -1)
+	var tr = d.Trigger
+	tr.Next = missed
+	if err := s.saveTrigger(d, tr); err != nil {
+		t.Fatal(err)
+	}
+	s.process()
+	var run = s.deploys.GetDeployByName(runName(d, missed))
+	if run == nil || run.State != model.StateReady || len(run.Job) != 2 || run.Job[0].Instance.Id == d.Job[0].Instance.Id {
+		t.Fatalf("Expected released cron deploy run, found: %+v", run)
+	}
+	d, _ = s.getDeploy(d.Id)
+	if d.State != model.StateCreated || !d.Trigger.Last.Equal(missed) || !d.Trigger.Next.After(time.Now()) {
+		t.Fatalf("Expected cron deploy queued with the next occurrence, found: %+v", d.Trigger)
+	}
+	// Processing again doesn't fire the same occurrence twice
+	s.process()
+	if resp := s.deploys.ListDeploys(); !resp.Success || len(resp.ResponseObjects) != 2 {
+		t.Fatalf("Expected a single cron deploy run, found: %+v", resp.ResponseObjects)
+	}
+}
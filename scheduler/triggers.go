@@ -0,0 +1,258 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hellgate75/k8s-deploy/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	minutesPerDay  = 24 * 60
+	minutesPerWeek = 7 * minutesPerDay
+	// Maximum number of adjacent blackout windows or skipped cron occurrences followed computing a due time
+	maxScheduleIterations = 1000
+)
+
+// Blackout window bounds, in minutes of the week for weekly windows or in minutes of the day for daily windows
+type blackoutWindow struct {
+	weekly bool
+	from   int
+	to     int
+}
+
+// Parses a blackout window bound: "<weekday> HH:MM" or "HH:MM", the week day is -1 when not provided
+func parseWindowBound(s string) (int, int, error) {
+	var fields = strings.Fields(s)
+	var weekday = -1
+	if len(fields) == 2 {
+		var day = strings.ToLower(fields[0])
+		if len(day) >= 3 {
+			if d, ok := cronDayNames[day[:3]]; ok {
+				weekday = d
+			}
+		}
+		if weekday < 0 {
+			return 0, 0, errors.New(fmt.Sprintf("Invalid week day in blackout window bound: %s", s))
+		}
+		fields = fields[1:]
+	}
+	if len(fields) != 1 {
+		return 0, 0, errors.New(fmt.Sprintf("Invalid blackout window bound: %s, expected format: [<weekday>] HH:MM", s))
+	}
+	var hm = strings.SplitN(fields[0], ":", 2)
+	if len(hm) != 2 {
+		return 0, 0, errors.New(fmt.Sprintf("Invalid time in blackout window bound: %s, expected format: HH:MM", s))
+	}
+	h, err1 := strconv.Atoi(hm[0])
+	m, err2 := strconv.Atoi(hm[1])
+	if err1 != nil || err2 != nil || h < 0 || h > 23 || m < 0 || m > 59 {
+		return 0, 0, errors.New(fmt.Sprintf("Invalid time in blackout window bound: %s, expected format: HH:MM", s))
+	}
+	return weekday, h*60 + m, nil
+}
+
+func parseBlackout(w model.BlackoutWindow) (blackoutWindow, error) {
+	fromDay, from, err := parseWindowBound(w.From)
+	if err != nil {
+		return blackoutWindow{}, err
+	}
+	toDay, to, err := parseWindowBound(w.To)
+	if err != nil {
+		return blackoutWindow{}, err
+	}
+	if (fromDay < 0) != (toDay < 0) {
+		return blackoutWindow{}, errors.New(fmt.Sprintf("Blackout window %s - %s bounds must be both weekly or both daily", w.From, w.To))
+	}
+	var b = blackoutWindow{
+		weekly: fromDay >= 0,
+		from:   from,
+		to:     to,
+	}
+	if b.weekly {
+		b.from += fromDay * minutesPerDay
+		b.to += toDay * minutesPerDay
+	}
+	if b.from == b.to {
+		return blackoutWindow{}, errors.New(fmt.Sprintf("Blackout window %s - %s is empty", w.From, w.To))
+	}
+	return b, nil
+}
+
+// Verifies the time falls in the window, collecting the window end
+func (b blackoutWindow) end(t time.Time) (time.Time, bool) {
+	var minute = t.Hour()*60 + t.Minute()
+	var days = 0
+	if b.weekly {
+		var week = int(t.Weekday())*minutesPerDay + minute
+		if !((b.from < b.to && week >= b.from && week < b.to) || (b.from > b.to && (week >= b.from || week < b.to))) {
+			return t, false
+		}
+		days = (b.to/minutesPerDay - int(t.Weekday()) + 7) % 7
+		if days == 0 && b.to%minutesPerDay <= minute {
+			days = 7
+		}
+	} else {
+		if !((b.from < b.to && minute >= b.from && minute < b.to) || (b.from > b.to && (minute >= b.from || minute < b.to))) {
+			return t, false
+		}
+		if b.to <= minute {
+			days = 1
+		}
+	}
+	var to = b.to % minutesPerDay
+	return time.Date(t.Year(), t.Month(), t.Day()+days, to/60, to%60, 0, 0, t.Location()), true
+}
+
+// Trigger time rules: the time location, the cron expression and the blackout windows
+type triggerSchedule struct {
+	loc       *time.Location
+	cron      *cronSchedule
+	blackouts []blackoutWindow
+}
+
+func newTriggerSchedule(t model.DeployTrigger) (*triggerSchedule, error) {
+	var s = triggerSchedule{
+		loc:       time.UTC,
+		blackouts: make([]blackoutWindow, 0),
+	}
+	if tz := strings.TrimSpace(t.Timezone); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid timezone %s, Error: %v", tz, err))
+		}
+		s.loc = loc
+	}
+	if t.Type == model.TriggerTypeCron {
+		c, err := parseCron(t.Cron)
+		if err != nil {
+			return nil, err
+		}
+		s.cron = c
+	}
+	for _, w := range t.Blackouts {
+		b, err := parseBlackout(w)
+		if err != nil {
+			return nil, err
+		}
+		s.blackouts = append(s.blackouts, b)
+	}
+	return &s, nil
+}
+
+// Verifies the time falls in a blackout window, collecting the end of the blackout period
+func (s *triggerSchedule) blackoutEnd(t time.Time) (time.Time, bool) {
+	t = t.In(s.loc)
+	var in = false
+	for i := 0; i < maxScheduleIterations; i++ {
+		var changed = false
+		for _, b := range s.blackouts {
+			if end, ok := b.end(t); ok {
+				t = end
+				in = true
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+	return t, in
+}
+
+// Computes the first time not in a blackout window, at or after the given time
+func (s *triggerSchedule) nextAllowed(t time.Time) time.Time {
+	if end, in := s.blackoutEnd(t); in {
+		return end
+	}
+	return t
+}
+
+// Computes the first cron occurrence strictly after the given time, skipping the occurrences in blackout windows.
+// A zero time is returned when there is no occurrence
+func (s *triggerSchedule) nextOccurrence(t time.Time) time.Time {
+	if s.cron == nil {
+		return time.Time{}
+	}
+	var n = s.cron.next(t.In(s.loc))
+	for i := 0; i < maxScheduleIterations && !n.IsZero(); i++ {
+		end, in := s.blackoutEnd(n)
+		if !in {
+			return n
+		}
+		n = s.cron.next(end.Add(-time.Minute))
+	}
+	return time.Time{}
+}
+
+// Lists the next planned due times of a trigger, starting from the persisted due time
+func (s *triggerSchedule) planned(t model.DeployTrigger, count int) []time.Time {
+	var list = make([]time.Time, 0)
+	var next = dueTime(t)
+	switch t.Type {
+	case model.TriggerTypeTime:
+		if !next.IsZero() && count > 0 {
+			list = append(list, next)
+		}
+	case model.TriggerTypeCron:
+		for len(list) < count && !next.IsZero() {
+			list = append(list, next)
+			next = s.nextOccurrence(next)
+		}
+	}
+	return list
+}
+
+// Collects the persisted due time, or the trigger time for triggers persisted without due time
+func dueTime(t model.DeployTrigger) time.Time {
+	if t.Next.IsZero() && t.Type == model.TriggerTypeTime {
+		return t.At
+	}
+	return t.Next
+}
+
+// Assign the default trigger type: cron when a cron expression is provided, time when a trigger time is provided,
+// manual otherwise. Validates the trigger and computes the first due time
+func prepareTrigger(t model.DeployTrigger, now time.Time) (model.DeployTrigger, error) {
+	if t.Type == "" {
+		switch {
+		case strings.TrimSpace(t.Cron) != "":
+			t.Type = model.TriggerTypeCron
+		case !t.At.IsZero():
+			t.Type = model.TriggerTypeTime
+		default:
+			t.Type = model.TriggerTypeManual
+		}
+	}
+	t.Next = time.Time{}
+	t.Last = time.Time{}
+	switch t.Type {
+	case model.TriggerTypeManual:
+	case model.TriggerTypeTime:
+		if t.At.IsZero() {
+			return t, errors.New("Trigger time is required for time triggers")
+		}
+	case model.TriggerTypeCron:
+		if strings.TrimSpace(t.Cron) == "" {
+			return t, errors.New("Cron expression is required for cron triggers")
+		}
+	default:
+		return t, errors.New(fmt.Sprintf("Unknown trigger type: %s", t.Type))
+	}
+	s, err := newTriggerSchedule(t)
+	if err != nil {
+		return t, err
+	}
+	switch t.Type {
+	case model.TriggerTypeTime:
+		t.Next = s.nextAllowed(t.At)
+	case model.TriggerTypeCron:
+		t.Next = s.nextOccurrence(now)
+		if t.Next.IsZero() {
+			return t, errors.New(fmt.Sprintf("Cron expression %s has no occurrence out of the blackout windows", t.Cron))
+		}
+	}
+	return t, nil
+}
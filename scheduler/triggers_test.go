@@ -0,0 +1,135 @@
+package scheduler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hellgate75/k8s-deploy/model"
+)
+
+func TestBlackoutWindowEnd(t *testing.T) {
+	for _, c := range []struct {
+		from     string
+		to       string
+		time     string
+		expected string
+	}{
+		// Weekly window wrapping around the week end
+		{"fri 16:00", "mon 08:00", "2020-06-05 15:59 +0000", ""},
+		{"fri 16:00", "mon 08:00", "2020-06-05 16:00 +0000", "2020-06-08 08:00 +0000"},
+		{"fri 16:00", "mon 08:00", "2020-06-06 10:00 +0000", "2020-06-08 08:00 +0000"},
+		{"fri 16:00", "mon 08:00", "2020-06-07 23:59 +0000", "2020-06-08 08:00 +0000"},
+		{"fri 16:00", "mon 08:00", "2020-06-08 07:59 +0000", "2020-06-08 08:00 +0000"},
+		{"fri 16:00", "mon 08:00", "2020-06-08 08:00 +0000", ""},
+		{"Monday 09:00", "Monday 17:00", "2020-06-01 10:00 +0000", "2020-06-01 17:00 +0000"},
+		{"Monday 09:00", "Monday 17:00", "2020-06-02 10:00 +0000", ""},
+		// Weekly window ending on its starting week day, a week later
+		{"wed 18:00", "wed 06:00", "2020-06-03 05:00 +0000", "2020-06-03 06:00 +0000"},
+		{"wed 18:00", "wed 06:00", "2020-06-03 10:00 +0000", ""},
+		{"wed 18:00", "wed 06:00", "2020-06-03 19:00 +0000", "2020-06-10 06:00 +0000"},
+		// Daily windows
+		{"22:00", "06:00", "2020-06-01 23:00 +0000", "2020-06-02 06:00 +0000"},
+		{"22:00", "06:00", "2020-06-02 03:00 +0000", "2020-06-02 06:00 +0000"},
+		{"22:00", "06:00", "2020-06-02 12:00 +0000", ""},
+		{"12:00", "13:00", "2020-06-02 12:30 +0000", "2020-06-02 13:00 +0000"},
+		{"12:00", "13:00", "2020-06-02 13:00 +0000", ""},
+	} {
+		b, err := parseBlackout(model.BlackoutWindow{From: c.from, To: c.to})
+		if err != nil {
+			t.Fatalf("Unexpected error parsing blackout window %s - %s: %v", c.from, c.to, err)
+		}
+		var tm = parseTestTime(t, c.time, "UTC")
+		var found = ""
+		if end, in := b.end(tm); in {
+			found = formatTestTime(end)
+		}
+		if found != c.expected {
+			t.Fatalf("Expected blackout window %s - %s end at %s %q, found %q", c.from, c.to, c.time, c.expected, found)
+		}
+	}
+}
+
+func TestParseBlackoutInvalid(t *testing.T) {
+	for _, w := range []model.BlackoutWindow{
+		{From: "fri 16:00", To: "08:00"},
+		{From: "funday 10:00", To: "mon 10:00"},
+		{From: "25:00", To: "26:00"},
+		{From: "10:00", To: "10:00"},
+		{From: "10", To: "11:00"},
+		{From: "mon 10:00 utc", To: "mon 11:00"},
+	} {
+		if _, err := parseBlackout(w); err == nil {
+			t.Fatalf("Expected error parsing blackout window %s - %s", w.From, w.To)
+		}
+	}
+}
+
+func TestTriggerScheduleNextOccurrence(t *testing.T) {
+	var weekend = model.BlackoutWindow{From: "fri 16:00", To: "mon 08:00"}
+	var night = model.BlackoutWindow{From: "22:00", To: "06:00"}
+	for _, c := range []struct {
+		cron      string
+		timezone  string
+		blackouts []model.BlackoutWindow
+		from      string
+		expected  string
+	}{
+		{"0 12 * * *", "", nil, "2020-06-05 13:00 +0000", "2020-06-06 12:00 +0000"},
+		// Occurrences in the weekend blackout are skipped
+		{"0 12 * * *", "", []model.BlackoutWindow{weekend}, "2020-06-05 13:00 +0000", "2020-06-08 12:00 +0000"},
+		{"0 * * * *", "", []model.BlackoutWindow{night}, "2020-06-01 21:30 +0000", "2020-06-02 06:00 +0000"},
+		// Adjacent windows are followed until the first allowed occurrence
+		{"*/30 * * * *", "", []model.BlackoutWindow{weekend, {From: "mon 08:00", To: "mon 12:00"}}, "2020-06-05 15:45 +0000", "2020-06-08 12:00 +0000"},
+		// Cron expressions and blackout windows refer to the trigger timezone
+		{"0 12 * * *", "Europe/Rome", nil, "2020-06-01 11:00 +0000", "2020-06-02 12:00 +0200"},
+		{"0 * * * *", "Europe/Rome", []model.BlackoutWindow{night}, "2020-06-01 19:30 +0000", "2020-06-02 06:00 +0200"},
+		{"0 9 * * *", "America/New_York", []model.BlackoutWindow{weekend}, "2020-03-06 15:00 +0000", "2020-03-09 09:00 -0400"},
+		// No occurrence out of the blackout windows
+		{"0 12 * * *", "", []model.BlackoutWindow{{From: "11:00", To: "13:00"}}, "2020-06-01 10:00 +0000", ""},
+	} {
+		sch, err := newTriggerSchedule(model.DeployTrigger{Type: model.TriggerTypeCron, Cron: c.cron, Timezone: c.timezone, Blackouts: c.blackouts})
+		if err != nil {
+			t.Fatalf("Unexpected error for cron %q: %v", c.cron, err)
+		}
+		if n := formatTestTime(sch.nextOccurrence(parseTestTime(t, c.from, "UTC"))); n != c.expected {
+			t.Fatalf("Expected %q %s next occurrence from %s %q, found %q", c.cron, c.timezone, c.from, c.expected, n)
+		}
+	}
+}
+
+func TestPrepareTrigger(t *testing.T) {
+	var now = parseTestTime(t, "2020-06-05 13:00 +0000", "UTC")
+	var weekend = []model.BlackoutWindow{{From: "fri 16:00", To: "mon 08:00"}}
+	tr, err := prepareTrigger(model.DeployTrigger{}, now)
+	if err != nil || tr.Type != model.TriggerTypeManual || !tr.Next.IsZero() {
+		t.Fatalf("Expected manual trigger, found: %+v, %v", tr, err)
+	}
+	// Time triggers in a blackout window are postponed to the window end
+	tr, err = prepareTrigger(model.DeployTrigger{At: parseTestTime(t, "2020-06-06 10:00 +0000", "UTC"), Blackouts: weekend}, now)
+	if err != nil || tr.Type != model.TriggerTypeTime || formatTestTime(tr.Next) != "2020-06-08 08:00 +0000" {
+		t.Fatalf("Expected postponed time trigger, found: %+v, %v", tr, err)
+	}
+	tr, err = prepareTrigger(model.DeployTrigger{Cron: "0 12 * * *", Blackouts: weekend, Last: now}, now)
+	if err != nil || tr.Type != model.TriggerTypeCron || formatTestTime(tr.Next) != "2020-06-08 12:00 +0000" || !tr.Last.IsZero() {
+		t.Fatalf("Expected cron trigger, found: %+v, %v", tr, err)
+	}
+	var planned = make([]string, 0)
+	sch, _ := newTriggerSchedule(tr)
+	for _, p := range sch.planned(tr, 3) {
+		planned = append(planned, formatTestTime(p))
+	}
+	if strings.Join(planned, ",") != "2020-06-08 12:00 +0000,2020-06-09 12:00 +0000,2020-06-10 12:00 +0000" {
+		t.Fatalf("Unexpected planned occurrences: %v", planned)
+	}
+	for _, invalid := range []model.DeployTrigger{
+		{Type: model.TriggerTypeTime},
+		{Type: model.TriggerTypeCron},
+		{Type: "sometimes"},
+		{Cron: "0 12 * * *", Timezone: "Mars/Olympus_Mons"},
+		{Cron: "0 12 * * *", Blackouts: []model.BlackoutWindow{{From: "11:00", To: "13:00"}}},
+	} {
+		if _, err := prepareTrigger(invalid, now); err == nil {
+			t.Fatalf("Expected invalid trigger error: %+v", invalid)
+		}
+	}
+}
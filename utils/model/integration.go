@@ -199,3 +199,39 @@ func CompareValues(value1, value2 string, dType DataType, cond model.Aggregator)
 	}
 	return false
 }
+
+// Verify if a record satisfies all the queries. Items of a query are combined via the
+// query operator (and, or, nor, nand), and operator is used when none is specified, as
+// well as eq aggregator is used for items without aggregator. The check function compares the record field selected by key with the query item value
+func MatchQueries(check func(key string, value string, cond model.Aggregator) bool, q ...model.Query) bool {
+	for _, qr := range q {
+		if len(qr.Items) == 0 {
+			continue
+		}
+		var matches = 0
+		for _, qi := range qr.Items {
+			var cond = qi.Aggregator
+			if cond == "" {
+				cond = model.AggregatorEq
+			}
+			if check(utils.TrimFieldName(qi.Key), qi.Value, cond) {
+				matches++
+			}
+		}
+		var matched bool
+		switch qr.Oper {
+		case model.OperOr:
+			matched = matches > 0
+		case model.OperNor:
+			matched = matches == 0
+		case model.OperNAnd:
+			matched = matches < len(qr.Items)
+		default:
+			matched = matches == len(qr.Items)
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/pkg/errors"
 	"os/exec"
@@ -94,3 +95,45 @@ func ExecuteCommand(command string, cmdArgs ...string) (string, error) {
 	}
 	return fmt.Sprintf("%s", stdoutStderr), err
 }
+
+// Describes the outcome of a command execution
+type CommandResult struct {
+	// Process exit code, -1 when the command cannot be started
+	ExitCode int
+	Stdout   string
+	Stderr   string
+}
+
+// Execute a Command by tokens, collecting exit code, standard output and standard error separately.
+// An error is returned when the command cannot be started or exits with a non zero exit code
+func ExecuteCommandArgsWithResult(command ...string) (result CommandResult, err error) {
+	result.ExitCode = -1
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("%v", r))
+		}
+	}()
+	if len(command) == 0 {
+		return result, errors.New("Command must have a least one value")
+	}
+	cmdSubject := command[0]
+	if len(cmdSubject) == 0 {
+		return result, errors.New("Command subject must not be empty")
+	}
+	cmd := exec.Command(cmdSubject, command[1:]...)
+	if cmd == nil {
+		return result, errors.New("Nil command cannot be executed")
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		result.ExitCode = exitErr.ExitCode()
+	} else if err == nil {
+		result.ExitCode = 0
+	}
+	return result, err
+}
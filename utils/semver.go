@@ -0,0 +1,330 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var semVerRegexp = regexp.MustCompile(`^v?([0-9]+)(\.([0-9]+))?(\.([0-9]+))?(-([0-9A-Za-z\-]+(\.[0-9A-Za-z\-]+)*))?(\+([0-9A-Za-z\-]+(\.[0-9A-Za-z\-]+)*))?$`)
+
+// Semantic version structure
+type SemVer struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	PreRelease string
+	Metadata   string
+	original   string
+}
+
+// Parse a semantic version string, missing minor and patch numbers are considered zero
+func ParseSemVer(v string) (*SemVer, error) {
+	var m = semVerRegexp.FindStringSubmatch(strings.TrimSpace(v))
+	if m == nil {
+		return nil, errors.New(fmt.Sprintf("Invalid semantic version: %s", v))
+	}
+	var sv = &SemVer{
+		PreRelease: m[7],
+		Metadata:   m[10],
+		original:   v,
+	}
+	sv.Major, _ = strconv.ParseUint(m[1], 10, 64)
+	if m[3] != "" {
+		sv.Minor, _ = strconv.ParseUint(m[3], 10, 64)
+	}
+	if m[5] != "" {
+		sv.Patch, _ = strconv.ParseUint(m[5], 10, 64)
+	}
+	return sv, nil
+}
+
+// Verify if the version is a pre-release version
+func (v *SemVer) IsPreRelease() bool {
+	return v.PreRelease != ""
+}
+
+// Original version string
+func (v *SemVer) Original() string {
+	return v.original
+}
+
+func (v *SemVer) String() string {
+	var s = fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.PreRelease != "" {
+		s += "-" + v.PreRelease
+	}
+	if v.Metadata != "" {
+		s += "+" + v.Metadata
+	}
+	return s
+}
+
+// Compare two semantic versions, returning -1, 0 or 1. Build metadata is ignored, as required by the specification
+func (v *SemVer) Compare(o *SemVer) int {
+	if d := compareUint(v.Major, o.Major); d != 0 {
+		return d
+	}
+	if d := compareUint(v.Minor, o.Minor); d != 0 {
+		return d
+	}
+	if d := compareUint(v.Patch, o.Patch); d != 0 {
+		return d
+	}
+	return comparePreRelease(v.PreRelease, o.PreRelease)
+}
+
+func compareUint(a uint64, b uint64) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+func comparePreRelease(a string, b string) int {
+	if a == b {
+		return 0
+	}
+	// A version without pre-release has higher precedence
+	if a == "" {
+		return 1
+	}
+	if b == "" {
+		return -1
+	}
+	var aParts = strings.Split(a, ".")
+	var bParts = strings.Split(b, ".")
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		aNum, aErr := strconv.ParseUint(aParts[i], 10, 64)
+		bNum, bErr := strconv.ParseUint(bParts[i], 10, 64)
+		switch {
+		case aErr == nil && bErr == nil:
+			if d := compareUint(aNum, bNum); d != 0 {
+				return d
+			}
+		case aErr == nil:
+			// Numeric identifiers have lower precedence than alphanumeric ones
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if d := strings.Compare(aParts[i], bParts[i]); d != 0 {
+				return d
+			}
+		}
+	}
+	return compareUint(uint64(len(aParts)), uint64(len(bParts)))
+}
+
+// Sort a list of version strings by semantic version, invalid versions follow in lexicographic order
+func SortSemVerStrings(versions []string) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		return CompareSemVerStrings(versions[i], versions[j]) < 0
+	})
+}
+
+// Compare two version strings by semantic version, invalid versions follow in lexicographic order
+func CompareSemVerStrings(a string, b string) int {
+	va, errA := ParseSemVer(a)
+	vb, errB := ParseSemVer(b)
+	switch {
+	case errA == nil && errB == nil:
+		if d := va.Compare(vb); d != 0 {
+			return d
+		}
+		return strings.Compare(va.Metadata, vb.Metadata)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+type semVerComparator struct {
+	operator string
+	version  *SemVer
+}
+
+func (c semVerComparator) check(v *SemVer) bool {
+	var d = v.Compare(c.version)
+	switch c.operator {
+	case "=", "":
+		return d == 0
+	case "!=":
+		return d != 0
+	case ">":
+		return d > 0
+	case ">=":
+		return d >= 0
+	case "<":
+		return d < 0
+	case "<=":
+		return d <= 0
+	}
+	return false
+}
+
+// Semantic version constraint, composed by alternative (||) sets of comparators to be all satisfied
+type SemVerConstraint struct {
+	sets     [][]semVerComparator
+	original string
+}
+
+var semVerConstraintRegexp = regexp.MustCompile(`^(=|!=|>=|<=|>|<|\^|~>|~)?\s*v?([0-9xX*]+)(\.([0-9xX*]+))?(\.([0-9xX*]+))?(-([0-9A-Za-z\-.]+))?(\+[0-9A-Za-z\-.]+)?$`)
+
+// Parse a semantic version constraint, supporting comparison operators (=, !=, >, >=, <, <=), caret (^1.2),
+// tilde (~2.0), wildcards (1.2.x, *), hyphen ranges (1.0 - 2.0), space or comma separated and-ed comparators and || alternatives
+func ParseSemVerConstraint(c string) (*SemVerConstraint, error) {
+	var constraint = &SemVerConstraint{
+		sets:     make([][]semVerComparator, 0),
+		original: c,
+	}
+	for _, alternative := range strings.Split(c, "||") {
+		var set = make([]semVerComparator, 0)
+		var tokens = strings.Fields(strings.ReplaceAll(alternative, ",", " "))
+		for i := 0; i < len(tokens); i++ {
+			var token = tokens[i]
+			// Join operators separated by spaces from their version (e.g.: >= 1.0)
+			if strings.Trim(token, "=!<>^~") == "" && i+1 < len(tokens) {
+				i++
+				token += tokens[i]
+			}
+			// Hyphen range
+			if i+2 < len(tokens) && tokens[i+1] == "-" {
+				lower, err := expandSemVerComparator(">=" + token)
+				if err != nil {
+					return nil, err
+				}
+				upper, err := expandSemVerComparator("<=" + tokens[i+2])
+				if err != nil {
+					return nil, err
+				}
+				set = append(set, lower...)
+				set = append(set, upper...)
+				i += 2
+				continue
+			}
+			comparators, err := expandSemVerComparator(token)
+			if err != nil {
+				return nil, err
+			}
+			set = append(set, comparators...)
+		}
+		constraint.sets = append(constraint.sets, set)
+	}
+	return constraint, nil
+}
+
+func isSemVerWildcard(s string) bool {
+	return s == "" || s == "x" || s == "X" || s == "*"
+}
+
+// Expand a single constraint token in a list of simple comparators
+func expandSemVerComparator(token string) ([]semVerComparator, error) {
+	var m = semVerConstraintRegexp.FindStringSubmatch(token)
+	if m == nil {
+		return nil, errors.New(fmt.Sprintf("Invalid semantic version constraint: %s", token))
+	}
+	var operator = m[1]
+	var out = make([]semVerComparator, 0)
+	if isSemVerWildcard(m[2]) {
+		// Any version
+		return out, nil
+	}
+	var major, _ = strconv.ParseUint(m[2], 10, 64)
+	var minor, patch uint64
+	var minorWild = isSemVerWildcard(m[4])
+	var patchWild = minorWild || isSemVerWildcard(m[6])
+	if !minorWild {
+		minor, _ = strconv.ParseUint(m[4], 10, 64)
+	}
+	if !patchWild {
+		patch, _ = strconv.ParseUint(m[6], 10, 64)
+	}
+	var base = &SemVer{Major: major, Minor: minor, Patch: patch, PreRelease: m[8]}
+	var lower = func(v *SemVer) semVerComparator { return semVerComparator{">=", v} }
+	var upper = func(v *SemVer) semVerComparator { return semVerComparator{"<", v} }
+	switch operator {
+	case "^":
+		var limit *SemVer
+		switch {
+		case major > 0 || minorWild:
+			limit = &SemVer{Major: major + 1}
+		case minor > 0 || patchWild:
+			limit = &SemVer{Minor: minor + 1}
+		default:
+			limit = &SemVer{Patch: patch + 1}
+		}
+		return append(out, lower(base), upper(limit)), nil
+	case "~", "~>":
+		if minorWild {
+			return append(out, lower(base), upper(&SemVer{Major: major + 1})), nil
+		}
+		return append(out, lower(base), upper(&SemVer{Major: major, Minor: minor + 1})), nil
+	case "", "=":
+		if minorWild {
+			return append(out, lower(base), upper(&SemVer{Major: major + 1})), nil
+		}
+		if patchWild {
+			return append(out, lower(base), upper(&SemVer{Major: major, Minor: minor + 1})), nil
+		}
+		return append(out, semVerComparator{"=", base}), nil
+	case "!=":
+		if minorWild {
+			return nil, errors.New(fmt.Sprintf("Wildcard not supported with != operator: %s", token))
+		}
+		return append(out, semVerComparator{"!=", base}), nil
+	case ">":
+		if minorWild {
+			return append(out, lower(&SemVer{Major: major + 1})), nil
+		}
+		if patchWild {
+			return append(out, lower(&SemVer{Major: major, Minor: minor + 1})), nil
+		}
+		return append(out, semVerComparator{">", base}), nil
+	case "<=":
+		if minorWild {
+			return append(out, upper(&SemVer{Major: major + 1})), nil
+		}
+		if patchWild {
+			return append(out, upper(&SemVer{Major: major, Minor: minor + 1})), nil
+		}
+		return append(out, semVerComparator{"<=", base}), nil
+	default:
+		return append(out, semVerComparator{operator, base}), nil
+	}
+}
+
+// Verify if a version satisfies the constraint. Pre-release versions are accepted only
+// by comparators on the same major, minor and patch numbers explicitly declaring a pre-release
+func (c *SemVerConstraint) Check(v *SemVer) bool {
+	for _, set := range c.sets {
+		var valid = true
+		var preReleaseAllowed = !v.IsPreRelease()
+		for _, comp := range set {
+			if !comp.check(v) {
+				valid = false
+				break
+			}
+			if comp.version.IsPreRelease() && comp.version.Major == v.Major &&
+				comp.version.Minor == v.Minor && comp.version.Patch == v.Patch {
+				preReleaseAllowed = true
+			}
+		}
+		if valid && preReleaseAllowed {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *SemVerConstraint) String() string {
+	return c.original
+}
@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSemVerConstraintCheck(t *testing.T) {
+	for _, c := range []struct {
+		constraint string
+		version    string
+		expected   bool
+	}{
+		{"^0.2.3", "0.2.3", true},
+		{"^0.2.3", "0.2.9", true},
+		{"^0.2.3", "0.3.0", false},
+		{"^0.2.3", "0.2.2", false},
+		{"^0.0.3", "0.0.4", false},
+		{"^1.2", "1.2.0", true},
+		{"^1.2", "1.9.9", true},
+		{"^1.2", "2.0.0", false},
+		{"^1.2", "1.1.9", false},
+		{"~1.2.3", "1.2.9", true},
+		{"~1.2.3", "1.3.0", false},
+		{"~>1.2", "1.2.5", true},
+		{"~>1.2", "1.3.0", false},
+		{"~2.0.x", "2.0.7", true},
+		{"~2.0.x", "2.1.0", false},
+		{"~1", "1.9.0", true},
+		{"~1", "2.0.0", false},
+		{"1.2.x", "1.2.4", true},
+		{"1.2.x", "1.3.0", false},
+		{"1.x", "1.9.0", true},
+		{"1.X", "2.0.0", false},
+		{"*", "3.4.5", true},
+		{"x", "0.0.1", true},
+		{">= 1.0, <2.0", "1.5.0", true},
+		{">= 1.0, <2.0", "2.0.0", false},
+		{">= 1.0, <2.0", "0.9.9", false},
+		{">=1.0 <2.0", "1.0.0", true},
+		{"1.0 - 2.0", "1.0.0", true},
+		{"1.0 - 2.0", "2.0.0", true},
+		{"1.0 - 2.0", "2.0.9", true},
+		{"1.0 - 2.0", "2.1.0", false},
+		{"1.0.0 - 2.0.0", "2.0.1", false},
+		{"1.0 - 2.0", "0.9.0", false},
+		{"<1.0 || >=3.0", "0.5.0", true},
+		{"<1.0 || >=3.0", "2.0.0", false},
+		{"<1.0 || >=3.0", "3.1.0", true},
+		{"!=1.2.3", "1.2.3", false},
+		{"!=1.2.3", "1.2.4", true},
+		{">1.2", "1.2.9", false},
+		{">1.2", "1.3.0", true},
+		{"<=1.2", "1.2.9", true},
+		{"<=1.2", "1.3.0", false},
+		{"=1.2.3", "1.2.3", true},
+		{"=1.2.3", "1.2.3+build.1", true},
+		// Pre-release versions match only comparators declaring a pre-release on the same version
+		{"^1.2.0", "1.2.3-rc.1", false},
+		{"^1.2.0", "1.3.0-beta", false},
+		{">=1.2.3-rc.0", "1.2.3-rc.1", true},
+		{">=1.2.3-rc.0", "1.2.4-rc.1", false},
+		{">=1.2.3-rc.0", "1.2.4", true},
+		{"^1.2.3-rc.1", "1.2.3", true},
+		{"^1.2.3-rc.1", "1.2.3-rc.0", false},
+	} {
+		cs, err := ParseSemVerConstraint(c.constraint)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %q: %v", c.constraint, err)
+		}
+		v, err := ParseSemVer(c.version)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing %q: %v", c.version, err)
+		}
+		if cs.Check(v) != c.expected {
+			t.Fatalf("Expected %q check of %q %v", c.constraint, c.version, c.expected)
+		}
+	}
+}
+
+func TestParseSemVerConstraintInvalid(t *testing.T) {
+	for _, c := range []string{"abc", ">=", "1.2.3.4", "!=1.x", "1.0 - abc"} {
+		if _, err := ParseSemVerConstraint(c); err == nil {
+			t.Fatalf("Expected error parsing %q", c)
+		}
+	}
+}
+
+func TestParseSemVer(t *testing.T) {
+	v, err := ParseSemVer("v1.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.String() != "1.2.0" || v.IsPreRelease() || v.Original() != "v1.2" {
+		t.Fatalf("Unexpected version %s", v)
+	}
+	v, err = ParseSemVer("1.2.3-alpha.1+build.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.PreRelease != "alpha.1" || v.Metadata != "build.5" || !v.IsPreRelease() {
+		t.Fatalf("Unexpected version %+v", v)
+	}
+	if _, err := ParseSemVer("1.2.3.4"); err == nil {
+		t.Fatal("Expected invalid version error")
+	}
+}
+
+func TestSortSemVerStrings(t *testing.T) {
+	var versions = []string{"latest", "1.10.0", "1.2.0", "1.2.0-rc.1", "1.2.0-alpha", "1.2.0-alpha.10", "1.2.0-alpha.2", "0.9", "beta", "1.2.0-1"}
+	SortSemVerStrings(versions)
+	var expected = []string{"0.9", "1.2.0-1", "1.2.0-alpha", "1.2.0-alpha.2", "1.2.0-alpha.10", "1.2.0-rc.1", "1.2.0", "1.10.0", "beta", "latest"}
+	if strings.Join(versions, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected %v, found %v", expected, versions)
+	}
+}
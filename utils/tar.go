@@ -3,6 +3,8 @@ package utils
 import (
 	"archive/tar"
 	"compress/gzip"
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
@@ -11,6 +13,16 @@ import (
 	"strings"
 )
 
+// Compose the path of an archive entry in the target folder, entries escaping the target folder are reported as error
+func archiveEntryPath(target string, name string) (string, error) {
+	var base = filepath.Clean(target)
+	var path = filepath.Join(base, name)
+	if path != base && !strings.HasPrefix(path, base+string(filepath.Separator)) {
+		return "", errors.New(fmt.Sprintf("Archive entry %s is outside of the target folder", name))
+	}
+	return path, nil
+}
+
 func addFileToTar(tw *tar.Writer, path string) error {
 	file, err := os.Open(path)
 	if err != nil {
@@ -154,16 +166,28 @@ func TarUnCompress(archive, target string, uncompress bool) error {
 	}
 	header, err := tr.Next()
 	for header != nil && err == nil {
-		path := filepath.Join(target, header.Name)
+		path, pErr := archiveEntryPath(target, header.Name)
+		if pErr != nil {
+			return pErr
+		}
 		if header.FileInfo().IsDir() {
 			var mode = os.FileMode(header.Mode)
 			_ = os.MkdirAll(path, mode)
+			header, err = tr.Next()
+			continue
+		}
+		// Symbolic links, hard links and special files are not extracted
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			header, err = tr.Next()
 			continue
 		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
 		var mode = os.FileMode(header.Mode)
 		size := header.Size
 		var b = make([]byte, size)
-		_, err := tr.Read(b)
+		_, err := io.ReadFull(tr, b)
 		if err != nil {
 			return err
 		}
@@ -173,6 +197,9 @@ func TarUnCompress(archive, target string, uncompress bool) error {
 		}
 		header, err = tr.Next()
 	}
+	if err != nil && err != io.EOF {
+		return err
+	}
 
 	return nil
 }
@@ -202,7 +229,10 @@ func TarUnCompressFilter(archive, target string, uncompress bool, filter string)
 	header, err := tr.Next()
 	for header != nil && err == nil {
 		if strings.Contains(header.Name, filter) {
-			path := filepath.Join(target, header.Name)
+			path, pErr := archiveEntryPath(target, header.Name)
+			if pErr != nil {
+				return pErr
+			}
 			if header.FileInfo().IsDir() {
 				if strings.Contains(header.Name, filter) {
 					var mode = os.FileMode(header.Mode)
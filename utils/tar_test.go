@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type archiveEntry struct {
+	name     string
+	body     string
+	typeflag byte
+	link     string
+}
+
+func writeTestTarGz(t *testing.T, file string, entries []archiveEntry) {
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	defer gw.Close()
+	tw := tar.NewWriter(gw)
+	defer tw.Close()
+	for _, e := range entries {
+		var typeflag = e.typeflag
+		if typeflag == 0 {
+			typeflag = tar.TypeReg
+		}
+		err := tw.WriteHeader(&tar.Header{
+			Name:     e.name,
+			Mode:     0644,
+			Size:     int64(len(e.body)),
+			Typeflag: typeflag,
+			Linkname: e.link,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestTarUnCompressExtractsFiles(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "tar-test")
+	defer os.RemoveAll(dir)
+	var archive = filepath.Join(dir, "chart.tgz")
+	writeTestTarGz(t, archive, []archiveEntry{
+		{name: "chart/Chart.yaml", body: "name: chart\n"},
+		{name: "chart/templates/cm.yaml", body: "kind: ConfigMap\n"},
+	})
+	var target = filepath.Join(dir, "out")
+	if err := TarUnCompress(archive, target, true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(target, "chart", "templates", "cm.yaml"))
+	if err != nil || string(data) != "kind: ConfigMap\n" {
+		t.Fatalf("Unexpected extracted file content: %q, error: %v", string(data), err)
+	}
+}
+
+func TestTarUnCompressRejectsPathTraversal(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "tar-test")
+	defer os.RemoveAll(dir)
+	var archive = filepath.Join(dir, "slip.tgz")
+	writeTestTarGz(t, archive, []archiveEntry{
+		{name: "chart/Chart.yaml", body: "name: chart\n"},
+		{name: "../../slip_marker", body: "slip"},
+	})
+	var target = filepath.Join(dir, "a", "b", "out")
+	if err := TarUnCompress(archive, target, true); err == nil {
+		t.Fatal("Expected error for entry outside of the target folder")
+	}
+	if ExistsFileOrFolder(filepath.Join(dir, "a", "slip_marker")) {
+		t.Fatal("Entry written outside of the target folder")
+	}
+}
+
+func TestTarUnCompressSkipsLinks(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "tar-test")
+	defer os.RemoveAll(dir)
+	var archive = filepath.Join(dir, "links.tgz")
+	writeTestTarGz(t, archive, []archiveEntry{
+		{name: "chart/link", typeflag: tar.TypeSymlink, link: "/etc"},
+		{name: "chart/hard", typeflag: tar.TypeLink, link: "/etc/passwd"},
+		{name: "chart/Chart.yaml", body: "name: chart\n"},
+	})
+	var target = filepath.Join(dir, "out")
+	if err := TarUnCompress(archive, target, true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, name := range []string{"link", "hard"} {
+		if _, err := os.Lstat(filepath.Join(target, "chart", name)); err == nil {
+			t.Fatalf("Link entry %s extracted", name)
+		}
+	}
+	if !ExistsFileOrFolder(filepath.Join(target, "chart", "Chart.yaml")) {
+		t.Fatal("Regular file not extracted")
+	}
+}
+
+func TestZipUnCompressRejectsPathTraversal(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "zip-test")
+	defer os.RemoveAll(dir)
+	var archive = filepath.Join(dir, "slip.zip")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	w, _ := zw.Create("../../slip_marker")
+	_, _ = w.Write([]byte("slip"))
+	_ = zw.Close()
+	_ = f.Close()
+	var target = filepath.Join(dir, "a", "b", "out")
+	if err := ZipUnCompress(archive, target); err == nil {
+		t.Fatal("Expected error for entry outside of the target folder")
+	}
+	if ExistsFileOrFolder(filepath.Join(dir, "a", "slip_marker")) {
+		t.Fatal("Entry written outside of the target folder")
+	}
+}
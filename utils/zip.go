@@ -100,12 +100,22 @@ func ZipUnCompress(archive, target string) error {
 	}
 
 	for _, file := range reader.File {
-		path := filepath.Join(target, file.Name)
+		path, err := archiveEntryPath(target, file.Name)
+		if err != nil {
+			return err
+		}
 		if file.FileInfo().IsDir() {
 			os.MkdirAll(path, file.Mode())
 			continue
 		}
+		// Symbolic links and special files are not extracted
+		if !file.Mode().IsRegular() {
+			continue
+		}
 
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
 		fileReader, err := file.Open()
 		if err != nil {
 			return err